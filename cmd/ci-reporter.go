@@ -17,34 +17,462 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strings"
-	"sync"
+	"time"
 
 	ci_reporter "github.com/leonardpahlke/ci-signal-report/pkg/ci-reporter"
 )
 
+// logger logs the subcommands that are run without the flags of the report (like diff), those set the logger via -log-level
+var logger = ci_reporter.NewLogger(os.Stderr, ci_reporter.LogLevelInfo)
+
 func main() {
+	// subcommands like 'ci-reporter schema print', flags like 'ci-reporter -short' run the report
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		runSubcommand(os.Args[1], os.Args[2:])
+		return
+	}
+	runReport()
+}
+
+func runSubcommand(name string, args []string) {
+	switch name {
+	case "schema":
+		if len(args) == 0 || args[0] != "print" {
+			ci_reporter.Fatalf("Usage: ci-reporter schema print")
+		}
+		fmt.Println(string(ci_reporter.ReportSchema()))
+	case "validate":
+		if len(args) != 1 {
+			ci_reporter.Fatalf("Usage: ci-reporter validate report.json")
+		}
+		violations, err := ci_reporter.ValidateReportFile(args[0])
+		if err != nil {
+			ci_reporter.Fatalf("Error validating report.\n[ERROR] %v", err)
+		}
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+		if len(violations) > 0 {
+			ci_reporter.Fatalf("%s does not match report schema v%s (%d violations)", args[0], ci_reporter.ReportSchemaVersion, len(violations))
+		}
+		fmt.Printf("%s matches report schema v%s\n", args[0], ci_reporter.ReportSchemaVersion)
+	case "serve":
+		meta := ci_reporter.SetMetaFromArgs(args)
+		if err := ci_reporter.Serve(meta); err != nil {
+			ci_reporter.Fatalf("Error serving metrics.\n[ERROR] %v", err)
+		}
+	case "cycle-report":
+		runCycleReport(args)
+	case "promotion":
+		runPromotion(args)
+	case "check":
+		runCheck(args)
+	case "trends":
+		runTrends(args)
+	case "handoff":
+		runHandoff(args)
+	case "diff":
+		runDiff(args)
+	case "simulate":
+		runSimulate(args)
+	case "ack":
+		runAck(args)
+	case "compare":
+		runCompare(args)
+	default:
+		ci_reporter.Fatalf("Unknown subcommand %q, options: [schema, validate, serve, cycle-report, promotion, check, trends, handoff, diff, simulate, ack, compare]", name)
+	}
+}
+
+// runCheck prints which conditions of the signal health tripped in json format and exits non-zero if the tree is not healthy enough to cut
+func runCheck(args []string) {
+	meta := ci_reporter.SetMetaFromArgs(args)
+	if len(meta.Flags.FailOn) == 0 {
+		conditions, err := ci_reporter.ParseFailConditions(ci_reporter.DefaultFailOn)
+		if err != nil {
+			ci_reporter.Fatalf("Error parsing default check conditions.\n[ERROR] %v", err)
+		}
+		meta.Flags.FailOn = conditions
+	}
+	report, _, err := meta.RequestReport(context.Background())
+	if err != nil {
+		ci_reporter.Fatalf("Error requesting report data.\n[ERROR] %v", err)
+	}
+	exitOnUnhealthySignal(report.Check(meta.Flags.FailOn), os.Stdout)
+}
+
+// exitOnUnhealthySignal writes the check result in json format and exits with ci_reporter.CheckUnhealthyExitCode if a condition tripped
+func exitOnUnhealthySignal(result ci_reporter.CheckResult, w io.Writer) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		ci_reporter.Fatalf("Error marshaling check result.\n[ERROR] %v", err)
+	}
+	fmt.Fprintln(w, string(data))
+	if !result.Healthy {
+		os.Exit(ci_reporter.CheckUnhealthyExitCode)
+	}
+}
+
+// runHandoff prints the markdown handoff document of the CI signal shift that ends now
+func runHandoff(args []string) {
+	meta := ci_reporter.SetMetaFromArgs(args)
+	handoff, err := ci_reporter.RequestHandoff(meta, meta.Flags.Since, time.Now())
+	if err != nil {
+		ci_reporter.Fatalf("Error requesting handoff data.\n[ERROR] %v", err)
+	}
+	fmt.Print(handoff)
+}
+
+// runDiff prints the records that have been added, removed or changed between two snapshots as text or json
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	// -snapshot-dir default: "" (the two newest snapshots of the directory are compared if no files are given)
+	snapshotDir := fs.String("snapshot-dir", "", "directory the report snapshots have been stored in, the two newest snapshots are compared")
+	// -output default: text
+	output := fs.String("output", "text", "output format, options: 'text', 'json'")
+	if err := fs.Parse(args); err != nil {
+		ci_reporter.Fatalf("Error parsing diff flags.\n[ERROR] %v", err)
+	}
+	paths := fs.Args()
+	if *snapshotDir != "" && len(paths) == 0 {
+		snapshotPaths, err := ci_reporter.ListSnapshots(*snapshotDir)
+		if err != nil {
+			ci_reporter.Fatalf("Error reading report snapshots.\n[ERROR] %v", err)
+		}
+		if len(snapshotPaths) < 2 {
+			ci_reporter.Fatalf("At least two snapshots are needed in %s to compare them, found %d", *snapshotDir, len(snapshotPaths))
+		}
+		paths = snapshotPaths[len(snapshotPaths)-2:]
+	}
+	if len(paths) != 2 || (*output != "text" && *output != "json") {
+		ci_reporter.Fatalf("Usage: ci-reporter diff [-output text|json] (-snapshot-dir DIR | OLD NEW)")
+	}
+	snapshots := []ci_reporter.Snapshot{}
+	for _, path := range paths {
+		snapshot, err := ci_reporter.ReadSnapshot(logger, path)
+		if err != nil {
+			ci_reporter.Fatalf("Error reading report snapshot %s.\n[ERROR] %v", path, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	diff := ci_reporter.DiffSnapshots(snapshots[0], snapshots[1])
+	if *output == "json" {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			ci_reporter.Fatalf("Error marshaling report diff.\n[ERROR] %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(diff)
+}
+
+// runSimulate replays the severity flags and check conditions against the snapshots of the -since window and prints which runs would have paged
+func runSimulate(args []string) {
+	meta := ci_reporter.SetMetaFromArgs(args)
+	if meta.Flags.SnapshotDir == "" {
+		ci_reporter.Fatalf("Usage: ci-reporter simulate -snapshot-dir DIR [-since 168h] [-threshold-warning 0.5] [-fail-on high-severity] [-output text|json]")
+	}
+	if len(meta.Flags.FailOn) == 0 {
+		conditions, err := ci_reporter.ParseFailConditions(ci_reporter.DefaultFailOn)
+		if err != nil {
+			ci_reporter.Fatalf("Error parsing default check conditions.\n[ERROR] %v", err)
+		}
+		meta.Flags.FailOn = conditions
+	}
+	snapshots, err := ci_reporter.LoadSnapshots(meta.Logger, meta.Flags.SnapshotDir, time.Now().Add(-meta.Flags.Since), time.Time{})
+	if err != nil {
+		ci_reporter.Fatalf("Error reading report snapshots.\n[ERROR] %v", err)
+	}
+	simulation := ci_reporter.Simulate(snapshots, meta.Flags.Severity, meta.Flags.FailOn)
+	if meta.Flags.JSONOut {
+		data, err := json.MarshalIndent(simulation, "", "  ")
+		if err != nil {
+			ci_reporter.Fatalf("Error marshaling simulation.\n[ERROR] %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(simulation)
+}
+
+// runAck adds, removes or lists the acknowledged known failures of the acks file
+func runAck(args []string) {
+	fs := flag.NewFlagSet("ack", flag.ExitOnError)
+	// -acks default: acks.yaml
+	acksPath := fs.String("acks", "acks.yaml", "yaml file with the acknowledged known failures")
+	// -reason default: "" (why the failure is accepted, like "tracked in #105242")
+	reason := fs.String("reason", "", "why the failure is accepted")
+	// -for default: 336h (two weeks)
+	duration := fs.Duration("for", 14*24*time.Hour, "time the failure is acknowledged for, the ack expires at the end of that day")
+	// -expires default: "" (last day the ack applies, overrides -for)
+	expires := fs.String("expires", "", "last day the ack applies (YYYY-MM-DD), overrides -for")
+	// -by default: "" (who acknowledged the failure)
+	by := fs.String("by", "", "who acknowledged the failure")
+	// -remove default: false
+	remove := fs.Bool("remove", false, "remove the ack of the job or issue")
+	// -prune default: false
+	prune := fs.Bool("prune", false, "remove all expired acks")
+	if err := fs.Parse(args); err != nil {
+		ci_reporter.Fatalf("Error parsing ack flags.\n[ERROR] %v", err)
+	}
+	acks, err := ci_reporter.LoadAcks(*acksPath)
+	if err != nil && !os.IsNotExist(err) {
+		ci_reporter.Fatalf("Error loading acknowledgements.\n[ERROR] %v", err)
+	}
+	now := time.Now()
+	switch {
+	case *prune:
+		var pruned int
+		acks, pruned = ci_reporter.PruneAcks(acks, now)
+		fmt.Printf("Removed %d expired acks\n", pruned)
+	case fs.NArg() == 0:
+		fmt.Print(ci_reporter.AcksText(acks, now))
+		return
+	case fs.NArg() != 1:
+		ci_reporter.Fatalf("Usage: ci-reporter ack [-acks acks.yaml] [-reason TEXT] [-for 336h | -expires YYYY-MM-DD] [-by NAME] [-remove] <dashboard/job|#issue>")
+	case *remove:
+		var ok bool
+		if acks, ok = ci_reporter.RemoveAck(acks, fs.Arg(0)); !ok {
+			ci_reporter.Fatalf("No ack found for %s in %s", fs.Arg(0), *acksPath)
+		}
+		fmt.Printf("Removed ack of %s\n", fs.Arg(0))
+	default:
+		ack := ci_reporter.Ack{Target: fs.Arg(0), Reason: *reason, Expires: *expires, By: *by}
+		if ack.Expires == "" {
+			ack.Expires = now.Add(*duration).Format("2006-01-02")
+		}
+		if acks, err = ci_reporter.SetAck(acks, ack); err != nil {
+			ci_reporter.Fatalf("Information given via flags is invalid.\n[ERROR] %v", err)
+		}
+		fmt.Printf("Acknowledged %s until %s\n", ack.Target, ack.Expires)
+	}
+	if err := ci_reporter.SaveAcks(*acksPath, acks); err != nil {
+		ci_reporter.Fatalf("Error storing acknowledgements.\n[ERROR] %v", err)
+	}
+}
+
+// runCompare prints the status of the jobs of the dashboards of multiple release branches as matrix
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	// -branches default: "" (release branches that are compared, like "master, 1.29, 1.28")
+	branches := fs.String("branches", "", "release branches whose dashboards are compared (like -branches 'master, 1.29, 1.28')")
+	// -dashboard default: blocking
+	dashboard := fs.String("dashboard", "blocking", "kind of the dashboards that are compared, options: 'blocking', 'informing'")
+	// -testgrid-url default: https://testgrid.k8s.io
+	testgridURL := fs.String("testgrid-url", "https://testgrid.k8s.io", "Base url of the testgrid instance")
+	// -output default: text
+	output := fs.String("output", "text", "output format, options: 'text', 'json'")
+	if err := fs.Parse(args); err != nil {
+		ci_reporter.Fatalf("Error parsing compare flags.\n[ERROR] %v", err)
+	}
+	branchList := []string{}
+	for _, branch := range strings.Split(*branches, ",") {
+		if branch = strings.TrimPrefix(strings.TrimSpace(branch), "v"); branch != "" {
+			branchList = append(branchList, branch)
+		}
+	}
+	if len(branchList) < 2 || (*dashboard != "blocking" && *dashboard != "informing") || (*output != "text" && *output != "json") {
+		ci_reporter.Fatalf("Usage: ci-reporter compare -branches 'master, 1.29, 1.28' [-dashboard blocking|informing] [-output text|json]")
+	}
+	comparison, err := ci_reporter.RequestBranchComparison(nil, *testgridURL, *dashboard, branchList, len(branchList))
+	if err != nil {
+		ci_reporter.Fatalf("Error requesting dashboards from testgrid.\n[ERROR] %v", err)
+	}
+	if *output == "json" {
+		data, err := json.MarshalIndent(comparison, "", "  ")
+		if err != nil {
+			ci_reporter.Fatalf("Error marshaling branch comparison.\n[ERROR] %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(comparison)
+}
+
+// runTrends prints the week-over-week changes recorded with -store for the weekly CI signal summary
+func runTrends(args []string) {
+	fs := flag.NewFlagSet("trends", flag.ExitOnError)
+	// -store default: "" (store the runs have been recorded in, e.g. sqlite:ci-signal.db)
+	store := fs.String("store", "", "store the runs have been recorded in (like sqlite:ci-signal.db)")
+	// -window default: 168h
+	window := fs.Duration("window", ci_reporter.DefaultTrendWindow, "time between the compared runs")
+	if err := fs.Parse(args); err != nil {
+		ci_reporter.Fatalf("Error parsing trends flags.\n[ERROR] %v", err)
+	}
+	if *store == "" {
+		ci_reporter.Fatalf("Usage: ci-reporter trends -store sqlite:ci-signal.db [-window 168h]")
+	}
+	trendStore, err := ci_reporter.OpenTrendStore(*store)
+	if err != nil {
+		ci_reporter.Fatalf("Error opening trend store.\n[ERROR] %v", err)
+	}
+	defer trendStore.Close()
+	trends, err := trendStore.Trends(time.Now(), *window)
+	if err != nil {
+		ci_reporter.Fatalf("Error reading trends.\n[ERROR] %v", err)
+	}
+	fmt.Print(trends)
+}
+
+// runPromotion prints whether an informing job meets the criteria to be promoted to release-blocking
+func runPromotion(args []string) {
+	criteria := ci_reporter.DefaultPromotionCriteria()
+	fs := flag.NewFlagSet("promotion", flag.ExitOnError)
+	// -job default: "" (name of the job like gce-cos-master-serial)
+	job := fs.String("job", "", "Name of the job that should be promoted")
+	// -dashboard default: sig-release-master-informing
+	dashboard := fs.String("dashboard", "sig-release-master-informing", "Testgrid dashboard of the job")
+	// -testgrid-url default: https://testgrid.k8s.io
+	testgridURL := fs.String("testgrid-url", "https://testgrid.k8s.io", "Base url of the testgrid instance")
+	// -min-pass-rate default: 0.75
+	fs.Float64Var(&criteria.MinPassRate, "min-pass-rate", criteria.MinPassRate, "Share of runs of the last week that have to pass")
+	// -max-runtime default: 2h
+	fs.DurationVar(&criteria.MaxRuntime, "max-runtime", criteria.MaxRuntime, "Median time a run may take")
+	// -max-run-interval default: 3h
+	fs.DurationVar(&criteria.MaxRunInterval, "max-run-interval", criteria.MaxRunInterval, "Time that may pass between two runs")
+	// -max-consecutive-failures default: 10
+	fs.IntVar(&criteria.MaxConsecutiveFailures, "max-consecutive-failures", criteria.MaxConsecutiveFailures, "Number of runs in a row that may fail")
+	if err := fs.Parse(args); err != nil {
+		ci_reporter.Fatalf("Error parsing promotion flags.\n[ERROR] %v", err)
+	}
+	if *job == "" {
+		ci_reporter.Fatalf("Usage: ci-reporter promotion -job NAME [-dashboard sig-release-master-informing]")
+	}
+	assessment, err := ci_reporter.RequestPromotionAssessment(nil, *testgridURL, *dashboard, *job, criteria)
+	if err != nil {
+		ci_reporter.Fatalf("Error requesting job history from testgrid.\n[ERROR] %v", err)
+	}
+	fmt.Print(assessment)
+}
+
+// runCycleReport prints a markdown retrospective of the release cycle based on stored snapshots
+func runCycleReport(args []string) {
+	fs := flag.NewFlagSet("cycle-report", flag.ExitOnError)
+	// -snapshot-dir default: "" (directory the report snapshots have been stored in)
+	snapshotDir := fs.String("snapshot-dir", "", "directory the report snapshots have been stored in")
+	// -since default: "" (first day of the release cycle, e.g. 2021-08-23)
+	since := fs.String("since", "", "first day of the release cycle (YYYY-MM-DD), all snapshots if not set")
+	// -until default: "" (last day of the release cycle, e.g. 2021-12-07)
+	until := fs.String("until", "", "last day of the release cycle (YYYY-MM-DD), all snapshots if not set")
+	if err := fs.Parse(args); err != nil {
+		ci_reporter.Fatalf("Error parsing cycle-report flags.\n[ERROR] %v", err)
+	}
+	if *snapshotDir == "" {
+		ci_reporter.Fatalf("Usage: ci-reporter cycle-report -snapshot-dir DIR [-since YYYY-MM-DD] [-until YYYY-MM-DD]")
+	}
+	sinceTime := parseCycleDate(*since)
+	untilTime := parseCycleDate(*until)
+	if !untilTime.IsZero() {
+		// include snapshots of the last day
+		untilTime = untilTime.Add(24 * time.Hour)
+	}
+	snapshots, err := ci_reporter.LoadSnapshots(logger, *snapshotDir, sinceTime, untilTime)
+	if err != nil {
+		ci_reporter.Fatalf("Error reading report snapshots.\n[ERROR] %v", err)
+	}
+	fmt.Print(ci_reporter.CycleReport(snapshots))
+}
+
+func parseCycleDate(date string) time.Time {
+	if date == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		ci_reporter.Fatalf("Error parsing date %q, expected YYYY-MM-DD.\n[ERROR] %v", date, err)
+	}
+	return t
+}
+
+func runReport() {
+	start := time.Now()
 	meta := ci_reporter.SetMeta()
-	cireporters := meta.GetReporters()
+	if meta.Flags.Watch {
+		ci_reporter.Watch(meta)
+		return
+	}
+
+	// set up the console, -out files and notifiers before requesting data, so invalid templates fail fast
+	sinks := meta.GetSinks()
+	notifierSinks := meta.GetNotifierSinks()
 
 	// request report data
-	report := ci_reporter.Report{}
-	var wg sync.WaitGroup
-	for _, r := range cireporters {
-		wg.Add(1)
-		report = append(report, r.RequestData(meta, &wg))
+	report, cireporters, err := meta.RequestReport(context.Background())
+	// fatalf prints the key numbers of the run before exiting, so wrapper scripts get them on every exit path
+	fatalf := func(message string, err error) {
+		fmt.Fprintln(os.Stderr, report.RunSummary(time.Since(start), err))
+		ci_reporter.Fatalf("%s\n[ERROR] %v", message, err)
+	}
+	if err != nil {
+		fatalf("Error requesting report data.", err)
 	}
-	wg.Wait()
 
-	// print report data
-	if meta.Flags.JSONOut {
-		report.PrintJSON()
-	} else {
-		for _, r := range cireporters {
-			reportData := r.GetData()
-			fmt.Printf("\n%s REPORT\n", strings.ToUpper(reportData.Name))
-			r.Print(meta, reportData)
+	// write report data to the console and -out files, a failing sink does not keep the report from the others
+	if err := ci_reporter.WriteSinks(context.Background(), report, sinks...); err != nil {
+		fatalf("Error writing report.", err)
+	}
+
+	// store report data as snapshot, the previous snapshot is used to find new failures
+	var previousReport *ci_reporter.Report
+	if meta.Flags.SnapshotDir != "" {
+		previous, ok, err := ci_reporter.LatestSnapshot(meta.Logger, meta.Flags.SnapshotDir)
+		if err != nil {
+			fatalf("Error reading previous report snapshot.", err)
+		}
+		if ok {
+			previousReport = &previous.Report
+		}
+		snapshot := ci_reporter.Snapshot{GeneratedAt: time.Now(), Report: report}
+		if _, err := ci_reporter.WriteSnapshot(meta.Flags.SnapshotDir, snapshot, meta.Flags.SnapshotCompression); err != nil {
+			fatalf("Error storing report snapshot.", err)
+		}
+	}
+
+	// record job statuses and issue counts of this run for the trends subcommand
+	if meta.Flags.Store != "" {
+		trendStore, err := ci_reporter.OpenTrendStore(meta.Flags.Store)
+		if err != nil {
+			fatalf("Error opening trend store.", err)
+		}
+		if err := trendStore.RecordRun(meta.NewTrendRun(time.Now(), report, cireporters)); err != nil {
+			fatalf("Error recording run in trend store.", err)
+		}
+		trendStore.Close()
+	}
+
+	// notify after the snapshot and the trend store have been written, so a failing notifier does not lose the data of the run
+	if err := ci_reporter.WriteSinks(context.Background(), report, notifierSinks...); err != nil {
+		fatalf("Error sending report notifications.", err)
+	}
+
+	// send sig subscriptions their slice of the report
+	if meta.Flags.SubscriptionsFile != "" && meta.Flags.ReadOnly {
+		meta.Logger.Info("Read-only mode, sig subscriptions are not notified (set -read-only=false to notify them)")
+	} else if meta.Flags.SubscriptionsFile != "" {
+		subscriptions, err := ci_reporter.LoadSubscriptions(meta.Flags.SubscriptionsFile)
+		if err != nil {
+			fatalf("Error loading sig subscriptions.", err)
+		}
+		if err := ci_reporter.NotifySubscriptions(meta, subscriptions, report, previousReport); err != nil {
+			fatalf("Error sending sig subscription notifications.", err)
+		}
+	}
+
+	// key numbers of the run for wrapper scripts and cron emails, written to stderr to keep the report output intact
+	fmt.Fprintln(os.Stderr, report.RunSummary(time.Since(start), nil))
+
+	// gate automation (like the release cut) on the signal health, the result is written to stderr to keep the report output intact
+	if len(meta.Flags.FailOn) > 0 {
+		if result := report.Check(meta.Flags.FailOn); !result.Healthy {
+			exitOnUnhealthySignal(result, os.Stderr)
 		}
 	}
 }
@@ -5,5 +5,8 @@ go 1.16
 require (
 	github.com/google/go-github/v34 v34.0.0
 	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/klauspost/compress v1.13.6
 	golang.org/x/oauth2 v0.0.0-20180821212333-d2e6202438be
+	gopkg.in/yaml.v2 v2.4.0
+	modernc.org/sqlite v1.14.6
 )
@@ -0,0 +1,252 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// knownIssuesEmoji heading of the acknowledged records
+const knownIssuesEmoji = "\U0001F515"
+
+// Ack acknowledges a long-running failure that is tracked and accepted, set in the acks file (-acks)
+type Ack struct {
+	// Target testgrid job or test with the dashboard or section it is listed in (like "Master-Blocking/gce-cos-master-serial"),
+	// or the number of a github issue (like "#105242" or "kubernetes/kubernetes#105242")
+	Target string `yaml:"target" json:"target"`
+	// Reason why the failure is accepted (like "tracked in #105242, fixed with the etcd bump")
+	Reason string `yaml:"reason" json:"reason"`
+	// Expires last day (YYYY-MM-DD) the ack applies, the record is reported as usual again after that day
+	Expires string `yaml:"expires" json:"expires"`
+	// By who acknowledged the failure
+	By string `yaml:"by,omitempty" json:"by,omitempty"`
+}
+
+// KnownIssue a record that has been acknowledged, it is listed compactly instead of in its field
+type KnownIssue struct {
+	// FieldTitle title of the field the record has been taken from (like "Master-Blocking" or "kubernetes/kubernetes")
+	FieldTitle string           `json:"field_title"`
+	Record     ReportDataRecord `json:"record"`
+	Ack        Ack              `json:"ack"`
+}
+
+type acksFile struct {
+	Acks []Ack `yaml:"acks"`
+}
+
+// LoadAcks reads the acknowledgements of a yaml file
+func LoadAcks(path string) ([]Ack, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file acksFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, err
+	}
+	for i, a := range file.Acks {
+		if err := a.validate(); err != nil {
+			return nil, fmt.Errorf("ack %d: %v", i, err)
+		}
+	}
+	return file.Acks, nil
+}
+
+// SaveAcks writes the acknowledgements sorted by target to a yaml file
+func SaveAcks(path string, acks []Ack) error {
+	sorted := append([]Ack{}, acks...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Target < sorted[j].Target })
+	data, err := yaml.Marshal(acksFile{Acks: sorted})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (a Ack) validate() error {
+	if a.Target == "" || a.Reason == "" || a.Expires == "" {
+		return fmt.Errorf("target, reason and expires have to be set")
+	}
+	if _, err := time.Parse(cycleDateLayout, a.Expires); err != nil {
+		return fmt.Errorf("expires %q does not match YYYY-MM-DD", a.Expires)
+	}
+	// jobs of the same name run on several dashboards, the ack only applies to the one it names
+	if !strings.HasPrefix(a.Target, "#") && !strings.Contains(a.Target, "/") {
+		return fmt.Errorf("target %q has to name the dashboard of the job like Master-Blocking/%s, or the issue like #105242", a.Target, a.Target)
+	}
+	return nil
+}
+
+// Active tells if the ack applies on the day of now, acks apply until the end of the day they expire
+func (a Ack) Active(now time.Time) bool {
+	return now.Format(cycleDateLayout) <= a.Expires
+}
+
+// SetAck adds the ack or replaces the ack of the same target
+func SetAck(acks []Ack, ack Ack) ([]Ack, error) {
+	if err := ack.validate(); err != nil {
+		return nil, err
+	}
+	result := []Ack{}
+	for _, a := range acks {
+		if a.Target != ack.Target {
+			result = append(result, a)
+		}
+	}
+	return append(result, ack), nil
+}
+
+// RemoveAck removes the ack of the target, false is returned if there is none
+func RemoveAck(acks []Ack, target string) ([]Ack, bool) {
+	result := []Ack{}
+	for _, a := range acks {
+		if a.Target != target {
+			result = append(result, a)
+		}
+	}
+	return result, len(result) != len(acks)
+}
+
+// PruneAcks removes the acks that expired before the day of now and returns how many have been removed
+func PruneAcks(acks []Ack, now time.Time) ([]Ack, int) {
+	result := []Ack{}
+	for _, a := range acks {
+		if a.Active(now) {
+			result = append(result, a)
+		}
+	}
+	return result, len(acks) - len(result)
+}
+
+// ApplyAcks moves the records of active acks from their fields to the known issues of the report. Acks expire on their own,
+// records of expired acks stay in their field and get a note, so it is visible why they show up again
+func ApplyAcks(report Report, acks []Ack, now time.Time) Report {
+	acknowledged := Report{}
+	for _, reportData := range report {
+		fields := []ReportDataField{}
+		knownIssues := append([]KnownIssue{}, reportData.KnownIssues...)
+		for _, field := range reportData.Data {
+			records := []ReportDataRecord{}
+			for _, record := range field.Records {
+				ack, ok := matchingAck(reportData.Name, field.Title, record, acks)
+				if !ok {
+					records = append(records, record)
+					continue
+				}
+				if ack.Active(now) {
+					knownIssues = append(knownIssues, KnownIssue{FieldTitle: field.Title, Record: record, Ack: ack})
+					continue
+				}
+				record.Notes = append(append([]string{}, record.Notes...), fmt.Sprintf("Acknowledgement expired on %s: %s", ack.Expires, ack.Reason))
+				records = append(records, record)
+			}
+			// github issues are sent as one field per issue, fields of acknowledged issues are left out
+			if reportData.Name == githubReport && len(records) == 0 && len(field.Records) > 0 {
+				continue
+			}
+			field.Records = records
+			fields = append(fields, field)
+		}
+		reportData.Data = fields
+		if len(knownIssues) > 0 {
+			reportData.KnownIssues = knownIssues
+		}
+		acknowledged = append(acknowledged, reportData)
+	}
+	return acknowledged
+}
+
+// matchingAck returns the ack of the record, jobs and tests are referenced by "field/title" (like "Master-Blocking/gce-cos-master-serial"),
+// github issues by "#number" or "owner/repo#number"
+func matchingAck(reportName string, fieldTitle string, record ReportDataRecord, acks []Ack) (Ack, bool) {
+	if isSummaryRecord(reportName, record) {
+		return Ack{}, false
+	}
+	for _, a := range acks {
+		if reportName == githubReport {
+			if a.Target == fmt.Sprintf("#%d", record.ID) || a.Target == fmt.Sprintf("%s#%d", fieldTitle, record.ID) {
+				return a, true
+			}
+		} else if a.Target == fmt.Sprintf("%s/%s", fieldTitle, record.Title) {
+			return a, true
+		}
+	}
+	return Ack{}, false
+}
+
+// knownIssueLine describes a known issue in one line like "gce-cos-master-serial (Master-Blocking): tracked in #105242, until 2021-11-01"
+func knownIssueLine(reportName string, k KnownIssue) string {
+	title := fmt.Sprintf("%s (%s)", k.Record.Title, k.FieldTitle)
+	if reportName == githubReport {
+		title = fmt.Sprintf("%s#%d %s", k.FieldTitle, k.Record.ID, k.Record.Title)
+	}
+	line := fmt.Sprintf("%s: %s, until %s", title, k.Ack.Reason, k.Ack.Expires)
+	if k.Ack.By != "" {
+		line += fmt.Sprintf(" (acked by %s)", k.Ack.By)
+	}
+	return line
+}
+
+// knownIssuesCount number of known issues of all reports
+func (r Report) knownIssuesCount() int {
+	count := 0
+	for _, reportData := range r {
+		count += len(reportData.KnownIssues)
+	}
+	return count
+}
+
+// PrintKnownIssues prints the acknowledged records of all reports, nothing is printed if there are none
+func (r Report) PrintKnownIssues(meta Meta) {
+	count := r.knownIssuesCount()
+	if count == 0 {
+		return
+	}
+	c := meta.console()
+	c.Printf("\n%s\n", c.heading(knownIssuesEmoji, fmt.Sprintf("KNOWN ISSUES (%d)", count)))
+	for _, reportData := range r {
+		for _, k := range reportData.KnownIssues {
+			c.Printf("- %s\n", knownIssueLine(reportData.Name, k))
+		}
+	}
+	c.Println()
+}
+
+// AcksText lists the acks like "gce-cos-master-serial: tracked in #105242, until 2021-11-01 (expired)"
+func AcksText(acks []Ack, now time.Time) string {
+	if len(acks) == 0 {
+		return "No acknowledgements\n"
+	}
+	var sb strings.Builder
+	for _, a := range acks {
+		sb.WriteString(fmt.Sprintf("%s: %s, until %s", a.Target, a.Reason, a.Expires))
+		if a.By != "" {
+			sb.WriteString(fmt.Sprintf(" (acked by %s)", a.By))
+		}
+		if !a.Active(now) {
+			sb.WriteString(" (expired)")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
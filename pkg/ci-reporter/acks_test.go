@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadAndSaveAcks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acks.yaml")
+	data := `acks:
+- target: Master-Blocking/gce-cos-master-serial
+  reason: "tracked in #105242"
+  expires: "2021-11-01"
+  by: alice
+`
+	if err := ioutil.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	acks, err := LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acks, err = SetAck(acks, Ack{Target: "#97783", Reason: "waiting for the etcd bump", Expires: "2021-10-20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveAcks(path, acks); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LoadAcks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Ack{
+		{Target: "#97783", Reason: "waiting for the etcd bump", Expires: "2021-10-20"},
+		{Target: "Master-Blocking/gce-cos-master-serial", Reason: "tracked in #105242", Expires: "2021-11-01", By: "alice"},
+	}
+	if !reflect.DeepEqual(saved, expected) {
+		t.Errorf("expected %v, got %v", expected, saved)
+	}
+
+	pruned, n := PruneAcks(saved, time.Date(2021, 10, 21, 9, 0, 0, 0, time.UTC))
+	if n != 1 || len(pruned) != 1 || pruned[0].Target != "Master-Blocking/gce-cos-master-serial" {
+		t.Errorf("expected the ack of #97783 to be pruned, got %v", pruned)
+	}
+	if _, err := SetAck(nil, Ack{Target: "Master-Blocking/gce-cos-master-serial", Reason: "flaky", Expires: "next week"}); err == nil {
+		t.Error("expected an invalid expiry to be rejected")
+	}
+	if _, err := SetAck(nil, Ack{Target: "gce-cos-master-serial", Reason: "flaky", Expires: "2021-11-01"}); err == nil {
+		t.Error("expected a job without dashboard to be rejected")
+	}
+}
+
+func TestApplyAcks(t *testing.T) {
+	report := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Notes: []string{"1 of 2 jobs passed"}},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: string(failing)},
+			{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: string(flaky)},
+		}}, {Title: "Master-Informing", Records: []ReportDataRecord{
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: string(failing)},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{
+			{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 105242, Title: "[Failing Test] gce-cos-master-serial"}}},
+			{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 97783, Title: "[Flaky Test] Pods should be restarted"}}},
+		}},
+	}
+	acks := []Ack{
+		{Target: "Master-Blocking/gce-cos-master-serial", Reason: "tracked in #105242", Expires: "2021-11-01"},
+		{Target: "kubernetes/kubernetes#105242", Reason: "fix lands with the etcd bump", Expires: "2021-11-01"},
+		{Target: "Master-Blocking/gce-cos-master-default", Reason: "flaky since the node image update", Expires: "2021-10-15"},
+	}
+	acknowledged := ApplyAcks(report, acks, time.Date(2021, 10, 20, 9, 0, 0, 0, time.UTC))
+
+	testgrid := acknowledged[0]
+	if len(testgrid.Data[0].Records) != 2 || testgrid.Data[0].Records[1].Title != "gce-cos-master-default" {
+		t.Fatalf("expected the acknowledged job to be moved out of the dashboard, got %v", testgrid.Data[0].Records)
+	}
+	if notes := testgrid.Data[0].Records[1].Notes; len(notes) != 1 || notes[0] != "Acknowledgement expired on 2021-10-15: flaky since the node image update" {
+		t.Errorf("expected a note on the job of the expired ack, got %v", notes)
+	}
+	if len(testgrid.KnownIssues) != 1 || knownIssueLine(testgridReport, testgrid.KnownIssues[0]) != "gce-cos-master-serial (Master-Blocking): tracked in #105242, until 2021-11-01" {
+		t.Errorf("unexpected known issues %v", testgrid.KnownIssues)
+	}
+	// the job of the same name on another dashboard is not acknowledged
+	if records := testgrid.Data[1].Records; len(records) != 1 || records[0].Title != "gce-cos-master-serial" {
+		t.Errorf("expected the job on Master-Informing to be reported, got %v", records)
+	}
+
+	github := acknowledged[1]
+	if len(github.Data) != 1 || github.Data[0].Records[0].ID != 97783 {
+		t.Errorf("expected only #97783 to be left in the github report, got %v", github.Data)
+	}
+	if len(github.KnownIssues) != 1 || knownIssueLine(githubReport, github.KnownIssues[0]) != "kubernetes/kubernetes#105242 [Failing Test] gce-cos-master-serial: fix lands with the etcd bump, until 2021-11-01" {
+		t.Errorf("unexpected known issues %v", github.KnownIssues)
+	}
+
+	markdown := MarkdownReport(Meta{Flags: metaFlags{EmojisOff: true}}, acknowledged)
+	if !strings.Contains(markdown, "## KNOWN ISSUES (2)\n\n- gce-cos-master-serial (Master-Blocking): tracked in #105242, until 2021-11-01\n") {
+		t.Errorf("expected a known issues section in the markdown report, got\n%s", markdown)
+	}
+	output := acknowledged.Output(time.Now())
+	if known := output.Sources[1].KnownIssues; len(known) != 1 || known[0].Record.Number != 105242 || known[0].Section != "kubernetes/kubernetes" {
+		t.Errorf("expected #105242 to be a known issue of the github source, got %v", known)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Annotation a manual note on a record of the report set via -annotations (like who is looking into a failing job)
+type Annotation struct {
+	// Record title of a testgrid job or test, or the number of a github issue (like "#105242")
+	Record string `json:"record"`
+	// Note shown with the record
+	Note string `json:"note"`
+	// SnoozedUntil the record is snoozed until this day (YYYY-MM-DD), the annotation is not carried over after that day
+	SnoozedUntil string `json:"snoozed_until,omitempty"`
+}
+
+// RecordAnnotation an annotation attached to a record, it is stored with the snapshot to carry it over to the next runs
+type RecordAnnotation struct {
+	Note         string     `json:"note"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	// Since day the annotation has been set the first time
+	Since time.Time `json:"since"`
+	// CarriedOver the annotation has been taken from the previous snapshot
+	CarriedOver bool `json:"carried_over,omitempty"`
+}
+
+type annotationsFile struct {
+	Annotations []Annotation `json:"annotations"`
+}
+
+// LoadAnnotations reads manual annotations from a json file, a file without annotations returns an empty list (see ApplyAnnotations)
+func LoadAnnotations(path string) ([]Annotation, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file annotationsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i, a := range file.Annotations {
+		if a.Record == "" || a.Note == "" {
+			return nil, fmt.Errorf("annotation %d: record and note have to be set", i)
+		}
+		if a.SnoozedUntil != "" {
+			if _, err := time.Parse(cycleDateLayout, a.SnoozedUntil); err != nil {
+				return nil, fmt.Errorf("annotation %d: snoozed_until %q does not match YYYY-MM-DD", i, a.SnoozedUntil)
+			}
+		}
+	}
+	if file.Annotations == nil {
+		return []Annotation{}, nil
+	}
+	return file.Annotations, nil
+}
+
+// ApplyAnnotations attaches the annotations to the matching records of the report. If annotations are set (-annotations, even if the file is empty)
+// they are the source of truth, annotations that have been removed from the file are cleared and the previous snapshot only tells since when
+// the others are set. If annotations are nil the annotations of the previous snapshot are carried forward, unless their snooze ended
+func ApplyAnnotations(report Report, annotations []Annotation, previous *Report, now time.Time) Report {
+	previousAnnotations := map[string][]RecordAnnotation{}
+	if previous != nil {
+		for _, reportData := range *previous {
+			for _, field := range reportData.Data {
+				for _, record := range field.Records {
+					if len(record.Annotations) > 0 {
+						previousAnnotations[recordKey(reportData.Name, field, record)] = record.Annotations
+					}
+				}
+			}
+		}
+	}
+
+	annotated := Report{}
+	for _, reportData := range report {
+		fields := []ReportDataField{}
+		for _, field := range reportData.Data {
+			records := []ReportDataRecord{}
+			for _, record := range field.Records {
+				carried := previousAnnotations[recordKey(reportData.Name, field, record)]
+				if annotations != nil {
+					record.Annotations = setAnnotations(reportData.Name, record, annotations, carried, now)
+				} else {
+					record.Annotations = carryAnnotations(carried, now)
+				}
+				if len(record.Annotations) > 0 {
+					record.Notes = append([]string{}, record.Notes...)
+				}
+				for _, a := range record.Annotations {
+					record.Notes = append(record.Notes, annotationNote(a, now))
+				}
+				records = append(records, record)
+			}
+			field.Records = records
+			fields = append(fields, field)
+		}
+		annotated = append(annotated, ReportData{Name: reportData.Name, Data: fields, Incomplete: reportData.Incomplete})
+	}
+	return annotated
+}
+
+// setAnnotations returns the annotations set for the record, annotations that have been set before keep the day they have been set the first time
+func setAnnotations(reportName string, record ReportDataRecord, annotations []Annotation, carried []RecordAnnotation, now time.Time) []RecordAnnotation {
+	since := map[string]time.Time{}
+	for _, a := range carried {
+		since[a.Note] = a.Since
+	}
+	result := []RecordAnnotation{}
+	for _, a := range annotations {
+		if !annotationMatches(reportName, record, a) {
+			continue
+		}
+		annotation := RecordAnnotation{Note: a.Note, Since: now}
+		if first, ok := since[a.Note]; ok {
+			annotation.Since = first
+		}
+		if a.SnoozedUntil != "" {
+			until, _ := time.Parse(cycleDateLayout, a.SnoozedUntil)
+			annotation.SnoozedUntil = &until
+		}
+		result = append(result, annotation)
+	}
+	return result
+}
+
+// carryAnnotations returns the annotations of the previous snapshot whose snooze did not end
+func carryAnnotations(carried []RecordAnnotation, now time.Time) []RecordAnnotation {
+	result := []RecordAnnotation{}
+	for _, a := range carried {
+		if a.SnoozedUntil != nil && !sameOrBeforeDay(now, *a.SnoozedUntil) {
+			continue
+		}
+		a.CarriedOver = true
+		result = append(result, a)
+	}
+	return result
+}
+
+// annotationMatches tells if the annotation is meant for the record (github issues are referenced by "#number")
+func annotationMatches(reportName string, record ReportDataRecord, a Annotation) bool {
+	if reportName == githubReport {
+		return a.Record == fmt.Sprintf("#%d", record.ID)
+	}
+	return a.Record == record.Title
+}
+
+// annotationNote describes the annotation (like "Annotation: alice is bisecting, carried over (3rd day)")
+func annotationNote(a RecordAnnotation, now time.Time) string {
+	note := fmt.Sprintf("Annotation: %s", a.Note)
+	if a.SnoozedUntil != nil {
+		note += fmt.Sprintf(", snoozed until %s", a.SnoozedUntil.Format(cycleDateLayout))
+	}
+	if a.CarriedOver {
+		note += fmt.Sprintf(", carried over (%s day)", ordinal(annotationDay(a.Since, now)))
+	}
+	return note
+}
+
+// annotationDay counts the days an annotation is shown, the day it has been set is the 1st day
+func annotationDay(since time.Time, now time.Time) int {
+	first := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.UTC)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return int(today.Sub(first).Hours()/24) + 1
+}
+
+// sameOrBeforeDay tells if the day of t is not after day
+func sameOrBeforeDay(t time.Time, day time.Time) bool {
+	return t.Format(cycleDateLayout) <= day.Format(cycleDateLayout)
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyAnnotations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations.json")
+	content := `{"annotations": [
+		{"record": "gce-cos-master-serial", "note": "alice is bisecting"},
+		{"record": "#105242", "note": "waiting for the etcd bump", "snoozed_until": "2021-10-21"}
+	]}`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	annotations, err := LoadAnnotations(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := func() Report {
+		return Report{
+			{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+				{ID: testgridReportDetails, Title: "gce-cos-master-serial", Notes: []string{"3 of 10 passed recently"}},
+			}}}},
+			{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{{ID: 105242}}}}},
+		}
+	}
+
+	// day 1: annotations are set
+	day1 := time.Date(2021, 10, 18, 9, 0, 0, 0, time.UTC)
+	first := ApplyAnnotations(report(), annotations, nil, day1)
+	if notes := first[0].Data[0].Records[0].Notes; !reflect.DeepEqual(notes, []string{"3 of 10 passed recently", "Annotation: alice is bisecting"}) {
+		t.Errorf("unexpected notes of the annotated job %v", notes)
+	}
+	if notes := first[1].Data[0].Records[0].Notes; !reflect.DeepEqual(notes, []string{"Annotation: waiting for the etcd bump, snoozed until 2021-10-21"}) {
+		t.Errorf("unexpected notes of the snoozed issue %v", notes)
+	}
+
+	// day 3: runs without -annotations carry the annotations over from the previous snapshot
+	day2 := ApplyAnnotations(report(), nil, &first, day1.Add(24*time.Hour))
+	day3 := ApplyAnnotations(report(), nil, &day2, day1.Add(48*time.Hour))
+	if notes := day3[0].Data[0].Records[0].Notes; !reflect.DeepEqual(notes, []string{"3 of 10 passed recently", "Annotation: alice is bisecting, carried over (3rd day)"}) {
+		t.Errorf("expected the annotation to be carried over, got %v", notes)
+	}
+	if notes := day3[1].Data[0].Records[0].Notes; !reflect.DeepEqual(notes, []string{"Annotation: waiting for the etcd bump, snoozed until 2021-10-21, carried over (3rd day)"}) {
+		t.Errorf("expected the snooze to be carried over, got %v", notes)
+	}
+
+	// day 5: the snooze ended
+	day5 := ApplyAnnotations(report(), nil, &day3, day1.Add(96*time.Hour))
+	if notes := day5[1].Data[0].Records[0].Notes; len(notes) != 0 {
+		t.Errorf("expected the snooze to end, got %v", notes)
+	}
+	if day5[0].Data[0].Records[0].Annotations[0].Since != day1 {
+		t.Errorf("expected the annotation to keep the day it has been set, got %v", day5[0].Data[0].Records[0].Annotations[0].Since)
+	}
+}
+
+func TestApplyAnnotationsFileIsSourceOfTruth(t *testing.T) {
+	report := func() Report {
+		return Report{{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial"},
+			{ID: testgridReportDetails, Title: "gce-cos-master-default"},
+		}}}}}
+	}
+	day1 := time.Date(2021, 10, 18, 9, 0, 0, 0, time.UTC)
+	first := ApplyAnnotations(report(), []Annotation{
+		{Record: "gce-cos-master-serial", Note: "alice is bisecting"},
+		{Record: "gce-cos-master-default", Note: "bob is bisecting"},
+	}, nil, day1)
+
+	// the annotation of gce-cos-master-default has been removed from the file
+	second := ApplyAnnotations(report(), []Annotation{{Record: "gce-cos-master-serial", Note: "alice is bisecting"}}, &first, day1.Add(24*time.Hour))
+	records := second[0].Data[0].Records
+	if len(records[0].Annotations) != 1 || records[0].Annotations[0].Since != day1 || records[0].Annotations[0].CarriedOver {
+		t.Errorf("expected the annotation that is still set to keep the day it has been set, got %+v", records[0].Annotations)
+	}
+	if len(records[1].Annotations) != 0 || len(records[1].Notes) != 0 {
+		t.Errorf("expected the removed annotation to be cleared, got %+v", records[1])
+	}
+
+	// an empty file clears all annotations
+	if third := ApplyAnnotations(report(), []Annotation{}, &second, day1.Add(48*time.Hour)); len(third[0].Data[0].Records[0].Annotations) != 0 {
+		t.Errorf("expected an empty file to clear the annotations, got %+v", third[0].Data[0].Records[0].Annotations)
+	}
+}
+
+func TestApplyAnnotationsMatchesReportersByName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations.json")
+	if err := ioutil.WriteFile(path, []byte(`{"annotations": [{"record": "#105242", "note": "waiting for the etcd bump"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking"}}},
+		{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{{ID: 105242}}}}},
+	}
+	// the reporters are not in the order of the report
+	github, testgrid := &GithubReport{}, &TestgridReport{}
+	if _, err := (Meta{Flags: metaFlags{Annotations: path}}).applyAnnotations(report, []CIReport{github, testgrid}); err != nil {
+		t.Fatal(err)
+	}
+	if github.GetData().Name != githubReport || len(github.GetData().Data[0].Records[0].Annotations) != 1 {
+		t.Errorf("expected the github report to get the annotated github data, got %+v", github.GetData())
+	}
+	if testgrid.GetData().Name != testgridReport {
+		t.Errorf("expected the testgrid report to get the testgrid data, got %+v", testgrid.GetData())
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, expected := range map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 21: "21st", 112: "112th"} {
+		if o := ordinal(n); o != expected {
+			t.Errorf("expected %s, got %s", expected, o)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// unassignedNote note of github issues nobody is assigned to
+const unassignedNote = "Unassigned"
+
+// assigneeWorkload number of reported github issues assigned to a contributor
+type assigneeWorkload struct {
+	Login  string
+	Issues int
+}
+
+// issueAssignees returns the logins of the users an issue is assigned to
+func issueAssignees(issue GithubIssueElement) []string {
+	logins := []string{}
+	for _, a := range issue.Assignees {
+		logins = append(logins, a.Login)
+	}
+	return logins
+}
+
+// workload counts the issues of the github report per assignee (most issues first) and the issues nobody is assigned to
+func workload(reportData ReportData) (workloads []assigneeWorkload, unassigned int) {
+	issues := map[string]int{}
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			if len(record.Assignees) == 0 {
+				unassigned++
+			}
+			for _, login := range record.Assignees {
+				issues[login]++
+			}
+		}
+	}
+	for login, count := range issues {
+		workloads = append(workloads, assigneeWorkload{Login: login, Issues: count})
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Issues != workloads[j].Issues {
+			return workloads[i].Issues > workloads[j].Issues
+		}
+		return workloads[i].Login < workloads[j].Login
+	})
+	return workloads, unassigned
+}
+
+// workloadLines summarizes the workload like ["alice: 3 issues", "bob: 1 issue", "unassigned: 2 issues"]
+func workloadLines(reportData ReportData) []string {
+	workloads, unassigned := workload(reportData)
+	lines := []string{}
+	for _, w := range workloads {
+		lines = append(lines, fmt.Sprintf("%s: %s", w.Login, issuesText(w.Issues)))
+	}
+	if unassigned > 0 {
+		lines = append(lines, fmt.Sprintf("%s: %s", strings.ToLower(unassignedNote), issuesText(unassigned)))
+	}
+	return lines
+}
+
+func issuesText(n int) string {
+	if n == 1 {
+		return "1 issue"
+	}
+	return fmt.Sprintf("%d issues", n)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWorkloadLines(t *testing.T) {
+	reportData := ReportData{Name: githubReport, Data: []ReportDataField{
+		{Records: []ReportDataRecord{{ID: 1, Assignees: []string{"bob"}}}},
+		{Records: []ReportDataRecord{{ID: 2, Assignees: []string{"alice", "bob"}}}},
+		{Records: []ReportDataRecord{{ID: 3, Assignees: []string{"alice"}}}},
+		{Records: []ReportDataRecord{{ID: 4, Assignees: []string{"bob"}}}},
+		{Records: []ReportDataRecord{{ID: 5}}},
+	}}
+	expected := []string{"bob: 3 issues", "alice: 2 issues", "unassigned: 1 issue"}
+	if got := workloadLines(reportData); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestGithubReportAssignees(t *testing.T) {
+	requestIssues := func(flags metaFlags) map[int64]ReportDataRecord {
+		r := &GithubReport{}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		reportData, err := r.RequestData(context.Background(), newTestMeta(flags), &wg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := map[int64]ReportDataRecord{}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				records[record.ID] = record
+			}
+		}
+		return records
+	}
+
+	records := requestIssues(metaFlags{})
+	if assigned := records[105242]; !reflect.DeepEqual(assigned.Assignees, []string{"alice"}) || strings.Contains(assigned.Highlight, unassignedEmoji) {
+		t.Errorf("expected #105242 to be assigned to alice, got assignees %v and highlight %q", assigned.Assignees, assigned.Highlight)
+	}
+	if unassigned := records[97783]; len(unassigned.Assignees) != 0 || unassigned.Highlight != unassignedEmoji {
+		t.Errorf("expected #97783 to be highlighted as unassigned, got assignees %v and highlight %q", unassigned.Assignees, unassigned.Highlight)
+	}
+	if notes := records[97783].Notes; !reflect.DeepEqual(notes[len(notes)-1:], []string{unassignedNote}) {
+		t.Errorf("expected the plain unassigned note on #97783, got %q", notes)
+	}
+
+	records = requestIssues(metaFlags{OnlyUnassigned: true})
+	if _, ok := records[105242]; ok || len(records) != 2 {
+		t.Errorf("expected only the 2 unassigned issues with -only-unassigned, got %d issues", len(records))
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Conditions of the signal health that can be set via -fail-on
+const (
+	// any job of a blocking dashboard is failing
+	conditionBlockingFailing = "blocking-failing"
+	// jobs of blocking dashboards are flaky
+	conditionBlockingFlaky = "blocking-flaky"
+	// open kind/failing-test issues are not labeled triage/accepted
+	conditionUntriagedIssues = "untriaged-issues"
+	// testgrid jobs are ranked with high severity (see SeverityConfig)
+	conditionHighSeverity = "high-severity"
+)
+
+// DefaultFailOn conditions that are checked by the check subcommand if -fail-on is not set
+const DefaultFailOn = conditionBlockingFailing
+
+// CheckUnhealthyExitCode exit code used if a condition set via -fail-on tripped (errors exit with 1)
+const CheckUnhealthyExitCode = 2
+
+// FailCondition a condition of the signal health that fails the check if more than Limit records match it
+type FailCondition struct {
+	Name  string
+	Limit int
+}
+
+// ConditionResult machine-readable result of a condition, Error is set if the condition could not be evaluated
+// (like the testgrid report is missing or incomplete), the condition trips then
+type ConditionResult struct {
+	Condition string   `json:"condition"`
+	Tripped   bool     `json:"tripped"`
+	Count     int      `json:"count"`
+	Limit     int      `json:"limit"`
+	Records   []string `json:"records"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// CheckResult machine-readable result of all conditions, the tree is healthy if no condition tripped
+type CheckResult struct {
+	Healthy    bool              `json:"healthy"`
+	Conditions []ConditionResult `json:"conditions"`
+}
+
+// ParseFailConditions parses -fail-on input, conditions can be limited with =N
+// ("blocking-failing, blocking-flaky=2" => [{blocking-failing 0} {blocking-flaky 2}])
+func ParseFailConditions(input string) ([]FailCondition, error) {
+	conditions := []FailCondition{}
+	for _, e := range splitListInput(input) {
+		parts := strings.SplitN(e, "=", 2)
+		condition := FailCondition{Name: strings.TrimSpace(parts[0])}
+		if condition.Name != conditionBlockingFailing && condition.Name != conditionBlockingFlaky && condition.Name != conditionUntriagedIssues && condition.Name != conditionHighSeverity {
+			return nil, fmt.Errorf("%q does not match options [%s, %s, %s, %s]", condition.Name, conditionBlockingFailing, conditionBlockingFlaky, conditionUntriagedIssues, conditionHighSeverity)
+		}
+		if len(parts) == 2 {
+			limit, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil || limit < 0 {
+				return nil, fmt.Errorf("limit of %s has to be a number >= 0, got %q", condition.Name, parts[1])
+			}
+			condition.Limit = limit
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// Check evaluates the conditions against the report, a condition trips if more than its limit of records match it.
+// A condition trips as well if the report it is evaluated on is missing or incomplete (like the testgrid report of
+// "check -report github" or after its -deadlines passed), so missing data is never taken for a healthy tree
+func (r Report) Check(conditions []FailCondition) CheckResult {
+	result := CheckResult{Healthy: true, Conditions: []ConditionResult{}}
+	for _, condition := range conditions {
+		if err := r.checkSource(conditionSource(condition.Name)); err != nil {
+			result.Healthy = false
+			result.Conditions = append(result.Conditions, ConditionResult{Condition: condition.Name, Tripped: true, Limit: condition.Limit, Records: []string{}, Error: err.Error()})
+			continue
+		}
+		count, records := 0, []string{}
+		switch condition.Name {
+		case conditionBlockingFailing:
+			count, records = r.blockingJobs(failing)
+		case conditionBlockingFlaky:
+			count, records = r.blockingJobs(flaky)
+		case conditionUntriagedIssues:
+			records = r.untriagedIssues()
+			count = len(records)
+		case conditionHighSeverity:
+			records = r.highSeverityJobs()
+			count = len(records)
+		}
+		tripped := count > condition.Limit
+		if tripped {
+			result.Healthy = false
+		}
+		result.Conditions = append(result.Conditions, ConditionResult{Condition: condition.Name, Tripped: tripped, Count: count, Limit: condition.Limit, Records: records})
+	}
+	return result
+}
+
+// conditionSource the report a condition is evaluated on
+func conditionSource(condition string) string {
+	if condition == conditionUntriagedIssues {
+		return githubReport
+	}
+	return testgridReport
+}
+
+// checkSource returns an error if the report of the source is missing or incomplete
+func (r Report) checkSource(source string) error {
+	for _, reportData := range r {
+		if reportData.Name != source {
+			continue
+		}
+		if reportData.Incomplete {
+			return fmt.Errorf("the %s report is incomplete", source)
+		}
+		return nil
+	}
+	return fmt.Errorf("the %s report is missing", source)
+}
+
+// blockingJobs counts the jobs of blocking dashboards with the status (using the dashboard summaries, so -short reports are checked as well)
+// and lists them as "dashboard/job"
+func (r Report) blockingJobs(status overallStatus) (int, []string) {
+	count, jobs := 0, []string{}
+	for _, reportData := range r {
+		if reportData.Name != testgridReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			if field.Emoji != masterBlockingEmoji {
+				continue
+			}
+			for _, record := range field.Records {
+				if record.ID == testgridReportSummary {
+					count += record.Counts[strings.ToLower(string(status))]
+				} else if record.Status == string(status) {
+					jobs = append(jobs, fmt.Sprintf("%s/%s", field.Title, record.Title))
+				}
+			}
+		}
+	}
+	return count, jobs
+}
+
+// untriagedIssues lists the open kind/failing-test issues of the github report, issues labeled triage/accepted are not
+// part of it (see githubIssueExcludedLabels)
+func (r Report) untriagedIssues() []string {
+	issues := []string{}
+	for _, reportData := range r {
+		if reportData.Name != githubReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if hasLabel(record, "kind/failing-test") {
+					// field.Title names the repository if multiple repositories are reported
+					issues = append(issues, fmt.Sprintf("%s#%d", field.Title, record.ID))
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// highSeverityJobs lists the testgrid jobs ranked with high severity as "dashboard/job"
+func (r Report) highSeverityJobs() []string {
+	jobs := []string{}
+	for _, reportData := range r {
+		if reportData.Name != testgridReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if !isSummaryRecord(reportData.Name, record) && record.Severity == HighSeverity {
+					jobs = append(jobs, fmt.Sprintf("%s/%s", field.Title, record.Title))
+				}
+			}
+		}
+	}
+	return jobs
+}
+
+func hasLabel(record ReportDataRecord, label string) bool {
+	for _, l := range record.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
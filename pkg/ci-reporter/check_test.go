@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseFailConditions(t *testing.T) {
+	conditions, err := ParseFailConditions("blocking-failing, blocking-flaky=2,untriaged-issues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FailCondition{{Name: conditionBlockingFailing}, {Name: conditionBlockingFlaky, Limit: 2}, {Name: conditionUntriagedIssues}}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Errorf("expected %v, got %v", expected, conditions)
+	}
+	for _, input := range []string{"blocking-passing", "blocking-flaky=-1", "blocking-flaky=many"} {
+		if _, err := ParseFailConditions(input); err == nil {
+			t.Errorf("expected %q to be invalid", input)
+		}
+	}
+}
+
+func TestReportCheck(t *testing.T) {
+	report, _, err := newTestMeta(metaFlags{}).RequestReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conditions, err := ParseFailConditions("blocking-failing, blocking-flaky=2, untriaged-issues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := report.Check(conditions)
+
+	// master-blocking has two flaky jobs, master-informing is failing but does not block the release
+	expected := CheckResult{Healthy: false, Conditions: []ConditionResult{
+		{Condition: conditionBlockingFailing, Tripped: false, Count: 0, Limit: 0, Records: []string{}},
+		{Condition: conditionBlockingFlaky, Tripped: false, Count: 2, Limit: 2, Records: []string{"Master-Blocking/gce-cos-master-default", "Master-Blocking/verify-master"}},
+		{Condition: conditionUntriagedIssues, Tripped: true, Count: 2, Limit: 0, Records: []string{"#105242", "#105965"}},
+	}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestReportCheckMissingSource(t *testing.T) {
+	conditions, err := ParseFailConditions("blocking-failing, untriaged-issues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name   string
+		report Report
+		errors []string
+	}{
+		{"testgrid report missing", Report{{Name: githubReport}}, []string{"the testgrid report is missing", ""}},
+		{"testgrid report incomplete", Report{{Name: githubReport}, {Name: testgridReport, Incomplete: true}}, []string{"the testgrid report is incomplete", ""}},
+		{"github report missing", Report{{Name: testgridReport}}, []string{"", "the github report is missing"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := tc.report.Check(conditions)
+			if result.Healthy {
+				t.Error("expected the check to be unhealthy if the data of a condition is missing")
+			}
+			for i, expected := range tc.errors {
+				if result.Conditions[i].Error != expected || result.Conditions[i].Tripped != (expected != "") {
+					t.Errorf("expected condition %s to have error %q, got %+v", result.Conditions[i].Condition, expected, result.Conditions[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// branchAliasRegex names release branches get in job names besides their version (like "gce-cos-k8sstable1-default")
+const branchAliasRegex = `master|k8sbeta|k8sstable[1-4]|beta|stable[1-4]`
+
+// missingStatus status of a job that is not part of the dashboard of a branch
+const missingStatus = "-"
+
+// BranchComparison status of the jobs of the dashboards of multiple release branches
+type BranchComparison struct {
+	// Branches like "master", "1.29" and "1.28"
+	Branches []string `json:"branches"`
+	// Dashboards testgrid dashboard of each branch (like "sig-release-1.29-blocking")
+	Dashboards []string      `json:"dashboards"`
+	Jobs       []ComparedJob `json:"jobs"`
+}
+
+// ComparedJob status of a job on each branch
+type ComparedJob struct {
+	// Job name with the branch replaced by "*" (like "gce-cos-*-default"), so the jobs of all branches can be matched
+	Job string `json:"job"`
+	// Statuses one per branch in the order of BranchComparison.Branches, "-" if the branch has no such job
+	Statuses []string `json:"statuses"`
+	// BrokenOnlyOn branch the job is failing on while it is not failing on the other branches,
+	// a strong hint of a branch-specific regression or a bad cherry-pick
+	BrokenOnlyOn string `json:"broken_only_on,omitempty"`
+	// Conflict name without the branch (like "conformance-*") the job shares with other jobs of the same dashboard
+	// (like "conformance-beta" and "conformance-stable1"), these jobs are listed by their own name and not compared
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// RequestBranchComparison requests the dashboards of the branches (like "sig-release-master-blocking" for branch master and
+// dashboard kind blocking) and compares the status of their jobs
+func RequestBranchComparison(client *http.Client, testgridURL string, kind string, branches []string, concurrency int) (BranchComparison, error) {
+	dashboards := make([]string, len(branches))
+	for i, branch := range branches {
+		dashboards[i] = fmt.Sprintf("sig-release-%s-%s", branch, kind)
+	}
+	jobs := make([]TestgridData, len(branches))
+	errs := runWorkerPool(concurrency, len(branches), func(i int) error {
+		data, err := reqTestgridSiteData(context.Background(), httpClientOrDefault(client), fmt.Sprintf("%s/%s", strings.TrimSuffix(testgridURL, "/"), dashboards[i]))
+		if err != nil {
+			return fmt.Errorf("requesting %s: %v", dashboards[i], err)
+		}
+		jobs[i] = data
+		return nil
+	})
+	if err := collectWorkerErrors(errs); err != nil {
+		return BranchComparison{}, err
+	}
+	comparison := CompareBranches(branches, jobs)
+	comparison.Dashboards = dashboards
+	return comparison, nil
+}
+
+// CompareBranches matches the jobs of the dashboards of the branches by their name without the branch, jobs are sorted by name.
+// Jobs of one dashboard whose names without the branch are the same are not matched, they are listed by their own name
+func CompareBranches(branches []string, dashboards []TestgridData) BranchComparison {
+	comparison := BranchComparison{Branches: branches, Jobs: []ComparedJob{}}
+	statuses := map[string][]string{}
+	conflicts := map[string]string{}
+	for i, jobs := range dashboards {
+		branchRegex := branchTokenRegex(branches[i])
+		jobNames := map[string][]string{}
+		for jobName := range jobs {
+			name := branchRegex.ReplaceAllString(jobName, "${1}*${3}")
+			jobNames[name] = append(jobNames[name], jobName)
+		}
+		for name, names := range jobNames {
+			for _, jobName := range names {
+				key := name
+				if len(names) > 1 {
+					key = jobName
+					conflicts[key] = name
+				}
+				if _, ok := statuses[key]; !ok {
+					statuses[key] = make([]string, len(branches))
+					for b := range branches {
+						statuses[key][b] = missingStatus
+					}
+				}
+				statuses[key][i] = string(jobs[jobName].OverallStatus)
+			}
+		}
+	}
+	names := []string{}
+	for name := range statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		job := ComparedJob{Job: name, Statuses: statuses[name], Conflict: conflicts[name]}
+		if job.Conflict == "" {
+			job.BrokenOnlyOn = brokenOnlyOn(branches, statuses[name])
+		}
+		comparison.Jobs = append(comparison.Jobs, job)
+	}
+	return comparison
+}
+
+// branchTokenRegex matches the branch in a job name (like "master" in "gce-cos-master-default" or "1.29" and "1-29" in "kind-1-29-parallel")
+func branchTokenRegex(branch string) *regexp.Regexp {
+	tokens := branchAliasRegex
+	if branch != "master" {
+		tokens += "|" + regexp.QuoteMeta(branch) + "|" + regexp.QuoteMeta(strings.ReplaceAll(branch, ".", "-"))
+	}
+	return regexp.MustCompile(fmt.Sprintf(`(^|-)(%s)(-|$)`, tokens))
+}
+
+// brokenOnlyOn returns the branch the job is failing on if it is failing on exactly one branch and runs on at least one other branch
+func brokenOnlyOn(branches []string, statuses []string) string {
+	brokenOn, runsOn := "", 0
+	for i, status := range statuses {
+		if status == missingStatus {
+			continue
+		}
+		runsOn++
+		if status == string(failing) {
+			if brokenOn != "" {
+				return ""
+			}
+			brokenOn = branches[i]
+		}
+	}
+	if runsOn < 2 {
+		return ""
+	}
+	return brokenOn
+}
+
+// String prints the status matrix, jobs that are broken on one branch only are listed first
+func (c BranchComparison) String() string {
+	var sb strings.Builder
+	if len(c.Dashboards) > 0 {
+		sb.WriteString(fmt.Sprintf("Job status per branch (%s)\n\n", strings.Join(c.Dashboards, ", ")))
+	} else {
+		sb.WriteString("Job status per branch\n\n")
+	}
+	broken := []string{}
+	for _, job := range c.Jobs {
+		if job.BrokenOnlyOn != "" {
+			broken = append(broken, fmt.Sprintf("- %s is failing on %s only", job.Job, job.BrokenOnlyOn))
+		}
+	}
+	if len(broken) > 0 {
+		sb.WriteString(fmt.Sprintf("BROKEN ON ONE BRANCH ONLY (%d), likely a branch-specific regression or a bad cherry-pick\n", len(broken)))
+		sb.WriteString(strings.Join(broken, "\n") + "\n\n")
+	}
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "JOB\t%s\t\n", strings.Join(c.Branches, "\t"))
+	for _, job := range c.Jobs {
+		marker := ""
+		if job.BrokenOnlyOn != "" {
+			marker = "<- " + job.BrokenOnlyOn
+		} else if job.Conflict != "" {
+			marker = fmt.Sprintf("(not compared, more than one job is %s)", job.Conflict)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", job.Job, strings.Join(job.Statuses, "\t"), marker)
+	}
+	w.Flush()
+	return sb.String()
+}
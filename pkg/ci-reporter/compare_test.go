@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareBranches(t *testing.T) {
+	dashboards := []TestgridData{
+		{
+			"gce-cos-master-default": {OverallStatus: passing},
+			"kind-master-parallel":   {OverallStatus: failing},
+			"build-master":           {OverallStatus: passing},
+			"verify-master":          {OverallStatus: failing},
+		},
+		{
+			"gce-cos-1.29-default":   {OverallStatus: failing},
+			"kind-1-29-parallel":     {OverallStatus: failing},
+			"build-1.29":             {OverallStatus: passing},
+			"conformance-k8sstable1": {OverallStatus: flaky},
+		},
+		{
+			"gce-cos-1.28-default": {OverallStatus: passing},
+			"kind-1-28-parallel":   {OverallStatus: passing},
+			"build-1.28":           {OverallStatus: failing},
+		},
+	}
+	comparison := CompareBranches([]string{"master", "1.29", "1.28"}, dashboards)
+	expected := []ComparedJob{
+		{Job: "build-*", Statuses: []string{"PASSING", "PASSING", "FAILING"}, BrokenOnlyOn: "1.28"},
+		{Job: "conformance-*", Statuses: []string{"-", "FLAKY", "-"}},
+		{Job: "gce-cos-*-default", Statuses: []string{"PASSING", "FAILING", "PASSING"}, BrokenOnlyOn: "1.29"},
+		{Job: "kind-*-parallel", Statuses: []string{"FAILING", "FAILING", "PASSING"}},
+		// a job that only runs on one branch is not compared
+		{Job: "verify-*", Statuses: []string{"FAILING", "-", "-"}},
+	}
+	if !reflect.DeepEqual(comparison.Jobs, expected) {
+		t.Errorf("expected %v, got %v", expected, comparison.Jobs)
+	}
+
+	text := comparison.String()
+	if !strings.Contains(text, "BROKEN ON ONE BRANCH ONLY (2)") || !strings.Contains(text, "- gce-cos-*-default is failing on 1.29 only") {
+		t.Errorf("expected the jobs broken on one branch to be listed, got\n%s", text)
+	}
+	if !strings.Contains(text, "gce-cos-*-default  PASSING  FAILING  PASSING  <- 1.29") {
+		t.Errorf("expected the broken job to be marked in the matrix, got\n%s", text)
+	}
+}
+
+func TestCompareBranchesConflict(t *testing.T) {
+	dashboards := []TestgridData{
+		{
+			"conformance-master": {OverallStatus: passing},
+		},
+		{
+			// both jobs are conformance-* without the branch
+			"conformance-beta":    {OverallStatus: failing},
+			"conformance-stable1": {OverallStatus: passing},
+			"build-1.29":          {OverallStatus: failing},
+		},
+	}
+	for i := 0; i < 10; i++ {
+		comparison := CompareBranches([]string{"master", "1.29"}, dashboards)
+		expected := []ComparedJob{
+			{Job: "build-*", Statuses: []string{"-", "FAILING"}},
+			{Job: "conformance-*", Statuses: []string{"PASSING", "-"}},
+			{Job: "conformance-beta", Statuses: []string{"-", "FAILING"}, Conflict: "conformance-*"},
+			{Job: "conformance-stable1", Statuses: []string{"-", "PASSING"}, Conflict: "conformance-*"},
+		}
+		if !reflect.DeepEqual(comparison.Jobs, expected) {
+			t.Fatalf("expected both jobs to be kept, got %v", comparison.Jobs)
+		}
+		if text := comparison.String(); !strings.Contains(text, "conformance-beta     -        FAILING  (not compared, more than one job is conformance-*)") {
+			t.Errorf("expected the conflict to be marked in the matrix, got\n%s", text)
+		}
+	}
+}
@@ -20,10 +20,14 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"net"
+	"net/http"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/google/go-github/v34/github"
 	"github.com/kelseyhightower/envconfig"
@@ -32,7 +36,13 @@ import (
 
 // Environment variables that can be set using the ci-reporter
 type metaEnv struct {
-	GithubToken string `envconfig:"GITHUB_AUTH_TOKEN" required:"true"`
+	// GithubToken is used if no other github credentials are set via flags (see GithubAuth)
+	GithubToken string `envconfig:"GITHUB_AUTH_TOKEN"`
+	// SMTPUsername and SMTPPassword are used to authenticate at the smtp server set via -smtp-server
+	SMTPUsername string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD"`
+	// GoogleCredentials path to the key file of the service account used to append the report to google docs and sheets
+	GoogleCredentials string `envconfig:"GOOGLE_APPLICATION_CREDENTIALS"`
 }
 
 // Flags that can be set using the ci-reporter
@@ -41,12 +51,122 @@ type metaFlags struct {
 	ShortOn bool
 	// EmojisOff tells if emojis should be printed
 	EmojisOff bool
+	// NoColor tells if terminal colors should be left out even if the output is a terminal (see NewConsole)
+	NoColor bool
 	// specifies a specific release version that should be included in the report like "1.22" or "1.22, 1.21"
 	ReleaseVersion []string
 	// JSONOut specifies if the output should be in json format
 	JSONOut bool
 	// Specify a report (if this is specified only one report will be printed e.g. SpecificReport: 'github' -> github report)
 	SpecificReport string
+	// WebhookURL if set the report gets posted to this url
+	WebhookURL string
+	// WebhookTemplate path to a go template file that is used to shape the webhook payload
+	WebhookTemplate string
+	// SlackWebhookURL if set a summary of the report gets posted to this slack incoming webhook
+	SlackWebhookURL string
+	// SlackTemplate path to a go template file that is used to shape the slack payload
+	SlackTemplate string
+	// EmailTo if set the report gets sent by mail to these recipients
+	EmailTo []string
+	// EmailFrom sender address of the report mail, SMTP_USERNAME is used if it is not set
+	EmailFrom string
+	// SMTPServer address of the smtp server used to send the report mail (like smtp.example.com:587)
+	SMTPServer string
+	// GoogleDoc if set the report gets appended to this google doc (document id)
+	GoogleDoc string
+	// GoogleSheet if set the report gets appended to this tab of a google spreadsheet (like "SPREADSHEET_ID/CI signal")
+	GoogleSheet string
+	// FailOn conditions of the signal health that make the run exit with CheckUnhealthyExitCode
+	FailOn []FailCondition
+	// MentionPolicy handles automation is permitted to mention, mentions in posted reports that are not permitted are neutralized
+	MentionPolicy *MentionPolicy
+	// ReadOnly if set all integrations that post or modify something (notifiers, issue comments, sig subscriptions) are disabled
+	ReadOnly bool
+	// Template path to a go template file that is used to render the report instead of the default output
+	Template string
+	// Out files the full report is written to in the format of their extension (like ["report.md", "report.json"]), see GetSinks
+	Out []string
+	// Sigs if set only records attributed to these sigs are reported (like ["sig-node", "sig-network"])
+	Sigs []string
+	// SnapshotDir if set the report of each run gets stored in this directory
+	SnapshotDir string
+	// SnapshotCompression compression used for stored snapshots ('zstd', 'gzip' or 'none')
+	SnapshotCompression string
+	// Annotations path to a json file with manual annotations of records (see LoadAnnotations)
+	Annotations string
+	// Acks path to a yaml file with acknowledged known failures (see LoadAcks)
+	Acks string
+	// Store if set the job statuses and issue counts of each run get recorded in this store (like sqlite:ci-signal.db)
+	Store string
+	// GithubAPI github api that is used to request issues ('rest' or 'graphql')
+	GithubAPI string
+	// Listen address the metrics server listens on in serve mode (like ":9090")
+	Listen string
+	// Interval time between two report refreshes in serve and watch mode
+	Interval time.Duration
+	// Since window of the handoff subcommand (issues closed during the window are resolved)
+	Since time.Duration
+	// Watch if set the report is refreshed every Interval and redrawn in the terminal
+	Watch bool
+	// RecordDir if set all http responses are stored in this directory
+	RecordDir string
+	// ReplayDir if set http responses are not requested but read from this directory (recorded with RecordDir)
+	ReplayDir string
+	// CacheDir directory http responses with an ETag or Last-Modified header are cached in, caching is off if it is empty (-no-cache)
+	CacheDir string
+	// Severity thresholds and emojis used to rank testgrid jobs
+	Severity SeverityConfig
+	// SubscriptionsFile json file with sig subscriptions (see Subscription)
+	SubscriptionsFile string
+	// Flakes if set only the flake analysis of master-blocking and master-informing is reported
+	Flakes bool
+	// PostToIssue if set the report gets posted as comment on this github issue (like kubernetes/sig-release#1234)
+	PostToIssue *IssueReference
+	// RecurrenceIndex json file with test and job names that have been tracked in issues of previous cycles
+	RecurrenceIndex string
+	// Cycle release cycle of this run (like "1.23"), tracking issues get stored in the recurrence index for this cycle
+	Cycle string
+	// DependencyHints map failing jobs to the components they exercise (see dependency-hints.json)
+	DependencyHints []DependencyHint
+	// Runbooks instructions attached to failing jobs per failure class (see runbooks.json)
+	Runbooks []Runbook
+	// Repositories github issues are requested from, kubernetes/kubernetes if none are set
+	Repositories []GithubRepository
+	// OnlyUnassigned if set only github issues nobody is assigned to are reported
+	OnlyUnassigned bool
+	// Milestone github issues are scoped to (like "v1.22"), the latest release version set via -v is used if it is not set (see milestone)
+	Milestone string
+	// TestgridURL base url of the testgrid instance, https://testgrid.k8s.io if it is not set
+	TestgridURL string
+	// ProwURL base url of the prow instance failed builds are linked to (spyglass), see prowURL
+	ProwURL string
+	// Dashboards testgrid dashboards that are reported, sig-release-master-blocking and sig-release-master-informing if none are set
+	Dashboards []string
+	// CorrelateDependencies if set failing jobs list merged pull requests that updated dependencies before the job started failing
+	CorrelateDependencies bool
+	// DependencyLabels labels of pull requests that update dependencies (like "area/dependency")
+	DependencyLabels []string
+	// Features experimental subsystems that have been turned on or off (see FeatureGates)
+	Features FeatureGates
+	// Concurrency maximum number of requests that are sent at the same time
+	Concurrency int
+	// Deadlines per source (like {"testgrid": 30s}), data collected until the deadline is reported and marked as incomplete
+	Deadlines map[string]time.Duration
+	// Triage if set the details of failing jobs in the testgrid report list the triage failure clusters of their failing tests
+	Triage bool
+	// TriageURL base url of the triage failure data, https://storage.googleapis.com/k8s-gubernator/triage if it is not set
+	TriageURL string
+	// Platforms platforms with dedicated owners whose jobs are summarized across dashboards (like ["windows", "arm64"])
+	Platforms []string
+	// Quarantine if set the tests that are quarantined via tags or the skip list are reported as well (quarantine report)
+	Quarantine bool
+	// QuarantineList path of a skip list with one quarantined test per line
+	QuarantineList string
+	// PRSignal if set the pull requests that fix failing tests and flakes are reported as well (pr-signal report)
+	PRSignal bool
+	// GroupBy if set the records of the whole report are printed grouped by 'sig', 'severity' or 'dashboard'
+	GroupBy string
 }
 
 // Meta meta struct to use ci-reporter functions
@@ -54,88 +174,849 @@ type Meta struct {
 	Env                metaEnv
 	Flags              metaFlags
 	GitHubClient       *github.Client
+	HTTPClient         *http.Client
 	DataPostProcessing func(CIReport, string, chan ReportDataField, *sync.WaitGroup) ReportData
+	// Console the reports are printed to, stdout if it is not set
+	Console *Console
+	// Logger diagnostics are written to (stderr), the default logger if it is not set
+	Logger *Logger
+	// dashboardCache testgrid dashboards shared between the reports of a run (see RequestReport)
+	dashboardCache *dashboardCache
+	// githubTokenSource credentials github requests are authenticated with, nil if only Env.GithubToken is used
+	githubTokenSource oauth2.TokenSource
 }
 
-func dataPostProcessing(r CIReport, reportName string, chanReportDataField chan ReportDataField, wg *sync.WaitGroup) ReportData {
-	reportData := ReportData{
-		Data: []ReportDataField{},
-		Name: reportName,
+// withContext returns the meta whose http client sends all requests with the context, so they are canceled if the context is done
+func (m Meta) withContext(ctx context.Context) Meta {
+	client := httpClientOrDefault(m.HTTPClient)
+	m.HTTPClient = &http.Client{Transport: contextTransport{ctx: ctx, next: client.Transport}, CheckRedirect: client.CheckRedirect, Jar: client.Jar, Timeout: client.Timeout}
+	return m
+}
+
+// newDataPostProcessing returns the function that collects report data and applies the filters set via flags
+func newDataPostProcessing(flags metaFlags) func(CIReport, string, chan ReportDataField, *sync.WaitGroup) ReportData {
+	return func(r CIReport, reportName string, chanReportDataField chan ReportDataField, wg *sync.WaitGroup) ReportData {
+		reportData := ReportData{
+			Data: []ReportDataField{},
+			Name: reportName,
+		}
+		for reportDataField := range chanReportDataField {
+			reportData.Data = append(reportData.Data, reportDataField)
+		}
+		reportData = postProcessReportData(flags, reportData)
+		r.PutData(reportData)
+		wg.Done()
+		return reportData
 	}
-	for reportDataField := range chanReportDataField {
-		reportData.Data = append(reportData.Data, reportDataField)
+}
+
+// postProcessReportData applies the filters set via flags to collected report data
+func postProcessReportData(flags metaFlags, reportData ReportData) ReportData {
+	reportData = filterReportDataBySigs(reportData, flags.Sigs)
+	if flags.Features.Enabled(FeatureDependencyHints) {
+		reportData = withDependencyHints(reportData, flags.DependencyHints)
 	}
-	r.PutData(reportData)
-	wg.Done()
-	return reportData
+	reportData = withRunbooks(reportData, flags.Runbooks)
+	return sortReportData(reportData)
 }
 
 // SetMeta this function is used to set meta information that is being needed to generate ci-signal-report
 func SetMeta() Meta {
-	// Flags
+	return SetMetaFromArgs(os.Args[1:])
+}
+
+// SetMetaFromArgs sets meta information like SetMeta but parses the given arguments instead of the command line (used by subcommands)
+func SetMetaFromArgs(args []string) Meta {
+	// Flags, they are parsed with an own flag set so the package does not take over the flags of the binary it is embedded in
+	fs := flag.NewFlagSet("ci-reporter", flag.ExitOnError)
+
 	// -short default: off
-	isFlagShortSet := flag.Bool("short", false, "Shortens the report")
+	isFlagShortSet := fs.Bool("short", false, "Shortens the report")
 
 	// -emoji-off - default : on
-	isFlagEmojiOff := flag.Bool("emoji-off", false, "Remove emojis from report print-out")
+	isFlagEmojiOff := fs.Bool("emoji-off", false, "Remove emojis from report print-out")
+
+	// -no-color default: off (colors are only written to terminals, NO_COLOR turns them off as well)
+	isNoColor := fs.Bool("no-color", false, "Do not color the print-out, colors are only written to terminals and can be turned off with the NO_COLOR environment variable as well")
+
+	// -log-level default: info
+	logLevel := fs.String("log-level", LogLevelInfo.String(), fmt.Sprintf("Verbosity of the log written to stderr, options: [%s], 'debug' logs every request with its duration, status and the github rate limit", strings.Join(logLevelNames, ", ")))
 
 	// -v default: ""
-	releaseVersion := flag.String("v", "", "Adds specific K8s release version to the report (like -v '1.22, 1.21' or -v 1.22)")
+	releaseVersion := fs.String("v", "", "Adds specific K8s release version to the report (like -v '1.22, 1.21' or -v 1.22)")
 
-	// -emoji-off - default : off
-	isJSONOut := flag.Bool("json", false, "Report gets printed out in json format")
+	// -json - default : off
+	isJSONOut := fs.Bool("json", false, "Report gets printed out in json format (shorthand for -output json)")
+
+	// -output default: text
+	output := fs.String("output", outputText, fmt.Sprintf("Output format, options: '%s', '%s' (json follows a versioned schema, see 'schema print')", outputText, outputJSON))
 
 	// -emoji-off - default : off
-	specificReport := flag.String("report", "", fmt.Sprintf("Specify report, options: '%s', '%s'", githubReport, testgridReport))
+	specificReport := fs.String("report", "", fmt.Sprintf("Specify report, options: '%s', '%s', '%s' (job health per cloud provider), '%s' (failure clusters of failing jobs), '%s' (job health per platform), '%s' (quarantined tests), '%s' (pull requests fixing failing tests)", githubReport, testgridReport, providerReport, triageReport, platformReport, quarantineReport, prSignalReport))
+
+	// -webhook-url default: ""
+	webhookURL := fs.String("webhook-url", "", "Post the report to a webhook (json payload)")
+
+	// -webhook-template default: ""
+	webhookTemplate := fs.String("webhook-template", "", "Go template file used to render the webhook payload")
+
+	// -slack-webhook-url default: ""
+	slackWebhookURL := fs.String("slack-webhook-url", "", "Post a summary of the report to a slack incoming webhook")
+
+	// -slack-template default: ""
+	slackTemplate := fs.String("slack-template", "", "Go template file used to render the slack payload")
+
+	// -email-to default: ""
+	emailTo := fs.String("email-to", "", "Send the report by mail to these recipients (like -email-to 'sig-node-leads@example.com, alice@example.com')")
+
+	// -email-from default: ""
+	emailFrom := fs.String("email-from", "", "Sender address of the report mail, SMTP_USERNAME is used if it is not set")
+
+	// -google-doc default: ""
+	googleDoc := fs.String("google-doc", "", "Append the report to a google doc (document id), the document has to be shared with the service account set via GOOGLE_APPLICATION_CREDENTIALS")
+
+	// -google-sheet default: ""
+	googleSheet := fs.String("google-sheet", "", fmt.Sprintf("Append the report to a tab of a google spreadsheet (like -google-sheet 'SPREADSHEET_ID/%s'), the spreadsheet has to be shared with the service account set via GOOGLE_APPLICATION_CREDENTIALS", DefaultGoogleSheetTab))
+
+	// -smtp-server default: ""
+	smtpServer := fs.String("smtp-server", "", "Address of the smtp server used to send the report mail (like smtp.example.com:587), credentials are read from SMTP_USERNAME and SMTP_PASSWORD")
+
+	// -sig default: ""
+	sigs := fs.String("sig", "", "Only report testgrid jobs and github issues of specific sigs (like -sig 'sig-node, sig-network')")
+
+	// -snapshot-dir default: ""
+	snapshotDir := fs.String("snapshot-dir", "", "Store the report of this run as a snapshot in the given directory")
+
+	// -snapshot-compression default: zstd
+	snapshotCompression := fs.String("snapshot-compression", compressionZstd, fmt.Sprintf("Compression of stored snapshots, options: '%s', '%s', '%s'", compressionZstd, compressionGzip, compressionNone))
+
+	// -annotations default: ""
+	annotations := fs.String("annotations", "", "Json file with manual annotations of records, the file is the source of truth (runs without it carry the annotations of the previous -snapshot-dir snapshot over)")
+
+	// -acks default: ""
+	acks := fs.String("acks", "", "Yaml file with acknowledged known failures, acknowledged jobs and issues are listed as known issues until the acknowledgement expires (see the ack subcommand)")
+
+	// -store default: ""
+	store := fs.String("store", "", "Record the status of every job and the issue counts of this run to report trends with the trends subcommand, runs are compared with runs of the same sources and filters (like -store sqlite:ci-signal.db)")
+
+	// -github-api default: rest
+	githubAPI := fs.String("github-api", githubAPIRest, fmt.Sprintf("Github api used to request issues, options: '%s', '%s' (graphql also requests assignees, linked PRs and project status)", githubAPIRest, githubAPIGraphQL))
+
+	// -listen default: :9090
+	listen := fs.String("listen", ":9090", "Address the metrics server listens on (serve mode)")
+
+	// -interval default: 10m
+	interval := fs.Duration("interval", 10*time.Minute, "Time between two report refreshes (serve and watch mode)")
+
+	// -since default: 168h
+	since := fs.Duration("since", DefaultHandoffSince, "Window of the handoff subcommand, failing-test issues closed in this window are listed as resolved (the simulate subcommand replays the snapshots of this window)")
+
+	// -watch default: false
+	isWatch := fs.Bool("watch", false, "Refresh the report every -interval and redraw the dashboard summaries, changes since the previous refresh are highlighted")
+
+	// -github-app-id default: 0 (github app credentials are used instead of GITHUB_AUTH_TOKEN if they are set)
+	githubAppID := fs.Int64("github-app-id", 0, "Id of the github app the report authenticates as (needs -github-app-installation-id and -github-app-private-key)")
+
+	// -github-app-installation-id default: 0
+	githubAppInstallationID := fs.Int64("github-app-installation-id", 0, "Id of the installation of the github app, installation tokens are refreshed before they expire")
+
+	// -github-app-private-key default: ""
+	githubAppPrivateKey := fs.String("github-app-private-key", "", "Pem file with the private key of the github app")
+
+	// -github-token-file default: "" (GITHUB_AUTH_TOKEN or the token of the gh cli)
+	githubTokenFile := fs.String("github-token-file", "", "File that contains the github token (like a mounted secret), it is read again whenever a token is needed")
+
+	// -record default: ""
+	recordDir := fs.String("record", "", "Store all http responses in the given directory (fixtures for -replay)")
+
+	// -replay default: ""
+	replayDir := fs.String("replay", "", "Answer http requests with responses recorded with -record instead of requesting them")
+
+	// -cache-dir default: user cache directory (like ~/.cache/ci-signal-report)
+	cacheDir := fs.String("cache-dir", DefaultCacheDir(), "Directory http responses are cached in, following runs send conditional requests and reuse cached responses that did not change")
+
+	// -no-cache default: off
+	isNoCache := fs.Bool("no-cache", false, "Do not cache http responses, every response is requested again")
+
+	defaultSeverity := DefaultSeverityConfig()
+
+	// -threshold-warning default: 0.5
+	thresholdWarning := fs.Float64("threshold-warning", defaultSeverity.ThresholdWarning, "Jobs with a recent success rate below this threshold get high severity")
+
+	// -threshold-info default: 0.8
+	thresholdInfo := fs.Float64("threshold-info", defaultSeverity.ThresholdInfo, "Jobs with a recent success rate below this threshold get medium severity")
+
+	// -new-test-runs default: 5
+	newTestRuns := fs.Float64("new-test-runs", defaultSeverity.NewTestRuns, "Jobs with less or equal recent runs are highlighted as new tests")
+
+	// -severity-scorer default: threshold
+	severityScorer := fs.String("severity-scorer", thresholdScorerName, fmt.Sprintf("Scorer used to rank testgrid jobs, options: '%s', '%s', '%s', '%s'", thresholdScorerName, testCountScorerName, daysFailingScorerName, dashboardWeightScorerName))
+
+	// -severity-emojis default: ""
+	severityEmojis := fs.String("severity-emojis", "", "Custom highlight per severity (like -severity-emojis '3=🚨, 2=⚠️, 1=👀')")
+
+	// -subscriptions default: ""
+	subscriptionsFile := fs.String("subscriptions", "", "Json file with sig subscriptions, each subscribed sig gets its new failures sent to its own slack channel or webhook")
+
+	// -flakes default: off
+	isFlakes := fs.Bool("flakes", false, "Flake analysis mode, ranks the flakiest jobs and tests of master-blocking and master-informing")
+
+	// -post-to-issue default: ""
+	postToIssue := fs.String("post-to-issue", "", "Post the report in markdown format as comment on a github issue, a previous report comment gets updated (like -post-to-issue kubernetes/sig-release#1234)")
+
+	// -recurrence-index default: ""
+	recurrenceIndex := fs.String("recurrence-index", "", "Json file that indexes tracking issues per release cycle, failures that have been tracked in a previous cycle are flagged (needs -cycle)")
+
+	// -cycle default: ""
+	cycle := fs.String("cycle", "", "Release cycle of this run (like -cycle 1.23), used by -recurrence-index")
+
+	// -dependency-hints default: "" (hints shipped with the binary)
+	dependencyHintsFile := fs.String("dependency-hints", "", "Json file mapping jobs to the components they exercise, failing jobs get a hint which dependency bump to suspect (default hints are shipped with the binary)")
+
+	// -runbooks default: "" (runbooks shipped with the binary)
+	runbooksFile := fs.String("runbooks", "", "Json file with runbooks per failure class (infra quota, registry outage, product regression, new test) that are attached to failing jobs, '-features runbooks=true' attaches the runbooks shipped with the binary")
+
+	// -repo default: kubernetes/kubernetes
+	repositories := fs.String("repo", defaultGithubRepository.String(), "Github repositories issues are requested from (like -repo 'kubernetes/kubernetes, kubernetes-sigs/kind')")
+
+	// -milestone default: "" (latest release version set via -v)
+	milestone := fs.String("milestone", "", "Only report github issues in this milestone (like -milestone v1.22), issues without milestone are listed as action items (defaults to the latest version set via -v)")
+
+	// -only-unassigned default: off
+	isOnlyUnassigned := fs.Bool("only-unassigned", false, "Only report github issues nobody is assigned to")
+
+	// -testgrid-url default: https://testgrid.k8s.io
+	testgridURL := fs.String("testgrid-url", defaultTestgridURL, "Base url of the testgrid instance")
+
+	// -prow-url default: "" (https://prow.k8s.io for the default testgrid instance)
+	prowURL := fs.String("prow-url", "", "Base url of the prow instance failed builds are linked to, https://prow.k8s.io if -testgrid-url is not set. Builds of other testgrid instances are only linked if it is set")
+
+	// -dashboards default: "" (sig-release-master-blocking, sig-release-master-informing)
+	dashboards := fs.String("dashboards", "", "Testgrid dashboards that are reported instead of master-blocking and master-informing (like -dashboards 'serving, eventing')")
+
+	// -correlate-dependencies default: off
+	isCorrelateDependencies := fs.Bool("correlate-dependencies", false, "Failing jobs list pull requests merged between their last pass and first failure that updated vendored dependencies or build images")
+
+	// -dependency-labels default: "area/dependency, dependencies"
+	dependencyLabels := fs.String("dependency-labels", "area/dependency, dependencies", "Labels of pull requests that update dependencies (used by -correlate-dependencies)")
+
+	// -features default: ""
+	features := fs.String("features", "", fmt.Sprintf("Turn subsystems on or off (like -features 'issue-clustering=false'), options: %s", featureUsage()))
+
+	// -concurrency default: 10
+	concurrency := fs.Int("concurrency", defaultConcurrency, "Maximum number of requests that are sent at the same time")
+
+	// -deadlines default: ""
+	deadlines := fs.String("deadlines", "", "Time per source after which the data collected so far is reported and marked incomplete (like -deadlines 'testgrid: 30s, github: 60s')")
+
+	// -triage default: false
+	isTriage := fs.Bool("triage", false, "Failing jobs of the testgrid report list the triage failure clusters of their failing tests ('-report triage' reports only the clusters)")
+
+	// -triage-url default: https://storage.googleapis.com/k8s-gubernator/triage
+	triageURL := fs.String("triage-url", defaultTriageURL, "Base url of the triage failure data")
+
+	// -platforms default: ""
+	platforms := fs.String("platforms", "", "Platforms whose jobs are summarized across dashboards in their own section, jobs are matched by name (like 'windows, arm64')")
+
+	// -quarantine default: false
+	isQuarantine := fs.Bool("quarantine", false, "Adds the quarantine report, lists tests that are quarantined via tags like [Flaky] or the skip list and the quarantines added and removed since the last snapshot")
+
+	// -pr-signal default: false
+	isPRSignal := fs.Bool("pr-signal", false, "Adds the pr-signal report, lists the failing jobs and the kind/failing-test and kind/flake issues with the pull requests that fix them, their review status and whether they are in the merge queue")
+
+	// -quarantine-list default: ""
+	quarantineList := fs.String("quarantine-list", "", "Path of a skip list with one quarantined test per line (lines starting with # are ignored)")
+
+	// -fail-on default: "" (the check subcommand uses blocking-failing)
+	failOn := fs.String("fail-on", "", fmt.Sprintf("Exit with code %d if one of the conditions trips, options: '%s', '%s=N' (more than N flaky blocking jobs), '%s' (kind/failing-test issues without triage/accepted), '%s' (jobs ranked with high severity)", CheckUnhealthyExitCode, conditionBlockingFailing, conditionBlockingFlaky, conditionUntriagedIssues, conditionHighSeverity))
+
+	// -mention-policy default: ""
+	mentionPolicyFile := fs.String("mention-policy", "", "Json file with github handles and slack groups automation is permitted to mention (like {\"allow\": [\"@alice\", \"<!subteam^S0123>\"], \"deny\": [\"@kubernetes/*\"]})")
+
+	// -read-only default: true
+	isReadOnly := fs.Bool("read-only", true, "Disables all integrations that post or modify something (webhooks, slack, issue comments, sig subscriptions) regardless of other flags, set -read-only=false to enable them")
+
+	// -template default: ""
+	reportTemplate := fs.String("template", "", "Go template file used to render the report (like meeting notes or a weekly email) instead of the default output")
+
+	// -out default: ""
+	out := fs.String("out", "", "Files the full report is written to besides the console, the format is chosen by the extension: '.md', '.json', '.html' or '.txt' (like 'report.md, report.json')")
+
+	// -group-by default: ""
+	groupBy := fs.String("group-by", "", fmt.Sprintf("Print the records of the whole report grouped, options: '%s', '%s', '%s'", groupBySig, groupBySeverity, groupByDashboard))
+
+	if err := fs.Parse(args); err != nil {
+		Fatalf("Error parsing flags.\n[ERROR] %v", err)
+	}
+
+	level, err := ParseLogLevel(*logLevel)
+	if err != nil {
+		Fatalf("Information given via flag -log-level is invalid.\n[ERROR] %v", err)
+	}
+	logger := NewLogger(os.Stderr, level)
+
+	if *output != outputText && *output != outputJSON {
+		Fatalf("Information given via flag -output does not match options [%s, %s]", outputText, outputJSON)
+	}
+
+	outFiles := splitListInput(*out)
+	for _, path := range outFiles {
+		if _, err := rendererForFile(Meta{}, path); err != nil {
+			Fatalf("Information given via flag -out is invalid.\n[ERROR] %v", err)
+		}
+	}
+
+	if *githubAPI != githubAPIRest && *githubAPI != githubAPIGraphQL {
+		Fatalf("Information given via flag -github-api does not match options [%s, %s]", githubAPIRest, githubAPIGraphQL)
+	}
+
+	if *groupBy != "" && *groupBy != groupBySig && *groupBy != groupBySeverity && *groupBy != groupByDashboard {
+		Fatalf("Information given via flag -group-by does not match options [%s, %s, %s]", groupBySig, groupBySeverity, groupByDashboard)
+	}
+
+	if *store != "" {
+		if _, _, err := ParseStore(*store); err != nil {
+			Fatalf("Information given via flag -store is invalid.\n[ERROR] %v", err)
+		}
+	}
+
+	if *concurrency < 1 {
+		Fatalf("Information given via flag -concurrency has to be at least 1")
+	}
+	if *interval <= 0 {
+		Fatalf("Information given via flag -interval has to be a positive duration")
+	}
+	if *since <= 0 {
+		Fatalf("Information given via flag -since has to be a positive duration")
+	}
+
+	severityEmojiMapping, err := parseSeverityEmojis(*severityEmojis)
+	if err != nil {
+		Fatalf("Information given via flag -severity-emojis is invalid.\n[ERROR] %v", err)
+	}
+	severityConfig := SeverityConfig{
+		ThresholdWarning: *thresholdWarning,
+		ThresholdInfo:    *thresholdInfo,
+		NewTestRuns:      *newTestRuns,
+		Emojis:           severityEmojiMapping,
+	}
+	if err := severityConfig.validate(); err != nil {
+		Fatalf("Information given via severity flags is invalid.\n[ERROR] %v", err)
+	}
+	severityConfig.Scorer, err = NewSeverityScorer(*severityScorer, severityConfig)
+	if err != nil {
+		Fatalf("Information given via flag -severity-scorer is invalid.\n[ERROR] %v", err)
+	}
+
+	if *recurrenceIndex != "" && *cycle == "" {
+		Fatalf("Flag -recurrence-index needs the release cycle of this run via flag -cycle")
+	}
+
+	dependencyHints, err := LoadDependencyHints(*dependencyHintsFile)
+	if err != nil {
+		Fatalf("Error loading dependency hints.\n[ERROR] %v", err)
+	}
+
+	failConditions, err := ParseFailConditions(*failOn)
+	if err != nil {
+		Fatalf("Information given via flag -fail-on is invalid.\n[ERROR] %v", err)
+	}
+
+	featureGates, err := ParseFeatureGates(*features)
+	if err != nil {
+		Fatalf("Information given via flag -features is invalid.\n[ERROR] %v", err)
+	}
+
+	sourceDeadlines, err := ParseDeadlines(*deadlines)
+	if err != nil {
+		Fatalf("Information given via flag -deadlines is invalid.\n[ERROR] %v", err)
+	}
+
+	// runbooks are opt-in, setting a file turns them on
+	var runbooks []Runbook
+	if *runbooksFile != "" || featureGates.Enabled(FeatureRunbooks) {
+		if runbooks, err = LoadRunbooks(*runbooksFile); err != nil {
+			Fatalf("Error loading runbooks.\n[ERROR] %v", err)
+		}
+	}
+
+	// the platforms report is opt-in, setting platforms turns it on
+	platformList := splitListInput(*platforms)
+	if len(platformList) == 0 && featureGates.Enabled(FeaturePlatforms) {
+		platformList = defaultPlatforms
+	}
+
+	repositoryList, err := splitRepositoryInput(*repositories)
+	if err != nil {
+		Fatalf("Information given via flag -repo is invalid.\n[ERROR] %v", err)
+	}
+
+	var mentionPolicy *MentionPolicy
+	if *mentionPolicyFile != "" {
+		if mentionPolicy, err = LoadMentionPolicy(*mentionPolicyFile); err != nil {
+			Fatalf("Error loading mention policy.\n[ERROR] %v", err)
+		}
+	}
 
-	flag.Parse()
+	var issueReference *IssueReference
+	if *postToIssue != "" {
+		ref, err := ParseIssueReference(*postToIssue)
+		if err != nil {
+			Fatalf("Information given via flag -post-to-issue is invalid.\n[ERROR] %v", err)
+		}
+		issueReference = &ref
+	}
 
 	var env metaEnv
-	err := envconfig.Process("", &env)
+	err = envconfig.Process("", &env)
 	if err != nil {
-		// "Make sure to provide a GITHUB_AUTH_TOKEN, received an error during env decoding"
-		log.Fatalf("Error processing flags.\n[ERROR] %v", err)
+		Fatalf("Error processing flags.\n[ERROR] %v", err)
+	}
+
+	// github credentials, replayed responses do not need any
+	var githubTokenSource oauth2.TokenSource
+	githubAuth := GithubAuth{
+		AppID:          *githubAppID,
+		InstallationID: *githubAppInstallationID,
+		PrivateKeyFile: *githubAppPrivateKey,
+		TokenFile:      *githubTokenFile,
+		Token:          env.GithubToken,
+	}
+	if *replayDir == "" {
+		// credentials are resolved with the first github request, so subcommands that do not request github work offline
+		githubTokenSource = newLazyGithubTokenSource(githubAuth, nil)
+	}
+
+	if *emailTo != "" {
+		if _, _, err := net.SplitHostPort(*smtpServer); err != nil {
+			Fatalf("Information given via flag -smtp-server is invalid, it has to be set to host:port if -email-to is set.\n[ERROR] %v", err)
+		}
+		if *emailFrom == "" && env.SMTPUsername == "" {
+			Fatalf("Information given via flag -email-from is invalid, set -email-from or SMTP_USERNAME to send the report by mail")
+		}
+	}
+
+	if (*googleDoc != "" || *googleSheet != "") && env.GoogleCredentials == "" {
+		Fatalf("Information given via flag -google-doc or -google-sheet is invalid, set GOOGLE_APPLICATION_CREDENTIALS to the key file of a service account")
+	}
+	if *googleSheet != "" {
+		if _, _, err := ParseGoogleSheet(*googleSheet); err != nil {
+			Fatalf("Information given via flag -google-sheet is invalid.\n[ERROR] %v", err)
+		}
+	}
+
+	// Setup http client, responses can be recorded or replayed
+	if *isNoCache || *replayDir != "" {
+		*cacheDir = ""
+	}
+	httpClient := &http.Client{}
+	// requests are logged closest to the network, so requests answered from the cache are logged with status 304
+	transport := NewLoggingTransport(logger, http.DefaultTransport)
+	if githubTokenSource != nil {
+		// tokens are set closest to the network, cached responses are keyed by the identity of the credentials and not by the token
+		transport = NewGithubAuthTransport(githubTokenSource, transport)
+	}
+	if *cacheDir != "" {
+		transport = NewCachingTransport(logger, *cacheDir, githubAuth.Identity(), transport)
+	}
+	if *replayDir != "" {
+		httpClient.Transport = NewLoggingTransport(logger, NewReplayTransport(*replayDir))
+	} else if *recordDir != "" {
+		httpClient.Transport = NewRecordingTransport(*recordDir, transport)
+	} else {
+		httpClient.Transport = transport
 	}
 
 	// Setup github client
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: env.GithubToken},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	ghClient := github.NewClient(tc)
+	ghClient := newGithubClient(httpClient, env.GithubToken)
+
+	flags := metaFlags{
+		ShortOn:               *isFlagShortSet,
+		EmojisOff:             *isFlagEmojiOff,
+		NoColor:               *isNoColor,
+		ReleaseVersion:        splitReleaseVersionInput(logger, *releaseVersion),
+		JSONOut:               *isJSONOut || *output == outputJSON,
+		SpecificReport:        *specificReport,
+		WebhookURL:            *webhookURL,
+		WebhookTemplate:       *webhookTemplate,
+		SlackWebhookURL:       *slackWebhookURL,
+		SlackTemplate:         *slackTemplate,
+		EmailTo:               splitListInput(*emailTo),
+		GoogleDoc:             *googleDoc,
+		GoogleSheet:           *googleSheet,
+		EmailFrom:             *emailFrom,
+		SMTPServer:            *smtpServer,
+		FailOn:                failConditions,
+		MentionPolicy:         mentionPolicy,
+		ReadOnly:              *isReadOnly,
+		Template:              *reportTemplate,
+		Out:                   outFiles,
+		Sigs:                  splitSigInput(*sigs),
+		SnapshotDir:           *snapshotDir,
+		SnapshotCompression:   *snapshotCompression,
+		Store:                 *store,
+		Annotations:           *annotations,
+		Acks:                  *acks,
+		GithubAPI:             *githubAPI,
+		Listen:                *listen,
+		Interval:              *interval,
+		Since:                 *since,
+		Watch:                 *isWatch,
+		RecordDir:             *recordDir,
+		ReplayDir:             *replayDir,
+		CacheDir:              *cacheDir,
+		Severity:              severityConfig,
+		SubscriptionsFile:     *subscriptionsFile,
+		Flakes:                *isFlakes,
+		PostToIssue:           issueReference,
+		RecurrenceIndex:       *recurrenceIndex,
+		Cycle:                 *cycle,
+		DependencyHints:       dependencyHints,
+		Runbooks:              runbooks,
+		Repositories:          repositoryList,
+		Milestone:             strings.TrimSpace(*milestone),
+		OnlyUnassigned:        *isOnlyUnassigned,
+		TestgridURL:           strings.TrimSuffix(*testgridURL, "/"),
+		ProwURL:               strings.TrimSuffix(*prowURL, "/"),
+		Dashboards:            splitListInput(*dashboards),
+		CorrelateDependencies: *isCorrelateDependencies,
+		DependencyLabels:      splitListInput(*dependencyLabels),
+		Features:              featureGates,
+		Concurrency:           *concurrency,
+		Deadlines:             sourceDeadlines,
+		Triage:                *isTriage,
+		TriageURL:             strings.TrimSuffix(*triageURL, "/"),
+		Platforms:             platformList,
+		Quarantine:            *isQuarantine,
+		QuarantineList:        *quarantineList,
+		PRSignal:              *isPRSignal,
+		GroupBy:               *groupBy,
+	}
 
 	// Set meta data
 	return Meta{
-		Env: env,
-		Flags: metaFlags{
-			ShortOn:        *isFlagShortSet,
-			EmojisOff:      *isFlagEmojiOff,
-			ReleaseVersion: splitReleaseVersionInput(*releaseVersion),
-			JSONOut:        *isJSONOut,
-			SpecificReport: *specificReport,
-		},
+		Env:                env,
+		Flags:              flags,
 		GitHubClient:       ghClient,
-		DataPostProcessing: dataPostProcessing,
+		HTTPClient:         httpClient,
+		DataPostProcessing: newDataPostProcessing(flags),
+		Console:            NewConsole(os.Stdout, flags.NoColor, flags.EmojisOff),
+		Logger:             logger,
+		githubTokenSource:  githubTokenSource,
+	}
+}
+
+// githubCredentials checks that github requests can be authenticated, the credentials of the token source are resolved if they have not been yet
+func (m Meta) githubCredentials() error {
+	if m.Env.GithubToken != "" {
+		return nil
+	}
+	if m.githubTokenSource == nil {
+		return fmt.Errorf("no github credentials are set")
 	}
+	_, err := m.githubTokenSource.Token()
+	return err
+}
+
+// newGithubClient returns a github client that authenticates with the token and sends its requests using the http client
+func newGithubClient(httpClient *http.Client, token string) *github.Client {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	return github.NewClient(oauth2.NewClient(ctx, ts))
+}
+
+// Output formats
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// defaults used if no repositories, testgrid url or dashboards are set
+var defaultGithubRepository = GithubRepository{Owner: "kubernetes", Repo: "kubernetes"}
+
+const defaultTestgridURL = "https://testgrid.k8s.io"
+
+const defaultTriageURL = "https://storage.googleapis.com/k8s-gubernator/triage"
+
+const defaultProwURL = "https://prow.k8s.io"
+
+// repositories returns the github repositories issues are requested from
+func (f metaFlags) repositories() []GithubRepository {
+	if len(f.Repositories) == 0 {
+		return []GithubRepository{defaultGithubRepository}
+	}
+	return f.Repositories
+}
+
+// testgridURL returns the base url of the testgrid instance
+func (f metaFlags) testgridURL() string {
+	if f.TestgridURL == "" {
+		return defaultTestgridURL
+	}
+	return f.TestgridURL
+}
+
+// prowURL returns the base url of the prow instance failed builds are linked to. prow.k8s.io only renders the builds of
+// the default testgrid instance, no url is returned for other instances if none is set
+func (f metaFlags) prowURL() string {
+	if f.ProwURL != "" {
+		return f.ProwURL
+	}
+	if f.testgridURL() == defaultTestgridURL {
+		return defaultProwURL
+	}
+	return ""
+}
+
+// triageURL returns the base url of the triage failure data
+func (f metaFlags) triageURL() string {
+	if f.TriageURL == "" {
+		return defaultTriageURL
+	}
+	return f.TriageURL
+}
+
+// concurrency returns the maximum number of requests that are sent at the same time
+func (f metaFlags) concurrency() int {
+	if f.Concurrency < 1 {
+		return defaultConcurrency
+	}
+	return f.Concurrency
+}
+
+// dashboards returns the testgrid dashboards that are reported
+func (f metaFlags) dashboards() []testgridJob {
+	if len(f.Dashboards) == 0 {
+		return []testgridJob{
+			{OutputName: "Master-Blocking", URLName: string(sigReleaseMasterBlocking), Emoji: masterBlockingEmoji},
+			{OutputName: "Master-Informing", URLName: string(sigReleaseMasterInforming), Emoji: masterInformingEmoji},
+		}
+	}
+	jobs := []testgridJob{}
+	for _, d := range f.Dashboards {
+		emoji := masterInformingEmoji
+		if strings.Contains(d, "blocking") {
+			emoji = masterBlockingEmoji
+		}
+		jobs = append(jobs, testgridJob{OutputName: d, URLName: d, Emoji: emoji})
+	}
+	return jobs
 }
 
 // GetReporters used to get reporters that implement methods like RequestData and Print
 func (m Meta) GetReporters() []CIReport {
+	if m.Flags.Flakes {
+		return []CIReport{&FlakeReport{}}
+	}
 	if m.Flags.SpecificReport == "" {
-		return []CIReport{&GithubReport{}, &TestgridReport{}}
+		reporters := []CIReport{&GithubReport{}, &TestgridReport{}}
+		if len(m.Flags.Platforms) > 0 {
+			reporters = append(reporters, &PlatformReport{})
+		}
+		if m.Flags.Quarantine || m.Flags.QuarantineList != "" {
+			reporters = append(reporters, &QuarantineReport{})
+		}
+		if m.Flags.PRSignal {
+			reporters = append(reporters, &PRSignalReport{})
+		}
+		return reporters
 	} else if m.Flags.SpecificReport == githubReport {
 		return []CIReport{&GithubReport{}}
 	} else if m.Flags.SpecificReport == testgridReport {
 		return []CIReport{&TestgridReport{}}
+	} else if m.Flags.SpecificReport == providerReport {
+		return []CIReport{&ProviderReport{}}
+	} else if m.Flags.SpecificReport == triageReport {
+		return []CIReport{&TriageReport{}}
+	} else if m.Flags.SpecificReport == platformReport {
+		return []CIReport{&PlatformReport{}}
+	} else if m.Flags.SpecificReport == quarantineReport {
+		return []CIReport{&QuarantineReport{}}
+	} else if m.Flags.SpecificReport == prSignalReport {
+		return []CIReport{&PRSignalReport{}}
 	} else {
-		log.Fatalf("Information given via flag -report does not match options [%s, %s]", githubReport, testgridReport)
+		m.logger().fatal(fmt.Sprintf("Information given via flag -report does not match options [%s, %s, %s, %s, %s, %s, %s]", githubReport, testgridReport, providerReport, triageReport, platformReport, quarantineReport, prSignalReport))
 	}
 	return nil
 }
 
+// RequestReport requests data from all reporters at the same time and returns the report together with the reporters that have been used.
+// Requests are canceled if the context is done. If sources could not be requested they are returned as SourceErrors,
+// the report contains the data of all sources then (the data of failed sources is incomplete)
+func (m Meta) RequestReport(ctx context.Context) (Report, []CIReport, error) {
+	cireporters := m.GetReporters()
+	m.dashboardCache = newDashboardCache()
+	report := make(Report, len(cireporters))
+	errs := make([]error, len(cireporters))
+	var wg sync.WaitGroup
+	for i, r := range cireporters {
+		wg.Add(1)
+		go func(i int, r CIReport) {
+			defer wg.Done()
+			report[i], errs[i] = m.requestSource(ctx, r)
+		}(i, r)
+	}
+	wg.Wait()
+	sourceErrs := SourceErrors{}
+	for i, err := range errs {
+		if err != nil {
+			report[i].Name = reportName(cireporters[i])
+			report[i].Incomplete = true
+			sourceErrs = append(sourceErrs, &SourceError{Source: report[i].Name, Err: err})
+		}
+	}
+	if len(sourceErrs) > 0 {
+		return report, cireporters, sourceErrs
+	}
+	var err error
+	if m.Flags.RecurrenceIndex != "" {
+		if report, err = m.flagRecurrences(report, cireporters); err != nil {
+			return report, cireporters, err
+		}
+	}
+	if m.Flags.Annotations != "" || m.Flags.SnapshotDir != "" {
+		if report, err = m.applyAnnotations(report, cireporters); err != nil {
+			return report, cireporters, err
+		}
+	}
+	if m.Flags.Acks != "" {
+		if report, err = m.applyAcks(report, cireporters); err != nil {
+			return report, cireporters, err
+		}
+	}
+	return report, cireporters, nil
+}
+
+// applyAcks moves the records acknowledged in the -acks file to the known issues of their report
+func (m Meta) applyAcks(report Report, cireporters []CIReport) (Report, error) {
+	acks, err := LoadAcks(m.Flags.Acks)
+	if err != nil {
+		return report, fmt.Errorf("loading acknowledgements: %v", err)
+	}
+	report = ApplyAcks(report, acks, time.Now())
+	for i, r := range cireporters {
+		r.PutData(report[i])
+	}
+	return report, nil
+}
+
+// applyAnnotations attaches the annotations set via -annotations, the annotations of the previous snapshot are carried forward if it is not set
+func (m Meta) applyAnnotations(report Report, cireporters []CIReport) (Report, error) {
+	var annotations []Annotation
+	if m.Flags.Annotations != "" {
+		var err error
+		if annotations, err = LoadAnnotations(m.Flags.Annotations); err != nil {
+			return report, fmt.Errorf("loading annotations: %v", err)
+		}
+	}
+	var previousReport *Report
+	if m.Flags.SnapshotDir != "" {
+		previous, ok, err := LatestSnapshot(m.logger(), m.Flags.SnapshotDir)
+		if err != nil {
+			return report, fmt.Errorf("reading previous report snapshot: %v", err)
+		}
+		if ok {
+			previousReport = &previous.Report
+		}
+	}
+	report = ApplyAnnotations(report, annotations, previousReport, time.Now())
+	putReportData(report, cireporters)
+	return report, nil
+}
+
+// putReportData stores the report data in the reporters of the same name
+func putReportData(report Report, cireporters []CIReport) {
+	for _, r := range cireporters {
+		for _, reportData := range report {
+			if reportData.Name == reportName(r) {
+				r.PutData(reportData)
+			}
+		}
+	}
+}
+
+// flagRecurrences notes failures that have been tracked in previous cycles and adds the tracking issues of this run to the recurrence index
+func (m Meta) flagRecurrences(report Report, cireporters []CIReport) (Report, error) {
+	index, err := LoadRecurrenceIndex(m.Flags.RecurrenceIndex)
+	if err != nil {
+		return report, fmt.Errorf("loading recurrence index: %v", err)
+	}
+	report = index.Annotate(report, m.Flags.Cycle)
+	for i, r := range cireporters {
+		r.PutData(report[i])
+	}
+	index.Track(report, m.Flags.Cycle)
+	if err := index.Save(m.Flags.RecurrenceIndex); err != nil {
+		return report, fmt.Errorf("storing recurrence index: %v", err)
+	}
+	return report, nil
+}
+
+// GetNotifiers used to get notifiers that have been configured via flags
+func (m Meta) GetNotifiers() []Notifier {
+	notifiers := []Notifier{}
+	if m.Flags.WebhookURL != "" {
+		notifiers = append(notifiers, WebhookNotifier{URL: m.Flags.WebhookURL, Template: mustLoadPayloadTemplate(m.Flags.WebhookTemplate)})
+	}
+	if m.Flags.SlackWebhookURL != "" {
+		notifiers = append(notifiers, SlackNotifier{URL: m.Flags.SlackWebhookURL, Template: mustLoadPayloadTemplate(m.Flags.SlackTemplate)})
+	}
+	if len(m.Flags.EmailTo) > 0 {
+		from := m.Flags.EmailFrom
+		if from == "" {
+			from = m.Env.SMTPUsername
+		}
+		notifiers = append(notifiers, EmailNotifier{To: m.Flags.EmailTo, From: from, Server: m.Flags.SMTPServer, Username: m.Env.SMTPUsername, Password: m.Env.SMTPPassword})
+	}
+	if m.Flags.PostToIssue != nil {
+		notifiers = append(notifiers, IssueCommentNotifier{Issue: *m.Flags.PostToIssue})
+	}
+	if m.Flags.GoogleDoc != "" {
+		notifiers = append(notifiers, GoogleDocsNotifier{DocumentID: m.Flags.GoogleDoc, Credentials: m.Env.GoogleCredentials})
+	}
+	if m.Flags.GoogleSheet != "" {
+		spreadsheetID, tab, _ := ParseGoogleSheet(m.Flags.GoogleSheet)
+		notifiers = append(notifiers, GoogleSheetsNotifier{SpreadsheetID: spreadsheetID, Tab: tab, Credentials: m.Env.GoogleCredentials})
+	}
+	if m.Flags.ReadOnly && len(notifiers) > 0 {
+		m.logger().Info("Read-only mode, configured notifiers are not sent (set -read-only=false to send them)", "notifiers", len(notifiers))
+		return []Notifier{}
+	}
+	return notifiers
+}
+
+// mustLoadPayloadTemplate returns nil if no template path has been specified
+func mustLoadPayloadTemplate(path string) *template.Template {
+	tmpl, err := loadOptionalPayloadTemplate(path)
+	if err != nil {
+		Fatalf("Error loading payload template %s.\n[ERROR] %v", path, err)
+	}
+	return tmpl
+}
+
+// loadOptionalPayloadTemplate returns nil if no template path has been specified
+func loadOptionalPayloadTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return LoadPayloadTemplate(path)
+}
+
+// This function is used to split comma separated input ("a, b" => ["a", "b"])
+func splitListInput(input string) []string {
+	list := []string{}
+	for _, e := range strings.Split(input, ",") {
+		if strings.TrimSpace(e) != "" {
+			list = append(list, strings.TrimSpace(e))
+		}
+	}
+	return list
+}
+
 // This function is used to split release version input ("1.22, 1.21" => ["1.22", "1.21"])
-func splitReleaseVersionInput(input string) []string {
+func splitReleaseVersionInput(logger *Logger, input string) []string {
 	re := regexp.MustCompile(`\d.\d\d`)
 	releaseVersion := []string{}
 
@@ -147,7 +1028,7 @@ func splitReleaseVersionInput(input string) []string {
 			if found {
 				releaseVersion = append(releaseVersion, trimStr)
 			} else {
-				fmt.Printf("%s does not match\n", trimStr)
+				logger.Warn("Release version does not match, it is left out", "version", trimStr)
 			}
 		}
 	}
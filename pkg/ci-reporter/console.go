@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Console writes the print-outs of the reports. Terminal colors are only written if the output is a terminal that supports them,
+// so print-outs piped into files or copied into the meeting notes stay plain text. Emojis are left out if they are turned off (-emoji-off)
+type Console struct {
+	w        io.Writer
+	terminal bool
+	colors   bool
+	emojis   bool
+}
+
+// NewConsole returns a console that writes to w, colors are written if w is a terminal, noColor (-no-color) is not set,
+// the NO_COLOR environment variable is not set (https://no-color.org) and TERM is not "dumb"
+func NewConsole(w io.Writer, noColor bool, emojisOff bool) *Console {
+	return &Console{w: w, terminal: isTerminal(w), colors: !noColor && colorsSupported(w), emojis: !emojisOff}
+}
+
+// isTerminal tells if w is a terminal and not a pipe or a file
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorsSupported tells if w is a terminal that renders colors, ansi colors are turned on for windows consoles (see enableVirtualTerminal)
+func colorsSupported(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !isTerminal(w) {
+		return false
+	}
+	return enableVirtualTerminal(w.(*os.File))
+}
+
+// Printf formats and writes to the console, colors are removed if the console does not write them
+func (c *Console) Printf(format string, a ...interface{}) {
+	c.write(fmt.Sprintf(format, a...))
+}
+
+// Print writes to the console like fmt.Print
+func (c *Console) Print(a ...interface{}) {
+	c.write(fmt.Sprint(a...))
+}
+
+// Println writes to the console like fmt.Println
+func (c *Console) Println(a ...interface{}) {
+	c.write(fmt.Sprintln(a...))
+}
+
+func (c *Console) write(s string) {
+	if !c.colors {
+		s = stripColors(s)
+	}
+	// print-outs are best effort like fmt.Print, write errors of the terminal are ignored
+	_, _ = io.WriteString(c.w, s)
+}
+
+// clear clears the terminal before it is redrawn (see Watch), nothing is written if the console is no terminal
+func (c *Console) clear() {
+	if c.terminal {
+		_, _ = io.WriteString(c.w, clearScreen)
+	}
+}
+
+// heading prefixes a title with the emoji of its section ("🔥 Tests in Master-Blocking"), only the title is returned if emojis are off
+func (c *Console) heading(emoji string, title string) string {
+	if !c.emojis || emoji == "" {
+		return title
+	}
+	return fmt.Sprintf("%s %s", emoji, title)
+}
+
+// recordLine the first line of a job or cluster like "FAILING 🔴🔴 job", the severity is written out if emojis are off
+func (c *Console) recordLine(record ReportDataRecord) string {
+	if !c.emojis {
+		return fmt.Sprintf("%s severity:%d, %s", record.Status, record.Severity, record.Title)
+	}
+	return fmt.Sprintf("%s %s %s", record.Status, record.Highlight, record.Title)
+}
+
+// console returns the console the report is printed to, stdout if none is set
+func (m Meta) console() *Console {
+	if m.Console != nil {
+		return m.Console
+	}
+	return NewConsole(os.Stdout, m.Flags.NoColor, m.Flags.EmojisOff)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConsoleStripsColorsOfPipedOutput(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConsole(&buf, false, false)
+	c.Printf("%spriority/critical-urgent%s\n", colorGreen, colorReset)
+	if buf.String() != "priority/critical-urgent\n" {
+		t.Errorf("expected colors to be left out of a buffer, got %q", buf.String())
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	NewConsole(f, false, false).Printf("%smilestone v1.23%s\n", colorBlue, colorReset)
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "milestone v1.23\n" {
+		t.Errorf("expected colors to be left out of a file, got %q", data)
+	}
+}
+
+func TestConsoleColorsSupported(t *testing.T) {
+	var buf bytes.Buffer
+	if colorsSupported(&buf) {
+		t.Error("expected a buffer not to support colors")
+	}
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	if colorsSupported(os.Stdout) {
+		t.Error("expected NO_COLOR to turn colors off")
+	}
+}
+
+func TestConsoleEmojis(t *testing.T) {
+	record := ReportDataRecord{Status: "FAILING", Highlight: statusFailingEmoji, Severity: HighSeverity, Title: "job"}
+	tests := []struct {
+		emojisOff       bool
+		expectedHeading string
+		expectedRecord  string
+	}{
+		{false, masterBlockingEmoji + " Tests in Master-Blocking", "FAILING " + statusFailingEmoji + " job"},
+		{true, "Tests in Master-Blocking", "FAILING severity:3, job"},
+	}
+	for _, tc := range tests {
+		c := NewConsole(&bytes.Buffer{}, true, tc.emojisOff)
+		if got := c.heading(masterBlockingEmoji, "Tests in Master-Blocking"); got != tc.expectedHeading {
+			t.Errorf("expected heading %q, got %q", tc.expectedHeading, got)
+		}
+		if got := c.recordLine(record); got != tc.expectedRecord {
+			t.Errorf("expected record line %q, got %q", tc.expectedRecord, got)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// number of entries listed per section of the cycle report
+	cycleReportLimit = 10
+	cycleDateLayout  = "2006-01-02"
+)
+
+// LoadSnapshots reads all snapshots stored in dir that have been generated between since and until (zero times are ignored)
+func LoadSnapshots(logger *Logger, dir string, since time.Time, until time.Time) ([]Snapshot, error) {
+	paths, err := ListSnapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []Snapshot{}
+	for _, path := range paths {
+		snapshot, err := ReadSnapshot(logger, path)
+		if err != nil {
+			return nil, err
+		}
+		if (!since.IsZero() && snapshot.GeneratedAt.Before(since)) || (!until.IsZero() && snapshot.GeneratedAt.After(until)) {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].GeneratedAt.Before(snapshots[j].GeneratedAt) })
+	return snapshots, nil
+}
+
+// redStreak a time span in which a job has been failing in consecutive snapshots
+type redStreak struct {
+	dashboard string
+	job       string
+	start     time.Time
+	end       time.Time
+	ongoing   bool
+}
+
+func (s redStreak) duration() time.Duration {
+	return s.end.Sub(s.start)
+}
+
+// CycleReport composes a markdown retrospective of the CI health over all given snapshots (ordered by time)
+func CycleReport(snapshots []Snapshot) string {
+	if len(snapshots) == 0 {
+		return "# CI signal cycle report\n\nNo snapshots found.\n"
+	}
+	first := snapshots[0].GeneratedAt
+	last := snapshots[len(snapshots)-1].GeneratedAt
+	streaks := failingStreaks(snapshots)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# CI signal cycle report %s - %s\n", first.Format(cycleDateLayout), last.Format(cycleDateLayout)))
+
+	sb.WriteString("\n## Overview\n\n")
+	sb.WriteString(fmt.Sprintf("This report is based on %d snapshots taken over %d days.\n", len(snapshots), int(last.Sub(first).Hours()/24)))
+	blockingIncidents := []redStreak{}
+	for _, s := range streaks {
+		if strings.Contains(strings.ToLower(s.dashboard), "blocking") {
+			blockingIncidents = append(blockingIncidents, s)
+		}
+	}
+	sb.WriteString(fmt.Sprintf("Blocking dashboards had %d incidents with failing jobs, %d jobs have been failing on any dashboard.\n", len(blockingIncidents), countJobs(streaks)))
+
+	sb.WriteString("\n## Major incidents\n\n")
+	sb.WriteString("Failures of jobs on blocking dashboards, ordered by the time they started.\n\n")
+	if len(blockingIncidents) == 0 {
+		sb.WriteString("No blocking job has been failing during the cycle.\n")
+	}
+	for _, s := range blockingIncidents {
+		sb.WriteString(fmt.Sprintf("- %s: %s failing for %s%s\n", s.start.Format(cycleDateLayout), s.job, formatDays(s.duration()), ongoingSuffix(s)))
+	}
+
+	sb.WriteString("\n## Longest red jobs\n\n")
+	longest := append([]redStreak{}, streaks...)
+	sort.SliceStable(longest, func(i, j int) bool { return longest[i].duration() > longest[j].duration() })
+	if len(longest) == 0 {
+		sb.WriteString("No job has been failing during the cycle.\n")
+	}
+	for i, s := range longest {
+		if i == cycleReportLimit {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s (%s) failing for %s from %s%s\n", i+1, s.job, s.dashboard, formatDays(s.duration()), s.start.Format(cycleDateLayout), ongoingSuffix(s)))
+	}
+
+	sb.WriteString("\n## Flake statistics\n\n")
+	writeFlakeStatistics(&sb, snapshots)
+
+	sb.WriteString("\n## Tracking issues\n\n")
+	writeIssueStatistics(&sb, snapshots)
+	return sb.String()
+}
+
+// failingStreaks finds all spans in which testgrid jobs have been failing, ordered by start time
+func failingStreaks(snapshots []Snapshot) []redStreak {
+	open := map[string]*redStreak{}
+	streaks := []redStreak{}
+	for _, snapshot := range snapshots {
+		failingNow := map[string]bool{}
+		forEachTestgridJob(snapshot.Report, func(dashboard string, record ReportDataRecord) {
+			if record.Status != string(failing) {
+				return
+			}
+			key := dashboard + "/" + record.Title
+			failingNow[key] = true
+			if s, ok := open[key]; ok {
+				s.end = snapshot.GeneratedAt
+				return
+			}
+			open[key] = &redStreak{dashboard: dashboard, job: record.Title, start: snapshot.GeneratedAt, end: snapshot.GeneratedAt}
+		})
+		// jobs that are not failing anymore close their streak, the streak ends with the last snapshot the job has been failing in
+		for key, s := range open {
+			if !failingNow[key] {
+				streaks = append(streaks, *s)
+				delete(open, key)
+			}
+		}
+	}
+	for _, s := range open {
+		s.ongoing = true
+		streaks = append(streaks, *s)
+	}
+	sort.SliceStable(streaks, func(i, j int) bool {
+		if streaks[i].start.Equal(streaks[j].start) {
+			return streaks[i].job < streaks[j].job
+		}
+		return streaks[i].start.Before(streaks[j].start)
+	})
+	return streaks
+}
+
+func writeFlakeStatistics(sb *strings.Builder, snapshots []Snapshot) {
+	flakyCount := map[string]int{}
+	flakyPerSnapshot := 0
+	for _, snapshot := range snapshots {
+		forEachTestgridJob(snapshot.Report, func(dashboard string, record ReportDataRecord) {
+			if record.Status == string(flaky) {
+				flakyCount[fmt.Sprintf("%s (%s)", record.Title, dashboard)]++
+				flakyPerSnapshot++
+			}
+		})
+	}
+	sb.WriteString(fmt.Sprintf("%d distinct jobs have been flaky, on average %.1f jobs were flaky per snapshot.\n", len(flakyCount), float64(flakyPerSnapshot)/float64(len(snapshots))))
+	jobs := []string{}
+	for job := range flakyCount {
+		jobs = append(jobs, job)
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if flakyCount[jobs[i]] == flakyCount[jobs[j]] {
+			return jobs[i] < jobs[j]
+		}
+		return flakyCount[jobs[i]] > flakyCount[jobs[j]]
+	})
+	if len(jobs) > 0 {
+		sb.WriteString("\nMost frequently flaky jobs:\n\n")
+	}
+	for i, job := range jobs {
+		if i == cycleReportLimit {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s flaky in %.0f%% of snapshots\n", i+1, job, 100*float64(flakyCount[job])/float64(len(snapshots))))
+	}
+}
+
+func writeIssueStatistics(sb *strings.Builder, snapshots []Snapshot) {
+	issueIDs := func(report Report) map[int64]string {
+		ids := map[int64]string{}
+		for _, reportData := range report {
+			if reportData.Name != githubReport {
+				continue
+			}
+			for _, field := range reportData.Data {
+				for _, record := range field.Records {
+					ids[record.ID] = record.Title
+				}
+			}
+		}
+		return ids
+	}
+	seen := map[int64]string{}
+	for _, snapshot := range snapshots {
+		for id, title := range issueIDs(snapshot.Report) {
+			seen[id] = title
+		}
+	}
+	firstIssues := issueIDs(snapshots[0].Report)
+	lastIssues := issueIDs(snapshots[len(snapshots)-1].Report)
+	opened, resolved := 0, 0
+	for id := range seen {
+		if _, ok := firstIssues[id]; !ok {
+			opened++
+		}
+		if _, ok := lastIssues[id]; !ok {
+			resolved++
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%d failing-test and flake issues have been tracked, %d were opened and %d resolved during the cycle, %d are still open.\n", len(seen), opened, resolved, len(lastIssues)))
+}
+
+// forEachTestgridJob calls f for every testgrid job record (summaries are skipped)
+func forEachTestgridJob(report Report, f func(dashboard string, record ReportDataRecord)) {
+	for _, reportData := range report {
+		if reportData.Name != testgridReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if record.ID == testgridReportDetails {
+					f(field.Title, record)
+				}
+			}
+		}
+	}
+}
+
+func countJobs(streaks []redStreak) int {
+	jobs := map[string]bool{}
+	for _, s := range streaks {
+		jobs[s.dashboard+"/"+s.job] = true
+	}
+	return len(jobs)
+}
+
+func formatDays(d time.Duration) string {
+	days := d.Hours() / 24
+	if days < 1 {
+		return fmt.Sprintf("%.0f hours", d.Hours())
+	}
+	return fmt.Sprintf("%.1f days", days)
+}
+
+func ongoingSuffix(s redStreak) string {
+	if s.ongoing {
+		return " (still failing)"
+	}
+	return ""
+}
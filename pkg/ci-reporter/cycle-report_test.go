@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCycleSnapshot(day int, failingJobs []string, flakyJobs []string, issues []int64) Snapshot {
+	records := []ReportDataRecord{{ID: testgridReportSummary}}
+	for _, job := range failingJobs {
+		records = append(records, ReportDataRecord{ID: testgridReportDetails, Title: job, Status: string(failing)})
+	}
+	for _, job := range flakyJobs {
+		records = append(records, ReportDataRecord{ID: testgridReportDetails, Title: job, Status: string(flaky)})
+	}
+	issueRecords := []ReportDataRecord{}
+	for _, id := range issues {
+		issueRecords = append(issueRecords, ReportDataRecord{ID: id, Title: "issue"})
+	}
+	return Snapshot{
+		GeneratedAt: time.Date(2021, 9, day, 0, 0, 0, 0, time.UTC),
+		Report: Report{
+			{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: records}}},
+			{Name: githubReport, Data: []ReportDataField{{Records: issueRecords}}},
+		},
+	}
+}
+
+func TestFailingStreaks(t *testing.T) {
+	snapshots := []Snapshot{
+		testCycleSnapshot(1, []string{"a"}, nil, nil),
+		testCycleSnapshot(2, []string{"a", "b"}, nil, nil),
+		testCycleSnapshot(4, []string{"b"}, nil, nil),
+		testCycleSnapshot(5, []string{"a", "b"}, nil, nil),
+	}
+	streaks := failingStreaks(snapshots)
+	if len(streaks) != 3 {
+		t.Fatalf("expected 3 streaks, got %+v", streaks)
+	}
+	// a failed until the 2nd, recovered on the 4th and failed again on the 5th, b is failing since the 2nd
+	expected := []struct {
+		job     string
+		days    float64
+		ongoing bool
+	}{{"a", 1, false}, {"b", 3, true}, {"a", 0, true}}
+	for i, e := range expected {
+		s := streaks[i]
+		if s.job != e.job || s.duration().Hours()/24 != e.days || s.ongoing != e.ongoing {
+			t.Errorf("expected streak %d to be %+v, got %s %g days ongoing=%t", i, e, s.job, s.duration().Hours()/24, s.ongoing)
+		}
+	}
+}
+
+func TestCycleReport(t *testing.T) {
+	snapshots := []Snapshot{
+		testCycleSnapshot(1, []string{"a"}, []string{"f"}, []int64{1, 2}),
+		testCycleSnapshot(3, []string{"a"}, []string{"f", "g"}, []int64{2, 3}),
+		testCycleSnapshot(5, nil, []string{"f"}, []int64{3}),
+	}
+	report := CycleReport(snapshots)
+	for _, expected := range []string{
+		"# CI signal cycle report 2021-09-01 - 2021-09-05",
+		"based on 3 snapshots taken over 4 days",
+		"- 2021-09-01: a failing for 2.0 days",
+		"1. a (Master-Blocking) failing for 2.0 days from 2021-09-01",
+		"2 distinct jobs have been flaky, on average 1.3 jobs were flaky per snapshot",
+		"1. f (Master-Blocking) flaky in 100% of snapshots",
+		"3 failing-test and flake issues have been tracked, 1 were opened and 2 resolved during the cycle, 1 are still open",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected cycle report to contain %q, got\n%s", expected, report)
+		}
+	}
+}
+
+func TestLoadSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cycle-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, day := range []int{1, 10, 20} {
+		if _, err := WriteSnapshot(dir, testCycleSnapshot(day, nil, nil, nil), "none"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshots, err := LoadSnapshots(discardLogger, dir, time.Date(2021, 9, 5, 0, 0, 0, 0, time.UTC), time.Date(2021, 9, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].GeneratedAt.Day() != 10 {
+		t.Errorf("expected only the snapshot of the 10th, got %d snapshots", len(snapshots))
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// dashboardCache shares the testgrid dashboards of one run between the reports, so every dashboard is requested once per run
+// instead of once per report that summarizes it
+type dashboardCache struct {
+	mu      sync.Mutex
+	entries map[string]*dashboardEntry
+}
+
+// dashboardEntry a dashboard that has been requested or is being requested, done is closed when data and err are set
+type dashboardEntry struct {
+	done chan struct{}
+	data TestgridData
+	err  error
+}
+
+func newDashboardCache() *dashboardCache {
+	return &dashboardCache{entries: map[string]*dashboardEntry{}}
+}
+
+// requestDashboard returns the summary of the dashboard, it is requested with the client if no other report of the run requested it yet.
+// Failed requests are not cached, a report whose context is still live requests the dashboard again if the request of another report
+// has been canceled (like by the deadline of that report)
+func (m Meta) requestDashboard(ctx context.Context, client *http.Client, jobBaseURL string) (TestgridData, error) {
+	if m.dashboardCache == nil {
+		return reqTestgridSiteData(ctx, client, jobBaseURL)
+	}
+	cache := m.dashboardCache
+	for {
+		cache.mu.Lock()
+		entry, ok := cache.entries[jobBaseURL]
+		if !ok {
+			entry = &dashboardEntry{done: make(chan struct{})}
+			cache.entries[jobBaseURL] = entry
+			cache.mu.Unlock()
+			entry.data, entry.err = reqTestgridSiteData(ctx, client, jobBaseURL)
+			if entry.err != nil {
+				cache.mu.Lock()
+				delete(cache.entries, jobBaseURL)
+				cache.mu.Unlock()
+			}
+			close(entry.done)
+			return entry.data, entry.err
+		}
+		cache.mu.Unlock()
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if entry.err != nil && isContextError(entry.err) && ctx.Err() == nil {
+			continue
+		}
+		return entry.data, entry.err
+	}
+}
+
+// requestDashboards returns the summaries of the dashboards in the order of the dashboards, see requestDashboard
+func (m Meta) requestDashboards(ctx context.Context, client *http.Client, dashboards []testgridJob) ([]TestgridData, error) {
+	dashboardJobs := make([]TestgridData, len(dashboards))
+	errs := runWorkerPool(m.Flags.concurrency(), len(dashboards), func(i int) error {
+		jobsData, err := m.requestDashboard(ctx, client, fmt.Sprintf("%s/%s", m.Flags.testgridURL(), dashboards[i].URLName))
+		dashboardJobs[i] = jobsData
+		return err
+	})
+	return dashboardJobs, collectWorkerErrors(errs)
+}
+
+// isContextError checks if the request failed because its context has been canceled or its deadline passed
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
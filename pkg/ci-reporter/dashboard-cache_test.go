@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// countingTransport counts the requests per url, the first request of blockFirst is held back until it is canceled
+type countingTransport struct {
+	next       http.RoundTripper
+	mu         sync.Mutex
+	requests   map[string]int
+	blockFirst string
+	blocked    chan struct{}
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.requests[req.URL.String()]++
+	first := t.requests[req.URL.String()] == 1
+	t.mu.Unlock()
+	if first && t.blockFirst != "" && strings.Contains(req.URL.String(), t.blockFirst) {
+		close(t.blocked)
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	return t.next.RoundTrip(req)
+}
+
+func TestRequestReportRequestsDashboardsOnce(t *testing.T) {
+	meta := newTestMeta(metaFlags{ShortOn: true, Platforms: []string{"windows"}})
+	transport := &countingTransport{next: meta.HTTPClient.Transport, requests: map[string]int{}}
+	meta.HTTPClient = &http.Client{Transport: transport}
+
+	if _, _, err := meta.RequestReport(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, dashboard := range meta.Flags.dashboards() {
+		url := fmt.Sprintf("%s/%s/summary", meta.Flags.testgridURL(), dashboard.URLName)
+		if transport.requests[url] != 1 {
+			t.Errorf("expected dashboard %s to be requested once by the testgrid and platforms report but got %d requests", dashboard.URLName, transport.requests[url])
+		}
+	}
+}
+
+func TestRequestDashboardAfterCanceledRequest(t *testing.T) {
+	meta := newTestMeta(metaFlags{})
+	transport := &countingTransport{next: meta.HTTPClient.Transport, requests: map[string]int{}, blockFirst: "master-blocking", blocked: make(chan struct{})}
+	client := &http.Client{Transport: transport}
+	meta.dashboardCache = newDashboardCache()
+	jobBaseURL := fmt.Sprintf("%s/%s", meta.Flags.testgridURL(), sigReleaseMasterBlocking)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := meta.requestDashboard(ctx, client, jobBaseURL)
+		canceled <- err
+	}()
+	<-transport.blocked
+	requested := make(chan error)
+	go func() {
+		data, err := meta.requestDashboard(context.Background(), client, jobBaseURL)
+		if err == nil && len(data) == 0 {
+			err = fmt.Errorf("expected the jobs of the dashboard")
+		}
+		requested <- err
+	}()
+	cancel()
+
+	if err := <-canceled; !isContextError(err) {
+		t.Errorf("expected the canceled request to fail with the context error but got %v", err)
+	}
+	if err := <-requested; err != nil {
+		t.Errorf("expected the dashboard to be requested again for the report whose context is live but got %v", err)
+	}
+}
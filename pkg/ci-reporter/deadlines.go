@@ -0,0 +1,196 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseDeadlines parses -deadlines input ("testgrid: 30s, github: 60s" => {testgrid: 30s, github: 1m})
+func ParseDeadlines(input string) (map[string]time.Duration, error) {
+	sources := []string{githubReport, testgridReport, flakeReport, providerReport, triageReport, platformReport, quarantineReport, prSignalReport}
+	deadlines := map[string]time.Duration{}
+	for _, e := range splitListInput(input) {
+		parts := strings.SplitN(e, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q does not match source: duration", e)
+		}
+		source := strings.TrimSpace(parts[0])
+		known := false
+		for _, s := range sources {
+			known = known || s == source
+		}
+		if !known {
+			return nil, fmt.Errorf("%q does not match options [%s]", source, strings.Join(sources, ", "))
+		}
+		deadline, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || deadline <= 0 {
+			return nil, fmt.Errorf("deadline of %s has to be a positive duration, got %q", source, strings.TrimSpace(parts[1]))
+		}
+		deadlines[source] = deadline
+	}
+	return deadlines, nil
+}
+
+// reportName returns the name of the report a reporter requests data for
+func reportName(r CIReport) string {
+	switch r.(type) {
+	case *GithubReport:
+		return githubReport
+	case *TestgridReport:
+		return testgridReport
+	case *FlakeReport:
+		return flakeReport
+	case *ProviderReport:
+		return providerReport
+	case *TriageReport:
+		return triageReport
+	case *PlatformReport:
+		return platformReport
+	case *QuarantineReport:
+		return quarantineReport
+	case *PRSignalReport:
+		return prSignalReport
+	}
+	return ""
+}
+
+// partialReportData fields a reporter collected so far, they are reported if the deadline of the source passes first
+type partialReportData struct {
+	mu      sync.Mutex
+	name    string
+	fields  []ReportDataField
+	expired bool
+}
+
+// add keeps a collected field, fields collected after the deadline are dropped
+func (p *partialReportData) add(field ReportDataField) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.expired {
+		p.fields = append(p.fields, field)
+	}
+	return !p.expired
+}
+
+// deadlineReport drops the data of a reporter that is put after the deadline passed, the partial data is kept instead
+type deadlineReport struct {
+	CIReport
+	partial *partialReportData
+}
+
+func (r deadlineReport) PutData(reportData ReportData) {
+	r.partial.mu.Lock()
+	defer r.partial.mu.Unlock()
+	if !r.partial.expired {
+		r.CIReport.PutData(reportData)
+	}
+}
+
+// requestSource requests the data of the reporter, within the deadline of its source if one has been set via -deadlines
+func (m Meta) requestSource(ctx context.Context, r CIReport) (ReportData, error) {
+	if deadline, ok := m.Flags.Deadlines[reportName(r)]; ok {
+		return m.requestWithDeadline(ctx, r, deadline)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	return r.RequestData(ctx, m, &wg)
+}
+
+// requestWithDeadline requests the data of the reporter, if the deadline passes first the fields collected so far
+// (like the dashboards that have been requested) are reported and the data is marked as incomplete.
+// Requests that are still running are canceled, their errors and results are dropped
+func (m Meta) requestWithDeadline(ctx context.Context, r CIReport, deadline time.Duration) (ReportData, error) {
+	deadlineCtx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+	partial := &partialReportData{name: reportName(r)}
+	postProcessing := m.DataPostProcessing
+	m.DataPostProcessing = func(cr CIReport, name string, c chan ReportDataField, wg *sync.WaitGroup) ReportData {
+		collected := make(chan ReportDataField)
+		go func() {
+			defer close(collected)
+			for field := range c {
+				if partial.add(field) {
+					collected <- field
+				}
+			}
+		}()
+		return postProcessing(deadlineReport{CIReport: cr, partial: partial}, name, collected, wg)
+	}
+
+	type result struct {
+		reportData ReportData
+		err        error
+	}
+	requested := make(chan result, 1)
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		reportData, err := r.RequestData(deadlineCtx, m, &wg)
+		requested <- result{reportData: reportData, err: err}
+	}()
+	select {
+	case res := <-requested:
+		// requests that failed because the deadline passed are reported like the deadline passed first
+		if res.err == nil || deadlineCtx.Err() == nil || ctx.Err() != nil {
+			return res.reportData, res.err
+		}
+	case <-deadlineCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return ReportData{Name: partial.name}, err
+		}
+	}
+
+	partial.mu.Lock()
+	defer partial.mu.Unlock()
+	partial.expired = true
+	reportData := postProcessReportData(m.Flags, ReportData{Name: partial.name, Data: append([]ReportDataField{}, partial.fields...)})
+	reportData.Incomplete = true
+	r.PutData(reportData)
+	m.logger().Warn("Deadline passed, the collected sections are reported", "deadline", deadline, "report", partial.name, "sections", len(reportData.Data))
+	return reportData, nil
+}
+
+// SourceError the data of a source (like testgrid) could not be requested
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s report: %v", e.Source, e.Err)
+}
+
+// Unwrap returns the error of the request
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// SourceErrors the sources of a run whose data could not be requested, the other sources are reported as usual
+type SourceErrors []*SourceError
+
+func (e SourceErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
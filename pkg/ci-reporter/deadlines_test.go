@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingTransport holds back responses of urls containing one of blocks until release is closed or the request is canceled
+type blockingTransport struct {
+	next     http.RoundTripper
+	blocks   []string
+	release  chan struct{}
+	canceled chan string
+}
+
+func (t blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, block := range t.blocks {
+		if !strings.Contains(req.URL.String(), block) {
+			continue
+		}
+		select {
+		case <-t.release:
+		case <-req.Context().Done():
+			if t.canceled != nil {
+				t.canceled <- block
+			}
+			return nil, req.Context().Err()
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+func TestRequestWithDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	meta := newTestMeta(metaFlags{SpecificReport: testgridReport, ShortOn: true, Deadlines: map[string]time.Duration{testgridReport: 200 * time.Millisecond}})
+	meta.HTTPClient = &http.Client{Transport: blockingTransport{next: meta.HTTPClient.Transport, blocks: []string{"master-informing"}, release: release}}
+
+	start := time.Now()
+	report, cireporters, err := meta.RequestReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the report to be requested within the deadline, took %s", elapsed)
+	}
+	reportData := report[0]
+	if !reportData.Incomplete {
+		t.Error("expected the testgrid report to be marked as incomplete")
+	}
+	titles := []string{}
+	for _, field := range reportData.Data {
+		titles = append(titles, field.Title)
+	}
+	if !reflect.DeepEqual(titles, []string{"Master-Blocking"}) {
+		t.Errorf("expected the dashboard requested before the deadline, got %v", titles)
+	}
+	if !cireporters[0].GetData().Incomplete {
+		t.Error("expected the reporter to keep the incomplete data")
+	}
+}
+
+func TestRequestWithDeadlineCancelsRequests(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	canceled := make(chan string, 4)
+	deadline := 500 * time.Millisecond
+	meta := newTestMeta(metaFlags{ShortOn: true, Deadlines: map[string]time.Duration{testgridReport: deadline, githubReport: deadline}})
+	meta.HTTPClient = &http.Client{Transport: blockingTransport{next: meta.HTTPClient.Transport, blocks: []string{"master-informing", "api.github.com"}, release: release, canceled: canceled}}
+
+	start := time.Now()
+	report, _, err := meta.RequestReport(context.Background())
+	if err != nil {
+		t.Fatalf("expected the errors of canceled requests to be dropped, got %v", err)
+	}
+	// sources are requested at the same time, so the run is bound by the longest deadline and not by the sum of all deadlines
+	if elapsed := time.Since(start); elapsed >= 2*deadline {
+		t.Errorf("expected the sources to be requested at the same time, took %s", elapsed)
+	}
+	for _, reportData := range report {
+		if !reportData.Incomplete {
+			t.Errorf("expected the %s report to be marked as incomplete", reportData.Name)
+		}
+	}
+	for _, block := range []string{"master-informing", "api.github.com"} {
+		select {
+		case <-canceled:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the requests to be canceled at the deadline (%s)", block)
+		}
+	}
+}
+
+func TestRequestReportReturnsSourceErrors(t *testing.T) {
+	meta := newTestMeta(metaFlags{ShortOn: true})
+	meta.HTTPClient = &http.Client{Transport: githubFailingTransport{}}
+	report, _, err := meta.RequestReport(context.Background())
+	sourceErrs, ok := err.(SourceErrors)
+	if !ok || len(sourceErrs) != 1 || sourceErrs[0].Source != githubReport || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected the error of the github report, got %v", err)
+	}
+	for _, reportData := range report {
+		if reportData.Incomplete != (reportData.Name == githubReport) {
+			t.Errorf("expected only the github report to be incomplete, got %s incomplete: %v", reportData.Name, reportData.Incomplete)
+		}
+	}
+}
+
+func TestParseDeadlines(t *testing.T) {
+	deadlines, err := ParseDeadlines("testgrid: 30s, github: 1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deadlines, map[string]time.Duration{"testgrid": 30 * time.Second, "github": time.Minute}) {
+		t.Errorf("unexpected deadlines %v", deadlines)
+	}
+	for _, input := range []string{"testgrid", "jenkins: 30s", "github: soon", "github: -1s"} {
+		if _, err := ParseDeadlines(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
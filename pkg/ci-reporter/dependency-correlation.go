@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v34/github"
+)
+
+const (
+	// merges in this time before the first failure are considered if the job never passed before
+	defaultFailureWindow = 72 * time.Hour
+	// number of merged pull requests per window that are searched, the search api allows 30 requests per minute
+	suspectPullRequestsLimit = 300
+	// number of unlabeled pull requests per window whose files are listed, the latest merges are checked first
+	suspectFileChecksLimit = 20
+)
+
+// files of vendored dependencies and build images, pull requests touching them are suspects for failures
+var dependencyPathPrefixes = []string{"vendor/", "build/dependencies.yaml", "build/build-image/", "build/common.sh", "cluster/images/", "images/"}
+
+// suspectPullRequest a merged pull request that bumped a dependency while a job started failing
+type suspectPullRequest struct {
+	Repo     GithubRepository
+	Number   int
+	Title    string
+	MergedAt time.Time
+}
+
+// dependencyPRFinder searches merged pull requests that touched dependencies, results are cached per time window.
+// Requests are sent with the context of the report, so they are canceled with it
+type dependencyPRFinder struct {
+	ctx          context.Context
+	client       *github.Client
+	repositories []GithubRepository
+	labels       []string
+
+	mu    sync.Mutex
+	cache map[string][]suspectPullRequest
+}
+
+func newDependencyPRFinder(ctx context.Context, meta Meta) *dependencyPRFinder {
+	return &dependencyPRFinder{
+		ctx:          ctx,
+		client:       meta.GitHubClient,
+		repositories: meta.Flags.repositories(),
+		labels:       meta.Flags.DependencyLabels,
+		cache:        map[string][]suspectPullRequest{},
+	}
+}
+
+// failureWindow returns the time between the last pass and the first failure of the failing tests of a job
+func failureWindow(jobData testgridValue) (start time.Time, end time.Time, ok bool) {
+	var firstFailure, lastPass int64
+	for _, t := range jobData.Tests {
+		if t.FailTimestamp > 0 && (firstFailure == 0 || t.FailTimestamp < firstFailure) {
+			firstFailure = t.FailTimestamp
+		}
+	}
+	if firstFailure == 0 {
+		return start, end, false
+	}
+	for _, t := range jobData.Tests {
+		if t.PassTimestamp > lastPass && t.PassTimestamp < firstFailure {
+			lastPass = t.PassTimestamp
+		}
+	}
+	// testgrid timestamps are in milliseconds
+	end = time.Unix(0, firstFailure*int64(time.Millisecond)).UTC()
+	if lastPass == 0 {
+		return end.Add(-defaultFailureWindow), end, true
+	}
+	return time.Unix(0, lastPass*int64(time.Millisecond)).UTC(), end, true
+}
+
+// dependencyNote lists the suspect pull requests that have been merged between the last pass and the first failure of a job
+func (f *dependencyPRFinder) dependencyNote(jobData testgridValue) (string, error) {
+	start, end, ok := failureWindow(jobData)
+	if !ok {
+		return "", nil
+	}
+	suspects, err := f.suspects(start, end)
+	if err != nil || len(suspects) == 0 {
+		return "", err
+	}
+	prs := []string{}
+	multipleRepositories := len(f.repositories) > 1
+	for _, pr := range suspects {
+		ref := fmt.Sprintf("#%d", pr.Number)
+		if multipleRepositories {
+			ref = pr.Repo.String() + ref
+		}
+		prs = append(prs, fmt.Sprintf("%s %s (merged %s)", ref, pr.Title, pr.MergedAt.Format("2006-01-02 15:04")))
+	}
+	return fmt.Sprintf("Suspect dependency PRs since %s: %s", start.Format("2006-01-02 15:04"), strings.Join(prs, ", ")), nil
+}
+
+// suspects returns the merged pull requests of the window that are labeled as dependency update or touch dependency files.
+// A failed window is cached without suspects, so jobs failing in the same window do not repeat the requests
+func (f *dependencyPRFinder) suspects(start time.Time, end time.Time) ([]suspectPullRequest, error) {
+	key := start.Format(time.RFC3339) + ".." + end.Format(time.RFC3339)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if cached, ok := f.cache[key]; ok {
+		return cached, nil
+	}
+	suspects := []suspectPullRequest{}
+	for _, repo := range f.repositories {
+		repoSuspects, err := f.repositorySuspects(repo, key)
+		if err != nil {
+			f.cache[key] = []suspectPullRequest{}
+			return nil, err
+		}
+		suspects = append(suspects, repoSuspects...)
+	}
+	// the latest merge is the most likely cause
+	sort.SliceStable(suspects, func(i, j int) bool { return suspects[i].MergedAt.After(suspects[j].MergedAt) })
+	f.cache[key] = suspects
+	return suspects, nil
+}
+
+// repositorySuspects returns the suspects of a repository merged in the window ("start..end")
+func (f *dependencyPRFinder) repositorySuspects(repo GithubRepository, window string) ([]suspectPullRequest, error) {
+	ctx := f.ctx
+	merged := []*github.Issue{}
+	opts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	query := fmt.Sprintf("repo:%s is:pr is:merged merged:%s", repo, window)
+	for len(merged) < suspectPullRequestsLimit {
+		result, resp, err := f.client.Search.Issues(ctx, query, opts)
+		if err != nil {
+			return nil, fmt.Errorf("searching pull requests merged in %s: %v", window, err)
+		}
+		merged = append(merged, result.Issues...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	// the search api can not sort by merge time, merged pull requests are closed at the time they are merged
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].GetClosedAt().After(merged[j].GetClosedAt()) })
+
+	suspects := []suspectPullRequest{}
+	fileChecks := 0
+	for _, pr := range merged {
+		suspect := f.hasDependencyLabel(pr)
+		if !suspect && fileChecks < suspectFileChecksLimit {
+			fileChecks++
+			files, _, err := f.client.PullRequests.ListFiles(ctx, repo.Owner, repo.Repo, pr.GetNumber(), &github.ListOptions{PerPage: 100})
+			if err != nil {
+				return nil, fmt.Errorf("listing files of %s#%d: %v", repo, pr.GetNumber(), err)
+			}
+			suspect = touchesDependencies(files)
+		}
+		if suspect {
+			suspects = append(suspects, suspectPullRequest{Repo: repo, Number: pr.GetNumber(), Title: pr.GetTitle(), MergedAt: pr.GetClosedAt()})
+		}
+	}
+	return suspects, nil
+}
+
+func (f *dependencyPRFinder) hasDependencyLabel(pr *github.Issue) bool {
+	for _, label := range pr.Labels {
+		for _, dependencyLabel := range f.labels {
+			if label.GetName() == dependencyLabel {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func touchesDependencies(files []*github.CommitFile) bool {
+	for _, file := range files {
+		if isDependencyPath(file.GetFilename()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDependencyPath checks if a file belongs to vendored dependencies (go.mod files included) or build images
+func isDependencyPath(path string) bool {
+	if path == "go.mod" || strings.HasSuffix(path, "/go.mod") {
+		return true
+	}
+	for _, prefix := range dependencyPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
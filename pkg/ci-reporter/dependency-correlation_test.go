@@ -0,0 +1,169 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v34/github"
+)
+
+func TestFailureWindow(t *testing.T) {
+	jobData := testgridValue{Tests: []test{
+		{FailTimestamp: 1636000000000, PassTimestamp: 1635000000000},
+		{FailTimestamp: 1636100000000, PassTimestamp: 1635500000000},
+		// passed after the first failure of the job
+		{FailTimestamp: 1636200000000, PassTimestamp: 1636100000000},
+	}}
+	start, end, ok := failureWindow(jobData)
+	if !ok {
+		t.Fatal("expected a failure window")
+	}
+	if !start.Equal(time.Unix(1635500000, 0)) || !end.Equal(time.Unix(1636000000, 0)) {
+		t.Errorf("unexpected failure window %s - %s", start, end)
+	}
+
+	// jobs without passes look back defaultFailureWindow
+	start, end, _ = failureWindow(testgridValue{Tests: []test{{FailTimestamp: 1636000000000}}})
+	if end.Sub(start) != defaultFailureWindow {
+		t.Errorf("expected failure window of %s, got %s", defaultFailureWindow, end.Sub(start))
+	}
+	if _, _, ok := failureWindow(testgridValue{}); ok {
+		t.Error("expected no failure window without failing tests")
+	}
+}
+
+func TestIsDependencyPath(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"vendor/go.etcd.io/etcd/client/v3/client.go": true,
+		"go.mod":                        true,
+		"staging/src/k8s.io/api/go.mod": true,
+		"build/dependencies.yaml":       true,
+		"pkg/kubelet/kubelet.go":        false,
+	} {
+		if isDependencyPath(path) != expected {
+			t.Errorf("expected isDependencyPath(%q) to be %t", path, expected)
+		}
+	}
+}
+
+func TestDependencyNote(t *testing.T) {
+	searches := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		expectedQuery := "repo:kubernetes/kubernetes is:pr is:merged merged:2021-10-23T14:40:00Z..2021-11-04T04:26:40Z"
+		if q := r.URL.Query().Get("q"); q != expectedQuery {
+			t.Errorf("expected query %q, got %q", expectedQuery, q)
+		}
+		fmt.Fprint(w, `{"total_count": 3, "items": [
+			{"number": 3, "title": "Update containerd", "closed_at": "2021-11-03T10:00:00Z"},
+			{"number": 1, "title": "Bump etcd to 3.5.1", "closed_at": "2021-11-01T10:00:00Z", "labels": [{"name": "area/dependency"}]},
+			{"number": 2, "title": "Fix kubelet typo", "closed_at": "2021-11-02T10:00:00Z"}
+		]}`)
+	})
+	mux.HandleFunc("/repos/kubernetes/kubernetes/pulls/2/files", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"filename": "pkg/kubelet/kubelet.go"}]`)
+	})
+	mux.HandleFunc("/repos/kubernetes/kubernetes/pulls/3/files", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"filename": "vendor/github.com/containerd/containerd/client.go"}]`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	finder := newDependencyPRFinder(context.Background(), Meta{GitHubClient: client, Flags: metaFlags{DependencyLabels: []string{"area/dependency"}}})
+	jobData := testgridValue{Tests: []test{{FailTimestamp: 1636000000000, PassTimestamp: 1635000000000}}}
+	note, err := finder.dependencyNote(jobData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Suspect dependency PRs since 2021-10-23 14:40: #3 Update containerd (merged 2021-11-03 10:00), #1 Bump etcd to 3.5.1 (merged 2021-11-01 10:00)"
+	if note != expected {
+		t.Errorf("expected note %q, got %q", expected, note)
+	}
+	// jobs with the same failure window are answered from the cache
+	if _, err := finder.dependencyNote(jobData); err != nil {
+		t.Fatal(err)
+	}
+	if searches != 1 {
+		t.Errorf("expected 1 search, got %d", searches)
+	}
+}
+
+func TestDependencyNoteLimitsFileChecks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		items := []string{}
+		for i := 1; i <= suspectFileChecksLimit+5; i++ {
+			items = append(items, fmt.Sprintf(`{"number": %d, "title": "PR %d", "closed_at": "2021-11-01T10:%02d:00Z"}`, i, i, i))
+		}
+		fmt.Fprintf(w, `{"total_count": %d, "items": [%s]}`, len(items), strings.Join(items, ","))
+	})
+	listed := map[string]bool{}
+	mux.HandleFunc("/repos/kubernetes/kubernetes/pulls/", func(w http.ResponseWriter, r *http.Request) {
+		listed[r.URL.Path] = true
+		fmt.Fprint(w, `[{"filename": "vendor/k8s.io/utils/net.go"}]`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	finder := newDependencyPRFinder(context.Background(), Meta{GitHubClient: client})
+	suspects, err := finder.suspects(time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 11, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != suspectFileChecksLimit || len(suspects) != suspectFileChecksLimit {
+		t.Fatalf("expected the files of %d pull requests to be listed, got %d requests and %d suspects", suspectFileChecksLimit, len(listed), len(suspects))
+	}
+	// the latest merges are checked
+	if suspects[0].Number != suspectFileChecksLimit+5 || listed["/repos/kubernetes/kubernetes/pulls/1/files"] {
+		t.Errorf("expected the files of the latest merges to be listed, got %+v", suspects)
+	}
+}
+
+func TestDependencyNoteErrors(t *testing.T) {
+	searches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches++
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+	}))
+	defer server.Close()
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	finder := newDependencyPRFinder(context.Background(), Meta{GitHubClient: client})
+	jobData := testgridValue{Tests: []test{{FailTimestamp: 1636000000000, PassTimestamp: 1635000000000}}}
+	if _, err := finder.dependencyNote(jobData); err == nil {
+		t.Fatal("expected the error of the search")
+	}
+	// the failed window is not requested again
+	if note, err := finder.dependencyNote(jobData); err != nil || note != "" || searches != 1 {
+		t.Errorf("expected the failed window to be cached without suspects, got note %q, error %v and %d searches", note, err, searches)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	// embed is used to ship the default dependency hints inside of the binary
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+//go:embed dependency-hints.json
+var defaultDependencyHints []byte
+
+// DependencyHint maps testgrid jobs to the components and images they exercise
+type DependencyHint struct {
+	// Job regular expression matched against the job name (like "containerd" or "^gce-")
+	Job string `json:"job"`
+	// Components that are exercised by matching jobs (like "etcd", "containerd", "cloud provider gce")
+	Components []string `json:"components"`
+
+	jobRegex *regexp.Regexp
+}
+
+// dependencyHintsFile format of the knowledge file set via -dependency-hints
+type dependencyHintsFile struct {
+	Hints []DependencyHint `json:"hints"`
+}
+
+// LoadDependencyHints reads dependency hints from a json file, the hints shipped with the binary are used if path is empty
+func LoadDependencyHints(path string) ([]DependencyHint, error) {
+	data := defaultDependencyHints
+	if path != "" {
+		var err error
+		data, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseDependencyHints(data)
+}
+
+func parseDependencyHints(data []byte) ([]DependencyHint, error) {
+	var file dependencyHintsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i, h := range file.Hints {
+		re, err := regexp.Compile(h.Job)
+		if err != nil {
+			return nil, fmt.Errorf("hint %d: %v", i, err)
+		}
+		file.Hints[i].jobRegex = re
+	}
+	return file.Hints, nil
+}
+
+// dependencyComponents returns the components of all hints that match the job, each component is only listed once
+func dependencyComponents(hints []DependencyHint, jobName string) []string {
+	components := []string{}
+	seen := map[string]bool{}
+	for _, h := range hints {
+		if h.jobRegex == nil || !h.jobRegex.MatchString(jobName) {
+			continue
+		}
+		for _, c := range h.Components {
+			if !seen[c] {
+				seen[c] = true
+				components = append(components, c)
+			}
+		}
+	}
+	return components
+}
+
+// withDependencyHints adds a note to every failing testgrid job which lists the components that the job exercises
+func withDependencyHints(reportData ReportData, hints []DependencyHint) ReportData {
+	if reportData.Name != testgridReport || len(hints) == 0 {
+		return reportData
+	}
+	fields := []ReportDataField{}
+	for _, field := range reportData.Data {
+		records := []ReportDataRecord{}
+		for _, record := range field.Records {
+			if record.ID == testgridReportDetails && record.Status == string(failing) {
+				if components := dependencyComponents(hints, record.Title); len(components) > 0 {
+					record.Notes = append(append([]string{}, record.Notes...), fmt.Sprintf("Dependencies: %s", strings.Join(components, ", ")))
+				}
+			}
+			records = append(records, record)
+		}
+		field.Records = records
+		fields = append(fields, field)
+	}
+	reportData.Data = fields
+	return reportData
+}
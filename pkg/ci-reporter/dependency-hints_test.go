@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestDefaultDependencyHints(t *testing.T) {
+	hints, err := LoadDependencyHints("")
+	if err != nil {
+		t.Fatalf("expected default dependency hints to be valid, got %v", err)
+	}
+	components := dependencyComponents(hints, "gce-cos-master-serial")
+	for _, expected := range []string{"cloud provider gce (cloud-provider-gcp)", "etcd (cluster/gce manifests)", "COS node image"} {
+		found := false
+		for _, c := range components {
+			found = found || c == expected
+		}
+		if !found {
+			t.Errorf("expected %q in hints of gce-cos-master-serial, got %v", expected, components)
+		}
+	}
+}
+
+func TestParseDependencyHints(t *testing.T) {
+	if _, err := parseDependencyHints([]byte(`{"hints": [{"job": "(", "components": ["etcd"]}]}`)); err == nil {
+		t.Error("expected invalid job expression to fail")
+	}
+	hints, err := parseDependencyHints([]byte(`{"hints": [{"job": "containerd", "components": ["containerd"]}, {"job": "node", "components": ["containerd", "runc"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if components := dependencyComponents(hints, "node-containerd"); !reflect.DeepEqual(components, []string{"containerd", "runc"}) {
+		t.Errorf("expected components to be listed once, got %v", components)
+	}
+}
+
+func TestTestgridReportDependencyHints(t *testing.T) {
+	hints, err := parseDependencyHints([]byte(`{"hints": [{"job": "master", "components": ["etcd"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := newTestMeta(metaFlags{DependencyHints: hints})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&TestgridReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			if record.ID != testgridReportDetails {
+				continue
+			}
+			hinted := record.Notes[len(record.Notes)-1] == "Dependencies: etcd"
+			// only failing jobs get hints, flaky jobs like verify-master don't
+			if hinted != (record.Status == string(failing)) {
+				t.Errorf("unexpected hint for %s job %s: %v", record.Status, record.Title, record.Notes)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Operations of a record change, the names follow json patch (RFC 6902)
+const (
+	DiffAdd     = "add"
+	DiffRemove  = "remove"
+	DiffReplace = "replace"
+)
+
+// ReportDiff records that have been added, removed or changed between two reports (like two stored snapshots)
+type ReportDiff struct {
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	Changes []RecordDiff `json:"changes"`
+}
+
+// RecordDiff one added, removed or changed record. Bots can react to specific transitions by matching
+// Op, Report and the status of Previous and Record (like a testgrid job that turned from FLAKY to FAILING)
+type RecordDiff struct {
+	// Op 'add', 'remove' or 'replace'
+	Op string `json:"op"`
+	// Path identifies the record across runs (see recordKey)
+	Path string `json:"path"`
+	// Report name of the report of the record (like 'testgrid')
+	Report string `json:"report"`
+	// Field title of the field of the record (like the dashboard 'Master-Blocking')
+	Field string `json:"field"`
+	// Record the record of the newer report, the removed record if Op is 'remove'
+	Record ReportDataRecord `json:"record"`
+	// Previous the record of the older report if Op is 'replace'
+	Previous *ReportDataRecord `json:"previous,omitempty"`
+}
+
+// DiffSnapshots compares the reports of two snapshots, records are changed if their status or severity changed
+func DiffSnapshots(previous Snapshot, current Snapshot) ReportDiff {
+	diff := ReportDiff{From: previous.GeneratedAt, To: current.GeneratedAt, Changes: []RecordDiff{}}
+	previousRecords := map[string]ReportDataRecord{}
+	for _, reportData := range previous.Report {
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if !isSummaryRecord(reportData.Name, record) {
+					previousRecords[recordKey(reportData.Name, field, record)] = record
+				}
+			}
+		}
+	}
+
+	currentKeys := map[string]bool{}
+	for _, reportData := range current.Report {
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if isSummaryRecord(reportData.Name, record) {
+					continue
+				}
+				key := recordKey(reportData.Name, field, record)
+				currentKeys[key] = true
+				change := RecordDiff{Path: key, Report: reportData.Name, Field: field.Title, Record: record}
+				before, ok := previousRecords[key]
+				if !ok {
+					change.Op = DiffAdd
+				} else if before.Status != record.Status || before.Severity != record.Severity {
+					change.Op = DiffReplace
+					change.Previous = &before
+				} else {
+					continue
+				}
+				diff.Changes = append(diff.Changes, change)
+			}
+		}
+	}
+
+	for _, reportData := range previous.Report {
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				key := recordKey(reportData.Name, field, record)
+				if !isSummaryRecord(reportData.Name, record) && !currentKeys[key] {
+					diff.Changes = append(diff.Changes, RecordDiff{Op: DiffRemove, Path: key, Report: reportData.Name, Field: field.Title, Record: record})
+				}
+			}
+		}
+	}
+	return diff
+}
+
+// String describes the changes in human readable text, one line per record
+func (d ReportDiff) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Changes from %s to %s\n", d.From.UTC().Format(time.RFC3339), d.To.UTC().Format(time.RFC3339)))
+	if len(d.Changes) == 0 {
+		sb.WriteString("No records changed.\n")
+	}
+	for _, c := range d.Changes {
+		title := c.Record.Title
+		if c.Report == githubReport {
+			title = fmt.Sprintf("%s#%d %s", c.Field, c.Record.ID, c.Record.Title)
+		} else if c.Field != "" {
+			title = fmt.Sprintf("%s %s", c.Field, c.Record.Title)
+		}
+		switch c.Op {
+		case DiffAdd:
+			sb.WriteString(strings.TrimSpace(fmt.Sprintf("+ [%s] %s %s", c.Report, title, c.Record.Status)) + "\n")
+		case DiffRemove:
+			sb.WriteString(strings.TrimSpace(fmt.Sprintf("- [%s] %s %s", c.Report, title, c.Record.Status)) + "\n")
+		case DiffReplace:
+			sb.WriteString(fmt.Sprintf("~ [%s] %s %s (severity %d) -> %s (severity %d)\n", c.Report, title, c.Previous.Status, c.Previous.Severity, c.Record.Status, c.Record.Severity))
+		}
+	}
+	return sb.String()
+}
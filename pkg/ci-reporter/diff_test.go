@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	from := time.Date(2021, 10, 18, 9, 0, 0, 0, time.UTC)
+	previous := Snapshot{GeneratedAt: from, Report: Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Notes: []string{"10 jobs total"}},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: "FLAKY", Severity: MediumSeverity},
+			{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FAILING", Severity: HighSeverity},
+			{ID: testgridReportDetails, Title: "kind-master-parallel", Status: "FLAKY", Severity: MediumSeverity},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 105242, Title: "[Failing test] gce-serial"}}}}},
+	}}
+	current := Snapshot{GeneratedAt: from.Add(24 * time.Hour), Report: Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Notes: []string{"11 jobs total"}},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: "FAILING", Severity: HighSeverity},
+			{ID: testgridReportDetails, Title: "kind-master-parallel", Status: "FLAKY", Severity: MediumSeverity},
+			{ID: testgridReportDetails, Title: "gce-cos-master-alpha", Status: "FAILING", Severity: HighSeverity},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 105242, Title: "[Failing test] gce-serial"}}}}},
+	}}
+
+	diff := DiffSnapshots(previous, current)
+	expected := `Changes from 2021-10-18T09:00:00Z to 2021-10-19T09:00:00Z
+~ [testgrid] Master-Blocking gce-cos-master-serial FLAKY (severity 2) -> FAILING (severity 3)
++ [testgrid] Master-Blocking gce-cos-master-alpha FAILING
+- [testgrid] Master-Blocking gce-cos-master-default FAILING
+`
+	if diff.String() != expected {
+		t.Errorf("expected diff\n%s\ngot\n%s", expected, diff)
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var patch struct {
+		Changes []struct {
+			Op       string
+			Path     string
+			Record   struct{ Status string }
+			Previous *struct{ Status string }
+		}
+	}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		t.Fatal(err)
+	}
+	change := patch.Changes[0]
+	if change.Op != DiffReplace || change.Path != "testgrid/Master-Blocking/1/gce-cos-master-serial" || change.Previous == nil || change.Previous.Status != "FLAKY" || change.Record.Status != "FAILING" {
+		t.Errorf("unexpected json change %+v", change)
+	}
+	if patch.Changes[1].Op != DiffAdd || patch.Changes[2].Op != DiffRemove || patch.Changes[2].Previous != nil {
+		t.Errorf("unexpected json changes %s", data)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// issueKindTagRegex tags of failing test and flake issue titles (like "[Failing Test]", "[Failing Job]" or "[Flaky test]")
+	issueKindTagRegex = regexp.MustCompile(`\[(failing|flaky)[ -]?(tests?|jobs?)\]`)
+	// issueSigTagRegex sig tags in issue titles (like "[sig-storage]"), test names start with the sig tag (like "[sig-node] Pods should be restarted")
+	issueSigTagRegex = regexp.MustCompile(`\[sig-[a-z-]+\]`)
+)
+
+// issueSubjects extracts the job and test names an issue title refers to, issues with a common subject likely track the same failure.
+// Jobs are only found if the title names one of the known jobs of the dashboards, so hyphenated words like "out-of-memory" are no jobs
+func issueSubjects(title string, knownJobs []string) []string {
+	title = issueKindTagRegex.ReplaceAllString(strings.ToLower(title), " ")
+	subjects := []string{}
+	jobs := []string{}
+	for _, job := range knownJobs {
+		if job = strings.ToLower(job); containsJobName(title, job) {
+			jobs = append(jobs, job)
+			subjects = append(subjects, "job "+job)
+		}
+	}
+	if sigTags := issueSigTagRegex.FindAllStringIndex(title, -1); len(sigTags) > 0 {
+		testName := strings.Join(strings.Fields(strings.TrimRight(title[sigTags[len(sigTags)-1][0]:], ".: ")), " ")
+		// titles that only name sigs and jobs do not name a test
+		remainder := issueSigTagRegex.ReplaceAllString(testName, " ")
+		for _, job := range jobs {
+			remainder = strings.ReplaceAll(remainder, job, " ")
+		}
+		if len(strings.Fields(remainder)) > 1 {
+			subjects = append(subjects, fmt.Sprintf("test %q", testName))
+		}
+	}
+	return subjects
+}
+
+// containsJobName tells if the title names the job as a whole word, "gce-serial" is not named by "gce-serial-alpha"
+func containsJobName(title string, job string) bool {
+	if job == "" {
+		return false
+	}
+	for start := 0; ; {
+		i := strings.Index(title[start:], job)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(job)
+		if (i == 0 || !isJobNameChar(title[i-1])) && (end == len(title) || !isJobNameChar(title[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isJobNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+// duplicateIssues returns for every issue the issues that share a known job or a test name with it and the names they share
+func duplicateIssues(issues GithubIssuesAfterID, knownJobs []string) map[int64]map[int64][]string {
+	issuesBySubject := map[string][]int64{}
+	for number, issue := range issues {
+		for _, subject := range issueSubjects(issue.Title, knownJobs) {
+			issuesBySubject[subject] = append(issuesBySubject[subject], number)
+		}
+	}
+	duplicates := map[int64]map[int64][]string{}
+	for subject, numbers := range issuesBySubject {
+		for _, number := range numbers {
+			for _, other := range numbers {
+				if number == other {
+					continue
+				}
+				if _, ok := duplicates[number]; !ok {
+					duplicates[number] = map[int64][]string{}
+				}
+				duplicates[number][other] = append(duplicates[number][other], subject)
+			}
+		}
+	}
+	return duplicates
+}
+
+// duplicateIssueNote suggests to consolidate an issue and its likely duplicates into the oldest issue
+func duplicateIssueNote(number int64, duplicates map[int64][]string, issues GithubIssuesAfterID) string {
+	numbers := []int64{}
+	subjects := []string{}
+	seen := map[string]bool{}
+	for other, shared := range duplicates {
+		numbers = append(numbers, other)
+		for _, subject := range shared {
+			if !seen[subject] {
+				seen[subject] = true
+				subjects = append(subjects, subject)
+			}
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	sort.Strings(subjects)
+	references := []string{}
+	for _, other := range numbers {
+		references = append(references, fmt.Sprintf("#%d", other))
+	}
+	if number < numbers[0] {
+		links := []string{}
+		for _, other := range numbers {
+			links = append(links, fmt.Sprintf("#%d %s", other, issues[other].HTMLURL))
+		}
+		return fmt.Sprintf("Likely duplicates %s (same %s), consider consolidating them into this issue", strings.Join(links, ", "), strings.Join(subjects, ", "))
+	}
+	return fmt.Sprintf("Likely duplicate of %s (same %s), consider consolidating into #%d %s", strings.Join(references, ", "), strings.Join(subjects, ", "), numbers[0], issues[numbers[0]].HTMLURL)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testKnownJobs job names of the dashboards issue titles are clustered by
+var testKnownJobs = []string{"ci-kubernetes-e2e-gci-gce-serial", "ci-kubernetes-e2e-gci-gce-alpha-features", "kubeadm-kinder-upgrade", "gce-serial"}
+
+func TestIssueSubjects(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected []string
+	}{
+		{"[Failing test][sig-storage] ci-kubernetes-e2e-gci-gce-serial", []string{"job ci-kubernetes-e2e-gci-gce-serial"}},
+		{"[Flaky Test] [sig-node] Pods should be restarted.", []string{`test "[sig-node] pods should be restarted"`}},
+		{"[sig-cluster-lifecycle] kubeadm-kinder-upgrade [sig-node] Pods should be restarted", []string{"job kubeadm-kinder-upgrade", `test "[sig-node] pods should be restarted"`}},
+		{"volume metrics tests failure", []string{}},
+		// hyphenated words and job names that only contain a known job name are no known jobs
+		{"[Failing test] node-e2e-flake out-of-memory in gce-serial-alpha", []string{}},
+	}
+	for _, test := range tests {
+		if subjects := issueSubjects(test.title, testKnownJobs); !reflect.DeepEqual(subjects, test.expected) {
+			t.Errorf("expected subjects %v of %q, got %v", test.expected, test.title, subjects)
+		}
+	}
+}
+
+func TestDuplicateIssues(t *testing.T) {
+	issues := GithubIssuesAfterID{
+		100: {Number: 100, Title: "[Failing Test] ci-kubernetes-e2e-gci-gce-serial", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/100"},
+		105: {Number: 105, Title: "[Flaky Test] ci-kubernetes-e2e-gci-gce-serial [sig-node] Pods should be restarted", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/105"},
+		110: {Number: 110, Title: "[Flaky test][sig-node] Pods should be restarted", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/110"},
+		120: {Number: 120, Title: "[Failing Test] ci-kubernetes-e2e-gci-gce-alpha-features", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/120"},
+	}
+	duplicates := duplicateIssues(issues, testKnownJobs)
+	if _, ok := duplicates[120]; ok || len(duplicates) != 3 {
+		t.Fatalf("expected issues 100, 105 and 110 to be duplicates, got %v", duplicates)
+	}
+
+	expected := map[int64]string{
+		100: "Likely duplicates #105 https://github.com/kubernetes/kubernetes/issues/105 (same job ci-kubernetes-e2e-gci-gce-serial), consider consolidating them into this issue",
+		105: `Likely duplicate of #100, #110 (same job ci-kubernetes-e2e-gci-gce-serial, test "[sig-node] pods should be restarted"), consider consolidating into #100 https://github.com/kubernetes/kubernetes/issues/100`,
+		110: `Likely duplicate of #105 (same test "[sig-node] pods should be restarted"), consider consolidating into #105 https://github.com/kubernetes/kubernetes/issues/105`,
+	}
+	// issues that only share hyphenated words are no duplicates
+	unrelated := GithubIssuesAfterID{
+		200: {Number: 200, Title: "[Flaky Test] kubelet out-of-memory in node-e2e-flake"},
+		201: {Number: 201, Title: "[Failing Test] scheduler out-of-memory in node-e2e-flake"},
+	}
+	if duplicates := duplicateIssues(unrelated, testKnownJobs); len(duplicates) != 0 {
+		t.Errorf("expected issues without a common known job or test to be no duplicates, got %v", duplicates)
+	}
+
+	for number, note := range expected {
+		if actual := duplicateIssueNote(number, duplicates[number], issues); actual != note {
+			t.Errorf("expected note of #%d\n%s\ngot\n%s", number, note, actual)
+		}
+	}
+}
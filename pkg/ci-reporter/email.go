@@ -0,0 +1,242 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// EmailNotifier sends the report to a list of recipients using a smtp server, the mail contains the markdown and the html rendering of the report
+type EmailNotifier struct {
+	To []string
+	// From sender address of the mail
+	From string
+	// Server address of the smtp server (like smtp.example.com:587)
+	Server string
+	// Username and Password used to authenticate at the smtp server (PLAIN), no authentication is used if Username is not set
+	Username string
+	Password string
+	// send is used to deliver the mail, smtp.SendMail is used if it is not set
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Notify extends EmailNotifier and sends the report by mail
+func (n EmailNotifier) Notify(meta Meta, report Report) error {
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	msg, err := emailMessage(n.From, n.To, emailSubject(report), MarkdownReport(meta, report), HTMLReport(meta, report), time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, err := net.SplitHostPort(n.Server)
+		if err != nil {
+			return fmt.Errorf("smtp server %q does not match host:port: %v", n.Server, err)
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+	send := n.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+	if err := send(n.Server, auth, n.From, n.To, msg); err != nil {
+		return fmt.Errorf("sending report mail to %s: %v", strings.Join(n.To, ", "), err)
+	}
+	return nil
+}
+
+// emailSubject is derived from the testgrid jobs with the worst severity (like "CI Signal: 3 master-blocking jobs FAILING")
+func emailSubject(report Report) string {
+	type group struct {
+		dashboard string
+		status    string
+		jobs      int
+	}
+	var worst Severity
+	groups := []*group{}
+	for _, reportData := range report {
+		if reportData.Name != testgridReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if record.ID != testgridReportDetails || record.Severity == 0 || record.Severity < worst {
+					continue
+				}
+				if record.Severity > worst {
+					worst = record.Severity
+					groups = []*group{}
+				}
+				dashboard := strings.ToLower(field.Title)
+				var g *group
+				for _, e := range groups {
+					if e.dashboard == dashboard && e.status == record.Status {
+						g = e
+					}
+				}
+				if g == nil {
+					g = &group{dashboard: dashboard, status: record.Status}
+					groups = append(groups, g)
+				}
+				g.jobs++
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return emailSubjectWithoutRatedJobs(report)
+	}
+	parts := []string{}
+	for _, g := range groups {
+		jobs := "jobs"
+		if g.jobs == 1 {
+			jobs = "job"
+		}
+		parts = append(parts, strings.TrimSpace(fmt.Sprintf("%d %s %s %s", g.jobs, g.dashboard, jobs, g.status)))
+	}
+	return "CI Signal: " + strings.Join(parts, ", ")
+}
+
+// emailSubjectWithoutRatedJobs is used if no testgrid job has been rated (like in a -short report), it only claims that no job is failing
+// or flaky if the complete testgrid data is part of the report and its dashboard summaries do not count any
+func emailSubjectWithoutRatedJobs(report Report) string {
+	hasTestgridData, failingJobs, flakyJobs := false, 0, 0
+	for _, reportData := range report {
+		if reportData.Name != testgridReport || reportData.Incomplete {
+			continue
+		}
+		hasTestgridData = true
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if record.ID == testgridReportSummary {
+					failingJobs += record.Counts[strings.ToLower(string(failing))]
+					flakyJobs += record.Counts[strings.ToLower(string(flaky))]
+				}
+			}
+		}
+	}
+	switch {
+	case !hasTestgridData:
+		return "CI Signal report"
+	case failingJobs == 0 && flakyJobs == 0:
+		return "CI Signal: no failing or flaky jobs"
+	default:
+		return fmt.Sprintf("CI Signal: %d failing and %d flaky jobs", failingJobs, flakyJobs)
+	}
+}
+
+// emailMessage composes a multipart/alternative mail with a plain text (markdown) and a html part
+func emailMessage(from string, to []string, subject string, text string, html string, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{{"text/plain; charset=utf-8", text}, {"text/html; charset=utf-8", html}} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	msg.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(to, ", ")))
+	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject)))
+	msg.WriteString(fmt.Sprintf("Date: %s\r\n", now.Format(time.RFC1123Z)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary()))
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"upper":     strings.ToUpper,
+	"plain":     stripColors,
+	"isSummary": isSummaryRecord,
+	"isGithub":  func(name string) bool { return name == githubReport },
+}).Parse(`<html>
+<body>
+{{- range .Report }}{{ $name := .Name }}
+<h2>{{ upper .Name }} report</h2>
+{{- range .Data }}{{ $field := . }}
+{{- if and .Title (not (isGithub $name)) }}
+<h3>{{ if and $.Emojis .Emoji }}{{ .Emoji }} {{ end }}{{ .Title }}</h3>
+{{- end }}
+<ul>
+{{- range .Records }}
+{{- if isSummary $name . }}
+{{- range .Notes }}
+<li>{{ plain . }}</li>
+{{- end }}
+{{- else }}
+<li>{{ if .Status }}<b>{{ .Status }}</b> {{ end }}{{ if and $.Emojis .Highlight }}{{ .Highlight }} {{ end }}
+{{- if .URL }}<a href="{{ .URL }}">{{ end }}{{ if isGithub $name }}{{ $field.Title }}#{{ .ID }} {{ end }}{{ .Title }}{{ if .URL }}</a>{{ end }}
+{{- if .Notes }}
+<ul>
+{{- range .Notes }}
+<li>{{ plain . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+</li>
+{{- end }}
+{{- end }}
+</ul>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// HTMLReport renders the report in html format (the same structure as MarkdownReport)
+func HTMLReport(meta Meta, report Report) string {
+	var buf bytes.Buffer
+	err := htmlReportTemplate.Execute(&buf, struct {
+		Report Report
+		Emojis bool
+	}{report, !meta.Flags.EmojisOff})
+	if err != nil {
+		// the template is static, executing it only fails if the report can not be rendered at all
+		return fmt.Sprintf("<html><body><p>%s</p></body></html>", template.HTMLEscapeString(err.Error()))
+	}
+	return buf.String()
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func emailTestReport() Report {
+	return Report{
+		{Name: testgridReport, Data: []ReportDataField{
+			{Title: "Master-Blocking", Records: []ReportDataRecord{
+				{ID: testgridReportSummary, Notes: []string{"10 jobs total"}},
+				{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: "FAILING", Severity: HighSeverity, URL: "https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-serial"},
+				{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FAILING", Severity: HighSeverity},
+				{ID: testgridReportDetails, Title: "kind-master-parallel", Status: "FLAKY", Severity: MediumSeverity},
+			}},
+			{Title: "Master-Informing", Records: []ReportDataRecord{
+				{ID: testgridReportDetails, Title: "gce-cos-master-alpha", Status: "FAILING", Severity: HighSeverity, Notes: []string{"<3 of 10 passed>"}},
+			}},
+		}},
+	}
+}
+
+func TestEmailSubject(t *testing.T) {
+	if subject := emailSubject(emailTestReport()); subject != "CI Signal: 2 master-blocking jobs FAILING, 1 master-informing job FAILING" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if subject := emailSubject(Report{{Name: testgridReport}}); subject != "CI Signal: no failing or flaky jobs" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+}
+
+func TestEmailSubjectWithoutRatedJobs(t *testing.T) {
+	shortReport := Report{{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+		{ID: testgridReportSummary, Counts: map[string]int{"total": 10, "passing": 7, "failing": 2, "flaky": 1}},
+	}}}}}
+	for _, tc := range []struct {
+		name     string
+		report   Report
+		expected string
+	}{
+		{"short report", shortReport, "CI Signal: 2 failing and 1 flaky jobs"},
+		{"github report", Report{{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{{ID: 105242}}}}}}, "CI Signal report"},
+		{"incomplete testgrid report", Report{{Name: testgridReport, Incomplete: true}}, "CI Signal report"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if subject := emailSubject(tc.report); subject != tc.expected {
+				t.Errorf("expected subject %q, got %q", tc.expected, subject)
+			}
+		})
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	var sent []byte
+	var recipients []string
+	n := EmailNotifier{
+		To:       []string{"sig-node-leads@example.com", "alice@example.com"},
+		From:     "ci-signal@example.com",
+		Server:   "smtp.example.com:587",
+		Username: "ci-signal@example.com",
+		Password: "secret",
+		send: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			if addr != "smtp.example.com:587" || a == nil || from != "ci-signal@example.com" {
+				t.Errorf("unexpected smtp parameters %s %v %s", addr, a, from)
+			}
+			recipients = to
+			sent = msg
+			return nil
+		},
+	}
+	if err := n.Notify(newTestMeta(metaFlags{}), emailTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recipients, n.To) {
+		t.Errorf("unexpected recipients %v", recipients)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(sent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "CI Signal: 2 master-blocking jobs FAILING, 1 master-informing job FAILING" {
+		t.Errorf("unexpected subject %q (%v)", subject, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+	parts := map[string]string{}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		content, _ := ioutil.ReadAll(part)
+		parts[part.Header.Get("Content-Type")] = string(content)
+	}
+	if text := parts["text/plain; charset=utf-8"]; !strings.Contains(text, "- **FAILING** [gce-cos-master-serial](https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-serial)") {
+		t.Errorf("expected the markdown report, got\n%s", text)
+	}
+	html := parts["text/html; charset=utf-8"]
+	for _, expected := range []string{
+		"<h3>Master-Blocking</h3>",
+		`<li><b>FAILING</b> <a href="https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-serial">gce-cos-master-serial</a>`,
+		"<li>&lt;3 of 10 passed&gt;</li>",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected %q in the html report, got\n%s", expected, html)
+		}
+	}
+
+	if err := n.Notify(newTestMeta(metaFlags{ReadOnly: true}), emailTestReport()); err != errReadOnly {
+		t.Errorf("expected the read-only error, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature subsystem that can be turned on or off via -features
+type Feature string
+
+// Features that can be set via -features, experimental (alpha) features are disabled by default
+const (
+	// FeatureIssueClustering notes on github issues that likely track the same failure (see duplicateIssues)
+	FeatureIssueClustering Feature = "issue-clustering"
+	// FeatureDependencyHints hints on failing jobs which dependency bump to suspect (see withDependencyHints)
+	FeatureDependencyHints Feature = "dependency-hints"
+	// FeatureRunbooks runbooks of the shipped runbooks.json on classified failing jobs (see withRunbooks)
+	FeatureRunbooks Feature = "runbooks"
+	// FeaturePlatforms the platforms report of the default platforms (see defaultPlatforms)
+	FeaturePlatforms Feature = "platforms"
+)
+
+// Stages of a feature
+const (
+	featureAlpha = "alpha"
+	featureBeta  = "beta"
+)
+
+type featureSpec struct {
+	Default bool
+	Stage   string
+}
+
+// knownFeatures all features that can be set via -features and whether they are enabled if they are not set
+var knownFeatures = map[Feature]featureSpec{
+	FeatureIssueClustering: {Default: false, Stage: featureAlpha},
+	FeatureDependencyHints: {Default: true, Stage: featureBeta},
+	FeatureRunbooks:        {Default: false, Stage: featureAlpha},
+	FeaturePlatforms:       {Default: false, Stage: featureAlpha},
+}
+
+// FeatureGates features that have been turned on or off, features that are not set use their default
+type FeatureGates map[Feature]bool
+
+// Enabled tells if the feature is turned on
+func (g FeatureGates) Enabled(f Feature) bool {
+	if enabled, ok := g[f]; ok {
+		return enabled
+	}
+	return knownFeatures[f].Default
+}
+
+// ParseFeatureGates parses -features input ("issue-clustering=false, dependency-hints=true" => {issue-clustering: false, dependency-hints: true})
+func ParseFeatureGates(input string) (FeatureGates, error) {
+	gates := FeatureGates{}
+	for _, e := range splitListInput(input) {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q does not match feature=true|false", e)
+		}
+		feature := Feature(strings.TrimSpace(parts[0]))
+		if _, ok := knownFeatures[feature]; !ok {
+			return nil, fmt.Errorf("%q does not match options [%s]", feature, featureUsage())
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("feature %s has to be set to true or false, got %q", feature, strings.TrimSpace(parts[1]))
+		}
+		gates[feature] = enabled
+	}
+	return gates, nil
+}
+
+// featureUsage lists the known features with their stage and default ("dependency-hints (beta, default true), ...")
+func featureUsage() string {
+	names := []string{}
+	for f := range knownFeatures {
+		names = append(names, string(f))
+	}
+	sort.Strings(names)
+	usage := []string{}
+	for _, name := range names {
+		spec := knownFeatures[Feature(name)]
+		usage = append(usage, fmt.Sprintf("%s (%s, default %t)", name, spec.Stage, spec.Default))
+	}
+	return strings.Join(usage, ", ")
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	gates, err := ParseFeatureGates("issue-clustering=false, dependency-hints=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gates.Enabled(FeatureIssueClustering) || !gates.Enabled(FeatureDependencyHints) {
+		t.Errorf("unexpected gates %v", gates)
+	}
+	var none FeatureGates
+	if !none.Enabled(FeatureDependencyHints) || none.Enabled(FeatureIssueClustering) {
+		t.Error("expected beta features to be enabled and alpha features to be disabled by default")
+	}
+	for _, input := range []string{"issue-clustering", "bigquery=true", "issue-clustering=maybe"} {
+		if _, err := ParseFeatureGates(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestFeatureGateDisablesDependencyHints(t *testing.T) {
+	hints, err := parseDependencyHints([]byte(`{"hints": [{"job": "master", "components": ["etcd"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := newTestMeta(metaFlags{DependencyHints: hints, Features: FeatureGates{FeatureDependencyHints: false}})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&TestgridReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			for _, note := range record.Notes {
+				if strings.HasPrefix(note, "Dependencies:") {
+					t.Errorf("expected no dependency hints if the feature is disabled, got %v", record.Notes)
+				}
+			}
+		}
+	}
+}
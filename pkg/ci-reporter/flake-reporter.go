@@ -0,0 +1,228 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// number of tests listed in the flakiest tests section
+	flakiestTestsLimit = 10
+	flakiestJobsTitle  = "Flakiest jobs"
+	flakiestTestsTitle = "Flakiest tests"
+)
+
+// FlakeReport used to implement RequestData & Print for the flake analysis of master-blocking and master-informing
+type FlakeReport struct {
+	ReportData ReportData
+}
+
+// flakeCandidate a job or test together with its flake rate that gets ranked
+type flakeCandidate struct {
+	record    ReportDataRecord
+	flakeRate float64
+}
+
+// RequestData this function is used to rank the flakiest jobs and tests and to find kind/flake issues tracking them
+func (r *FlakeReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	meta = meta.withContext(ctx)
+	dashboards := meta.Flags.dashboards()
+	client := httpClientOrDefault(meta.HTTPClient)
+	dashboardJobs := make([][]flakeCandidate, len(dashboards))
+	dashboardTests := make([][]flakeCandidate, len(dashboards))
+	errs := runWorkerPool(meta.Flags.concurrency(), len(dashboards), func(i int) error {
+		jobBaseURL := fmt.Sprintf("%s/%s", meta.Flags.testgridURL(), dashboards[i].URLName)
+		jobsData, err := meta.requestDashboard(ctx, client, jobBaseURL)
+		if err != nil {
+			return err
+		}
+		dashboardJobs[i], dashboardTests[i] = rankFlakes(meta.logger(), dashboards[i].OutputName, jobBaseURL, jobsData)
+		return nil
+	})
+	if err := collectWorkerErrors(errs); err != nil {
+		return ReportData{Name: flakeReport}, fmt.Errorf("requesting dashboards: %v", err)
+	}
+	jobs := []flakeCandidate{}
+	tests := []flakeCandidate{}
+	for i := range dashboards {
+		jobs = append(jobs, dashboardJobs[i]...)
+		tests = append(tests, dashboardTests[i]...)
+	}
+	sortFlakeCandidates(jobs)
+	sortFlakeCandidates(tests)
+	if len(tests) > flakiestTestsLimit {
+		tests = tests[:flakiestTestsLimit]
+	}
+
+	repositories := meta.Flags.repositories()
+	repositoryIssues := make([]GithubIssuesAfterID, len(repositories))
+	errs = runWorkerPool(meta.Flags.concurrency(), len(repositories), func(i int) error {
+		issues, err := requestGithubIssuesWithAPI(meta, newGithubIssueRequest(meta, repositories[i], "kind/flake"))
+		repositoryIssues[i] = issues
+		return err
+	})
+	if err := collectWorkerErrors(errs); err != nil {
+		return ReportData{Name: flakeReport}, fmt.Errorf("requesting github issues: %v", err)
+	}
+	// issue numbers are only unique within a repository
+	flakeIssues := map[string]GithubIssueElement{}
+	for i, issues := range repositoryIssues {
+		for number, issue := range issues {
+			flakeIssues[fmt.Sprintf("%s#%d", repositories[i], number)] = issue
+		}
+	}
+	c := make(chan ReportDataField)
+	go func() {
+		defer close(c)
+		c <- ReportDataField{Emoji: statusFlakyEmoji, Title: flakiestJobsTitle, Records: withFlakeIssues(jobs, flakeIssues)}
+		c <- ReportDataField{Emoji: statusFlakyEmoji, Title: flakiestTestsTitle, Records: withFlakeIssues(tests, flakeIssues)}
+	}()
+	return meta.DataPostProcessing(r, flakeReport, c, wg), nil
+}
+
+// rankFlakes calculates the flake rate of all flaky jobs of a dashboard (failed recent runs) and of their tests (testgrid healthiness)
+func rankFlakes(logger *Logger, dashboard string, jobBaseURL string, jobsData TestgridData) (jobs []flakeCandidate, tests []flakeCandidate) {
+	for jobName, jobData := range jobsData {
+		for _, test := range jobData.Healthiness.Tests {
+			if test.Flakiness <= 0 {
+				continue
+			}
+			tests = append(tests, flakeCandidate{
+				record: ReportDataRecord{
+					ID:     testgridReportDetails,
+					Title:  test.DisplayName,
+					URL:    fmt.Sprintf("%s#%s", jobBaseURL, jobName),
+					Status: string(flaky),
+					Sigs:   extractSigs(test.DisplayName),
+					Notes:  []string{fmt.Sprintf("%.1f%% flaky in %s (%s), trend: %s", test.Flakiness, jobName, dashboard, flakeTrend(test.ChangeFromLastInterval))},
+				},
+				flakeRate: test.Flakiness / 100,
+			})
+		}
+		if jobData.OverallStatus != flaky {
+			continue
+		}
+		passes, runs := parseRecentRuns(logger, jobData.Status)
+		if runs == 0 {
+			continue
+		}
+		flakeRate := 1 - passes/runs
+		notes := []string{fmt.Sprintf("%.0f%% of recent runs failed (%g of %g passed) in %s", flakeRate*100, passes, runs, dashboard)}
+		if jobData.Healthiness.AverageFlakiness > 0 || jobData.Healthiness.PreviousFlakiness > 0 {
+			trend := "stable"
+			if jobData.Healthiness.AverageFlakiness > jobData.Healthiness.PreviousFlakiness {
+				trend = "up"
+			} else if jobData.Healthiness.AverageFlakiness < jobData.Healthiness.PreviousFlakiness {
+				trend = "down"
+			}
+			notes = append(notes, fmt.Sprintf("Flakiness %.1f%% (previously %.1f%%), trend: %s", jobData.Healthiness.AverageFlakiness, jobData.Healthiness.PreviousFlakiness, trend))
+		}
+		jobs = append(jobs, flakeCandidate{
+			record: ReportDataRecord{
+				ID:             testgridReportDetails,
+				Title:          jobName,
+				URL:            fmt.Sprintf("%s#%s", jobBaseURL, jobName),
+				Status:         string(flaky),
+				Notes:          notes,
+				RecentPassRate: floatPointer(passes / runs),
+			},
+			flakeRate: flakeRate,
+		})
+	}
+	return jobs, tests
+}
+
+// flakeTrend translates the testgrid change of flakiness into a readable trend
+func flakeTrend(change string) string {
+	switch change {
+	case "UP":
+		return "up"
+	case "DOWN":
+		return "down"
+	default:
+		return "stable"
+	}
+}
+
+// sortFlakeCandidates orders candidates from the highest to the lowest flake rate
+func sortFlakeCandidates(candidates []flakeCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].flakeRate == candidates[j].flakeRate {
+			return candidates[i].record.Title < candidates[j].record.Title
+		}
+		return candidates[i].flakeRate > candidates[j].flakeRate
+	})
+}
+
+// withFlakeIssues adds a note to every candidate whether a kind/flake issue mentions the job or test in its title, issues are keyed by "owner/repo#number"
+func withFlakeIssues(candidates []flakeCandidate, issues map[string]GithubIssueElement) []ReportDataRecord {
+	records := []ReportDataRecord{}
+	for _, c := range candidates {
+		record := c.record
+		tracking := []string{}
+		for ref, issue := range issues {
+			if strings.Contains(strings.ToLower(issue.Title), strings.ToLower(record.Title)) {
+				tracking = append(tracking, ref)
+			}
+		}
+		sort.Strings(tracking)
+		if len(tracking) > 0 {
+			record.Notes = append(record.Notes, fmt.Sprintf("Tracked in %s", strings.Join(tracking, ", ")))
+		} else {
+			record.Notes = append(record.Notes, "No kind/flake issue found")
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Print extends FlakeReport and prints report data to the console
+func (r *FlakeReport) Print(meta Meta, reportData ReportData) {
+	c := meta.console()
+	for _, field := range reportData.Data {
+		c.Printf("\n\n%s\n", c.heading(field.Emoji, strings.ToUpper(field.Title)))
+		for i, record := range field.Records {
+			c.Printf("%d. %s\n", i+1, record.Title)
+			if !meta.Flags.ShortOn {
+				c.Printf("- %s\n", record.URL)
+			}
+			for _, note := range record.Notes {
+				c.Printf("- %s\n", note)
+			}
+		}
+	}
+	c.Println()
+}
+
+// PutData extends FlakeReport and stores the data at runtime to the struct val ReportData
+func (r *FlakeReport) PutData(reportData ReportData) {
+	r.ReportData = reportData
+}
+
+// GetData extends FlakeReport and returns the data that has been stored at runtime int the struct val ReportData
+func (r FlakeReport) GetData() ReportData {
+	return r.ReportData
+}
+
+func floatPointer(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRankFlakes(t *testing.T) {
+	jobsData := TestgridData{
+		"slightly-flaky": {OverallStatus: flaky, Status: "8 of 10 (80.0%) recent columns passed"},
+		"very-flaky": {
+			OverallStatus: flaky,
+			Status:        "2 of 10 (20.0%) recent columns passed",
+			Healthiness: healthiness{
+				AverageFlakiness:  30,
+				PreviousFlakiness: 10,
+				Tests: []healthinessTest{
+					{DisplayName: "[sig-node] Pods restart", Flakiness: 25, ChangeFromLastInterval: "UP"},
+					{DisplayName: "stable test", Flakiness: 0},
+				},
+			},
+		},
+		"passing": {OverallStatus: passing, Status: "10 of 10 (100.0%) recent columns passed"},
+	}
+	jobs, tests := rankFlakes(discardLogger, "Master-Blocking", "https://testgrid.k8s.io/sig-release-master-blocking", jobsData)
+	sortFlakeCandidates(jobs)
+
+	titles := []string{}
+	for _, j := range jobs {
+		titles = append(titles, j.record.Title)
+	}
+	if !reflect.DeepEqual(titles, []string{"very-flaky", "slightly-flaky"}) {
+		t.Errorf("expected flaky jobs ordered by flake rate, got %v", titles)
+	}
+	if !strings.Contains(strings.Join(jobs[0].record.Notes, "\n"), "trend: up") {
+		t.Errorf("expected an upwards trend, got %v", jobs[0].record.Notes)
+	}
+	if len(tests) != 1 || tests[0].flakeRate != 0.25 || !reflect.DeepEqual(tests[0].record.Sigs, []string{"sig-node"}) {
+		t.Errorf("expected one flaky test with flake rate 0.25 attributed to sig-node, got %+v", tests)
+	}
+
+	// the same issue number in two repositories are two issues
+	records := withFlakeIssues(jobs, map[string]GithubIssueElement{
+		"kubernetes/kubernetes#42": {Number: 42, Title: "[Flaky job] very-flaky times out"},
+		"kubernetes-sigs/kind#42":  {Number: 42, Title: "very-flaky fails to create cluster"},
+	})
+	if last := records[0].Notes[len(records[0].Notes)-1]; last != "Tracked in kubernetes-sigs/kind#42, kubernetes/kubernetes#42" {
+		t.Errorf("expected very-flaky to be tracked in both issues #42, got %q", last)
+	}
+	if last := records[1].Notes[len(records[1].Notes)-1]; last != "No kind/flake issue found" {
+		t.Errorf("expected slightly-flaky to be untracked, got %q", last)
+	}
+}
+
+func TestFlakeReportRequestData(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&FlakeReport{}).RequestData(context.Background(), newTestMeta(metaFlags{}), &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reportData.Name != flakeReport || len(reportData.Data) == 0 || reportData.Data[0].Title != flakiestJobsTitle {
+		t.Fatalf("unexpected flake report %+v", reportData)
+	}
+	titles := []string{}
+	for _, record := range reportData.Data[0].Records {
+		titles = append(titles, record.Title)
+	}
+	expected := []string{"gce-cos-master-default", "post-release-push-image-setcap", "verify-master"}
+	if !reflect.DeepEqual(titles, expected) {
+		t.Errorf("expected flakiest jobs %v, got %v", expected, titles)
+	}
+}
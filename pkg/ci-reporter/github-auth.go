@@ -0,0 +1,302 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// githubAPIHost requests to this host are authenticated with the github token
+	githubAPIHost = "api.github.com"
+	// githubAppJWTLifetime github accepts app JWTs that expire within 10 minutes
+	githubAppJWTLifetime = 9 * time.Minute
+)
+
+// GithubAuth credentials used to authenticate at github, the first configured source is used:
+// a github app installation, a token file, the GITHUB_AUTH_TOKEN environment variable and the token of the gh cli
+type GithubAuth struct {
+	// AppID, InstallationID and PrivateKeyFile of a github app, installation tokens are requested with them and refreshed before they expire
+	AppID          int64
+	InstallationID int64
+	PrivateKeyFile string
+	// TokenFile file that contains a token, it is read again if it has been modified so rotated secrets are picked up
+	TokenFile string
+	// Token set via GITHUB_AUTH_TOKEN
+	Token string
+}
+
+// NewGithubTokenSource returns the token source of the first configured credentials (see GithubAuth),
+// the token of the gh cli is used if none are configured
+func NewGithubTokenSource(auth GithubAuth, httpClient *http.Client) (oauth2.TokenSource, error) {
+	switch {
+	case auth.AppID != 0 || auth.InstallationID != 0 || auth.PrivateKeyFile != "":
+		if auth.AppID == 0 || auth.InstallationID == 0 || auth.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("github app authentication needs an app id, an installation id and a private key file")
+		}
+		data, err := ioutil.ReadFile(auth.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseRSAPrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key %s: %v", auth.PrivateKeyFile, err)
+		}
+		return oauth2.ReuseTokenSource(nil, &githubAppTokenSource{
+			appID:          auth.AppID,
+			installationID: auth.InstallationID,
+			key:            key,
+			client:         httpClientOrDefault(httpClient),
+			now:            time.Now,
+		}), nil
+	case auth.TokenFile != "":
+		src := &fileTokenSource{path: auth.TokenFile}
+		if _, err := src.Token(); err != nil {
+			return nil, err
+		}
+		return src, nil
+	case auth.Token != "":
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: auth.Token}), nil
+	}
+	token, err := ghCLIToken()
+	if err != nil {
+		return nil, fmt.Errorf("no github credentials found, set GITHUB_AUTH_TOKEN, -github-token-file or the -github-app-* flags, or log in with the gh cli (%v)", err)
+	}
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), nil
+}
+
+// Identity returns the account the credentials of NewGithubTokenSource authenticate as, it stays the same
+// if the token is rotated (token file) or refreshed (github app) and is used to key cached responses
+func (a GithubAuth) Identity() string {
+	switch {
+	case a.AppID != 0 || a.InstallationID != 0 || a.PrivateKeyFile != "":
+		return fmt.Sprintf("app:%d/installation:%d", a.AppID, a.InstallationID)
+	case a.TokenFile != "":
+		return "token-file:" + a.TokenFile
+	case a.Token != "":
+		return fmt.Sprintf("token:%x", sha256.Sum256([]byte(a.Token)))
+	}
+	return "gh"
+}
+
+// githubAppTokenSource requests installation tokens of a github app, tokens are valid for one hour
+type githubAppTokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	client         *http.Client
+	now            func() time.Time
+}
+
+func (s *githubAppTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.jwt()
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("https://%s/app/installations/%d/access_tokens", githubAPIHost, s.installationID)
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting installation token: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading installation token: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("requesting installation token failed with status %s: %s", resp.Status, body)
+	}
+	var installationToken struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &installationToken); err != nil {
+		return nil, fmt.Errorf("unmarshal installation token: %v", err)
+	}
+	return &oauth2.Token{AccessToken: installationToken.Token, TokenType: "Bearer", Expiry: installationToken.ExpiresAt}, nil
+}
+
+// jwt returns the json web token (RS256) the app authenticates with to request installation tokens,
+// it is issued a minute in the past to allow for clock drift
+func (s *githubAppTokenSource) jwt() (string, error) {
+	now := s.now()
+	return signJWT(s.key, map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": s.appID,
+	})
+}
+
+// signJWT returns a json web token of the claims signed with the key (RS256)
+func signJWT(key *rsa.PrivateKey, claims map[string]interface{}) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(data)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey parses the pem encoded private key github generates for apps (PKCS #1), PKCS #8 keys are accepted as well
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no pem encoded key found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key is not a rsa key")
+	}
+	return key, nil
+}
+
+// fileTokenSource reads the token from a file (like a mounted kubernetes secret), the token is read again if the file has been modified
+type fileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	token   *oauth2.Token
+}
+
+func (s *fileTokenSource) Token() (*oauth2.Token, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && info.ModTime().Equal(s.modTime) {
+		return s.token, nil
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("token file %s is empty", s.path)
+	}
+	s.token, s.modTime = &oauth2.Token{AccessToken: token}, info.ModTime()
+	return s.token, nil
+}
+
+// newLazyGithubTokenSource returns a token source that resolves the credentials (see NewGithubTokenSource) when the first token is requested,
+// so runs that do not request github (like simulate) neither run the gh cli nor request app tokens
+func newLazyGithubTokenSource(auth GithubAuth, httpClient *http.Client) oauth2.TokenSource {
+	return &lazyGithubTokenSource{auth: auth, httpClient: httpClient}
+}
+
+type lazyGithubTokenSource struct {
+	auth       GithubAuth
+	httpClient *http.Client
+
+	once   sync.Once
+	source oauth2.TokenSource
+	err    error
+}
+
+func (s *lazyGithubTokenSource) Token() (*oauth2.Token, error) {
+	s.once.Do(func() {
+		s.source, s.err = NewGithubTokenSource(s.auth, s.httpClient)
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.source.Token()
+}
+
+// ghCLIToken returns the token the gh cli is logged in with
+func ghCLIToken() (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gh", "auth", "token")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("gh auth token: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("gh auth token returned no token")
+	}
+	return token, nil
+}
+
+// NewGithubAuthTransport returns a transport that authenticates requests to the github api with the current token of the source,
+// tokens that expire (like installation tokens of a github app) are refreshed. Requests to other hosts (like testgrid) are sent without token
+func NewGithubAuthTransport(source oauth2.TokenSource, next http.RoundTripper) http.RoundTripper {
+	return githubAuthTransport{source: source, next: next}
+}
+
+type githubAuthTransport struct {
+	source oauth2.TokenSource
+	next   http.RoundTripper
+}
+
+func (t githubAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if req.URL.Host != githubAPIHost {
+		return next.RoundTrip(req)
+	}
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("github token: %v", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
+	return next.RoundTrip(req)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// installationTokenTransport answers installation token requests of a github app and records the authorization headers of all requests
+type installationTokenTransport struct {
+	tokens        int
+	authorization map[string]string
+}
+
+func (t *installationTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.authorization[req.URL.Host+req.URL.Path] = req.Header.Get("Authorization")
+	body := "[]"
+	status := http.StatusOK
+	if req.Method == http.MethodPost {
+		t.tokens++
+		// the first token has already expired, so the next request refreshes it
+		expiresAt := time.Now().Add(-time.Minute)
+		if t.tokens > 1 {
+			expiresAt = time.Now().Add(time.Hour)
+		}
+		body = fmt.Sprintf(`{"token": "ghs_%d", "expires_at": %q}`, t.tokens, expiresAt.Format(time.RFC3339))
+		status = http.StatusCreated
+	}
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func writeTestPrivateKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key, path
+}
+
+func TestGithubAppJWT(t *testing.T) {
+	key, _ := writeTestPrivateKey(t)
+	now := time.Date(2021, 11, 4, 10, 0, 0, 0, time.UTC)
+	src := &githubAppTokenSource{appID: 1234, installationID: 5678, key: key, now: func() time.Time { return now }}
+	jwt, err := src.jwt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected header, claims and signature, got %q", jwt)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+		t.Errorf("expected a valid RS256 signature: %v", err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]int64
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != 1234 || claims["iat"] != now.Add(-time.Minute).Unix() || claims["exp"] != now.Add(githubAppJWTLifetime).Unix() {
+		t.Errorf("unexpected claims %v", claims)
+	}
+}
+
+func TestGithubAppInstallationTokenRefresh(t *testing.T) {
+	_, keyFile := writeTestPrivateKey(t)
+	transport := &installationTokenTransport{authorization: map[string]string{}}
+	src, err := NewGithubTokenSource(GithubAuth{AppID: 1234, InstallationID: 5678, PrivateKeyFile: keyFile}, &http.Client{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: NewGithubAuthTransport(src, transport)}
+
+	for _, expected := range []string{"Bearer ghs_1", "Bearer ghs_2", "Bearer ghs_2"} {
+		resp, err := client.Get("https://api.github.com/repos/kubernetes/kubernetes/issues")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := transport.authorization["api.github.com/repos/kubernetes/kubernetes/issues"]; got != expected {
+			t.Errorf("expected authorization %q, got %q", expected, got)
+		}
+	}
+	if transport.tokens != 2 {
+		t.Errorf("expected the expired installation token to be refreshed once, got %d tokens", transport.tokens)
+	}
+	if auth := transport.authorization["api.github.com/app/installations/5678/access_tokens"]; !strings.HasPrefix(auth, "Bearer ey") {
+		t.Errorf("expected installation tokens to be requested with the app jwt, got %q", auth)
+	}
+
+	resp, err := client.Get("https://testgrid.k8s.io/sig-release-master-blocking/summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := transport.authorization["testgrid.k8s.io/sig-release-master-blocking/summary"]; got != "" {
+		t.Errorf("expected no github token to be sent to testgrid, got %q", got)
+	}
+}
+
+func TestGithubTokenSourceSelection(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		auth     GithubAuth
+		expected string
+	}{
+		{"token file wins over the environment", GithubAuth{TokenFile: tokenFile, Token: "from-env"}, "from-file"},
+		{"environment", GithubAuth{Token: "from-env"}, "from-env"},
+	}
+	for _, tc := range tests {
+		src, err := NewGithubTokenSource(tc.auth, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		token, err := src.Token()
+		if err != nil || token.AccessToken != tc.expected {
+			t.Errorf("%s: expected token %q, got %v (%v)", tc.name, tc.expected, token, err)
+		}
+	}
+
+	if _, err := NewGithubTokenSource(GithubAuth{AppID: 1234}, nil); err == nil {
+		t.Error("expected incomplete github app credentials to be rejected")
+	}
+	if _, err := NewGithubTokenSource(GithubAuth{TokenFile: filepath.Join(t.TempDir(), "missing")}, nil); err == nil {
+		t.Error("expected a missing token file to be rejected")
+	}
+}
+
+func TestFileTokenSourceRotation(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(tokenFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	src := &fileTokenSource{path: tokenFile}
+	if token, err := src.Token(); err != nil || token.AccessToken != "first" {
+		t.Fatalf("expected the token of the file, got %v (%v)", token, err)
+	}
+
+	// the file is only read again if it has been modified
+	if err := ioutil.WriteFile(tokenFile, []byte("second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tokenFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := src.Token(); err != nil || token.AccessToken != "first" {
+		t.Errorf("expected the cached token of the unmodified file, got %v (%v)", token, err)
+	}
+	rotated := modTime.Add(time.Minute)
+	if err := os.Chtimes(tokenFile, rotated, rotated); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := src.Token(); err != nil || token.AccessToken != "second" {
+		t.Errorf("expected the rotated token, got %v (%v)", token, err)
+	}
+}
+
+func TestLazyGithubTokenSource(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	// the token file does not need to exist before the first token is requested
+	src := newLazyGithubTokenSource(GithubAuth{TokenFile: tokenFile}, nil)
+	if err := ioutil.WriteFile(tokenFile, []byte("token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := src.Token(); err != nil || token.AccessToken != "token" {
+		t.Errorf("expected the credentials to be resolved with the first token, got %v (%v)", token, err)
+	}
+
+	if _, err := newLazyGithubTokenSource(GithubAuth{AppID: 1234}, nil).Token(); err == nil {
+		t.Error("expected incomplete github app credentials to be rejected")
+	}
+	meta := Meta{githubTokenSource: newLazyGithubTokenSource(GithubAuth{AppID: 1234}, nil)}
+	if err := meta.githubCredentials(); err == nil {
+		t.Error("expected an error for invalid github credentials")
+	}
+	if err := (Meta{Env: metaEnv{GithubToken: "token"}}).githubCredentials(); err != nil {
+		t.Errorf("expected the token of the environment to be used, got %v", err)
+	}
+}
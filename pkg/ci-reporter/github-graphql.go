@@ -0,0 +1,283 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Github api options
+const (
+	githubAPIRest    = "rest"
+	githubAPIGraphQL = "graphql"
+)
+
+const githubGraphQLURL = "https://api.github.com/graphql"
+
+// This query requests one page of issues including all information used in the report, so one request per page is needed
+const githubIssuesQuery = `query($owner: String!, $repo: String!, $states: [IssueState!], $labels: [String!], $since: DateTime, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    issues(first: 100, after: $cursor, states: $states, labels: $labels, filterBy: {since: $since}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number
+        title
+        url
+        state
+        createdAt
+        updatedAt
+        closedAt
+        comments { totalCount }
+        milestone { title }
+        labels(first: 50) { nodes { name color } }
+        assignees(first: 10) { nodes { login } }
+        timelineItems(first: 25, itemTypes: [CONNECTED_EVENT, CROSS_REFERENCED_EVENT]) {
+          nodes {
+            ... on ConnectedEvent { subject { ... on PullRequest { number url state } } }
+            ... on CrossReferencedEvent { source { ... on PullRequest { number url state } } }
+          }
+        }
+        projectItems(first: 5) {
+          nodes {
+            project { title }
+            fieldValueByName(name: "Status") { ... on ProjectV2ItemFieldSingleSelectValue { name } }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// GetGithubIssuesGraphQL get github issues using the github graphql v4 api
+func GetGithubIssuesGraphQL(cfg GithubIssueRequest) (GithubIssuesAfterID, error) {
+	return requestGithubIssuesGraphQL(cfg)
+}
+
+// requestGithubIssuesGraphQL requests all pages of issues using the github graphql v4 api and filters them (see filterGithubIssues)
+func requestGithubIssuesGraphQL(cfg GithubIssueRequest) (GithubIssuesAfterID, error) {
+	issues, err := requestAllGithubIssuesGraphQL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return filterGithubIssues(issues), nil
+}
+
+// requestAllGithubIssuesGraphQL requests all pages of issues one after another using the github graphql v4 api
+func requestAllGithubIssuesGraphQL(cfg GithubIssueRequest) (GithubIssues, error) {
+	variables := map[string]interface{}{
+		"owner":  cfg.Owner,
+		"repo":   cfg.Repo,
+		"states": []string{"OPEN"},
+	}
+	if state, ok := cfg.Params[IssueReqParamState]; ok {
+		variables["states"] = []string{strings.ToUpper(state)}
+	}
+	// graphql returns issues with any of the labels, the rest and search apis issues with all labels (like "kind/failing-test,sig/node").
+	// Only the first label is requested, issues without the other labels are filtered
+	requiredLabels := []string{}
+	if labels := cfg.Params[IssueReqParamLabels]; labels != "" {
+		for _, label := range strings.Split(labels, ",") {
+			requiredLabels = append(requiredLabels, strings.TrimSpace(label))
+		}
+		variables["labels"] = requiredLabels[:1]
+	}
+	if since, ok := cfg.Params[IssueReqParamSince]; ok {
+		timestamp, err := githubTimestamp(since)
+		if err != nil {
+			return nil, err
+		}
+		variables["since"] = timestamp
+	}
+
+	collectedIssues := GithubIssues{}
+	for {
+		page, err := requestGithubIssuesPageGraphQL(httpClientOrDefault(cfg.HTTPClient), variables, cfg.AuthToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range page.Data.Repository.Issues.Nodes {
+			if issue := node.toGithubIssueElement(); hasAllLabels(issue, requiredLabels) {
+				collectedIssues = append(collectedIssues, issue)
+			}
+		}
+		pageInfo := page.Data.Repository.Issues.PageInfo
+		if !pageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = pageInfo.EndCursor
+	}
+	return collectedIssues, nil
+}
+
+// hasAllLabels tells if the issue carries all labels
+func hasAllLabels(issue GithubIssueElement, labels []string) bool {
+	for _, label := range labels {
+		found := false
+		for _, l := range issue.Labels {
+			found = found || l.Name == label
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// requestGithubIssuesPageGraphQL sends a http request to the github graphql api to list one page of issues
+func requestGithubIssuesPageGraphQL(client *http.Client, variables map[string]interface{}, authToken string) (graphQLIssuesResponse, error) {
+	var page graphQLIssuesResponse
+	err := requestGithubGraphQL(client, githubIssuesQuery, variables, authToken, &page.Data)
+	return page, err
+}
+
+// requestGithubGraphQL sends a query to the github graphql api and unmarshals the data of the response into data
+func requestGithubGraphQL(client *http.Client, query string, variables map[string]interface{}, authToken string, data interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("marshal graphql request: %v", err)
+	}
+	req, err := http.NewRequest("POST", githubGraphQLURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("creating graphql request: %v", err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", authToken))
+	req.Header.Add("Content-Type", "application/json")
+	// Send http request
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending graphql request: %v", err)
+	}
+	defer resp.Body.Close()
+	// Read body and unmarshal bytes
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading graphql response: %v", err)
+	}
+	// errors like a missing or invalid token are answered with a status code and a message instead of graphql errors
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("graphql request of %v/%v failed with status %s: %s", variables["owner"], variables["repo"], resp.Status, body)
+	}
+	response := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("unmarshal graphql response: %v (response: %s)", err, body)
+	}
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("graphql request of %v/%v failed: %s", variables["owner"], variables["repo"], response.Errors[0].Message)
+	}
+	if err := json.Unmarshal(response.Data, data); err != nil {
+		return fmt.Errorf("unmarshal graphql response: %v (response: %s)", err, body)
+	}
+	return nil
+}
+
+// The types below reflect the response of githubIssuesQuery
+
+type graphQLIssuesResponse struct {
+	Data struct {
+		Repository struct {
+			Issues struct {
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+				Nodes    []graphQLIssue  `json:"nodes"`
+			} `json:"issues"`
+		} `json:"repository"`
+	} `json:"data"`
+}
+
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type graphQLIssue struct {
+	Number    int64      `json:"number"`
+	Title     string     `json:"title"`
+	URL       string     `json:"url"`
+	State     string     `json:"state"`
+	CreatedAt string     `json:"createdAt"`
+	UpdatedAt string     `json:"updatedAt"`
+	ClosedAt  string     `json:"closedAt"`
+	Milestone *Milestone `json:"milestone"`
+	Comments  struct {
+		TotalCount int64 `json:"totalCount"`
+	} `json:"comments"`
+	Labels struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+	Assignees struct {
+		Nodes []Assignee `json:"nodes"`
+	} `json:"assignees"`
+	TimelineItems struct {
+		Nodes []struct {
+			Subject *LinkedPullRequest `json:"subject"`
+			Source  *LinkedPullRequest `json:"source"`
+		} `json:"nodes"`
+	} `json:"timelineItems"`
+	ProjectItems struct {
+		Nodes []struct {
+			Project struct {
+				Title string `json:"title"`
+			} `json:"project"`
+			FieldValueByName *struct {
+				Name string `json:"name"`
+			} `json:"fieldValueByName"`
+		} `json:"nodes"`
+	} `json:"projectItems"`
+}
+
+// toGithubIssueElement transforms a graphql issue into the same format the rest api returns
+func (i graphQLIssue) toGithubIssueElement() GithubIssueElement {
+	issue := GithubIssueElement{
+		HTMLURL:   i.URL,
+		Number:    i.Number,
+		Title:     i.Title,
+		Labels:    i.Labels.Nodes,
+		State:     strings.ToLower(i.State),
+		Milestone: i.Milestone,
+		Comments:  i.Comments.TotalCount,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+		ClosedAt:  i.ClosedAt,
+		Assignees: i.Assignees.Nodes,
+	}
+	seenPRs := map[int64]bool{}
+	for _, item := range i.TimelineItems.Nodes {
+		for _, pr := range []*LinkedPullRequest{item.Subject, item.Source} {
+			// timeline items that do not reference a pull request are decoded as empty objects
+			if pr != nil && pr.Number != 0 && !seenPRs[pr.Number] {
+				seenPRs[pr.Number] = true
+				issue.LinkedPRs = append(issue.LinkedPRs, *pr)
+			}
+		}
+	}
+	for _, item := range i.ProjectItems.Nodes {
+		if item.FieldValueByName != nil && item.FieldValueByName.Name != "" {
+			issue.ProjectStatus = fmt.Sprintf("%s: %s", item.Project.Title, item.FieldValueByName.Name)
+			break
+		}
+	}
+	return issue
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// serverTransport sends all requests to the test server
+type serverTransport struct {
+	server *httptest.Server
+}
+
+func (t serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// graphQLIssuesPages pages of githubIssuesQuery responses, the second page is requested with the cursor of the first
+var graphQLIssuesPages = map[string]string{
+	"": `{"data": {"repository": {"issues": {"pageInfo": {"hasNextPage": true, "endCursor": "page-2"}, "nodes": [
+		{"number": 105242, "title": "[Failing test] gce-serial", "url": "https://github.com/kubernetes/kubernetes/issues/105242", "state": "OPEN",
+		 "labels": {"nodes": [{"name": "kind/failing-test"}, {"name": "sig/node"}]}, "assignees": {"nodes": [{"login": "alice"}]},
+		 "milestone": {"title": "v1.23"},
+		 "timelineItems": {"nodes": [{"subject": {"number": 1, "url": "https://github.com/kubernetes/kubernetes/pull/1", "state": "OPEN"}}, {"source": {"number": 1, "url": "https://github.com/kubernetes/kubernetes/pull/1", "state": "OPEN"}}, {}]},
+		 "projectItems": {"nodes": [{"project": {"title": "CI Signal"}, "fieldValueByName": {"name": "Observing"}}]}},
+		{"number": 3, "title": "accepted", "url": "https://github.com/kubernetes/kubernetes/issues/3", "state": "OPEN", "labels": {"nodes": [{"name": "triage/accepted"}]}}
+	]}}}}`,
+	"page-2": `{"data": {"repository": {"issues": {"pageInfo": {"hasNextPage": false, "endCursor": ""}, "nodes": [
+		{"number": 105965, "title": "[Flaky test] volume metrics", "url": "https://github.com/kubernetes/kubernetes/issues/105965", "state": "OPEN", "labels": {"nodes": [{"name": "kind/failing-test"}, {"name": "kind/flake"}]}}
+	]}}}}`,
+}
+
+func TestGetGithubIssuesGraphQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s with authorization %q", r.URL, r.Header.Get("Authorization"))
+		}
+		var request struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		if request.Variables["owner"] != "kubernetes" || fmt.Sprint(request.Variables["labels"]) != "[kind/failing-test]" {
+			t.Errorf("unexpected variables %v", request.Variables)
+		}
+		cursor, _ := request.Variables["cursor"].(string)
+		fmt.Fprint(w, graphQLIssuesPages[cursor])
+	}))
+	defer server.Close()
+
+	issues, err := GetGithubIssuesGraphQL(GithubIssueRequest{
+		Owner:      "kubernetes",
+		Repo:       "kubernetes",
+		Params:     GithubIssueRequestParameters{IssueReqParamLabels: "kind/failing-test"},
+		AuthToken:  "token",
+		HTTPClient: &http.Client{Transport: serverTransport{server: server}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := []int{}
+	for number := range issues {
+		numbers = append(numbers, int(number))
+	}
+	sort.Ints(numbers)
+	// issues of both pages are requested, issues labeled triage/accepted are filtered
+	if !reflect.DeepEqual(numbers, []int{105242, 105965}) {
+		t.Fatalf("expected issues [105242 105965], got %v", numbers)
+	}
+	issue := issues[105242]
+	if issue.State != "open" || issue.Milestone == nil || issue.Milestone.Title != "v1.23" || len(issue.Assignees) != 1 || issue.Assignees[0].Login != "alice" {
+		t.Errorf("expected the issue in the format of the rest api, got %+v", issue)
+	}
+	if len(issue.LinkedPRs) != 1 || issue.LinkedPRs[0].Number != 1 {
+		t.Errorf("expected pull request #1 to be linked once, got %+v", issue.LinkedPRs)
+	}
+	if issue.ProjectStatus != "CI Signal: Observing" {
+		t.Errorf("expected the project status, got %q", issue.ProjectStatus)
+	}
+}
+
+func TestGetGithubIssuesGraphQLRequiresAllLabels(t *testing.T) {
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		requested = append(requested, fmt.Sprint(request.Variables["labels"]))
+		cursor, _ := request.Variables["cursor"].(string)
+		fmt.Fprint(w, graphQLIssuesPages[cursor])
+	}))
+	defer server.Close()
+
+	// like the rest api only issues with both labels are returned, graphql would return issues with either label
+	issues, err := GetGithubIssuesGraphQL(GithubIssueRequest{
+		Owner:      "kubernetes",
+		Repo:       "kubernetes",
+		Params:     GithubIssueRequestParameters{IssueReqParamLabels: "kind/failing-test, sig/node"},
+		HTTPClient: &http.Client{Transport: serverTransport{server: server}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[105242].Number != 105242 {
+		t.Errorf("expected only issue 105242 with both labels, got %v", issues)
+	}
+	if !reflect.DeepEqual(requested, []string{"[kind/failing-test]", "[kind/failing-test]"}) {
+		t.Errorf("expected only the first label to be requested, got %v", requested)
+	}
+}
+
+func TestGetGithubIssuesGraphQLErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{"graphql error", http.StatusOK, `{"data": null, "errors": [{"message": "Could not resolve to a Repository"}]}`, "Could not resolve to a Repository"},
+		{"invalid token", http.StatusUnauthorized, `{"message": "Bad credentials"}`, "401 Unauthorized"},
+		{"invalid response", http.StatusOK, `<html>`, "unmarshal graphql response"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+			_, err := GetGithubIssuesGraphQL(GithubIssueRequest{Owner: "kubernetes", Repo: "missing", HTTPClient: &http.Client{Transport: serverTransport{server: server}}})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -17,73 +17,200 @@ limitations under the License.
 package cireporter
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"regexp"
+	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+// number of requests (one per label) per repository of the github report
+const githubRequestsPerRepository = 2
+
 // GithubReport used to implement RequestData & Print for github report data
 type GithubReport struct {
 	ReportData ReportData
 }
 
 // RequestData this function is used to get github report data
-func (r *GithubReport) RequestData(meta Meta, wg *sync.WaitGroup) ReportData {
-	fourMonthsAgo := time.Now().AddDate(0, -4, 0)
-	fourMonthsAgoStr := fmt.Sprintf("%d-%d-%d", fourMonthsAgo.Year(), fourMonthsAgo.Month(), fourMonthsAgo.Day())
-	requestCfg := []GithubIssueRequest{
-		{
-			Owner:     "kubernetes",
-			Repo:      "kubernetes",
-			Params:    GithubIssueRequestParameters{IssueReqParamLabels: "kind/failing-test", IssueReqParamSince: fourMonthsAgoStr, IssueReqParamPerpage: "20"},
-			AuthToken: meta.Env.GithubToken,
-		},
-		{
-			Owner:     "kubernetes",
-			Repo:      "kubernetes",
-			Params:    GithubIssueRequestParameters{IssueReqParamLabels: "kind/flake", IssueReqParamSince: fourMonthsAgoStr, IssueReqParamPerpage: "20"},
-			AuthToken: meta.Env.GithubToken,
-		},
-	}
-	// request github issue data
-	allReqGithubIssues := GithubIssuesAfterID{}
-	var internalWg sync.WaitGroup
-	for _, cfg := range requestCfg {
-		internalWg.Add(1)
-		go func(cfg GithubIssueRequest) {
-			githubIssues := GetGithubIssues(cfg)
-			for k, v := range githubIssues {
-				allReqGithubIssues[k] = v
+func (r *GithubReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	meta = meta.withContext(ctx)
+	repositories := meta.Flags.repositories()
+	requestCfg := []GithubIssueRequest{}
+	for _, repo := range repositories {
+		// one request per label, see githubRequestsPerRepository
+		requestCfg = append(requestCfg,
+			newGithubIssueRequest(meta, repo, "kind/failing-test"),
+			newGithubIssueRequest(meta, repo, "kind/flake"),
+		)
+	}
+	knownJobs := []string{}
+	if meta.Flags.Features.Enabled(FeatureIssueClustering) {
+		knownJobs = requestKnownJobs(ctx, meta)
+	}
+	c := make(chan ReportDataField)
+	var err error
+	go func() {
+		defer close(c)
+		// request github issue data with a bounded number of workers, issue numbers are only unique per repository.
+		// Repositories are passed on in order as soon as both of their requests finished, so repositories requested before a deadline (-deadlines) are reported
+		requestedIssues := make([]GithubIssuesAfterID, len(requestCfg))
+		errs := runWorkerPoolInOrder(meta.Flags.concurrency(), len(requestCfg), func(i int) error {
+			issues, err := requestGithubIssuesWithAPI(meta, requestCfg[i])
+			requestedIssues[i] = issues
+			return err
+		}, func(i int) {
+			if i%githubRequestsPerRepository != githubRequestsPerRepository-1 {
+				return
+			}
+			repo := repositories[i/githubRequestsPerRepository]
+			issues := GithubIssuesAfterID{}
+			for _, requested := range requestedIssues[i-githubRequestsPerRepository+1 : i+1] {
+				for k, v := range requested {
+					issues[k] = v
+				}
+			}
+			// the repository is only named if issues of multiple repositories are reported
+			title := ""
+			if len(repositories) > 1 {
+				title = repo.String()
 			}
-			internalWg.Done()
-		}(cfg)
+			for field := range transformIntoReportData(meta, title, issues, knownJobs) {
+				c <- field
+			}
+		})
+		err = collectWorkerErrors(errs)
+	}()
+	// DataPostProcessing collects data requested via assembleGithubRequests/2 and returns ReportData, err is set before c is closed
+	reportData := meta.DataPostProcessing(r, githubReport, c, wg)
+	if err != nil {
+		return reportData, fmt.Errorf("requesting github issues: %v", err)
 	}
-	internalWg.Wait()
-	// DataPostProcessing collects data requested via assembleGithubRequests/2 and returns ReportData
-	return meta.DataPostProcessing(r, githubReport, transformIntoReportData(meta, allReqGithubIssues), wg)
+	return reportData, nil
 }
 
-// Print extends GithubReport and prints report data to the console
+// requestKnownJobs returns the sorted job names of the dashboards, issue titles are clustered by them (see duplicateIssues).
+// Clustering is a hint, no jobs are returned if the dashboards could not be requested
+func requestKnownJobs(ctx context.Context, meta Meta) []string {
+	dashboardJobs, err := meta.requestDashboards(ctx, httpClientOrDefault(meta.HTTPClient), meta.Flags.dashboards())
+	if err != nil {
+		meta.logger().Warn("Could not request the jobs of the dashboards, issues are clustered by test names only", "error", err)
+		return nil
+	}
+	seen := map[string]bool{}
+	jobs := []string{}
+	for _, jobsData := range dashboardJobs {
+		for name := range jobsData {
+			if !seen[name] {
+				seen[name] = true
+				jobs = append(jobs, name)
+			}
+		}
+	}
+	sort.Strings(jobs)
+	return jobs
+}
+
+// newGithubIssueRequest returns the request config used to get open issues of a repository with a label that have been updated in the last four months
+func newGithubIssueRequest(meta Meta, repo GithubRepository, label string) GithubIssueRequest {
+	// the day is used instead of the time, so the request url only changes once a day and cached responses are reused
+	fourMonthsAgo := time.Now().UTC().AddDate(0, -4, 0).Truncate(24 * time.Hour).Format(time.RFC3339)
+	return GithubIssueRequest{
+		Owner:      repo.Owner,
+		Repo:       repo.Repo,
+		Params:     GithubIssueRequestParameters{IssueReqParamLabels: label, IssueReqParamSince: fourMonthsAgo},
+		AuthToken:  meta.Env.GithubToken,
+		HTTPClient: meta.HTTPClient,
+	}
+}
+
+// requestGithubIssuesWithAPI requests issues using the github api set via -github-api
+func requestGithubIssuesWithAPI(meta Meta, cfg GithubIssueRequest) (GithubIssuesAfterID, error) {
+	if meta.Flags.GithubAPI == githubAPIGraphQL {
+		return requestGithubIssuesGraphQL(cfg)
+	}
+	return requestGithubIssues(cfg)
+}
+
+// requestAllGithubIssuesWithAPI requests issues without filtering them using the github api set via -github-api
+func requestAllGithubIssuesWithAPI(meta Meta, cfg GithubIssueRequest) (GithubIssues, error) {
+	if meta.Flags.GithubAPI == githubAPIGraphQL {
+		return requestAllGithubIssuesGraphQL(cfg)
+	}
+	return requestAllGithubIssues(cfg)
+}
+
+// Print extends GithubReport and prints report data to the console, issues are split into milestone sections if they are scoped to a milestone
 func (r GithubReport) Print(meta Meta, reportData ReportData) {
-	fmt.Print("\n\n")
-	for _, data := range reportData.Data {
+	c := meta.console()
+	c.Print("\n\n")
+	milestone := meta.Flags.milestone()
+	if milestone == "" {
+		printGithubIssues(c, meta, reportData.Data)
+		c.Println()
+	} else {
+		for _, section := range milestoneSections(reportData, milestone) {
+			c.Printf("%s (%d)\n", strings.ToUpper(section.Title), section.count())
+			printGithubIssues(c, meta, section.Fields)
+			c.Println()
+		}
+	}
+	if lines := workloadLines(reportData); len(lines) > 0 {
+		c.Print("WORKLOAD\n")
+		for _, line := range lines {
+			c.Printf("- %s\n", line)
+		}
+		c.Println()
+	}
+}
+
+func printGithubIssues(c *Console, meta Meta, fields []ReportDataField) {
+	for _, data := range fields {
 		for _, records := range data.Records {
-			fmt.Printf("#%d %s %s\n", records.ID, records.Title, records.Sig)
+			// data.Title names the repository if multiple repositories are reported
+			c.Printf("%s\n", c.heading(records.Highlight, fmt.Sprintf("%s#%d %s %s", data.Title, records.ID, records.Title, records.Sig)))
 			if !meta.Flags.ShortOn {
-				fmt.Printf("- %s\n", records.URL)
+				c.Printf("- %s\n", records.URL)
 			}
 			for _, note := range records.Notes {
-				fmt.Printf("- %s\n", note)
+				c.Printf("- %s\n", githubNoteColors(records, note))
 			}
 		}
 	}
-	fmt.Println()
+}
+
+// githubNoteColors colors the labels and the milestone of an issue in a note, priorities are green, kinds red and the milestone blue.
+// The unassigned note is red. Notes are stored without colors, so they are only written to terminals (see Console)
+func githubNoteColors(record ReportDataRecord, note string) string {
+	if note == unassignedNote {
+		return fmt.Sprintf("%s%s%s", colorRed, note, colorReset)
+	}
+	milestone := ""
+	if record.Milestone != "" && strings.HasSuffix(note, "milestone "+record.Milestone) {
+		note = strings.TrimSuffix(note, "milestone "+record.Milestone)
+		milestone = fmt.Sprintf("%smilestone %s%s", colorBlue, record.Milestone, colorReset)
+	}
+	labels := map[string]bool{}
+	for _, label := range record.Labels {
+		labels[label] = true
+	}
+	tokens := strings.Split(note, " ")
+	for i, token := range tokens {
+		if !labels[token] {
+			continue
+		}
+		if strings.Contains(token, "priority") {
+			tokens[i] = fmt.Sprintf("%s%s%s", colorGreen, token, colorReset)
+		} else if strings.Contains(token, "kind/") {
+			tokens[i] = fmt.Sprintf("%s%s%s", colorRed, token, colorReset)
+		}
+	}
+	return strings.Join(tokens, " ") + milestone
 }
 
 // PutData extends GithubReport and stores the data at runtime to the struct val ReportData
@@ -96,158 +223,340 @@ func (r GithubReport) GetData() ReportData {
 	return r.ReportData
 }
 
-// run all github requests to assemble data
-func transformIntoReportData(meta Meta, issues GithubIssuesAfterID) chan ReportDataField {
+// transformIntoReportData transforms the issues into report data, issues are sorted by their number
+func transformIntoReportData(meta Meta, title string, issues GithubIssuesAfterID, knownJobs []string) chan ReportDataField {
 	c := make(chan ReportDataField)
-	sigRegex := regexp.MustCompile(`sig/[a-zA-Z-]+`)
+	numbers := []int64{}
+	for number := range issues {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	milestone := meta.Flags.milestone()
+	duplicates := map[int64]map[int64][]string{}
+	if meta.Flags.Features.Enabled(FeatureIssueClustering) {
+		duplicates = duplicateIssues(issues, knownJobs)
+	}
 	go func() {
 		defer close(c)
-		var wg sync.WaitGroup
-		for _, issue := range issues {
-			wg.Add(1)
-			go func(issue GithubIssueElement) {
-				notes := []string{}
-				// add timestamp to report notes
-				if !meta.Flags.ShortOn {
-					updatedHighlight := ""
-					createdHighlight := ""
-					if !meta.Flags.EmojisOff {
-						if checkTimeBefore(issue.UpdatedAt, time.Now().AddDate(0, -1, 0)) {
-							updatedHighlight += statusFailingEmoji
-						}
-						if !checkTimeBefore(issue.UpdatedAt, time.Now().AddDate(0, 0, -2)) {
-							updatedHighlight += statusNewEmoji
-						}
-						if checkTimeBefore(issue.CreatedAt, time.Now().AddDate(0, -1, 0)) {
-							createdHighlight += statusFailingEmoji
-						}
-						if !checkTimeBefore(issue.CreatedAt, time.Now().AddDate(0, 0, -3)) {
-							createdHighlight += statusNewEmoji
-						}
+		for _, number := range numbers {
+			issue := issues[number]
+			reported, actionItem := milestoneScope(issue, milestone)
+			assignees := issueAssignees(issue)
+			if !reported || (meta.Flags.OnlyUnassigned && len(assignees) > 0) {
+				continue
+			}
+			notes := []string{}
+			highlight := ""
+			if actionItem {
+				notes = append(notes, fmt.Sprintf("Action item: not yet triaged into milestone %s", milestone))
+				highlight = actionItemEmoji
+			}
+			// add timestamp to report notes
+			if !meta.Flags.ShortOn {
+				updatedHighlight := ""
+				createdHighlight := ""
+				if !meta.Flags.EmojisOff {
+					if checkTimeBefore(issue.UpdatedAt, time.Now().AddDate(0, -1, 0)) {
+						updatedHighlight += statusFailingEmoji
 					}
-					notes = append(notes, fmt.Sprintf("%sCreated %s, %sUpdated %s, Comments: %d", createdHighlight, strings.Split(issue.CreatedAt, "T")[0], updatedHighlight, strings.Split(issue.UpdatedAt, "T")[0], issue.Comments))
-				}
-				// add lables to notes
-				lablesToNote := ""
-				sigsInvolved := []string{}
-				for _, label := range issue.Labels {
-					// filter sigs from notes
-					sig := sigRegex.FindString(label.Name)
-					if sig != "" {
-						sigsInvolved = append(sigsInvolved, sig)
+					if !checkTimeBefore(issue.UpdatedAt, time.Now().AddDate(0, 0, -2)) {
+						updatedHighlight += statusNewEmoji
 					}
-					// filter flag priority & kind/
-					if strings.Contains(label.Name, "priority") {
-						lablesToNote += fmt.Sprintf("%s%s%s ", colorGreen, label.Name, colorReset)
+					if checkTimeBefore(issue.CreatedAt, time.Now().AddDate(0, -1, 0)) {
+						createdHighlight += statusFailingEmoji
 					}
-					if strings.Contains(label.Name, "kind/") {
-						lablesToNote += fmt.Sprintf("%s%s%s ", colorRed, label.Name, colorReset)
+					if !checkTimeBefore(issue.CreatedAt, time.Now().AddDate(0, 0, -3)) {
+						createdHighlight += statusNewEmoji
 					}
 				}
-				// add milestone to lables if it is set
-				if !meta.Flags.ShortOn {
-					if issue.Milestone != nil {
-						lablesToNote += fmt.Sprintf("%smilestone %s%s", colorBlue, issue.Milestone.Title, colorReset)
+				notes = append(notes, fmt.Sprintf("%sCreated %s, %sUpdated %s, Comments: %d", createdHighlight, strings.Split(issue.CreatedAt, "T")[0], updatedHighlight, strings.Split(issue.UpdatedAt, "T")[0], issue.Comments))
+			}
+			// add lables to notes
+			lablesToNote := ""
+			severity := LightSeverity
+			sigsInvolved := []string{}
+			normalizedSigs := []string{}
+			labels := []string{}
+			for _, label := range issue.Labels {
+				labels = append(labels, label.Name)
+				// filter sigs from notes
+				if sig := sigNameRegex.FindString(label.Name); sig != "" {
+					sigsInvolved = append(sigsInvolved, sig)
+					normalizedSigs = append(normalizedSigs, normalizeSig(sig))
+				}
+				// filter flag priority & kind/
+				if strings.Contains(label.Name, "priority") {
+					lablesToNote += fmt.Sprintf("%s ", label.Name)
+					severity = issuePrioritySeverity(label.Name, severity)
+				}
+				if strings.Contains(label.Name, "kind/") {
+					lablesToNote += fmt.Sprintf("%s ", label.Name)
+				}
+			}
+			// add milestone to lables if it is set
+			if !meta.Flags.ShortOn {
+				if issue.Milestone != nil {
+					lablesToNote += fmt.Sprintf("milestone %s", issue.Milestone.Title)
+				}
+			}
+			if lablesToNote != "" {
+				notes = append(notes, lablesToNote)
+			}
+			// highlight issues nobody is accountable for
+			if len(assignees) == 0 {
+				notes = append(notes, unassignedNote)
+				highlight += unassignedEmoji
+			}
+			// add assignees, linked pull requests and project status if they have been requested
+			if !meta.Flags.ShortOn {
+				if len(assignees) > 0 {
+					notes = append(notes, fmt.Sprintf("Assignees: %s", strings.Join(assignees, ", ")))
+				}
+				if len(issue.LinkedPRs) > 0 {
+					linkedPRs := []string{}
+					for _, pr := range issue.LinkedPRs {
+						linkedPRs = append(linkedPRs, fmt.Sprintf("#%d (%s)", pr.Number, strings.ToLower(pr.State)))
 					}
+					notes = append(notes, fmt.Sprintf("Linked PRs: %s", strings.Join(linkedPRs, ", ")))
 				}
-				if lablesToNote != "" {
-					notes = append(notes, lablesToNote)
+				if issue.ProjectStatus != "" {
+					notes = append(notes, fmt.Sprintf("Project status: %s", issue.ProjectStatus))
 				}
-				// set information in ReportDataRecord
-				c <- ReportDataField{
-					Emoji: "",
-					Title: "",
-					Records: []ReportDataRecord{
-						{
-							URL:   issue.HTMLURL,
-							ID:    issue.Number,
-							Title: issue.Title,
-							Notes: notes,
-							Sig:   fmt.Sprintf("%v", sigsInvolved),
-						},
+			}
+			// suggest to consolidate issues that track the same job or test
+			if d, ok := duplicates[number]; ok {
+				notes = append(notes, duplicateIssueNote(number, d, issues))
+			}
+			issueMilestone := ""
+			if issue.Milestone != nil {
+				issueMilestone = issue.Milestone.Title
+			}
+			// set information in ReportDataRecord
+			c <- ReportDataField{
+				Emoji: "",
+				Title: title,
+				Records: []ReportDataRecord{
+					{
+						URL:       issue.HTMLURL,
+						ID:        issue.Number,
+						Title:     issue.Title,
+						Notes:     notes,
+						Sig:       fmt.Sprintf("%v", sigsInvolved),
+						Sigs:      normalizedSigs,
+						Severity:  severity,
+						Highlight: highlight,
+						Labels:    labels,
+						Milestone: issueMilestone,
+						Assignees: assignees,
 					},
-				}
-				wg.Done()
-			}(issue)
+				},
+			}
 		}
-		wg.Wait()
 	}()
 	return c
 }
 
 // GetGithubIssues get github issues
-func GetGithubIssues(cfg GithubIssueRequest) GithubIssuesAfterID {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues%s", cfg.Owner, cfg.Repo, "?state=open")
-	for param, val := range cfg.Params {
-		url += fmt.Sprintf("&%s=%s", param, val)
+func GetGithubIssues(cfg GithubIssueRequest) (GithubIssuesAfterID, error) {
+	return requestGithubIssues(cfg)
+}
+
+// requestGithubIssues requests all pages of issues using the search api, which excludes pull requests and issues labeled with one of
+// githubIssueExcludedLabels, so issues are not filtered after they have been requested
+func requestGithubIssues(cfg GithubIssueRequest) (GithubIssuesAfterID, error) {
+	pageURL, err := githubIssuesSearchURL(cfg)
+	if err != nil {
+		return nil, err
 	}
-	collectedIssues := GithubIssuesAfterID{}
-	for issues := range assembleGithubIssues(url, cfg.AuthToken) {
-		for k, issue := range issues {
-			collectedIssues[k] = issue
+	client := httpClientOrDefault(cfg.HTTPClient)
+	issues := GithubIssuesAfterID{}
+	for pageURL != "" {
+		requestedIssues, next, err := requestGithubSearchPage(client, pageURL, cfg.AuthToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range requestedIssues {
+			issues[issue.Number] = issue
 		}
+		pageURL = next
 	}
-	return collectedIssues
+	return issues, nil
 }
 
-func assembleGithubIssues(url string, authToken string) chan GithubIssuesAfterID {
-	c := make(chan GithubIssuesAfterID)
-	go func() {
-		defer close(c)
-		wg := sync.WaitGroup{}
-		wg.Add(1)
-		go requestGithubIssues(c, &wg, url, 1, authToken)
-		wg.Wait()
-	}()
-	return c
+// githubIssuesSearchURL returns the url of the first page of the issue search, the parameters of the request are translated into qualifiers
+// ("repo:kubernetes/kubernetes is:issue is:open label:"kind/flake" -label:"triage/accepted" ... updated:>=2021-06-03T00:00:00Z").
+// The search api returns up to 1000 issues per query
+func githubIssuesSearchURL(cfg GithubIssueRequest) (string, error) {
+	qualifiers := []string{fmt.Sprintf("repo:%s/%s", cfg.Owner, cfg.Repo), "is:issue"}
+	state := "open"
+	if s, ok := cfg.Params[IssueReqParamState]; ok {
+		state = s
+	}
+	if state != "all" {
+		qualifiers = append(qualifiers, "is:"+state)
+	}
+	if labels := cfg.Params[IssueReqParamLabels]; labels != "" {
+		// like the labels parameter of the issues endpoint, issues have to carry all labels
+		for _, label := range strings.Split(labels, ",") {
+			qualifiers = append(qualifiers, fmt.Sprintf("label:%q", strings.TrimSpace(label)))
+		}
+	}
+	for _, label := range githubIssueExcludedLabels {
+		qualifiers = append(qualifiers, fmt.Sprintf("-label:%q", label))
+	}
+	if since, ok := cfg.Params[IssueReqParamSince]; ok {
+		timestamp, err := githubTimestamp(since)
+		if err != nil {
+			return "", err
+		}
+		qualifiers = append(qualifiers, "updated:>="+timestamp)
+	}
+	query := url.Values{}
+	query.Set("q", strings.Join(qualifiers, " "))
+	query.Set(string(IssueReqParamPerpage), "100")
+	for _, param := range []GithubIssueRequestParameter{IssueReqParamSort, IssueReqParamPerpage} {
+		if val, ok := cfg.Params[param]; ok {
+			query.Set(string(param), val)
+		}
+	}
+	return fmt.Sprintf("https://api.github.com/search/issues?%s", query.Encode()), nil
 }
 
-// requestGithubIssues sends a http request to github to list issues
-func requestGithubIssues(c chan GithubIssuesAfterID, wg *sync.WaitGroup, url string, page int, authToken string) {
-	url = fmt.Sprintf("%s&%s=%d", url, string(IssueReqParamPage), page)
-	req, err := http.NewRequest("GET", url, nil)
+// requestGithubSearchPage requests one page of the issue search and returns the url of the next page ("" on the last page)
+func requestGithubSearchPage(client *http.Client, pageURL string, authToken string) (GithubIssues, string, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
 	if err != nil {
-		log.Fatalf("Error on creating http request.\n[ERROR] -%v", err)
+		return nil, "", fmt.Errorf("creating request %s: %v", pageURL, err)
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", authToken))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("requesting %s: %v", pageURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading response of %s: %v", pageURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("requesting %s failed with status %s: %s", pageURL, resp.Status, body)
+	}
+	var result struct {
+		Items GithubIssues `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, "", fmt.Errorf("unmarshal issues of %s: %v (response: %s)", pageURL, err, body)
+	}
+	return result.Items, nextPageURL(resp.Header), nil
+}
+
+// requestAllGithubIssues requests all pages of issues one after another following the rel="next" Link header of each page (RFC 5988)
+func requestAllGithubIssues(cfg GithubIssueRequest) (GithubIssues, error) {
+	pageURL, err := githubIssuesURL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := httpClientOrDefault(cfg.HTTPClient)
+	collectedIssues := GithubIssues{}
+	for pageURL != "" {
+		requestedIssues, next, err := requestGithubIssuesPage(client, pageURL, cfg.AuthToken)
+		if err != nil {
+			return nil, err
+		}
+		collectedIssues = append(collectedIssues, requestedIssues...)
+		pageURL = next
+	}
+	return collectedIssues, nil
+}
+
+// githubIssuesURL returns the url of the first page of issues (open unless IssueReqParamState is set), labels and the date window are filtered by github.
+// Pages are requested with 100 issues (the maximum) unless IssueReqParamPerpage is set
+func githubIssuesURL(cfg GithubIssueRequest) (string, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set(string(IssueReqParamPerpage), "100")
+	for param, val := range cfg.Params {
+		switch param {
+		case IssueReqParamPage:
+			// pages are followed via Link headers
+			continue
+		case IssueReqParamSince:
+			since, err := githubTimestamp(val)
+			if err != nil {
+				return "", err
+			}
+			val = since
+		}
+		query.Set(string(param), val)
+	}
+	return fmt.Sprintf("https://api.github.com/repos/%s/%s/issues?%s", cfg.Owner, cfg.Repo, query.Encode()), nil
+}
+
+// githubTimestamp converts the since parameter into the ISO 8601 timestamp github expects, dates like 2021-6-3 are accepted as well
+func githubTimestamp(since string) (string, error) {
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		if t, err = time.Parse("2006-1-2", since); err != nil {
+			return "", fmt.Errorf("parsing since parameter %s: %v", since, err)
+		}
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// requestGithubIssuesPage sends a http request to github to list one page of issues and returns the url of the next page ("" on the last page)
+func requestGithubIssuesPage(client *http.Client, pageURL string, authToken string) (GithubIssues, string, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating request %s: %v", pageURL, err)
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", authToken))
 	// Send http request
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatalf("Error on sending http request.\n[ERROR] -%v", err)
+		return nil, "", fmt.Errorf("requesting %s: %v", pageURL, err)
 	}
 	defer resp.Body.Close()
 	// Read body and unmarshal bytes
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatalf("Error on read from response body.\n[ERROR] -%v", err)
+		return nil, "", fmt.Errorf("reading response of %s: %v", pageURL, err)
 	}
 	requestedIssues, err := UnmarshalGithubIssue(body)
 	if err != nil {
-		fmt.Println(url)
-		fmt.Println(string(body))
-		log.Fatalf("Error on UnmarshalGithubIssue.\n[ERROR] -%v", err)
+		return nil, "", fmt.Errorf("unmarshal issues of %s: %v (response: %s)", pageURL, err, body)
 	}
-	// if result is not empty, request data from next website too
-	if len(requestedIssues) != 0 {
-		page++
-		wg.Add(1)
-		go requestGithubIssues(c, wg, url, page, authToken)
+	return requestedIssues, nextPageURL(resp.Header), nil
+}
+
+// nextPageURL returns the url of the rel="next" link of a Link header like
+// <https://api.github.com/repositories/20580498/issues?page=2>; rel="next", <https://api.github.com/repositories/20580498/issues?page=5>; rel="last"
+func nextPageURL(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
 	}
-	c <- filterGithubIssues(requestedIssues)
-	wg.Done()
+	return ""
 }
 
+// githubIssueExcludedLabels issues with one of these labels are not reported. The rest api excludes them in the search query
+// (see githubIssuesSearchURL), the issues connection of the graphql api can not exclude labels, so issues requested with it are filtered
+var githubIssueExcludedLabels = []string{"priority/backlog", "triage/accepted", "lifecycle/rotten", "lifecycle/stale"}
+
 func filterGithubIssues(issues GithubIssues) GithubIssuesAfterID {
 	filteredIssues := GithubIssuesAfterID{}
 	for _, i := range issues {
 		fine := true
 		for _, label := range i.Labels {
-			// issues should not contain any of these lables
-			fine = fine && !strings.Contains(label.Name, "priority/backlog")
-			fine = fine && !strings.Contains(label.Name, "triage/accepted")
-			fine = fine && !strings.Contains(label.Name, "lifecycle/rotten")
-			fine = fine && !strings.Contains(label.Name, "lifecycle/stale")
+			for _, excluded := range githubIssueExcludedLabels {
+				fine = fine && !strings.Contains(label.Name, excluded)
+			}
 		}
 		// issues should not be a pull request
 		fine = fine && !strings.Contains(i.HTMLURL, "pull")
@@ -258,6 +567,21 @@ func filterGithubIssues(issues GithubIssues) GithubIssuesAfterID {
 	return filteredIssues
 }
 
+// issuePrioritySeverity maps priority labels to a severity, the highest severity of all labels is kept
+func issuePrioritySeverity(label string, current Severity) Severity {
+	severity := LightSeverity
+	switch label {
+	case "priority/critical-urgent":
+		severity = HighSeverity
+	case "priority/important-soon":
+		severity = MediumSeverity
+	}
+	if severity > current {
+		return severity
+	}
+	return current
+}
+
 func checkTimeBefore(s string, u time.Time) bool {
 	layout := "2006-01-02T15:04:05Z"
 	t, _ := time.Parse(layout, s)
@@ -272,21 +596,51 @@ type GithubIssueRequestParameters map[GithubIssueRequestParameter]string
 // GithubIssueRequestParameter parameter option that can be used to request issues from github
 type GithubIssueRequestParameter string
 
-// IssueReqParamLabels, IssueReqParamSort, IssueReqParamSince, IssueReqParamPerpage can be set to define how to get issues from github,  IssueReqParamPage get overwritten is not applied
+// IssueReqParamLabels, IssueReqParamState, IssueReqParamSort, IssueReqParamSince, IssueReqParamPerpage can be set to define how to get issues from github, IssueReqParamPage is not applied since pages are followed via Link headers
 const (
 	IssueReqParamLabels  GithubIssueRequestParameter = "labels"
+	IssueReqParamState   GithubIssueRequestParameter = "state"
 	IssueReqParamSort    GithubIssueRequestParameter = "sort"
 	IssueReqParamSince   GithubIssueRequestParameter = "since"
 	IssueReqParamPerpage GithubIssueRequestParameter = "per_page"
 	IssueReqParamPage    GithubIssueRequestParameter = "page"
 )
 
+// GithubRepository a github repository issues are requested from (like kubernetes/kubernetes)
+type GithubRepository struct {
+	Owner string
+	Repo  string
+}
+
+func (r GithubRepository) String() string {
+	return fmt.Sprintf("%s/%s", r.Owner, r.Repo)
+}
+
+// This function is used to split repository input ("kubernetes/kubernetes, kubernetes-sigs/kind" => [{kubernetes kubernetes} {kubernetes-sigs kind}])
+func splitRepositoryInput(input string) ([]GithubRepository, error) {
+	repositories := []GithubRepository{}
+	for _, e := range strings.Split(input, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		parts := strings.Split(e, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%q does not match owner/repo", e)
+		}
+		repositories = append(repositories, GithubRepository{Owner: parts[0], Repo: parts[1]})
+	}
+	return repositories, nil
+}
+
 // GithubIssueRequest used to define how to gather github issue information
 type GithubIssueRequest struct {
 	Owner     string
 	Repo      string
 	Params    GithubIssueRequestParameters
 	AuthToken string
+	// HTTPClient used to send requests, http.DefaultClient is used if it is not set
+	HTTPClient *http.Client
 }
 
 // GITHUB ISSUES
@@ -321,6 +675,10 @@ type GithubIssueElement struct {
 	CreatedAt string     `json:"created_at"`
 	UpdatedAt string     `json:"updated_at"`
 	ClosedAt  string     `json:"closed_at"`
+	Assignees []Assignee `json:"assignees"`
+	// LinkedPRs and ProjectStatus are only requested using the graphql api
+	LinkedPRs     []LinkedPullRequest `json:"linked_prs,omitempty"`
+	ProjectStatus string              `json:"project_status,omitempty"`
 }
 
 // Label github label
@@ -333,3 +691,15 @@ type Label struct {
 type Milestone struct {
 	Title string `json:"title"`
 }
+
+// Assignee github user an issue is assigned to
+type Assignee struct {
+	Login string `json:"login"`
+}
+
+// LinkedPullRequest github pull request that references an issue
+type LinkedPullRequest struct {
+	Number int64  `json:"number"`
+	URL    string `json:"url"`
+	State  string `json:"state"`
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFilterGithubIssues(t *testing.T) {
+	issues := GithubIssues{
+		{Number: 1, HTMLURL: "https://github.com/kubernetes/kubernetes/issues/1", Labels: []Label{{Name: "kind/flake"}}},
+		{Number: 2, HTMLURL: "https://github.com/kubernetes/kubernetes/issues/2", Labels: []Label{{Name: "priority/backlog"}}},
+		{Number: 3, HTMLURL: "https://github.com/kubernetes/kubernetes/issues/3", Labels: []Label{{Name: "triage/accepted"}}},
+		{Number: 4, HTMLURL: "https://github.com/kubernetes/kubernetes/issues/4", Labels: []Label{{Name: "lifecycle/rotten"}}},
+		{Number: 5, HTMLURL: "https://github.com/kubernetes/kubernetes/issues/5", Labels: []Label{{Name: "lifecycle/stale"}}},
+		{Number: 6, HTMLURL: "https://github.com/kubernetes/kubernetes/pull/6", Labels: []Label{{Name: "kind/flake"}}},
+		{Number: 7, HTMLURL: "https://github.com/kubernetes/kubernetes/issues/7"},
+	}
+	filtered := filterGithubIssues(issues)
+	numbers := []int{}
+	for number := range filtered {
+		numbers = append(numbers, int(number))
+	}
+	sort.Ints(numbers)
+	if !reflect.DeepEqual(numbers, []int{1, 7}) {
+		t.Errorf("expected issues [1 7] to pass the filter, got %v", numbers)
+	}
+}
+
+func TestGetGithubIssues(t *testing.T) {
+	meta := newTestMeta(metaFlags{})
+	issues, err := GetGithubIssues(GithubIssueRequest{
+		Owner:      "kubernetes",
+		Repo:       "kubernetes",
+		Params:     GithubIssueRequestParameters{IssueReqParamLabels: "kind/failing-test"},
+		HTTPClient: meta.HTTPClient,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues without excluded labels, got %d", len(issues))
+	}
+	issue, ok := issues[105242]
+	if !ok {
+		t.Fatal("expected issue #105242 to be requested")
+	}
+	if issue.Milestone == nil || issue.Milestone.Title != "v1.23" {
+		t.Errorf("expected milestone v1.23, got %v", issue.Milestone)
+	}
+	if len(issue.Assignees) != 1 || issue.Assignees[0].Login != "alice" {
+		t.Errorf("expected assignee alice, got %v", issue.Assignees)
+	}
+}
+
+func TestRequestGithubIssuesFollowsLinkHeader(t *testing.T) {
+	dir := t.TempDir()
+	// two pages of the issue search, the first one links to the second one
+	pages := []httpFixture{
+		{
+			URL:    "https://api.github.com/search/issues?per_page=100&q=repo%3Akubernetes%2Fkubernetes+is%3Aissue+is%3Aopen+label%3A%22kind%2Fflake%22+-label%3A%22priority%2Fbacklog%22+-label%3A%22triage%2Faccepted%22+-label%3A%22lifecycle%2Frotten%22+-label%3A%22lifecycle%2Fstale%22+updated%3A%3E%3D2021-06-03T00%3A00%3A00Z",
+			Header: http.Header{"Link": {`<https://api.github.com/search/issues?page=2&per_page=100&q=repo%3Akubernetes%2Fkubernetes+is%3Aissue+is%3Aopen+label%3A%22kind%2Fflake%22>; rel="next", <https://api.github.com/search/issues?page=2&per_page=100&q=repo%3Akubernetes%2Fkubernetes+is%3Aissue+is%3Aopen+label%3A%22kind%2Fflake%22>; rel="last"`}},
+			Body:   `{"total_count": 2, "items": [{"number": 1, "html_url": "https://github.com/kubernetes/kubernetes/issues/1"}]}`,
+		},
+		{
+			URL:    "https://api.github.com/search/issues?page=2&per_page=100&q=repo%3Akubernetes%2Fkubernetes+is%3Aissue+is%3Aopen+label%3A%22kind%2Fflake%22",
+			Header: http.Header{"Link": {`<https://api.github.com/search/issues?page=1&per_page=100&q=repo%3Akubernetes%2Fkubernetes+is%3Aissue+is%3Aopen+label%3A%22kind%2Fflake%22>; rel="prev"`}},
+			Body:   `{"total_count": 2, "items": [{"number": 2, "html_url": "https://github.com/kubernetes/kubernetes/issues/2"}]}`,
+		},
+	}
+	for _, page := range pages {
+		page.Method, page.StatusCode = "GET", http.StatusOK
+		req, _ := http.NewRequest(page.Method, page.URL, nil)
+		path, err := fixturePath(dir, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := json.Marshal(page)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	issues, err := requestGithubIssues(GithubIssueRequest{
+		Owner:      "kubernetes",
+		Repo:       "kubernetes",
+		Params:     GithubIssueRequestParameters{IssueReqParamLabels: "kind/flake", IssueReqParamSince: "2021-6-3"},
+		HTTPClient: &http.Client{Transport: NewReplayTransport(dir)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := issues[1]; !ok || len(issues) != 2 {
+		t.Errorf("expected issues of both pages, got %v", issues)
+	}
+}
+
+func TestGithubIssuesURL(t *testing.T) {
+	issuesURL, err := githubIssuesURL(GithubIssueRequest{
+		Owner:  "kubernetes",
+		Repo:   "kubernetes",
+		Params: GithubIssueRequestParameters{IssueReqParamLabels: "kind/flake", IssueReqParamSince: "2021-10-20T15:04:05+02:00", IssueReqParamPage: "3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "https://api.github.com/repos/kubernetes/kubernetes/issues?labels=kind%2Fflake&per_page=100&since=2021-10-20T13%3A04%3A05Z&state=open"
+	if issuesURL != expected {
+		t.Errorf("expected %s, got %s", expected, issuesURL)
+	}
+	if _, err := githubIssuesURL(GithubIssueRequest{Params: GithubIssueRequestParameters{IssueReqParamSince: "last week"}}); err == nil {
+		t.Error("expected an error for an invalid since parameter")
+	}
+}
+
+func TestGithubIssuesSearchURL(t *testing.T) {
+	searchURL, err := githubIssuesSearchURL(GithubIssueRequest{
+		Owner:  "kubernetes",
+		Repo:   "kubernetes",
+		Params: GithubIssueRequestParameters{IssueReqParamLabels: "kind/failing-test, sig/node", IssueReqParamSince: "2021-10-20T15:04:05+02:00", IssueReqParamSort: "updated"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(searchURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// excluded labels are part of the query, issues are not filtered after they have been requested
+	expected := `repo:kubernetes/kubernetes is:issue is:open label:"kind/failing-test" label:"sig/node" -label:"priority/backlog" -label:"triage/accepted" -label:"lifecycle/rotten" -label:"lifecycle/stale" updated:>=2021-10-20T13:04:05Z`
+	if q := parsed.Query().Get("q"); q != expected {
+		t.Errorf("expected the query %s, got %s", expected, q)
+	}
+	if parsed.Path != "/search/issues" || parsed.Query().Get("sort") != "updated" || parsed.Query().Get("per_page") != "100" {
+		t.Errorf("expected a sorted search with 100 issues per page, got %s", searchURL)
+	}
+
+	searchURL, err = githubIssuesSearchURL(GithubIssueRequest{Owner: "kubernetes", Repo: "kubernetes", Params: GithubIssueRequestParameters{IssueReqParamState: "all"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed, _ = url.Parse(searchURL); strings.Contains(parsed.Query().Get("q"), "is:open") {
+		t.Errorf("expected no state qualifier for all issues, got %s", searchURL)
+	}
+	if _, err := githubIssuesSearchURL(GithubIssueRequest{Params: GithubIssueRequestParameters{IssueReqParamSince: "last week"}}); err == nil {
+		t.Error("expected an error for an invalid since parameter")
+	}
+}
+
+func TestGithubReportRequestData(t *testing.T) {
+	meta := newTestMeta(metaFlags{})
+	r := &GithubReport{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := r.RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := map[int64]ReportDataRecord{}
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			records[record.ID] = record
+		}
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 issues in the report, got %d", len(records))
+	}
+	tests := []struct {
+		id               int64
+		expectedSigs     []string
+		expectedSeverity Severity
+	}{
+		{id: 105242, expectedSigs: []string{"sig-storage"}, expectedSeverity: MediumSeverity},
+		{id: 105965, expectedSigs: []string{"sig-storage", "sig-node"}, expectedSeverity: LightSeverity},
+		{id: 97783, expectedSigs: []string{"sig-windows"}, expectedSeverity: LightSeverity},
+	}
+	for _, tc := range tests {
+		record, ok := records[tc.id]
+		if !ok {
+			t.Errorf("expected issue #%d in the report", tc.id)
+			continue
+		}
+		if !reflect.DeepEqual(record.Sigs, tc.expectedSigs) {
+			t.Errorf("#%d: expected sigs %v, got %v", tc.id, tc.expectedSigs, record.Sigs)
+		}
+		if record.Severity != tc.expectedSeverity {
+			t.Errorf("#%d: expected severity %d, got %d", tc.id, tc.expectedSeverity, record.Severity)
+		}
+		for _, note := range record.Notes {
+			if strings.Contains(note, "\033[") {
+				t.Errorf("#%d: expected the notes to be stored without colors, got %q", tc.id, note)
+			}
+		}
+	}
+}
+
+func TestGithubNoteColors(t *testing.T) {
+	record := ReportDataRecord{Labels: []string{"kind/failing-test", "priority/important-soon", "sig/node"}, Milestone: "v1.23"}
+	expected := colorRed + "kind/failing-test" + colorReset + " " + colorGreen + "priority/important-soon" + colorReset + " " + colorBlue + "milestone v1.23" + colorReset
+	if got := githubNoteColors(record, "kind/failing-test priority/important-soon milestone v1.23"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if got := githubNoteColors(record, unassignedNote); got != colorRed+unassignedNote+colorReset {
+		t.Errorf("expected the unassigned note in red, got %q", got)
+	}
+	// other notes are kept as they are
+	if got := githubNoteColors(record, "Assignees: alice"); got != "Assignees: alice" {
+		t.Errorf("expected the note without colors, got %q", got)
+	}
+}
+
+func TestSplitRepositoryInput(t *testing.T) {
+	repositories, err := splitRepositoryInput("kubernetes/kubernetes, kubernetes-sigs/kind,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []GithubRepository{{Owner: "kubernetes", Repo: "kubernetes"}, {Owner: "kubernetes-sigs", Repo: "kind"}}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("expected repositories %v, got %v", expected, repositories)
+	}
+	for _, input := range []string{"kubernetes", "kubernetes/", "knative/serving/docs"} {
+		if _, err := splitRepositoryInput(input); err == nil {
+			t.Errorf("expected %q to be invalid", input)
+		}
+	}
+	if repositories := (metaFlags{}).repositories(); !reflect.DeepEqual(repositories, []GithubRepository{defaultGithubRepository}) {
+		t.Errorf("expected kubernetes/kubernetes by default, got %v", repositories)
+	}
+}
@@ -0,0 +1,400 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	googleScopes    = "https://www.googleapis.com/auth/documents https://www.googleapis.com/auth/spreadsheets"
+	googleDocsAPI   = "https://docs.googleapis.com/v1/documents"
+	googleSheetsAPI = "https://sheets.googleapis.com/v4/spreadsheets"
+	// DefaultGoogleSheetTab tab of the spreadsheet the report is appended to if no tab is set
+	DefaultGoogleSheetTab = "CI signal"
+)
+
+// GoogleDocsNotifier appends the report to a google doc (like the CI signal meeting notes),
+// the document has to be shared with the service account
+type GoogleDocsNotifier struct {
+	DocumentID string
+	// Credentials path to the key file (json) of the service account
+	Credentials string
+}
+
+// Notify extends GoogleDocsNotifier and appends a generated-at heading, the dashboard summary table and the report,
+// the markdown of the report is rendered as headings, bold text and links of the doc
+func (n GoogleDocsNotifier) Notify(meta Meta, report Report) error {
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	client, err := newGoogleClient(meta, n.Credentials)
+	if err != nil {
+		return err
+	}
+	documentURL := fmt.Sprintf("%s/%s", googleDocsAPI, url.PathEscape(n.DocumentID))
+	var document struct {
+		Body struct {
+			Content []struct {
+				EndIndex int `json:"endIndex"`
+			} `json:"content"`
+		} `json:"body"`
+	}
+	if err := googleRequest(client, http.MethodGet, documentURL+"?fields=body(content(endIndex))", nil, &document); err != nil {
+		return fmt.Errorf("reading google doc %s: %v", n.DocumentID, err)
+	}
+	if len(document.Body.Content) == 0 {
+		return fmt.Errorf("reading google doc %s: document has no content", n.DocumentID)
+	}
+	// text is inserted before the newline that ends the body
+	end := document.Body.Content[len(document.Body.Content)-1].EndIndex - 1
+	requests := googleDocRequests(end, generatedAtHeading(time.Now()), dashboardSummaryTable(report), MarkdownReport(meta, report))
+	if err := googleRequest(client, http.MethodPost, documentURL+":batchUpdate", map[string]interface{}{"requests": requests}, nil); err != nil {
+		return fmt.Errorf("appending report to google doc %s: %v", n.DocumentID, err)
+	}
+	return nil
+}
+
+// googleDocRequests returns the batch update requests that append the heading, the table and the markdown at index end of the document.
+// The markdown is inserted and styled first, the table and the heading are inserted before it, so the styled ranges move along with the text.
+// The table is filled from the last to the first cell, so the index of the cells that are left does not change
+func googleDocRequests(end int, heading string, table [][]string, markdown string) []map[string]interface{} {
+	// the markdown starts a new paragraph after the newline that is inserted before it
+	text, styles := googleDocText(end+1, markdown)
+	requests := []map[string]interface{}{
+		{"insertText": map[string]interface{}{"text": "\n" + text, "location": map[string]int{"index": end}}},
+	}
+	requests = append(requests, styles...)
+	if len(table) > 0 {
+		columns := len(table[0])
+		requests = append(requests, map[string]interface{}{"insertTable": map[string]interface{}{
+			"rows": len(table), "columns": columns, "location": map[string]int{"index": end},
+		}})
+		// the table is inserted after a new paragraph, each row and cell starts with one index and each cell ends with a newline
+		tableStart := end + 1
+		for row := len(table) - 1; row >= 0; row-- {
+			for column := columns - 1; column >= 0; column-- {
+				if table[row][column] == "" {
+					continue
+				}
+				index := tableStart + 3 + row*(2*columns+1) + 2*column
+				requests = append(requests, map[string]interface{}{"insertText": map[string]interface{}{
+					"text": table[row][column], "location": map[string]int{"index": index},
+				}})
+			}
+		}
+	}
+	requests = append(requests,
+		map[string]interface{}{"insertText": map[string]interface{}{"text": "\n" + heading, "location": map[string]int{"index": end}}},
+		map[string]interface{}{"updateParagraphStyle": map[string]interface{}{
+			"range":          map[string]int{"startIndex": end + 1, "endIndex": end + 1 + utf16Length(heading)},
+			"paragraphStyle": map[string]string{"namedStyleType": "HEADING_2"},
+			"fields":         "namedStyleType",
+		}},
+	)
+	return requests
+}
+
+var (
+	// markdownHeadingRegex matches the headings of the markdown report ("## GITHUB report")
+	markdownHeadingRegex = regexp.MustCompile(`^(#{1,5}) (.*)$`)
+	// markdownInlineRegex matches bold text ("**FAILING**") and links ("[[Failing Test] gce-serial](https://...)") of the markdown report,
+	// link texts can contain brackets, so they end at the last "](" of the line
+	markdownInlineRegex = regexp.MustCompile(`\*\*([^*]+)\*\*|\[(.+)\]\((https?://[^\s)]+)\)`)
+)
+
+// googleDocText returns the plain text of the markdown report and the requests that style it when it is inserted at index start.
+// Headings are nested below the generated-at heading (HEADING_2), "## GITHUB report" becomes a HEADING_3
+func googleDocText(start int, markdown string) (string, []map[string]interface{}) {
+	var sb strings.Builder
+	styles := []map[string]interface{}{}
+	index := start
+	write := func(s string) {
+		sb.WriteString(s)
+		index += utf16Length(s)
+	}
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(markdown, "\n"), "\n") {
+		lineStart := index
+		namedStyle := ""
+		if match := markdownHeadingRegex.FindStringSubmatch(strings.TrimSuffix(line, "\n")); match != nil {
+			namedStyle = fmt.Sprintf("HEADING_%d", len(match[1])+1)
+			line = strings.TrimPrefix(line, match[1]+" ")
+		}
+		for {
+			match := markdownInlineRegex.FindStringSubmatchIndex(line)
+			if match == nil {
+				write(line)
+				break
+			}
+			write(line[:match[0]])
+			styleStart := index
+			if match[2] >= 0 {
+				write(line[match[2]:match[3]])
+				styles = append(styles, googleDocTextStyle(styleStart, index, map[string]interface{}{"bold": true}, "bold"))
+			} else {
+				write(line[match[4]:match[5]])
+				styles = append(styles, googleDocTextStyle(styleStart, index, map[string]interface{}{"link": map[string]string{"url": line[match[6]:match[7]]}}, "link"))
+			}
+			line = line[match[1]:]
+		}
+		if namedStyle != "" {
+			styles = append(styles, map[string]interface{}{"updateParagraphStyle": map[string]interface{}{
+				"range":          map[string]int{"startIndex": lineStart, "endIndex": index},
+				"paragraphStyle": map[string]string{"namedStyleType": namedStyle},
+				"fields":         "namedStyleType",
+			}})
+		}
+	}
+	return sb.String(), styles
+}
+
+func googleDocTextStyle(startIndex int, endIndex int, style map[string]interface{}, fields string) map[string]interface{} {
+	return map[string]interface{}{"updateTextStyle": map[string]interface{}{
+		"range":     map[string]int{"startIndex": startIndex, "endIndex": endIndex},
+		"textStyle": style,
+		"fields":    fields,
+	}}
+}
+
+// utf16Length google docs indexes text in utf-16 code units (emojis take two)
+func utf16Length(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// GoogleSheetsNotifier appends the report to a tab of a google spreadsheet, the spreadsheet has to be shared with the service account
+type GoogleSheetsNotifier struct {
+	SpreadsheetID string
+	// Tab title of the tab the rows are appended to, DefaultGoogleSheetTab if it is not set
+	Tab string
+	// Credentials path to the key file (json) of the service account
+	Credentials string
+}
+
+// Notify extends GoogleSheetsNotifier and appends a generated-at row, the dashboard summary table and one row per line of the report in markdown format
+func (n GoogleSheetsNotifier) Notify(meta Meta, report Report) error {
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	client, err := newGoogleClient(meta, n.Credentials)
+	if err != nil {
+		return err
+	}
+	tab := n.Tab
+	if tab == "" {
+		tab = DefaultGoogleSheetTab
+	}
+	rows := [][]string{{generatedAtHeading(time.Now())}, {}}
+	if table := dashboardSummaryTable(report); len(table) > 0 {
+		rows = append(append(rows, table...), []string{})
+	}
+	for _, line := range strings.Split(strings.TrimRight(MarkdownReport(meta, report), "\n"), "\n") {
+		rows = append(rows, []string{line})
+	}
+	appendURL := fmt.Sprintf("%s/%s/values/%s:append?valueInputOption=RAW&insertDataOption=INSERT_ROWS",
+		googleSheetsAPI, url.PathEscape(n.SpreadsheetID), url.PathEscape(fmt.Sprintf("'%s'!A1", strings.ReplaceAll(tab, "'", "''"))))
+	if err := googleRequest(client, http.MethodPost, appendURL, map[string]interface{}{"values": rows}, nil); err != nil {
+		return fmt.Errorf("appending report to tab %q of google spreadsheet %s: %v", tab, n.SpreadsheetID, err)
+	}
+	return nil
+}
+
+// ParseGoogleSheet parses a spreadsheet set like "SPREADSHEET_ID/Tab", DefaultGoogleSheetTab is used if no tab is set
+func ParseGoogleSheet(sheet string) (spreadsheetID string, tab string, err error) {
+	parts := strings.SplitN(strings.TrimSpace(sheet), "/", 2)
+	spreadsheetID, tab = strings.TrimSpace(parts[0]), DefaultGoogleSheetTab
+	if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+		tab = strings.TrimSpace(parts[1])
+	}
+	if spreadsheetID == "" {
+		return "", "", fmt.Errorf("%q does not match SPREADSHEET_ID/Tab", sheet)
+	}
+	return spreadsheetID, tab, nil
+}
+
+// generatedAtHeading heading of the appended report like "CI signal report, generated at 2021-10-20 09:00 UTC"
+func generatedAtHeading(generatedAt time.Time) string {
+	return fmt.Sprintf("CI signal report, generated at %s", generatedAt.UTC().Format("2006-01-02 15:04 MST"))
+}
+
+// dashboardSummaryTable the number of jobs per status of each testgrid dashboard, the first row names the columns
+func dashboardSummaryTable(report Report) [][]string {
+	statuses := []overallStatus{total, passing, flaky, failing}
+	table := [][]string{}
+	for _, reportData := range report {
+		if reportData.Name != testgridReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if record.ID != testgridReportSummary {
+					continue
+				}
+				row := []string{field.Title}
+				for _, status := range statuses {
+					row = append(row, strconv.Itoa(record.Counts[strings.ToLower(string(status))]))
+				}
+				table = append(table, row)
+			}
+		}
+	}
+	if len(table) == 0 {
+		return table
+	}
+	header := []string{"Dashboard"}
+	for _, status := range statuses {
+		header = append(header, strings.Title(strings.ToLower(string(status))))
+	}
+	return append([][]string{header}, table...)
+}
+
+// newGoogleClient returns a client that authenticates requests with access tokens of the service account
+func newGoogleClient(meta Meta, credentials string) (*http.Client, error) {
+	if credentials == "" {
+		return nil, fmt.Errorf("no google credentials set, set GOOGLE_APPLICATION_CREDENTIALS to the key file of a service account")
+	}
+	client := httpClientOrDefault(meta.HTTPClient)
+	source, err := NewGoogleTokenSource(credentials, client)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: &oauth2.Transport{Source: source, Base: client.Transport}, Timeout: client.Timeout}, nil
+}
+
+// googleRequest sends the payload in json format and unmarshals the response into result if it is not nil
+func googleRequest(client *http.Client, method string, url string, payload interface{}, result interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("request failed with status %s: %s", resp.Status, data)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// googleServiceAccount fields of the key file of a service account that are used to request access tokens
+type googleServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewGoogleTokenSource returns the access tokens of the service account key file (json), tokens are refreshed before they expire
+func NewGoogleTokenSource(credentials string, httpClient *http.Client) (oauth2.TokenSource, error) {
+	data, err := ioutil.ReadFile(credentials)
+	if err != nil {
+		return nil, err
+	}
+	var account googleServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("parsing service account key %s: %v", credentials, err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return nil, fmt.Errorf("service account key %s needs client_email, private_key and token_uri", credentials)
+	}
+	key, err := parseRSAPrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key of %s: %v", credentials, err)
+	}
+	return oauth2.ReuseTokenSource(nil, &googleServiceAccountTokenSource{
+		account: account,
+		key:     key,
+		client:  httpClientOrDefault(httpClient),
+		now:     time.Now,
+	}), nil
+}
+
+// googleServiceAccountTokenSource exchanges a json web token signed by the service account for an access token (valid for one hour)
+type googleServiceAccountTokenSource struct {
+	account googleServiceAccount
+	key     *rsa.PrivateKey
+	client  *http.Client
+	now     func() time.Time
+}
+
+func (s *googleServiceAccountTokenSource) Token() (*oauth2.Token, error) {
+	now := s.now()
+	assertion, err := signJWT(s.key, map[string]interface{}{
+		"iss":   s.account.ClientEmail,
+		"scope": googleScopes,
+		"aud":   s.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.PostForm(s.account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("requesting google access token: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading google access token: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting google access token failed with status %s: %s", resp.Status, body)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("unmarshal google access token: %v", err)
+	}
+	return &oauth2.Token{AccessToken: token.AccessToken, TokenType: token.TokenType, Expiry: now.Add(time.Duration(token.ExpiresIn) * time.Second)}, nil
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// googleAPITransport answers the token, docs and sheets requests of the google exporters and records the requests
+type googleAPITransport struct {
+	requests []string
+	bodies   map[string][]byte
+	tokens   int
+}
+
+func (t *googleAPITransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	request := req.Method + " " + req.URL.Host + req.URL.EscapedPath()
+	t.requests = append(t.requests, request)
+	body := "{}"
+	if req.Body != nil {
+		data, _ := ioutil.ReadAll(req.Body)
+		t.bodies[request] = data
+	}
+	switch {
+	case req.URL.Host == "oauth2.googleapis.com":
+		t.tokens++
+		body = `{"access_token": "ya29.test", "token_type": "Bearer", "expires_in": 3599}`
+	case req.Header.Get("Authorization") != "Bearer ya29.test":
+		return &http.Response{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized", Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("{}")), Request: req}, nil
+	case req.Method == http.MethodGet:
+		body = `{"body": {"content": [{"endIndex": 1}, {"endIndex": 42}]}}`
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func writeTestServiceAccount(t *testing.T) string {
+	_, keyFile := writeTestPrivateKey(t)
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(googleServiceAccount{ClientEmail: "ci-reporter@example.iam.gserviceaccount.com", PrivateKey: string(key), TokenURI: "https://oauth2.googleapis.com/token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func googleTestReport() Report {
+	return Report{{Name: testgridReport, Data: []ReportDataField{
+		{Title: "Master-Blocking", Records: []ReportDataRecord{{ID: testgridReportSummary, Counts: map[string]int{"total": 10, "passing": 7, "flaky": 1, "failing": 2}, Notes: []string{"10 jobs total"}}}},
+		{Title: "Master-Informing", Records: []ReportDataRecord{{ID: testgridReportSummary, Counts: map[string]int{"total": 20, "passing": 20}, Notes: []string{"20 jobs total"}}}},
+	}}}
+}
+
+func TestDashboardSummaryTable(t *testing.T) {
+	expected := [][]string{
+		{"Dashboard", "Total", "Passing", "Flaky", "Failing"},
+		{"Master-Blocking", "10", "7", "1", "2"},
+		{"Master-Informing", "20", "20", "0", "0"},
+	}
+	if table := dashboardSummaryTable(googleTestReport()); !reflect.DeepEqual(table, expected) {
+		t.Errorf("expected %v, got %v", expected, table)
+	}
+}
+
+func TestGoogleDocRequests(t *testing.T) {
+	requests := googleDocRequests(1, "CI signal report 🔥", [][]string{{"a", "b"}, {"c", ""}}, "report\n")
+	if text := requests[0]["insertText"].(map[string]interface{})["text"]; text != "\nreport" {
+		t.Errorf("expected the report to be inserted first, got %q", text)
+	}
+	indexes := []int{}
+	for _, r := range requests[2:5] {
+		indexes = append(indexes, r["insertText"].(map[string]interface{})["location"].(map[string]int)["index"])
+	}
+	// a table inserted at index 1 starts at 2, its cells are filled from the last to the first cell (empty cells are left out)
+	if !reflect.DeepEqual(indexes, []int{10, 7, 5}) {
+		t.Errorf("expected the cells to be filled at [10 7 5], got %v", indexes)
+	}
+	style := requests[6]["updateParagraphStyle"].(map[string]interface{})["range"].(map[string]int)
+	if style["startIndex"] != 2 || style["endIndex"] != 21 {
+		t.Errorf("expected the heading to span [2, 21) in utf-16 code units, got %v", style)
+	}
+}
+
+func TestGoogleDocText(t *testing.T) {
+	markdown := "## TESTGRID report\n\n- **FAILING** 🔴 [[Failing Test] gce-serial](https://testgrid.k8s.io/a#gce-serial)\n  - Sig's involved [sig-node]\n"
+	text, styles := googleDocText(10, markdown)
+	if text != "TESTGRID report\n\n- FAILING 🔴 [Failing Test] gce-serial\n  - Sig's involved [sig-node]" {
+		t.Errorf("expected the text without markdown, got %q", text)
+	}
+	ranges := []map[string]int{}
+	for _, style := range styles {
+		for _, request := range style {
+			ranges = append(ranges, request.(map[string]interface{})["range"].(map[string]int))
+		}
+	}
+	// "TESTGRID report\n" spans [10, 26), "FAILING" starts after "\n- ", the link text after the emoji that takes two utf-16 code units
+	expected := []map[string]int{
+		{"startIndex": 10, "endIndex": 26},
+		{"startIndex": 29, "endIndex": 36},
+		{"startIndex": 40, "endIndex": 65},
+	}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("expected the ranges %v, got %v", expected, ranges)
+	}
+	if _, ok := styles[0]["updateParagraphStyle"]; !ok || styles[0]["updateParagraphStyle"].(map[string]interface{})["paragraphStyle"].(map[string]string)["namedStyleType"] != "HEADING_3" {
+		t.Errorf("expected the heading to be styled as HEADING_3, got %v", styles[0])
+	}
+	if bold := styles[1]["updateTextStyle"].(map[string]interface{}); bold["fields"] != "bold" {
+		t.Errorf("expected bold text, got %v", bold)
+	}
+	link := styles[2]["updateTextStyle"].(map[string]interface{})
+	if link["fields"] != "link" || !reflect.DeepEqual(link["textStyle"], map[string]interface{}{"link": map[string]string{"url": "https://testgrid.k8s.io/a#gce-serial"}}) {
+		t.Errorf("expected a link to the job, got %v", link)
+	}
+}
+
+func TestGoogleNotifiers(t *testing.T) {
+	credentials := writeTestServiceAccount(t)
+	transport := &googleAPITransport{bodies: map[string][]byte{}}
+	meta := Meta{Flags: metaFlags{EmojisOff: true}, HTTPClient: &http.Client{Transport: transport}}
+
+	if err := (GoogleDocsNotifier{DocumentID: "doc-1", Credentials: credentials}).Notify(meta, googleTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	var update struct {
+		Requests []map[string]json.RawMessage `json:"requests"`
+	}
+	if err := json.Unmarshal(transport.bodies["POST docs.googleapis.com/v1/documents/doc-1:batchUpdate"], &update); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(update.Requests[0]["insertText"]), `"index":41`) || strings.Contains(string(update.Requests[0]["insertText"]), "#") {
+		t.Errorf("expected the report to be inserted without markdown at the end of the document, got %s", update.Requests[0])
+	}
+	tables := 0
+	for _, request := range update.Requests {
+		if table, ok := request["insertTable"]; ok && strings.Contains(string(table), `"index":41`) {
+			tables++
+		}
+	}
+	if tables != 1 {
+		t.Errorf("expected the summary table to be inserted at the end of the document, got %v", update.Requests)
+	}
+
+	if err := (GoogleSheetsNotifier{SpreadsheetID: "sheet-1", Tab: "CI signal"}).Notify(meta, googleTestReport()); err == nil {
+		t.Error("expected an error without credentials")
+	}
+	if err := (GoogleSheetsNotifier{SpreadsheetID: "sheet-1", Tab: "CI signal", Credentials: credentials}).Notify(meta, googleTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	var appended struct {
+		Values [][]string `json:"values"`
+	}
+	if err := json.Unmarshal(transport.bodies["POST sheets.googleapis.com/v4/spreadsheets/sheet-1/values/%27CI%20signal%27%21A1:append"], &appended); err != nil {
+		t.Fatalf("expected rows to be appended to the tab, got requests %v (%v)", transport.requests, err)
+	}
+	if !strings.HasPrefix(appended.Values[0][0], "CI signal report, generated at ") || !reflect.DeepEqual(appended.Values[2], []string{"Dashboard", "Total", "Passing", "Flaky", "Failing"}) {
+		t.Errorf("expected a generated-at row and the summary table, got %v", appended.Values[:3])
+	}
+	if transport.tokens != 2 {
+		t.Errorf("expected one access token per notifier, got %d", transport.tokens)
+	}
+
+	meta.Flags.ReadOnly = true
+	if err := (GoogleDocsNotifier{DocumentID: "doc-1", Credentials: credentials}).Notify(meta, googleTestReport()); err != errReadOnly {
+		t.Errorf("expected read-only mode to be respected, got %v", err)
+	}
+}
+
+func TestParseGoogleSheet(t *testing.T) {
+	if id, tab, err := ParseGoogleSheet("sheet-1/Weekly notes"); err != nil || id != "sheet-1" || tab != "Weekly notes" {
+		t.Errorf("unexpected spreadsheet %q tab %q (%v)", id, tab, err)
+	}
+	if _, tab, _ := ParseGoogleSheet("sheet-1"); tab != DefaultGoogleSheetTab {
+		t.Errorf("expected the default tab, got %q", tab)
+	}
+	if _, _, err := ParseGoogleSheet("/tab"); err == nil {
+		t.Error("expected a missing spreadsheet id to be rejected")
+	}
+}
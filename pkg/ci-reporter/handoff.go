@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHandoffSince window of the handoff subcommand if -since is not set
+const DefaultHandoffSince = 7 * 24 * time.Hour
+
+// column of issues that are not on a project board
+const handoffNoProjectStatus = "No project status"
+
+// Handoff what has been investigated, observed and resolved during a CI signal shift
+type Handoff struct {
+	Since time.Time
+	Until time.Time
+	// Investigated open failing-test and flake issues per project board column
+	Investigated []HandoffColumn
+	// Observed failing jobs of blocking dashboards (see isBlockingDashboard)
+	Observed []HandoffJob
+	// Resolved failing-test issues closed during the shift
+	Resolved []HandoffIssue
+}
+
+// HandoffColumn issues of a project board column (like "CI Signal: In Progress")
+type HandoffColumn struct {
+	Name   string
+	Issues []HandoffIssue
+}
+
+// HandoffIssue a github issue of the handoff, Reference is "#number" or "owner/repo#number" if multiple repositories are reported
+type HandoffIssue struct {
+	Reference string
+	Title     string
+	URL       string
+	Assignees []string
+	ClosedAt  time.Time
+}
+
+// HandoffJob a failing job of a blocking dashboard
+type HandoffJob struct {
+	Dashboard    string
+	Job          string
+	URL          string
+	FailureClass string
+}
+
+// RequestHandoff requests the open and recently closed issues of all repositories and the failing blocking jobs.
+// Open issues are requested using the graphql api whatever -github-api is set to, the rest api does not know the project board columns
+func RequestHandoff(meta Meta, since time.Duration, now time.Time) (Handoff, error) {
+	if err := meta.githubCredentials(); err != nil {
+		return Handoff{}, fmt.Errorf("the project board columns of the handoff are requested using the github graphql api, which needs github credentials (like GITHUB_AUTH_TOKEN): %v", err)
+	}
+	meta.Flags.GithubAPI = githubAPIGraphQL
+	start := now.Add(-since)
+	repositories := meta.Flags.repositories()
+	open := map[GithubRepository]GithubIssues{}
+	closed := map[GithubRepository]GithubIssues{}
+	for _, repo := range repositories {
+		for _, label := range []string{"kind/failing-test", "kind/flake"} {
+			issues, err := requestAllGithubIssuesWithAPI(meta, newGithubIssueRequest(meta, repo, label))
+			if err != nil {
+				return Handoff{}, fmt.Errorf("requesting open issues of %s: %v", repo, err)
+			}
+			open[repo] = append(open[repo], issues...)
+		}
+		// closed issues are requested using the rest api, issues updated since the start of the shift are filtered by github
+		issues, err := requestAllGithubIssues(GithubIssueRequest{
+			Owner:      repo.Owner,
+			Repo:       repo.Repo,
+			Params:     GithubIssueRequestParameters{IssueReqParamLabels: "kind/failing-test", IssueReqParamState: "closed", IssueReqParamSince: start.UTC().Format(time.RFC3339)},
+			AuthToken:  meta.Env.GithubToken,
+			HTTPClient: meta.HTTPClient,
+		})
+		if err != nil {
+			return Handoff{}, fmt.Errorf("requesting closed issues of %s: %v", repo, err)
+		}
+		closed[repo] = issues
+	}
+
+	// the jobs of the dashboards are needed even if the report is shortened
+	meta.Flags.ShortOn = false
+	var wg sync.WaitGroup
+	wg.Add(1)
+	testgridData, err := (&TestgridReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		return Handoff{}, err
+	}
+	return composeHandoff(repositories, open, closed, testgridData, start, now), nil
+}
+
+// composeHandoff groups the open issues by project column, keeps the issues closed after start and the failing jobs of blocking dashboards
+func composeHandoff(repositories []GithubRepository, open map[GithubRepository]GithubIssues, closed map[GithubRepository]GithubIssues, testgridData ReportData, start time.Time, now time.Time) Handoff {
+	handoff := Handoff{Since: start, Until: now}
+	reference := func(repo GithubRepository, number int64) string {
+		if len(repositories) > 1 {
+			return fmt.Sprintf("%s#%d", repo, number)
+		}
+		return fmt.Sprintf("#%d", number)
+	}
+
+	columns := map[string][]HandoffIssue{}
+	for _, repo := range repositories {
+		seen := map[int64]bool{}
+		for _, issue := range open[repo] {
+			// issues labeled as failing-test and flake are requested twice
+			if seen[issue.Number] || strings.Contains(issue.HTMLURL, "pull") {
+				continue
+			}
+			seen[issue.Number] = true
+			column := issue.ProjectStatus
+			if column == "" {
+				column = handoffNoProjectStatus
+			}
+			assignees := []string{}
+			for _, a := range issue.Assignees {
+				assignees = append(assignees, a.Login)
+			}
+			columns[column] = append(columns[column], HandoffIssue{Reference: reference(repo, issue.Number), Title: issue.Title, URL: issue.HTMLURL, Assignees: assignees})
+		}
+		for _, issue := range closed[repo] {
+			closedAt, err := time.Parse(time.RFC3339, issue.ClosedAt)
+			if err != nil || closedAt.Before(start) || strings.Contains(issue.HTMLURL, "pull") {
+				continue
+			}
+			handoff.Resolved = append(handoff.Resolved, HandoffIssue{Reference: reference(repo, issue.Number), Title: issue.Title, URL: issue.HTMLURL, ClosedAt: closedAt})
+		}
+	}
+	names := []string{}
+	for name := range columns {
+		if name != handoffNoProjectStatus {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := columns[handoffNoProjectStatus]; ok {
+		names = append(names, handoffNoProjectStatus)
+	}
+	for _, name := range names {
+		issues := columns[name]
+		sort.SliceStable(issues, func(i, j int) bool { return issues[i].Reference < issues[j].Reference })
+		handoff.Investigated = append(handoff.Investigated, HandoffColumn{Name: name, Issues: issues})
+	}
+	sort.SliceStable(handoff.Resolved, func(i, j int) bool { return handoff.Resolved[i].ClosedAt.After(handoff.Resolved[j].ClosedAt) })
+
+	for _, field := range testgridData.Data {
+		if !isBlockingDashboard(field.Title) {
+			continue
+		}
+		for _, record := range field.Records {
+			if record.ID == testgridReportDetails && record.Status == string(failing) {
+				handoff.Observed = append(handoff.Observed, HandoffJob{Dashboard: field.Title, Job: record.Title, URL: record.URL, FailureClass: record.FailureClass})
+			}
+		}
+	}
+	return handoff
+}
+
+// isBlockingDashboard tells if the dashboard blocks releases by its name (like "Master-Blocking", "1.22-blocking" or -dashboards "knative-blocking")
+func isBlockingDashboard(title string) bool {
+	return strings.Contains(strings.ToLower(title), "blocking")
+}
+
+// String composes the markdown handoff document
+func (h Handoff) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# CI signal handoff %s\n\n", h.Until.Format(cycleDateLayout)))
+	sb.WriteString(fmt.Sprintf("Shift from %s to %s.\n", h.Since.Format(cycleDateLayout), h.Until.Format(cycleDateLayout)))
+
+	sb.WriteString("\n## Being investigated\n")
+	if len(h.Investigated) == 0 {
+		sb.WriteString("\nNo open failing-test or flake issues.\n")
+	}
+	for _, column := range h.Investigated {
+		sb.WriteString(fmt.Sprintf("\n### %s\n\n", column.Name))
+		for _, issue := range column.Issues {
+			line := fmt.Sprintf("- [%s](%s) %s", issue.Reference, issue.URL, issue.Title)
+			if len(issue.Assignees) > 0 {
+				line += fmt.Sprintf(" (assigned to %s)", strings.Join(issue.Assignees, ", "))
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	sb.WriteString("\n## Failing blocking jobs\n\n")
+	if len(h.Observed) == 0 {
+		sb.WriteString("No blocking job is failing.\n")
+	}
+	for _, job := range h.Observed {
+		line := fmt.Sprintf("- [%s](%s) on %s", job.Job, job.URL, job.Dashboard)
+		if job.FailureClass != "" {
+			line += fmt.Sprintf(" (%s)", job.FailureClass)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	sb.WriteString("\n## Resolved\n\n")
+	if len(h.Resolved) == 0 {
+		sb.WriteString("No failing-test issue has been closed during the shift.\n")
+	}
+	for _, issue := range h.Resolved {
+		sb.WriteString(fmt.Sprintf("- [%s](%s) %s, closed %s\n", issue.Reference, issue.URL, issue.Title, issue.ClosedAt.Format(cycleDateLayout)))
+	}
+	return sb.String()
+}
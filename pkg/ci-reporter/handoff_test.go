@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComposeHandoff(t *testing.T) {
+	repo := GithubRepository{Owner: "kubernetes", Repo: "kubernetes"}
+	now := time.Date(2021, 10, 20, 12, 0, 0, 0, time.UTC)
+	open := map[GithubRepository]GithubIssues{repo: {
+		{Number: 105242, Title: "[Failing test] gce-serial", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/105242", ProjectStatus: "CI Signal: In Progress", Assignees: []Assignee{{Login: "alice"}}},
+		{Number: 105300, Title: "[Flaky test] kind-parallel", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/105300"},
+		// requested as failing-test and flake
+		{Number: 105242, Title: "[Failing test] gce-serial", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/105242", ProjectStatus: "CI Signal: In Progress", Assignees: []Assignee{{Login: "alice"}}},
+		{Number: 105301, Title: "Fix flake", HTMLURL: "https://github.com/kubernetes/kubernetes/pull/105301"},
+	}}
+	closed := map[GithubRepository]GithubIssues{repo: {
+		{Number: 105100, Title: "[Failing test] gce-default", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/105100", ClosedAt: "2021-10-19T08:00:00Z"},
+		// closed before the shift, updated during the shift
+		{Number: 104000, Title: "[Failing test] old", HTMLURL: "https://github.com/kubernetes/kubernetes/issues/104000", ClosedAt: "2021-10-01T08:00:00Z"},
+	}}
+	testgridData := ReportData{Name: testgridReport, Data: []ReportDataField{
+		{Title: "master-blocking", Emoji: masterBlockingEmoji, Records: []ReportDataRecord{
+			{ID: testgridReportSummary},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: "FAILING", URL: "https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-serial", FailureClass: "product regression"},
+			{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FLAKY"},
+		}},
+		{Title: "master-informing", Emoji: masterInformingEmoji, Records: []ReportDataRecord{
+			{ID: testgridReportDetails, Title: "gce-cos-master-alpha", Status: "FAILING"},
+		}},
+		// blocking dashboards are found by their name, not by the emoji
+		{Title: "1.22-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportDetails, Title: "gce-cos-1.22-serial", Status: "FAILING", URL: "https://testgrid.k8s.io/sig-release-1.22-blocking#gce-cos-1.22-serial"},
+		}},
+	}}
+
+	handoff := composeHandoff([]GithubRepository{repo}, open, closed, testgridData, now.Add(-DefaultHandoffSince), now)
+	expected := `# CI signal handoff 2021-10-20
+
+Shift from 2021-10-13 to 2021-10-20.
+
+## Being investigated
+
+### CI Signal: In Progress
+
+- [#105242](https://github.com/kubernetes/kubernetes/issues/105242) [Failing test] gce-serial (assigned to alice)
+
+### No project status
+
+- [#105300](https://github.com/kubernetes/kubernetes/issues/105300) [Flaky test] kind-parallel
+
+## Failing blocking jobs
+
+- [gce-cos-master-serial](https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-serial) on master-blocking (product regression)
+- [gce-cos-1.22-serial](https://testgrid.k8s.io/sig-release-1.22-blocking#gce-cos-1.22-serial) on 1.22-Blocking
+
+## Resolved
+
+- [#105100](https://github.com/kubernetes/kubernetes/issues/105100) [Failing test] gce-default, closed 2021-10-19
+`
+	if handoff.String() != expected {
+		t.Errorf("expected handoff\n%s\ngot\n%s", expected, handoff)
+	}
+}
+
+func TestRequestHandoffUsesGraphQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/graphql":
+			fmt.Fprint(w, `{"data": {"repository": {"issues": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"number": 105242, "title": "[Failing test] gce-serial", "url": "https://github.com/kubernetes/kubernetes/issues/105242", "state": "OPEN",
+				 "labels": {"nodes": [{"name": "kind/failing-test"}]},
+				 "projectItems": {"nodes": [{"project": {"title": "CI Signal"}, "fieldValueByName": {"name": "In Progress"}}]}}
+			]}}}}`)
+		case strings.HasPrefix(r.URL.Path, "/repos/"):
+			fmt.Fprint(w, `[]`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+	meta := newTestMeta(metaFlags{GithubAPI: githubAPIRest, TestgridURL: server.URL})
+	meta.HTTPClient = &http.Client{Transport: serverTransport{server: server}}
+
+	// the project board columns are only known to the graphql api
+	if _, err := RequestHandoff(meta, DefaultHandoffSince, time.Now()); err == nil || !strings.Contains(err.Error(), "graphql") {
+		t.Errorf("expected an error that the graphql api needs github credentials, got %v", err)
+	}
+
+	meta.Env.GithubToken = "token"
+	handoff, err := RequestHandoff(meta, DefaultHandoffSince, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handoff.Investigated) != 1 || handoff.Investigated[0].Name != "CI Signal: In Progress" {
+		t.Errorf("expected the issue in its project board column with -github-api rest, got %+v", handoff.Investigated)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultCacheDir directory in the user cache directory http responses are cached in if -cache-dir is not set
+const defaultCacheDir = "ci-signal-report"
+
+// DefaultCacheDir returns the directory http responses are cached in by default ("" if the user has no cache directory)
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, defaultCacheDir)
+}
+
+// NewCachingTransport returns a transport that stores GET responses with an ETag or Last-Modified header in dir.
+// Following requests of the same url are sent as conditional requests (If-None-Match, If-Modified-Since)
+// and answered with the cached response if the server responds 304 Not Modified, github does not count these
+// requests against the rate limit. Responses are cached per identity (see GithubAuth.Identity), so tokens that are
+// rotated or refreshed keep using the cached responses. The cache only saves quota, responses that can not be
+// cached are logged and returned as they are
+func NewCachingTransport(logger *Logger, dir, identity string, next http.RoundTripper) http.RoundTripper {
+	return &cachingTransport{logger: logger, dir: dir, identity: identity, next: next}
+}
+
+type cachingTransport struct {
+	logger   *Logger
+	dir      string
+	identity string
+	next     http.RoundTripper
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+	path := t.cachePath(req)
+	cached, ok := readCachedResponse(path)
+	if ok && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && ok {
+		resp.Body.Close()
+		header := cached.Header.Clone()
+		// rate limit headers of the 304 response are more recent than the cached ones
+		for k, v := range resp.Header {
+			if strings.HasPrefix(k, "X-Ratelimit-") {
+				header[k] = v
+			}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+			StatusCode:    cached.StatusCode,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK || (resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	entry, err := json.Marshal(httpFixture{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+	})
+	if err == nil {
+		err = os.MkdirAll(t.dir, 0o700)
+	}
+	if err == nil {
+		err = writeFileAtomic(path, entry)
+	}
+	if err != nil {
+		t.logger.Warn("Could not cache response", "url", req.URL.String(), "error", err)
+	}
+	return resp, nil
+}
+
+// cachePath returns the file a response is cached in, responses of different identities are cached separately
+func (t *cachingTransport) cachePath(req *http.Request) string {
+	key := sha256.Sum256([]byte(req.URL.String() + "\n" + t.identity))
+	return filepath.Join(t.dir, fmt.Sprintf("%x.json", key))
+}
+
+// readCachedResponse reads a cached response, ok is false if there is none or it can not be read
+func readCachedResponse(path string) (cached httpFixture, ok bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cached, false
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return cached, false
+	}
+	return cached, true
+}
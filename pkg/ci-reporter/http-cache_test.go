@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCachingTransport(t *testing.T) {
+	requests := 0
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Remaining", "5000")
+		w.Write([]byte(`{"summary": "ok"}`))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewCachingTransport(discardLogger, t.TempDir(), "token-file:/secrets/token", http.DefaultTransport)}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL + "/api/summary")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `{"summary": "ok"}` {
+			t.Errorf("request %d: expected the cached body, got %d %s", i, resp.StatusCode, body)
+		}
+		if i > 0 && resp.Header.Get("X-RateLimit-Remaining") != "4999" {
+			t.Errorf("request %d: expected the rate limit of the 304 response, got %s", i, resp.Header.Get("X-RateLimit-Remaining"))
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("expected 3 requests of which 2 are conditional, got %d requests, %d not modified", requests, notModified)
+	}
+
+	// responses are reused for rotated tokens of the same identity
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/summary", nil)
+	req.Header.Set("Authorization", "token rotated")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if notModified != 3 {
+		t.Errorf("expected a conditional request for a rotated token")
+	}
+}
+
+func TestCachingTransportSeparatesIdentities(t *testing.T) {
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"summary": "ok"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for _, identity := range []string{GithubAuth{AppID: 1, InstallationID: 2}.Identity(), GithubAuth{Token: "other"}.Identity()} {
+		client := &http.Client{Transport: NewCachingTransport(discardLogger, dir, identity, http.DefaultTransport)}
+		resp, err := client.Get(server.URL + "/api/summary")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if notModified != 0 {
+		t.Errorf("expected responses of other identities not to be reused")
+	}
+}
+
+func TestCachingTransportUnwritableDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"summary": "ok"}`))
+	}))
+	defer server.Close()
+
+	// the cache directory can not be created below a file
+	file := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	client := &http.Client{Transport: NewCachingTransport(NewLogger(&logs, LogLevelWarn), filepath.Join(file, "cache"), "gh", http.DefaultTransport)}
+	resp, err := client.Get(server.URL + "/api/summary")
+	if err != nil {
+		t.Fatalf("expected the response without error if it can not be cached, got %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"summary": "ok"}` {
+		t.Errorf("expected the response body, got %s", body)
+	}
+	if !strings.Contains(logs.String(), `msg="Could not cache response"`) {
+		t.Errorf("expected the cache error to be logged, got %q", logs.String())
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Query parameters that depend on the time of the request and are ignored to match recorded responses
+var volatileQueryParams = map[string]bool{
+	string(IssueReqParamSince): true,
+}
+
+// volatileSearchQualifierRegex qualifiers of github search queries that change with every run, they are ignored like volatileQueryParams
+var volatileSearchQualifierRegex = regexp.MustCompile(`\s*updated:>=\S+`)
+
+var fixtureNameRegex = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// httpFixture a recorded http response
+type httpFixture struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// NewRecordingTransport returns a transport that forwards requests to next and stores every response in dir
+func NewRecordingTransport(dir string, next http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{dir: dir, next: next}
+}
+
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := fixturePath(t.dir, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	fixture, err := json.MarshalIndent(httpFixture{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return resp, writeFileAtomic(path, fixture)
+}
+
+// NewReplayTransport returns a transport that answers requests with responses recorded by NewRecordingTransport in dir
+func NewReplayTransport(dir string) http.RoundTripper {
+	return &replayTransport{dir: dir}
+}
+
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, err := fixturePath(t.dir, req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s %s: %v", req.Method, req.URL, err)
+	}
+	var fixture httpFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("could not parse recorded response %s: %v", path, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.StatusCode, http.StatusText(fixture.StatusCode)),
+		StatusCode:    fixture.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        fixture.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(fixture.Body)),
+		ContentLength: int64(len(fixture.Body)),
+		Request:       req,
+	}, nil
+}
+
+// fixturePath returns a readable, stable file name for a request.
+// Query parameters are sorted and volatile parameters dropped, request bodies (graphql) are hashed.
+func fixturePath(dir string, req *http.Request) (string, error) {
+	query := req.URL.Query()
+	keys := []string{}
+	for k := range query {
+		if !volatileQueryParams[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	parts := []string{strings.ToLower(req.Method), req.URL.Host, req.URL.Path}
+	for _, k := range keys {
+		values := query[k]
+		if k == "q" {
+			values = []string{}
+			for _, v := range query[k] {
+				values = append(values, volatileSearchQualifierRegex.ReplaceAllString(v, ""))
+			}
+		}
+		parts = append(parts, k, strings.Join(values, ","))
+	}
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%x", sha256.Sum256(content))[:12])
+	}
+	name := strings.Trim(fixtureNameRegex.ReplaceAllString(strings.Join(parts, "_"), "_"), "_")
+	if len(name) > 200 {
+		name = name[:187] + fmt.Sprintf("_%x", sha256.Sum256([]byte(name)))[:13]
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// httpClientOrDefault returns the default http client if no client has been configured
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `<next>; rel="next"`)
+		fmt.Fprintf(w, "response for %s", r.URL.Query().Get("page"))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	recorder := &http.Client{Transport: NewRecordingTransport(dir, http.DefaultTransport)}
+	resp, err := recorder.Get(server.URL + "/issues?page=1&since=2021-1-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "response for 1" {
+		t.Fatalf("expected the recorder to pass the response through, got %q", body)
+	}
+	server.Close()
+
+	// the volatile since parameter does not matter for replaying, the page parameter does
+	replayer := &http.Client{Transport: NewReplayTransport(dir)}
+	resp, err = replayer.Get(server.URL + "/issues?since=2021-6-6&page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "response for 1" || resp.StatusCode != http.StatusOK {
+		t.Errorf("expected recorded response, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Link") != `<next>; rel="next"` {
+		t.Errorf("expected recorded headers, got %v", resp.Header)
+	}
+	if _, err := replayer.Get(server.URL + "/issues?page=2"); err == nil {
+		t.Error("expected an error for a request that has not been recorded")
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v34/github"
+)
+
+// issueCommentMarker hidden html comment that tags comments created by the ci-reporter, the tagged comment gets updated instead of creating a new one
+const issueCommentMarker = "<!-- ci-signal-report -->"
+
+var (
+	issueReferenceRegex = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+	colorCodeRegex      = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// IssueReference a github issue like kubernetes/sig-release#1234
+type IssueReference struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (i IssueReference) String() string {
+	return fmt.Sprintf("%s/%s#%d", i.Owner, i.Repo, i.Number)
+}
+
+// ParseIssueReference parses an issue reference in the format owner/repo#1234
+func ParseIssueReference(input string) (IssueReference, error) {
+	match := issueReferenceRegex.FindStringSubmatch(strings.TrimSpace(input))
+	if match == nil {
+		return IssueReference{}, fmt.Errorf("%q does not match owner/repo#number", input)
+	}
+	number, err := strconv.Atoi(match[3])
+	if err != nil {
+		return IssueReference{}, err
+	}
+	return IssueReference{Owner: match[1], Repo: match[2], Number: number}, nil
+}
+
+// IssueCommentNotifier posts the report in markdown format as comment on a github issue, a previous report comment gets updated
+type IssueCommentNotifier struct {
+	Issue IssueReference
+}
+
+// Notify extends IssueCommentNotifier and creates or updates the report comment on the issue
+func (n IssueCommentNotifier) Notify(meta Meta, report Report) error {
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	ctx := context.Background()
+	body := issueCommentMarker + "\n" + meta.Flags.MentionPolicy.neutralizeGithubMentions(MarkdownReport(meta, report))
+	comment, err := findReportComment(ctx, meta.GitHubClient, n.Issue)
+	if err != nil {
+		return err
+	}
+	if comment != nil {
+		_, _, err = meta.GitHubClient.Issues.EditComment(ctx, n.Issue.Owner, n.Issue.Repo, comment.GetID(), &github.IssueComment{Body: &body})
+	} else {
+		_, _, err = meta.GitHubClient.Issues.CreateComment(ctx, n.Issue.Owner, n.Issue.Repo, n.Issue.Number, &github.IssueComment{Body: &body})
+	}
+	if err != nil {
+		return fmt.Errorf("posting report comment on %s: %v", n.Issue, err)
+	}
+	return nil
+}
+
+// findReportComment returns the last comment of the issue that has been tagged with the issueCommentMarker by the authenticated user
+// (nil if there is none), tagged comments of others (like a pasted copy of the report) are not edited
+func findReportComment(ctx context.Context, client *github.Client, issue IssueReference) (*github.IssueComment, error) {
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("requesting the authenticated user: %v", err)
+	}
+	var found *github.IssueComment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := client.Issues.ListComments(ctx, issue.Owner, issue.Repo, issue.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing comments of %s: %v", issue, err)
+		}
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.GetBody(), issueCommentMarker) && comment.GetUser().GetLogin() == user.GetLogin() {
+				found = comment
+			}
+		}
+		if resp.NextPage == 0 {
+			return found, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// MarkdownReport renders the report in markdown format
+func MarkdownReport(meta Meta, report Report) string {
+	var sb strings.Builder
+	for _, reportData := range report {
+		sb.WriteString(fmt.Sprintf("## %s report\n", strings.ToUpper(reportData.Name)))
+		if reportData.Name == githubReport {
+			sb.WriteString("\n")
+			if milestone := meta.Flags.milestone(); milestone != "" {
+				writeMarkdownMilestoneSections(&sb, meta, reportData, milestone)
+				writeMarkdownWorkload(&sb, reportData)
+				continue
+			}
+		}
+		for _, field := range reportData.Data {
+			// github fields are titled with the repository of the issue, it is part of the record title instead
+			if field.Title != "" && reportData.Name != githubReport {
+				if meta.Flags.EmojisOff || field.Emoji == "" {
+					sb.WriteString(fmt.Sprintf("\n### %s\n\n", field.Title))
+				} else {
+					sb.WriteString(fmt.Sprintf("\n### %s %s\n\n", field.Emoji, field.Title))
+				}
+			} else if reportData.Name != githubReport {
+				sb.WriteString("\n")
+			}
+			for _, record := range field.Records {
+				writeMarkdownRecord(&sb, meta, reportData.Name, field.Title, record)
+			}
+		}
+		if reportData.Name == githubReport {
+			sb.WriteString("\n")
+			writeMarkdownWorkload(&sb, reportData)
+			continue
+		}
+		sb.WriteString("\n")
+	}
+	writeMarkdownKnownIssues(&sb, meta, report)
+	return sb.String()
+}
+
+// writeMarkdownKnownIssues renders the acknowledged records of all reports in one line each
+func writeMarkdownKnownIssues(sb *strings.Builder, meta Meta, report Report) {
+	count := report.knownIssuesCount()
+	if count == 0 {
+		return
+	}
+	if meta.Flags.EmojisOff {
+		sb.WriteString(fmt.Sprintf("## KNOWN ISSUES (%d)\n\n", count))
+	} else {
+		sb.WriteString(fmt.Sprintf("## %s KNOWN ISSUES (%d)\n\n", knownIssuesEmoji, count))
+	}
+	for _, reportData := range report {
+		for _, k := range reportData.KnownIssues {
+			sb.WriteString(fmt.Sprintf("- %s\n", knownIssueLine(reportData.Name, k)))
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// writeMarkdownMilestoneSections renders github issues in the milestone and issues not yet triaged into the milestone
+func writeMarkdownMilestoneSections(sb *strings.Builder, meta Meta, reportData ReportData, milestone string) {
+	for _, section := range milestoneSections(reportData, milestone) {
+		title := section.Title
+		if section.ActionItems {
+			title += " (action items)"
+		}
+		sb.WriteString(fmt.Sprintf("### %s (%d)\n\n", title, section.count()))
+		for _, field := range section.Fields {
+			for _, record := range field.Records {
+				writeMarkdownRecord(sb, meta, reportData.Name, field.Title, record)
+			}
+		}
+		sb.WriteString("\n")
+	}
+}
+
+// writeMarkdownWorkload renders the number of github issues per assignee
+func writeMarkdownWorkload(sb *strings.Builder, reportData ReportData) {
+	lines := workloadLines(reportData)
+	if len(lines) == 0 {
+		return
+	}
+	sb.WriteString("### Workload\n\n")
+	for _, line := range lines {
+		sb.WriteString(fmt.Sprintf("- %s\n", line))
+	}
+	sb.WriteString("\n")
+}
+
+func writeMarkdownRecord(sb *strings.Builder, meta Meta, reportName string, fieldTitle string, record ReportDataRecord) {
+	if isSummaryRecord(reportName, record) {
+		for _, note := range record.Notes {
+			sb.WriteString(fmt.Sprintf("- %s\n", stripColors(note)))
+		}
+		sb.WriteString("\n")
+		return
+	}
+	title := record.Title
+	if reportName == githubReport {
+		title = fmt.Sprintf("%s#%d %s", fieldTitle, record.ID, record.Title)
+	} else if reportName == prSignalReport && fieldTitle != failingJobsFixesTitle {
+		title = fmt.Sprintf("#%d %s", record.ID, record.Title)
+	}
+	if record.URL != "" {
+		title = fmt.Sprintf("[%s](%s)", title, record.URL)
+	}
+	prefix := ""
+	if record.Status != "" {
+		prefix = fmt.Sprintf("**%s** ", record.Status)
+	}
+	if !meta.Flags.EmojisOff && record.Highlight != "" {
+		prefix += record.Highlight + " "
+	}
+	sb.WriteString(fmt.Sprintf("- %s%s\n", prefix, title))
+	for _, note := range record.Notes {
+		sb.WriteString(fmt.Sprintf("  - %s\n", strings.TrimSpace(stripColors(note))))
+	}
+}
+
+// stripColors removes terminal color codes that are part of console notes
+func stripColors(s string) string {
+	return colorCodeRegex.ReplaceAllString(s, "")
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v34/github"
+)
+
+func TestParseIssueReference(t *testing.T) {
+	ref, err := ParseIssueReference("kubernetes/sig-release#1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != (IssueReference{Owner: "kubernetes", Repo: "sig-release", Number: 1234}) {
+		t.Errorf("unexpected issue reference %+v", ref)
+	}
+	for _, input := range []string{"kubernetes/sig-release", "sig-release#1234", "kubernetes/sig-release#abc"} {
+		if _, err := ParseIssueReference(input); err == nil {
+			t.Errorf("expected %q to be invalid", input)
+		}
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	report := testSubscriptionReport()
+	report[1].Data[0].Records[0].URL = "https://github.com/kubernetes/kubernetes/issues/1"
+	report[1].Data[0].Records[0].Notes = []string{colorRed + "kind/failing-test" + colorReset + " "}
+	markdown := MarkdownReport(Meta{}, report)
+	for _, expected := range []string{
+		"## TESTGRID report",
+		"### Master-Blocking",
+		"- 2 jobs total",
+		"- **FAILING** gce-serial",
+		"- [#1 node issue](https://github.com/kubernetes/kubernetes/issues/1)\n  - kind/failing-test\n",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected markdown to contain %q, got\n%s", expected, markdown)
+		}
+	}
+}
+
+func TestIssueCommentNotifier(t *testing.T) {
+	tests := []struct {
+		name           string
+		comments       []*github.IssueComment
+		expectedMethod string
+		expectedPath   string
+	}{
+		{
+			name:           "create comment",
+			comments:       []*github.IssueComment{{ID: github.Int64(1), Body: github.String("lgtm")}},
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/repos/kubernetes/sig-release/issues/1234/comments",
+		},
+		{
+			name:           "update tagged comment",
+			comments:       []*github.IssueComment{{ID: github.Int64(2), Body: github.String(issueCommentMarker + "\nold report"), User: &github.User{Login: github.String("ci-signal-bot")}}},
+			expectedMethod: http.MethodPatch,
+			expectedPath:   "/repos/kubernetes/sig-release/issues/comments/2",
+		},
+		{
+			name:           "tagged comment of another user",
+			comments:       []*github.IssueComment{{ID: github.Int64(3), Body: github.String(issueCommentMarker + "\npasted report"), User: &github.User{Login: github.String("alice")}}},
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/repos/kubernetes/sig-release/issues/1234/comments",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var method, path, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == "/user" {
+					_ = json.NewEncoder(w).Encode(github.User{Login: github.String("ci-signal-bot")})
+					return
+				}
+				if r.Method == http.MethodGet {
+					_ = json.NewEncoder(w).Encode(tc.comments)
+					return
+				}
+				var comment github.IssueComment
+				if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+					t.Error(err)
+				}
+				method, path, body = r.Method, r.URL.Path, comment.GetBody()
+				_ = json.NewEncoder(w).Encode(comment)
+			}))
+			defer server.Close()
+			client := github.NewClient(server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			n := IssueCommentNotifier{Issue: IssueReference{Owner: "kubernetes", Repo: "sig-release", Number: 1234}}
+			if err := n.Notify(Meta{GitHubClient: client}, testSubscriptionReport()); err != nil {
+				t.Fatal(err)
+			}
+			if method != tc.expectedMethod || path != tc.expectedPath {
+				t.Errorf("expected %s %s, got %s %s", tc.expectedMethod, tc.expectedPath, method, path)
+			}
+			if !strings.HasPrefix(body, issueCommentMarker) {
+				t.Errorf("expected comment to be tagged, got %q", body)
+			}
+		})
+	}
+}
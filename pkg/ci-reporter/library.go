@@ -0,0 +1,399 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Reporter generates the report for other binaries that embed the ci-reporter, it does not read flags or
+// environment variables, does not print anything and returns errors instead of exiting
+type Reporter struct {
+	meta              Meta
+	githubToken       string
+	githubTokenSource oauth2.TokenSource
+	sinks             []Sink
+}
+
+// Option configures a Reporter (see New)
+type Option func(*Reporter) error
+
+// New returns a Reporter, without options it reports the github issues of kubernetes/kubernetes and the
+// testgrid dashboards sig-release-master-blocking and sig-release-master-informing like the ci-reporter binary
+func New(opts ...Option) (*Reporter, error) {
+	dependencyHints, err := LoadDependencyHints("")
+	if err != nil {
+		return nil, err
+	}
+	runbooks, err := LoadRunbooks("")
+	if err != nil {
+		return nil, err
+	}
+	r := &Reporter{meta: Meta{
+		Flags: metaFlags{
+			Severity:        DefaultSeverityConfig(),
+			Concurrency:     defaultConcurrency,
+			DependencyHints: dependencyHints,
+			Runbooks:        runbooks,
+			ReadOnly:        true,
+		},
+		HTTPClient: &http.Client{},
+		Logger:     discardLogger,
+	}}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// WithGithubToken sets the token used to request github issues
+func WithGithubToken(token string) Option {
+	return func(r *Reporter) error {
+		r.githubToken = token
+		return nil
+	}
+}
+
+// WithGithubAuth authenticates requests to github with the credentials (like a github app, see NewGithubTokenSource),
+// expiring tokens are refreshed while the reporter is used
+func WithGithubAuth(auth GithubAuth) Option {
+	return func(r *Reporter) error {
+		source, err := NewGithubTokenSource(auth, nil)
+		if err != nil {
+			return err
+		}
+		r.githubTokenSource = source
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http client all requests are sent with
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *Reporter) error {
+		if client == nil {
+			return fmt.Errorf("http client is nil")
+		}
+		r.meta.HTTPClient = client
+		return nil
+	}
+}
+
+// WithLogger sets the logger warnings and requests are logged with (like NewLogger(os.Stderr, LogLevelInfo)),
+// nothing is logged without it
+func WithLogger(logger *Logger) Option {
+	return func(r *Reporter) error {
+		if logger == nil {
+			return fmt.Errorf("logger is nil")
+		}
+		r.meta.Logger = logger
+		return nil
+	}
+}
+
+// WithReport only requests one report ('github', 'testgrid', 'provider', 'triage', 'platform', 'quarantine' or 'pr-signal')
+func WithReport(name string) Option {
+	return func(r *Reporter) error {
+		for _, n := range []string{githubReport, testgridReport, providerReport, triageReport, platformReport, quarantineReport, prSignalReport} {
+			if n == name {
+				r.meta.Flags.SpecificReport = name
+				return nil
+			}
+		}
+		return fmt.Errorf("report %q does not match options [%s, %s, %s, %s, %s, %s, %s]", name, githubReport, testgridReport, providerReport, triageReport, platformReport, quarantineReport, prSignalReport)
+	}
+}
+
+// WithShort shortens the report (less details per record)
+func WithShort() Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.ShortOn = true
+		return nil
+	}
+}
+
+// WithoutEmojis leaves emojis out of the report
+func WithoutEmojis() Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.EmojisOff = true
+		return nil
+	}
+}
+
+// WithSigs only reports the records attributed to these sigs (like "sig-node")
+func WithSigs(sigs ...string) Option {
+	return func(r *Reporter) error {
+		for _, sig := range sigs {
+			r.meta.Flags.Sigs = append(r.meta.Flags.Sigs, normalizeSig(sig))
+		}
+		return nil
+	}
+}
+
+// WithRepositories sets the github repositories issues are requested from
+func WithRepositories(repositories ...GithubRepository) Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.Repositories = repositories
+		return nil
+	}
+}
+
+// WithMilestone only reports github issues in the milestone (like "v1.22"), issues without milestone are reported as action items
+func WithMilestone(milestone string) Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.Milestone = strings.TrimSpace(milestone)
+		return nil
+	}
+}
+
+// WithOnlyUnassigned only reports github issues nobody is assigned to
+func WithOnlyUnassigned() Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.OnlyUnassigned = true
+		return nil
+	}
+}
+
+// WithTestgrid sets the testgrid instance and the dashboards that are reported
+func WithTestgrid(url string, dashboards ...string) Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.TestgridURL = strings.TrimSuffix(url, "/")
+		r.meta.Flags.Dashboards = dashboards
+		return nil
+	}
+}
+
+// WithProw sets the prow instance failed builds are linked to, builds of testgrid instances other than
+// https://testgrid.k8s.io are only linked if it is set
+func WithProw(url string) Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.ProwURL = strings.TrimSuffix(url, "/")
+		return nil
+	}
+}
+
+// WithConcurrency sets the maximum number of requests that are sent at the same time
+func WithConcurrency(n int) Option {
+	return func(r *Reporter) error {
+		if n < 1 {
+			return fmt.Errorf("concurrency has to be at least 1, got %d", n)
+		}
+		r.meta.Flags.Concurrency = n
+		return nil
+	}
+}
+
+// WithDeadlines sets a deadline per source (like {"testgrid": 30 * time.Second}), see ParseDeadlines
+func WithDeadlines(deadlines map[string]time.Duration) Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.Deadlines = deadlines
+		return nil
+	}
+}
+
+// WithFeatures turns subsystems on or off, features that are not set use their default
+func WithFeatures(gates FeatureGates) Option {
+	return func(r *Reporter) error {
+		for f := range gates {
+			if _, ok := knownFeatures[f]; !ok {
+				return fmt.Errorf("feature %q does not match options [%s]", f, featureUsage())
+			}
+		}
+		r.meta.Flags.Features = gates
+		return nil
+	}
+}
+
+// WithSeverity sets the thresholds, emojis and scorer used to rank testgrid jobs
+func WithSeverity(config SeverityConfig) Option {
+	return func(r *Reporter) error {
+		if err := config.validate(); err != nil {
+			return err
+		}
+		r.meta.Flags.Severity = config
+		return nil
+	}
+}
+
+// WithSinks adds sinks the report is written to by Publish
+func WithSinks(sinks ...Sink) Option {
+	return func(r *Reporter) error {
+		r.sinks = append(r.sinks, sinks...)
+		return nil
+	}
+}
+
+// WithReadOnly turns the read-only mode on or off. Like the ci-reporter binary the reporter is read-only by default,
+// Publish refuses to send the notifiers added with WithNotifier until the mode has been turned off with WithReadOnly(false)
+func WithReadOnly(readOnly bool) Option {
+	return func(r *Reporter) error {
+		r.meta.Flags.ReadOnly = readOnly
+		return nil
+	}
+}
+
+// WithNotifier sends the report with the notifier (like a SlackNotifier) when it is published, see WithReadOnly
+func WithNotifier(n Notifier) Option {
+	return func(r *Reporter) error {
+		r.sinks = append(r.sinks, notifierSink{meta: r.contextMeta, notifier: n})
+		return nil
+	}
+}
+
+// Generate requests the report. Requests are canceled if the context is done, if sources could not be requested
+// their errors are returned (see SourceErrors)
+func (r *Reporter) Generate(ctx context.Context) (*Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	report, _, err := r.contextMeta(ctx).RequestReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Publish writes the report to all sinks, the first error is returned after all sinks have been written to
+func (r *Reporter) Publish(ctx context.Context, report *Report) error {
+	return WriteSinks(ctx, *report, r.sinks...)
+}
+
+// contextMeta returns the configuration of the reporter, all requests are sent with the context
+func (r *Reporter) contextMeta(ctx context.Context) Meta {
+	meta := r.meta
+	next := NewLoggingTransport(meta.logger(), r.meta.HTTPClient.Transport)
+	if r.githubTokenSource != nil {
+		next = NewGithubAuthTransport(r.githubTokenSource, next)
+	}
+	meta.HTTPClient = &http.Client{Transport: contextTransport{ctx: ctx, next: next}, Timeout: r.meta.HTTPClient.Timeout}
+	meta.GitHubClient = newGithubClient(meta.HTTPClient, r.githubToken)
+	meta.Env.GithubToken = r.githubToken
+	meta.githubTokenSource = r.githubTokenSource
+	meta.DataPostProcessing = newDataPostProcessing(meta.Flags)
+	return meta
+}
+
+// contextTransport sends all requests with the context, so they are canceled if the context is done
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req.WithContext(t.ctx))
+}
+
+// Renderer renders the report in a format like markdown or json
+type Renderer interface {
+	Render(w io.Writer, report Report) error
+}
+
+// MarkdownRenderer renders the report in markdown format (see MarkdownReport)
+type MarkdownRenderer struct {
+	EmojisOff bool
+}
+
+// Render extends MarkdownRenderer
+func (m MarkdownRenderer) Render(w io.Writer, report Report) error {
+	_, err := io.WriteString(w, MarkdownReport(Meta{Flags: metaFlags{EmojisOff: m.EmojisOff}}, report))
+	return err
+}
+
+// HTMLRenderer renders the report in html format (see HTMLReport)
+type HTMLRenderer struct {
+	EmojisOff bool
+}
+
+// Render extends HTMLRenderer
+func (h HTMLRenderer) Render(w io.Writer, report Report) error {
+	_, err := io.WriteString(w, HTMLReport(Meta{Flags: metaFlags{EmojisOff: h.EmojisOff}}, report))
+	return err
+}
+
+// JSONRenderer renders the report in the versioned output format (see package schema)
+type JSONRenderer struct{}
+
+// Render extends JSONRenderer
+func (JSONRenderer) Render(w io.Writer, report Report) error {
+	data, err := json.MarshalIndent(report.Output(time.Now()), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// TemplateRenderer renders the report with a go template (see LoadPayloadTemplate)
+type TemplateRenderer struct {
+	Template  *template.Template
+	EmojisOff bool
+}
+
+// Render extends TemplateRenderer
+func (t TemplateRenderer) Render(w io.Writer, report Report) error {
+	rendered, err := RenderReportTemplate(Meta{Flags: metaFlags{EmojisOff: t.EmojisOff}}, t.Template, report)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, rendered)
+	return err
+}
+
+// Sink receives the generated report (like a file, a writer or a notifier)
+type Sink interface {
+	Write(ctx context.Context, report Report) error
+}
+
+// WriterSink renders the report to a writer
+type WriterSink struct {
+	Writer   io.Writer
+	Renderer Renderer
+}
+
+// Write extends WriterSink
+func (s WriterSink) Write(ctx context.Context, report Report) error {
+	return s.Renderer.Render(s.Writer, report)
+}
+
+// notifierSink sends the report with a notifier using the configuration of the reporter or the run
+type notifierSink struct {
+	meta     func(ctx context.Context) Meta
+	notifier Notifier
+}
+
+// Write sends the report, notifiers that are not part of this package are refused in read-only mode as well
+func (s notifierSink) Write(ctx context.Context, report Report) error {
+	meta := s.meta(ctx)
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	return s.notifier.Notify(meta, report)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestReporterGenerate(t *testing.T) {
+	var out bytes.Buffer
+	reporter, err := New(
+		WithHTTPClient(&http.Client{Transport: NewReplayTransport(testFixturesDir)}),
+		WithReport(testgridReport),
+		WithShort(),
+		WithSinks(WriterSink{Writer: &out, Renderer: MarkdownRenderer{EmojisOff: true}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := reporter.Generate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*report) != 1 || (*report)[0].Name != testgridReport || len((*report)[0].Data) == 0 {
+		t.Fatalf("expected the testgrid report, got %+v", report)
+	}
+	if err := reporter.Publish(context.Background(), report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "## TESTGRID report\n") {
+		t.Errorf("expected the markdown report to be written to the sink, got\n%s", out.String())
+	}
+}
+
+func TestReporterGenerateReturnsErrors(t *testing.T) {
+	reporter, err := New(WithHTTPClient(&http.Client{Transport: failingTransport{}}), WithReport(testgridReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reporter.Generate(context.Background()); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected the request error to be returned, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reporter, _ = New(WithHTTPClient(&http.Client{Transport: NewReplayTransport(testFixturesDir)}), WithReport(testgridReport))
+	if _, err := reporter.Generate(ctx); err == nil {
+		t.Error("expected an error if the context is canceled")
+	}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	if _, err := New(WithReport("jenkins")); err == nil {
+		t.Error("expected an error for an unknown report")
+	}
+	if _, err := New(WithConcurrency(0)); err == nil {
+		t.Error("expected an error for concurrency 0")
+	}
+}
+
+// githubFailingTransport fails requests to the github api and replays the testgrid fixtures
+type githubFailingTransport struct{}
+
+func (githubFailingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "api.github.com" {
+		return nil, errors.New("connection refused")
+	}
+	return NewReplayTransport(testFixturesDir).RoundTrip(req)
+}
+
+func TestReporterGenerateReturnsGithubErrors(t *testing.T) {
+	reporter, err := New(WithHTTPClient(&http.Client{Transport: githubFailingTransport{}}), WithShort())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the testgrid data is requested when the github requests fail, the report must not be returned without the error
+	for i := 0; i < 20; i++ {
+		report, err := reporter.Generate(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Fatalf("expected the github request error to be returned, got %v (%+v)", err, report)
+		}
+	}
+}
+
+// testgridFailingTransport fails requests to testgrid and replays the github fixtures
+type testgridFailingTransport struct{}
+
+func (testgridFailingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "testgrid.k8s.io" {
+		return nil, errors.New("connection refused")
+	}
+	return NewReplayTransport(testFixturesDir).RoundTrip(req)
+}
+
+func TestReporterWritesNothingToStderr(t *testing.T) {
+	stderr := os.Stderr
+	defer func() { os.Stderr = stderr }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+
+	// the dashboards can not be requested to cluster the issues, which is logged as warning
+	reporter, err := New(WithHTTPClient(&http.Client{Transport: testgridFailingTransport{}}), WithReport(githubReport), WithShort(), WithFeatures(FeatureGates{FeatureIssueClustering: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reporter.Generate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	written, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) > 0 {
+		t.Errorf("expected the reporter to write nothing to stderr, got\n%s", written)
+	}
+}
+
+func TestReporterWithLogger(t *testing.T) {
+	var logs bytes.Buffer
+	reporter, err := New(
+		WithHTTPClient(&http.Client{Transport: testgridFailingTransport{}}),
+		WithReport(githubReport),
+		WithShort(),
+		WithFeatures(FeatureGates{FeatureIssueClustering: true}),
+		WithLogger(NewLogger(&logs, LogLevelDebug)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reporter.Generate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`level=warn msg="Could not request the jobs of the dashboards`, `level=debug msg="http request" method=GET url="https://api.github.com/search/issues?`} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("expected %q to be logged with the logger of the reporter, got\n%s", expected, logs.String())
+		}
+	}
+	if _, err := New(WithLogger(nil)); err == nil {
+		t.Error("expected an error for a nil logger")
+	}
+}
+
+// recordingNotifier counts the reports it has been sent
+type recordingNotifier struct {
+	sent *int
+}
+
+func (n recordingNotifier) Notify(meta Meta, report Report) error {
+	*n.sent++
+	return nil
+}
+
+func TestReporterReadOnly(t *testing.T) {
+	sent := 0
+	reporter, err := New(WithNotifier(recordingNotifier{sent: &sent}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reporter.Publish(context.Background(), &Report{}); err != errReadOnly || sent != 0 {
+		t.Errorf("expected notifiers to be refused by default, got %v (%d sent)", err, sent)
+	}
+
+	reporter, err = New(WithNotifier(recordingNotifier{sent: &sent}), WithReadOnly(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reporter.Publish(context.Background(), &Report{}); err != nil || sent != 1 {
+		t.Errorf("expected the notifier to be sent with WithReadOnly(false), got %v (%d sent)", err, sent)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel verbosity of the log, logs are written to stderr so they never mix with the report on stdout
+type LogLevel int
+
+// Log levels, a logger writes the entries of its level and all levels above
+const (
+	LogLevelError LogLevel = iota
+	LogLevelWarn
+	LogLevelInfo
+	LogLevelDebug
+)
+
+var logLevelNames = []string{"error", "warn", "info", "debug"}
+
+func (l LogLevel) String() string {
+	if l < LogLevelError || l > LogLevelDebug {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return logLevelNames[l]
+}
+
+// ParseLogLevel parses a level like "debug" or "warn"
+func ParseLogLevel(level string) (LogLevel, error) {
+	for i, name := range logLevelNames {
+		if strings.EqualFold(strings.TrimSpace(level), name) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogLevelInfo, fmt.Errorf("log level %q does not match options [%s]", level, strings.Join(logLevelNames, ", "))
+}
+
+// Logger writes structured log entries in logfmt format like
+// `time=2021-11-04T10:00:00Z level=debug msg="http request" method=GET url=https://testgrid.k8s.io/... status=200 duration=120ms`
+type Logger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level LogLevel
+	now   func() time.Time
+}
+
+// NewLogger returns a logger that writes the entries of level and above to w
+func NewLogger(w io.Writer, level LogLevel) *Logger {
+	return &Logger{w: w, level: level, now: time.Now}
+}
+
+// discardLogger is used if no logger has been set, so the package does not write anything on its own (like when it is embedded, see New)
+var discardLogger = NewLogger(ioutil.Discard, LogLevelError)
+
+// fatalLogger logs the errors of Fatalf, it only writes errors so it does not depend on -log-level
+var fatalLogger = NewLogger(os.Stderr, LogLevelError)
+
+// Enabled tells if entries of the level are written
+func (l *Logger) Enabled(level LogLevel) bool {
+	return level <= l.level
+}
+
+// Debug writes a debug entry, keyvals are pairs of keys and values like "url", url, "status", 200
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LogLevelDebug, msg, keyvals)
+}
+
+// Info writes an info entry
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LogLevelInfo, msg, keyvals)
+}
+
+// Warn writes a warning entry
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LogLevelWarn, msg, keyvals)
+}
+
+// Error writes an error entry
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LogLevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level LogLevel, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("time=%s level=%s msg=%s", l.now().UTC().Format(time.RFC3339), level, logfmtValue(msg)))
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		sb.WriteString(fmt.Sprintf(" %v=%s", keyvals[i], logfmtValue(value)))
+	}
+	sb.WriteString("\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// logging is best effort, write errors of stderr are ignored
+	_, _ = io.WriteString(l.w, sb.String())
+}
+
+// logfmtValue quotes values that contain spaces, quotes, equal signs or line breaks
+func logfmtValue(v interface{}) string {
+	var s string
+	switch value := v.(type) {
+	case time.Duration:
+		s = value.Round(time.Millisecond).String()
+	case error:
+		s = value.Error()
+	default:
+		s = fmt.Sprint(value)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\n\t") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// Fatalf logs the message as error and exits. Messages like "Error requesting testgrid data.\n[ERROR] EOF"
+// are logged as msg="Error requesting testgrid data" error=EOF
+func Fatalf(format string, v ...interface{}) {
+	fatalLogger.fatal(fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) fatal(message string) {
+	if parts := strings.SplitN(message, ".\n[ERROR] ", 2); len(parts) == 2 {
+		l.Error(parts[0], "error", parts[1])
+	} else {
+		l.Error(message)
+	}
+	os.Exit(1)
+}
+
+// logger returns the logger set via -log-level or WithLogger, nothing is logged if no logger has been set
+func (m Meta) logger() *Logger {
+	if m.Logger != nil {
+		return m.Logger
+	}
+	return discardLogger
+}
+
+// NewLoggingTransport returns a transport that logs each request with its url, duration, status and the
+// rate limit state of the github api. Requests are logged at debug level, a github rate limit that is almost used up is logged as warning
+func NewLoggingTransport(logger *Logger, next http.RoundTripper) http.RoundTripper {
+	return loggingTransport{logger: logger, next: next}
+}
+
+type loggingTransport struct {
+	logger *Logger
+	next   http.RoundTripper
+}
+
+// rateLimitWarning share of the github rate limit that is left when a warning is logged
+const rateLimitWarning = 0.1
+
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	duration := time.Since(start)
+	if err != nil {
+		t.logger.Debug("http request failed", "method", req.Method, "url", req.URL.Redacted(), "duration", duration, "error", err)
+		return nil, err
+	}
+	keyvals := []interface{}{"method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "duration", duration}
+	remaining, limit := resp.Header.Get("X-Ratelimit-Remaining"), resp.Header.Get("X-Ratelimit-Limit")
+	if remaining != "" {
+		keyvals = append(keyvals, "ratelimit_remaining", remaining, "ratelimit_limit", limit)
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			keyvals = append(keyvals, "ratelimit_reset", time.Unix(reset, 0).UTC().Format(time.RFC3339))
+		}
+	}
+	t.logger.Debug("http request", keyvals...)
+	if left, err := strconv.Atoi(remaining); err == nil {
+		if max, err := strconv.Atoi(limit); err == nil && max > 0 && float64(left) < float64(max)*rateLimitWarning {
+			t.logger.Warn("github rate limit is almost used up", "ratelimit_remaining", left, "ratelimit_limit", max)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// MentionPolicy github handles and slack groups automation is permitted to mention or assign (set via -mention-policy).
+// Entries are github users ("@alice"), github teams ("@kubernetes/sig-node-leads"), all teams of an org ("@kubernetes/*"),
+// slack users ("<@U0123>") and slack groups ("<!subteam^S0123>"). If Allow is set only listed handles are permitted,
+// handles listed in Deny are never permitted
+type MentionPolicy struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+var (
+	// githubMentionRegex @handles in text posted to github, email addresses and handles in code spans are not matched
+	githubMentionRegex = regexp.MustCompile("(^|[^\\w@/.`])@([A-Za-z0-9][A-Za-z0-9-]*(?:/[A-Za-z0-9][A-Za-z0-9_.-]*)?)")
+	// slackMentionRegex user, group and broadcast mentions in text posted to slack (like "<!subteam^S0123|@sig-node-leads>")
+	slackMentionRegex = regexp.MustCompile(`<(@[UW][A-Z0-9]+|!subteam\^[A-Z0-9]+|!channel|!here|!everyone)(?:\|([^>]*))?>`)
+)
+
+// LoadMentionPolicy reads a mention policy from a json file like {"allow": ["@alice"], "deny": ["@kubernetes/*"]}
+func LoadMentionPolicy(path string) (*MentionPolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy MentionPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	for _, entry := range append(append([]string{}, policy.Allow...), policy.Deny...) {
+		if !strings.HasPrefix(entry, "@") && !strings.HasPrefix(entry, "<") {
+			return nil, fmt.Errorf("%q is neither a github handle (@alice, @org/team) nor a slack mention (<@U0123>, <!subteam^S0123>)", entry)
+		}
+	}
+	return &policy, nil
+}
+
+// Permits tells if automation may mention the handle, every handle is permitted if no policy is set
+func (p *MentionPolicy) Permits(mention string) bool {
+	if p == nil {
+		return true
+	}
+	mention = mentionKey(mention)
+	for _, entry := range p.Deny {
+		if mentionMatches(entry, mention) {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, entry := range p.Allow {
+		if mentionMatches(entry, mention) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterMentions returns the permitted mentions, the others are dropped and logged
+func (p *MentionPolicy) filterMentions(logger *Logger, mentions []string) []string {
+	permitted := []string{}
+	for _, m := range mentions {
+		if p.Permits(m) {
+			permitted = append(permitted, m)
+		} else {
+			logger.Warn("Mention is not permitted by the mention policy and has been dropped", "mention", m)
+		}
+	}
+	return permitted
+}
+
+// neutralizeGithubMentions wraps @handles that are not permitted in code spans, so github does not notify them
+// (like an issue title "@alice please look" in a report comment)
+func (p *MentionPolicy) neutralizeGithubMentions(text string) string {
+	if p == nil {
+		return text
+	}
+	return githubMentionRegex.ReplaceAllStringFunc(text, func(match string) string {
+		groups := githubMentionRegex.FindStringSubmatch(match)
+		if p.Permits("@" + groups[2]) {
+			return match
+		}
+		return fmt.Sprintf("%s`@%s`", groups[1], groups[2])
+	})
+}
+
+// neutralizeSlackMentions replaces slack mentions that are not permitted with their plain label, so slack does not notify them
+func (p *MentionPolicy) neutralizeSlackMentions(text string) string {
+	if p == nil {
+		return text
+	}
+	return slackMentionRegex.ReplaceAllStringFunc(text, func(match string) string {
+		groups := slackMentionRegex.FindStringSubmatch(match)
+		if p.Permits("<" + groups[1] + ">") {
+			return match
+		}
+		if groups[2] != "" {
+			return groups[2]
+		}
+		return strings.TrimPrefix(groups[1], "!")
+	})
+}
+
+// mentionKey normalizes a mention for comparison ("<!subteam^S0123|@leads>" -> "<!subteam^s0123>", "@Alice" -> "@alice")
+func mentionKey(mention string) string {
+	mention = strings.ToLower(strings.TrimSpace(mention))
+	if i := strings.Index(mention, "|"); strings.HasPrefix(mention, "<") && i > 0 {
+		mention = mention[:i] + ">"
+	}
+	return mention
+}
+
+// mentionMatches tells if a policy entry matches the mention, "@org/*" matches all teams of the org
+func mentionMatches(entry string, mention string) bool {
+	entry = mentionKey(entry)
+	if strings.HasSuffix(entry, "/*") {
+		return strings.HasPrefix(mention, strings.TrimSuffix(entry, "*"))
+	}
+	return entry == mention
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMentionPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mentions.json")
+	content := `{"allow": ["@alice", "@kubernetes/*", "<!subteam^S0123>"], "deny": ["@kubernetes/owners"]}`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadMentionPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for mention, expected := range map[string]bool{
+		"@alice":                     true,
+		"@Alice":                     true,
+		"@bob":                       false,
+		"@kubernetes/sig-node-leads": true,
+		"@kubernetes/owners":         false,
+		"<!subteam^S0123|@leads>":    true,
+		"<!subteam^S9999>":           false,
+	} {
+		if permitted := policy.Permits(mention); permitted != expected {
+			t.Errorf("expected %s to be permitted: %t", mention, expected)
+		}
+	}
+	var none *MentionPolicy
+	if !none.Permits("@bob") {
+		t.Error("expected every mention to be permitted without policy")
+	}
+
+	text := "[Failing test] @bob please look, cc @alice, mail bob@example.com, `@carol`"
+	expected := "[Failing test] `@bob` please look, cc @alice, mail bob@example.com, `@carol`"
+	if neutralized := policy.neutralizeGithubMentions(text); neutralized != expected {
+		t.Errorf("expected %q, got %q", expected, neutralized)
+	}
+	slack := "<!subteam^S0123|@leads> <!subteam^S9999|@everyone-else> <!channel>"
+	if neutralized := policy.neutralizeSlackMentions(slack); neutralized != "<!subteam^S0123|@leads> @everyone-else channel" {
+		t.Errorf("unexpected slack text %q", neutralized)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"allow": ["alice"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMentionPolicy(path); err == nil {
+		t.Error("expected an error for an entry that is no handle")
+	}
+}
+
+func TestSubscriptionMentions(t *testing.T) {
+	var message map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	meta := Meta{Flags: metaFlags{MentionPolicy: &MentionPolicy{Allow: []string{"<!subteam^S0123>"}}}}
+	subscriptions := []Subscription{{Sig: "sig-node", SlackWebhookURL: server.URL, Mentions: []string{"<!subteam^S0123>", "<@U0999>"}}}
+	if err := NotifySubscriptions(meta, subscriptions, testSubscriptionReport(), nil); err != nil {
+		t.Fatal(err)
+	}
+	text := message["text"]
+	if !strings.HasSuffix(text, "cc <!subteam^S0123>\n") {
+		t.Errorf("expected only the permitted group to be mentioned, got %q", text)
+	}
+}
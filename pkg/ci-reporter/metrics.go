@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metricsNamespace = "ci_signal"
+
+// metricsServer holds the metrics of the last report that has been requested without errors
+type metricsServer struct {
+	meta    Meta
+	mu      sync.RWMutex
+	metrics string
+}
+
+// refresh requests the report and replaces the metrics, if the report can not be requested the metrics of the last refresh are kept
+func (s *metricsServer) refresh(ctx context.Context) error {
+	start := time.Now()
+	report, _, err := s.meta.RequestReport(ctx)
+	if err != nil {
+		return err
+	}
+	metrics := FormatMetrics(report, start, time.Since(start))
+	s.mu.Lock()
+	s.metrics = metrics
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *metricsServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, s.metrics)
+}
+
+// Serve refreshes the report every meta.Flags.Interval and exposes it as prometheus metrics on meta.Flags.Listen/metrics.
+// Failed refreshes are logged and the metrics of the last successful refresh are served until the next interval
+func Serve(meta Meta) error {
+	server := &metricsServer{meta: meta}
+	refresh := func() {
+		start := time.Now()
+		if err := server.refresh(context.Background()); err != nil {
+			meta.logger().Error("Could not refresh report metrics, serving the metrics of the last refresh", "error", err)
+			return
+		}
+		meta.logger().Info("Refreshed report metrics", "duration", time.Since(start))
+	}
+	refresh()
+	go func() {
+		for range time.Tick(meta.Flags.Interval) {
+			refresh()
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", server)
+	meta.logger().Info("Serving metrics", "address", meta.Flags.Listen+"/metrics")
+	return http.ListenAndServe(meta.Flags.Listen, mux)
+}
+
+// FormatMetrics transforms a report into the prometheus text exposition format
+func FormatMetrics(report Report, refreshedAt time.Time, refreshDuration time.Duration) string {
+	dashboardJobs := []string{}
+	dashboardJobsTotal := []string{}
+	jobPassRates := []string{}
+	issueCounts := map[string]int{}
+	for _, reportData := range report {
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				switch {
+				case reportData.Name == testgridReport && record.ID == testgridReportSummary:
+					for status, count := range record.Counts {
+						if status == strings.ToLower(string(total)) {
+							// the total is its own metric, so summing up the statuses does not count the jobs twice
+							dashboardJobsTotal = append(dashboardJobsTotal, fmt.Sprintf("%s_dashboard_jobs_total{dashboard=%q} %d", metricsNamespace, field.Title, count))
+							continue
+						}
+						dashboardJobs = append(dashboardJobs, fmt.Sprintf("%s_dashboard_jobs{dashboard=%q,status=%q} %d", metricsNamespace, field.Title, status, count))
+					}
+				case reportData.Name == testgridReport && record.RecentPassRate != nil:
+					jobPassRates = append(jobPassRates, fmt.Sprintf("%s_job_recent_pass_rate{dashboard=%q,job=%q,status=%q} %g", metricsNamespace, field.Title, record.Title, strings.ToLower(record.Status), *record.RecentPassRate))
+				case reportData.Name == githubReport:
+					sigs := record.Sigs
+					if len(sigs) == 0 {
+						sigs = []string{"none"}
+					}
+					for _, sig := range sigs {
+						issueCounts[fmt.Sprintf("%s_open_issues{sig=%q,severity=\"%d\"}", metricsNamespace, sig, record.Severity)]++
+					}
+				}
+			}
+		}
+	}
+	issues := []string{}
+	for series, count := range issueCounts {
+		issues = append(issues, fmt.Sprintf("%s %d", series, count))
+	}
+
+	var sb strings.Builder
+	writeMetric(&sb, "dashboard_jobs", "Number of testgrid jobs of a dashboard per overall status", dashboardJobs)
+	writeMetric(&sb, "dashboard_jobs_total", "Number of testgrid jobs of a dashboard", dashboardJobsTotal)
+	writeMetric(&sb, "job_recent_pass_rate", "Share of recent runs that passed of failing and flaky testgrid jobs", jobPassRates)
+	writeMetric(&sb, "open_issues", "Number of open failing-test and flake issues per sig and severity", issues)
+	writeMetric(&sb, "last_refresh_timestamp_seconds", "Unix time of the last report refresh", []string{fmt.Sprintf("%s_last_refresh_timestamp_seconds %d", metricsNamespace, refreshedAt.Unix())})
+	writeMetric(&sb, "refresh_duration_seconds", "Time it took to request the report", []string{fmt.Sprintf("%s_refresh_duration_seconds %g", metricsNamespace, refreshDuration.Seconds())})
+	return sb.String()
+}
+
+func writeMetric(sb *strings.Builder, name string, help string, series []string) {
+	// series are sorted so the output is stable between refreshes
+	sort.Strings(series)
+	sb.WriteString(fmt.Sprintf("# HELP %s_%s %s\n", metricsNamespace, name, help))
+	sb.WriteString(fmt.Sprintf("# TYPE %s_%s gauge\n", metricsNamespace, name))
+	for _, s := range series {
+		sb.WriteString(s + "\n")
+	}
+}
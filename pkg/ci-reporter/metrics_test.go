@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMetrics(t *testing.T) {
+	passRate := 0.5
+	report := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Counts: map[string]int{"total": 3, "passing": 1, "failing": 1, "flaky": 1, "stale": 0}},
+			{ID: 1, Title: "ci-kubernetes-e2e-gce", Status: "FAILING", RecentPassRate: &passRate},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Title: "CI Signal Board", Records: []ReportDataRecord{
+			{Title: "#1 a", Sigs: []string{"node"}, Severity: 2},
+			{Title: "#2 b"},
+		}}}},
+	}
+	metrics := FormatMetrics(report, time.Unix(1636000000, 0), 2*time.Second)
+	for _, expected := range []string{
+		"ci_signal_dashboard_jobs{dashboard=\"Master-Blocking\",status=\"failing\"} 1\n",
+		"ci_signal_dashboard_jobs_total{dashboard=\"Master-Blocking\"} 3\n",
+		"ci_signal_job_recent_pass_rate{dashboard=\"Master-Blocking\",job=\"ci-kubernetes-e2e-gce\",status=\"failing\"} 0.5\n",
+		"ci_signal_open_issues{sig=\"node\",severity=\"2\"} 1\n",
+		"ci_signal_open_issues{sig=\"none\",severity=\"0\"} 1\n",
+		"ci_signal_last_refresh_timestamp_seconds 1636000000\n",
+		"ci_signal_refresh_duration_seconds 2\n",
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("expected metrics to contain %q but got:\n%s", expected, metrics)
+		}
+	}
+	// summing up the statuses must not count the jobs twice
+	if strings.Contains(metrics, "status=\"total\"") {
+		t.Errorf("expected the total not to be a status of ci_signal_dashboard_jobs but got:\n%s", metrics)
+	}
+}
+
+func TestMetricsServerKeepsMetricsOfLastRefresh(t *testing.T) {
+	server := &metricsServer{meta: newTestMeta(metaFlags{SpecificReport: testgridReport, ShortOn: true})}
+	if err := server.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	refreshed := rec.Body.String()
+	if !strings.Contains(refreshed, "ci_signal_dashboard_jobs_total") {
+		t.Fatalf("expected the metrics of the report but got:\n%s", refreshed)
+	}
+
+	server.meta.HTTPClient = &http.Client{Transport: failingTransport{}}
+	if err := server.refresh(context.Background()); err == nil {
+		t.Fatal("expected the refresh to fail if the report can not be requested")
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Body.String() != refreshed {
+		t.Errorf("expected the metrics of the last refresh to be served after a failed refresh but got:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// milestone returns the milestone github issues are scoped to, set via -milestone or taken from the latest release version set via -v ("1.22, 1.21" => "v1.22").
+// No milestone is returned if neither is set
+func (f metaFlags) milestone() string {
+	if f.Milestone != "" {
+		return f.Milestone
+	}
+	latest := ""
+	for _, v := range f.ReleaseVersion {
+		if latest == "" || releaseVersionLess(latest, v) {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return ""
+	}
+	return "v" + latest
+}
+
+// releaseVersionLess compares release versions by their minor version ("1.9" < "1.22")
+func releaseVersionLess(a string, b string) bool {
+	aParts, bParts := strings.Split(strings.TrimPrefix(a, "v"), "."), strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		if aErr != nil || bErr != nil {
+			return a < b
+		}
+		if aNum != bNum {
+			return aNum < bNum
+		}
+	}
+	return len(aParts) < len(bParts)
+}
+
+// sameMilestone tells if two milestone titles name the same milestone ("v1.22" and "1.22" do)
+func sameMilestone(a string, b string) bool {
+	normalize := func(m string) string { return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(m)), "v") }
+	return normalize(a) == normalize(b)
+}
+
+// milestoneScope decides how an issue is reported if issues are scoped to a milestone: issues of the milestone are reported,
+// issues without milestone are reported as action items (they still have to be triaged into the milestone) and issues of other milestones are left out
+func milestoneScope(issue GithubIssueElement, milestone string) (reported bool, actionItem bool) {
+	if milestone == "" {
+		return true, false
+	}
+	if issue.Milestone == nil || issue.Milestone.Title == "" {
+		return true, true
+	}
+	return sameMilestone(issue.Milestone.Title, milestone), false
+}
+
+// milestoneSection github issues of the report that are or are not yet triaged into the milestone
+type milestoneSection struct {
+	Title string
+	// ActionItems tells if the issues of the section still have to be triaged into the milestone
+	ActionItems bool
+	Fields      []ReportDataField
+}
+
+// milestoneSections splits the github report into issues in the milestone and issues not yet triaged into the milestone
+func milestoneSections(reportData ReportData, milestone string) []milestoneSection {
+	in := milestoneSection{Title: fmt.Sprintf("In milestone %s", milestone)}
+	notTriaged := milestoneSection{Title: fmt.Sprintf("Not yet triaged into milestone %s", milestone), ActionItems: true}
+	for _, field := range reportData.Data {
+		inRecords, notTriagedRecords := []ReportDataRecord{}, []ReportDataRecord{}
+		for _, record := range field.Records {
+			if sameMilestone(record.Milestone, milestone) {
+				inRecords = append(inRecords, record)
+			} else {
+				notTriagedRecords = append(notTriagedRecords, record)
+			}
+		}
+		if len(inRecords) > 0 {
+			in.Fields = append(in.Fields, ReportDataField{Emoji: field.Emoji, Title: field.Title, Records: inRecords})
+		}
+		if len(notTriagedRecords) > 0 {
+			notTriaged.Fields = append(notTriaged.Fields, ReportDataField{Emoji: field.Emoji, Title: field.Title, Records: notTriagedRecords})
+		}
+	}
+	return []milestoneSection{in, notTriaged}
+}
+
+// count returns the number of issues of the section
+func (s milestoneSection) count() int {
+	count := 0
+	for _, field := range s.Fields {
+		count += len(field.Records)
+	}
+	return count
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMilestoneFlag(t *testing.T) {
+	tests := []struct {
+		flags    metaFlags
+		expected string
+	}{
+		{metaFlags{}, ""},
+		{metaFlags{Milestone: "v1.30"}, "v1.30"},
+		{metaFlags{ReleaseVersion: []string{"1.9", "1.22", "1.21"}}, "v1.22"},
+		{metaFlags{Milestone: "v1.23", ReleaseVersion: []string{"1.22"}}, "v1.23"},
+	}
+	for _, tc := range tests {
+		if got := tc.flags.milestone(); got != tc.expected {
+			t.Errorf("%+v: expected milestone %q, got %q", tc.flags, tc.expected, got)
+		}
+	}
+}
+
+func TestMilestoneScope(t *testing.T) {
+	tests := []struct {
+		name               string
+		issue              GithubIssueElement
+		milestone          string
+		expectedReported   bool
+		expectedActionItem bool
+	}{
+		{"no milestone scope", GithubIssueElement{}, "", true, false},
+		{"in milestone", GithubIssueElement{Milestone: &Milestone{Title: "v1.23"}}, "1.23", true, false},
+		{"other milestone", GithubIssueElement{Milestone: &Milestone{Title: "v1.22"}}, "v1.23", false, false},
+		{"not triaged", GithubIssueElement{}, "v1.23", true, true},
+	}
+	for _, tc := range tests {
+		reported, actionItem := milestoneScope(tc.issue, tc.milestone)
+		if reported != tc.expectedReported || actionItem != tc.expectedActionItem {
+			t.Errorf("%s: expected reported %t, action item %t, got %t, %t", tc.name, tc.expectedReported, tc.expectedActionItem, reported, actionItem)
+		}
+	}
+}
+
+func TestGithubReportMilestone(t *testing.T) {
+	meta := newTestMeta(metaFlags{Milestone: "v1.23"})
+	r := &GithubReport{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := r.RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sections := milestoneSections(reportData, "v1.23")
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 milestone sections, got %d", len(sections))
+	}
+	if sections[0].count() != 1 || sections[0].Fields[0].Records[0].ID != 105242 {
+		t.Errorf("expected issue #105242 in milestone v1.23, got %+v", sections[0].Fields)
+	}
+	if sections[1].count() != 2 || !sections[1].ActionItems {
+		t.Errorf("expected 2 issues not yet triaged into milestone v1.23, got %+v", sections[1].Fields)
+	}
+	for _, field := range sections[1].Fields {
+		for _, record := range field.Records {
+			if !strings.HasPrefix(record.Highlight, actionItemEmoji) || record.Notes[0] != "Action item: not yet triaged into milestone v1.23" {
+				t.Errorf("#%d: expected action item, got highlight %q and notes %v", record.ID, record.Highlight, record.Notes)
+			}
+		}
+	}
+
+	markdown := MarkdownReport(meta, Report{reportData})
+	for _, expected := range []string{"### In milestone v1.23 (1)", "### Not yet triaged into milestone v1.23 (action items) (2)"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in markdown report:\n%s", expected, markdown)
+		}
+	}
+	if o := (Report{reportData}).Output(time.Now()); o.Sources[0].Sections[0].Records[0].Milestone != "v1.23" {
+		t.Errorf("expected milestone in json output, got %+v", o.Sources[0].Sections[0].Records[0])
+	}
+}
+
+func TestGithubReportOtherMilestone(t *testing.T) {
+	meta := newTestMeta(metaFlags{ReleaseVersion: []string{"1.22"}})
+	r := &GithubReport{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := r.RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			if record.ID == 105242 {
+				t.Errorf("expected issue #105242 of milestone v1.23 to be left out of milestone v1.22")
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/leonardpahlke/ci-signal-report/pkg/ci-reporter/schema"
+)
+
+// Notifier this interface is implemented by integrations that send the report to an external system
+type Notifier interface {
+	Notify(meta Meta, report Report) error
+}
+
+// errReadOnly is returned by every integration that would post or modify something while -read-only is set
+var errReadOnly = errors.New("read-only mode is enabled, set -read-only=false to post or modify anything")
+
+// NotificationData is the data passed to user provided payload templates
+type NotificationData struct {
+	// GeneratedAt time the report has been generated (RFC3339)
+	GeneratedAt string
+	// Report all report data that has been requested
+	Report Report
+	// Output the report in the versioned output format (the same data that is printed with -output json)
+	Output schema.Output
+	// Mentions handles that should be mentioned with the report and are permitted by the mention policy (slack subscriptions)
+	Mentions []string
+}
+
+// WebhookNotifier posts the report to a generic webhook, by default the payload is the report in the versioned output format (see package schema)
+type WebhookNotifier struct {
+	URL      string
+	Template *template.Template
+}
+
+// Notify extends WebhookNotifier and sends the report to the webhook url
+func (n WebhookNotifier) Notify(meta Meta, report Report) error {
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	report = plainReport(report)
+	var payload []byte
+	var err error
+	if n.Template != nil {
+		payload, err = executePayloadTemplate(meta, n.Template, report, nil)
+	} else {
+		payload, err = json.Marshal(report.Output(time.Now()))
+	}
+	if err != nil {
+		return err
+	}
+	return postPayload(httpClientOrDefault(meta.HTTPClient), n.URL, payload)
+}
+
+// SlackNotifier posts the report to a slack incoming webhook, by default the payload is a short text summary
+type SlackNotifier struct {
+	URL      string
+	Template *template.Template
+	// Channel overwrites the default channel of the incoming webhook
+	Channel string
+	// Text renders the default payload text, summaryText is used if it is not set
+	Text func(Report) string
+	// Mentions slack users or groups (like "<!subteam^S0123>") that are mentioned below the text if the mention policy permits them
+	Mentions []string
+}
+
+// Notify extends SlackNotifier and sends the report to the slack webhook url
+func (n SlackNotifier) Notify(meta Meta, report Report) error {
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	report = plainReport(report)
+	var payload []byte
+	var err error
+	mentions := meta.Flags.MentionPolicy.filterMentions(meta.logger(), n.Mentions)
+	if n.Template != nil {
+		payload, err = executePayloadTemplate(meta, n.Template, report, mentions)
+		payload = []byte(meta.Flags.MentionPolicy.neutralizeSlackMentions(string(payload)))
+	} else {
+		text := n.Text
+		if text == nil {
+			text = summaryText
+		}
+		body := text(report)
+		if len(mentions) > 0 {
+			body += "cc " + strings.Join(mentions, " ") + "\n"
+		}
+		message := map[string]string{"text": meta.Flags.MentionPolicy.neutralizeSlackMentions(body)}
+		if n.Channel != "" {
+			message["channel"] = n.Channel
+		}
+		payload, err = json.Marshal(message)
+	}
+	if err != nil {
+		return err
+	}
+	return postPayload(httpClientOrDefault(meta.HTTPClient), n.URL, payload)
+}
+
+// LoadPayloadTemplate reads a go template file that is used to shape the payload of a notifier
+func LoadPayloadTemplate(path string) (*template.Template, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(path).Funcs(payloadTemplateFuncs).Parse(string(content))
+}
+
+// Functions that can be used inside of payload templates
+var payloadTemplateFuncs = template.FuncMap{
+	// json escapes a value so it can be embedded in a json payload e.g. {"text": {{ json .GeneratedAt }}}
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"join":    strings.Join,
+	"summary": summaryText,
+	// plain removes terminal colors (like the colored labels of github issue notes)
+	"plain":       stripColors,
+	"groupBy":     templateGroupBy,
+	"minSeverity": templateMinSeverity,
+	"isSummary":   isSummaryRecord,
+	// emoji is replaced when the template is executed, emojis are left out if -emoji-off is set
+	"emoji": func(emoji string) string { return emoji },
+}
+
+// executePayloadTemplate renders the report with a copy of the template, templates are shared by sinks and notifiers that render at the same time
+func executePayloadTemplate(meta Meta, tmpl *template.Template, report Report, mentions []string) ([]byte, error) {
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl = tmpl.Funcs(template.FuncMap{"emoji": templateEmoji(meta)})
+	var buf bytes.Buffer
+	generatedAt := time.Now().UTC()
+	err = tmpl.Execute(&buf, NotificationData{
+		GeneratedAt: generatedAt.Format(time.RFC3339),
+		Report:      report,
+		Output:      report.Output(generatedAt),
+		Mentions:    mentions,
+	})
+	return buf.Bytes(), err
+}
+
+// plainReport returns a copy of the report without terminal colors (like the colored labels of github issue notes),
+// payloads are shown outside of a terminal
+func plainReport(report Report) Report {
+	plain := make(Report, len(report))
+	for i, reportData := range report {
+		fields := make([]ReportDataField, len(reportData.Data))
+		for j, field := range reportData.Data {
+			records := make([]ReportDataRecord, len(field.Records))
+			for k, record := range field.Records {
+				notes := make([]string, len(record.Notes))
+				for l, note := range record.Notes {
+					notes[l] = stripColors(note)
+				}
+				if record.Notes == nil {
+					notes = nil
+				}
+				record.Notes = notes
+				records[k] = record
+			}
+			field.Records = records
+			fields[j] = field
+		}
+		reportData.Data = fields
+		plain[i] = reportData
+	}
+	return plain
+}
+
+// summaryText creates a short plain text summary of the report (testgrid summaries and the number of github issues)
+func summaryText(report Report) string {
+	var sb strings.Builder
+	for _, reportData := range report {
+		sb.WriteString(fmt.Sprintf("%s report\n", strings.ToUpper(reportData.Name)))
+		records := 0
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if isSummaryRecord(reportData.Name, record) {
+					sb.WriteString(fmt.Sprintf("%s: %s\n", field.Title, strings.Join(record.Notes, ", ")))
+				} else {
+					records++
+				}
+			}
+		}
+		if reportData.Name == githubReport {
+			sb.WriteString(fmt.Sprintf("%d open issues\n", records))
+		}
+	}
+	return sb.String()
+}
+
+func postPayload(client *http.Client, url string, payload []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("notification to %s failed with status %s: %s", url, resp.Status, string(body))
+	}
+	return nil
+}
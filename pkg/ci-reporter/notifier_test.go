@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// coloredNotesReport a report whose notes contain terminal colors like the labels of github issues
+func coloredNotesReport() Report {
+	return Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Notes: []string{colorRed + "1 jobs failing" + colorReset}},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{
+			{ID: 1, Title: "node issue", Notes: []string{colorBlue + "sig/node" + colorReset}},
+		}}}},
+	}
+}
+
+func TestNotifierPayloadsWithoutColors(t *testing.T) {
+	var payload string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload = string(body)
+	}))
+	defer server.Close()
+
+	notesTemplate := template.Must(template.New("notes").Funcs(payloadTemplateFuncs).Parse(`{{ range .Report }}{{ range .Data }}{{ range .Records }}{{ join .Notes ", " }};{{ end }}{{ end }}{{ end }}`))
+	report := coloredNotesReport()
+	for _, tc := range []struct {
+		name     string
+		notifier Notifier
+		expected string
+	}{
+		{"webhook json", WebhookNotifier{URL: server.URL}, "sig/node"},
+		{"webhook template", WebhookNotifier{URL: server.URL, Template: notesTemplate}, "1 jobs failing;sig/node;"},
+		{"slack text", SlackNotifier{URL: server.URL}, "Master-Blocking: 1 jobs failing"},
+		{"slack template", SlackNotifier{URL: server.URL, Template: notesTemplate}, "1 jobs failing;sig/node;"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			payload = ""
+			if err := tc.notifier.Notify(Meta{}, report); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(payload, "\033[") || strings.Contains(payload, `\u001b[`) {
+				t.Errorf("expected the payload without terminal colors, got %q", payload)
+			}
+			if !strings.Contains(payload, tc.expected) {
+				t.Errorf("expected %q in the payload, got %q", tc.expected, payload)
+			}
+		})
+	}
+	// the report of the caller is not changed
+	if report[1].Data[0].Records[0].Notes[0] != colorBlue+"sig/node"+colorReset {
+		t.Errorf("expected the notes of the report to keep their colors, got %q", report[1].Data[0].Records[0].Notes[0])
+	}
+}
+
+func TestExecutePayloadTemplateKeepsSharedTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("emoji").Funcs(payloadTemplateFuncs).Parse(`{{ emoji "✅" }}`))
+	payload, err := executePayloadTemplate(Meta{Flags: metaFlags{EmojisOff: true}}, tmpl, Report{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "" {
+		t.Errorf("expected no emoji with emojis off, got %q", payload)
+	}
+	// the template is shared by sinks and notifiers, the settings of one rendering must not leak into the others
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "✅" {
+		t.Errorf("expected the shared template to keep its funcs, got %q", buf.String())
+	}
+}
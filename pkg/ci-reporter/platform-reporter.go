@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// platformAliases job name tokens that identify a platform besides the name of the platform
+var platformAliases = map[string][]string{
+	"windows": {"win"},
+	"arm64":   {"aarch64"},
+}
+
+// defaultPlatforms platforms of the platforms report if it is turned on via -features platforms=true and -platforms is not set
+var defaultPlatforms = []string{"windows", "arm64"}
+
+// PlatformReport used to implement RequestData & Print for the job health of platforms with dedicated owners (like windows and arm64)
+type PlatformReport struct {
+	ReportData ReportData
+}
+
+// RequestData this function is used to summarize the jobs of all dashboards that run on one of the platforms set via -platforms
+func (r *PlatformReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	dashboards := meta.Flags.dashboards()
+	client := httpClientOrDefault(meta.HTTPClient)
+	dashboardJobs, err := meta.requestDashboards(ctx, client, dashboards)
+	if err != nil {
+		return ReportData{Name: platformReport}, fmt.Errorf("requesting dashboards: %v", err)
+	}
+
+	c := make(chan ReportDataField)
+	go func() {
+		defer close(c)
+		for _, platform := range meta.Flags.Platforms {
+			jobs := []providerJob{}
+			for i, dashboard := range dashboards {
+				jobBaseURL := fmt.Sprintf("%s/%s", meta.Flags.testgridURL(), dashboard.URLName)
+				for jobName, jobData := range dashboardJobs[i] {
+					if runsOnPlatform(jobName, platform) {
+						jobs = append(jobs, providerJob{name: jobName, jobBaseURL: jobBaseURL, data: jobData})
+					}
+				}
+			}
+			// platforms without jobs on the dashboards get no section
+			if len(jobs) > 0 {
+				c <- jobGroupSummary(meta, platform, jobs)
+			}
+		}
+	}()
+	return meta.DataPostProcessing(r, platformReport, c, wg), nil
+}
+
+// runsOnPlatform checks if the job name contains the platform or one of its aliases (like "ci-kubernetes-e2e-windows-containerd-gce" runs on windows)
+func runsOnPlatform(jobName string, platform string) bool {
+	platform = strings.ToLower(platform)
+	for _, token := range jobNameTokens(jobName) {
+		if token == platform {
+			return true
+		}
+		for _, alias := range platformAliases[platform] {
+			if token == alias {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Print extends PlatformReport and prints report data to the console
+func (r *PlatformReport) Print(meta Meta, reportData ReportData) {
+	c := meta.console()
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			if record.ID == testgridReportSummary {
+				c.Printf("\n\n%s jobs across dashboards\n", field.Title)
+				for _, note := range record.Notes {
+					c.Printf("- %s\n", note)
+				}
+				c.Print("\n")
+				continue
+			}
+			c.Printf("%s\n", c.recordLine(record))
+			c.Printf("- %s\n", record.URL)
+			for _, note := range record.Notes {
+				c.Printf("- %s\n", note)
+			}
+		}
+	}
+	c.Println()
+}
+
+// PutData extends PlatformReport and stores the data at runtime to the struct val ReportData
+func (r *PlatformReport) PutData(reportData ReportData) {
+	r.ReportData = reportData
+}
+
+// GetData extends PlatformReport and returns the data that has been stored at runtime int the struct val ReportData
+func (r PlatformReport) GetData() ReportData {
+	return r.ReportData
+}
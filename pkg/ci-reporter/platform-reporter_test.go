@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRunsOnPlatform(t *testing.T) {
+	tests := []struct {
+		jobName  string
+		platform string
+		expected bool
+	}{
+		{"ci-kubernetes-e2e-windows-containerd-gce-master", "windows", true},
+		{"capz-windows-2022-master", "Windows", true},
+		{"ci-kubernetes-e2e-win-1809", "windows", true},
+		{"ci-kubernetes-e2e-ubuntu-gce-arm64", "arm64", true},
+		{"ci-kubernetes-node-aarch64", "arm64", true},
+		{"ci-kubernetes-e2e-gci-gce", "windows", false},
+		{"kubeadm-twin-upgrade", "windows", false},
+	}
+	for _, test := range tests {
+		if runsOnPlatform(test.jobName, test.platform) != test.expected {
+			t.Errorf("expected runsOnPlatform(%q, %q) to be %t", test.jobName, test.platform, test.expected)
+		}
+	}
+}
+
+func TestPlatformReportRequestData(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&PlatformReport{}).RequestData(context.Background(), newTestMeta(metaFlags{Platforms: []string{"windows", "gce"}}), &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the dashboards have no windows jobs, so only the gce jobs are summarized
+	if len(reportData.Data) != 1 || reportData.Data[0].Title != "gce" {
+		t.Fatalf("expected one section of gce jobs, got %+v", reportData.Data)
+	}
+	records := reportData.Data[0].Records
+	if records[0].ID != testgridReportSummary || records[0].Counts["total"] != 2 {
+		t.Errorf("expected a summary of 2 jobs, got %+v", records[0])
+	}
+	if len(records) != 3 || records[1].Title != "gce-cos-master-serial" {
+		t.Errorf("expected failing and flaky jobs ordered by severity, got %+v", records[1:])
+	}
+}
+
+func TestPlatformsOptIn(t *testing.T) {
+	if meta := SetMetaFromArgs([]string{"-replay", testFixturesDir}); len(meta.Flags.Platforms) != 0 {
+		t.Errorf("expected no platforms by default, got %v", meta.Flags.Platforms)
+	}
+	if meta := SetMetaFromArgs([]string{"-replay", testFixturesDir, "-features", "platforms=true"}); !reflect.DeepEqual(meta.Flags.Platforms, defaultPlatforms) {
+		t.Errorf("expected the default platforms if the report is turned on, got %v", meta.Flags.Platforms)
+	}
+	if meta := SetMetaFromArgs([]string{"-replay", testFixturesDir, "-platforms", "s390x"}); !reflect.DeepEqual(meta.Flags.Platforms, []string{"s390x"}) {
+		t.Errorf("expected the platforms that are set, got %v", meta.Flags.Platforms)
+	}
+}
@@ -0,0 +1,549 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fix status of the records of the pr-signal report, records are ordered by their fix status
+const (
+	// fixStatusUnowned nobody is assigned and no pull request fixes it, the record needs an owner
+	fixStatusUnowned = "UNOWNED"
+	// fixStatusNoFix someone is assigned but no pull request fixes it yet
+	fixStatusNoFix = "NO FIX"
+	// fixStatusPending an open pull request fixes it
+	fixStatusPending = "FIX PENDING"
+	// fixStatusMerged a pull request that fixes it has been merged, the issue is open until the signal recovered
+	fixStatusMerged = "FIX MERGED"
+)
+
+var fixStatusOrder = map[string]int{fixStatusUnowned: 0, fixStatusNoFix: 1, fixStatusPending: 2, fixStatusMerged: 3}
+
+// title of the section of failing and flaky jobs of the pr-signal report
+const failingJobsFixesTitle = "Failing jobs"
+
+// closingKeywordRegex matches references that close an issue once the pull request is merged
+// ("Fixes #123", "fixes: kubernetes/kubernetes#123" or "Closes https://github.com/kubernetes/kubernetes/issues/123")
+var closingKeywordRegex = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?)\s*:?\s+(?:https://github\.com/([\w.-]+/[\w.-]+)/issues/|([\w.-]+/[\w.-]+)?#)(\d+)\b`)
+
+// This query requests one page of failing-test and flake issues with the pull requests that reference them
+const githubFixesQuery = `query($owner: String!, $repo: String!, $labels: [String!], $since: DateTime, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    issues(first: 50, after: $cursor, states: [OPEN], labels: $labels, filterBy: {since: $since}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number
+        title
+        url
+        labels(first: 50) { nodes { name } }
+        assignees(first: 10) { nodes { login } }
+        timelineItems(last: 50, itemTypes: [CONNECTED_EVENT, CROSS_REFERENCED_EVENT]) {
+          nodes {
+            ... on ConnectedEvent { subject { ...fixPullRequest } }
+            ... on CrossReferencedEvent { willCloseTarget source { ...fixPullRequest } }
+          }
+        }
+      }
+    }
+  }
+}
+
+fragment fixPullRequest on PullRequest {
+  number
+  title
+  url
+  state
+  isDraft
+  body
+  reviewDecision
+  author { login }
+  repository { nameWithOwner }
+  labels(first: 30) { nodes { name } }
+  mergeQueueEntry { position }
+}`
+
+// FixPR pull request that references a tracked issue
+type FixPR struct {
+	// Repository of the pull request like "kubernetes/kubernetes"
+	Repository string `json:"repository"`
+	Number     int64  `json:"number"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Author     string `json:"author"`
+	// State "open" or "merged" (closed pull requests are left out)
+	State string `json:"state"`
+	// Review like "lgtm, approved", "changes requested", "awaiting review" or "draft"
+	Review string `json:"review"`
+	// InMergeQueue the pull request is queued for merge (github merge queue or the tide pool: lgtm and approved without do-not-merge labels)
+	InMergeQueue bool `json:"in_merge_queue"`
+	// Closes the pull request closes the issue (closing keyword like "Fixes #123" or linked via the sidebar), otherwise it only references it
+	Closes bool `json:"closes"`
+}
+
+// PRSignalReport used to implement RequestData & Print for the pull requests that fix failing tests and flakes
+type PRSignalReport struct {
+	ReportData ReportData
+}
+
+// trackedIssue a failing-test or flake issue and the pull requests that reference it
+type trackedIssue struct {
+	repo  GithubRepository
+	issue GithubIssueElement
+	prs   []FixPR
+}
+
+// RequestData this function is used to find open pull requests that fix the tracked failing-test and flake issues and the failing jobs they track
+func (r *PRSignalReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	meta = meta.withContext(ctx)
+	dashboards := meta.Flags.dashboards()
+	client := httpClientOrDefault(meta.HTTPClient)
+	dashboardJobs, err := meta.requestDashboards(ctx, client, dashboards)
+	if err != nil {
+		return ReportData{Name: prSignalReport}, fmt.Errorf("requesting dashboards: %v", err)
+	}
+
+	repositories := meta.Flags.repositories()
+	repositoryIssues := make([][]trackedIssue, len(repositories))
+	errs := runWorkerPool(meta.Flags.concurrency(), len(repositories), func(i int) error {
+		issues, err := requestTrackedIssues(meta, repositories[i])
+		repositoryIssues[i] = issues
+		return err
+	})
+	if err := collectWorkerErrors(errs); err != nil {
+		return ReportData{Name: prSignalReport}, fmt.Errorf("requesting github issues: %v", err)
+	}
+
+	c := make(chan ReportDataField)
+	go func() {
+		defer close(c)
+		tracked := []trackedIssue{}
+		for _, issues := range repositoryIssues {
+			tracked = append(tracked, issues...)
+		}
+		jobs := []ReportDataRecord{}
+		for i, dashboard := range dashboards {
+			jobBaseURL := fmt.Sprintf("%s/%s", meta.Flags.testgridURL(), dashboard.URLName)
+			for jobName, jobData := range dashboardJobs[i] {
+				if jobData.OverallStatus == failing || jobData.OverallStatus == flaky {
+					jobs = append(jobs, jobFixRecord(meta, jobName, fmt.Sprintf("%s#%s", jobBaseURL, jobName), fmt.Sprintf("%s in %s", jobData.OverallStatus, dashboard.OutputName), tracked, len(repositories) > 1))
+				}
+			}
+		}
+		sortFixRecords(jobs)
+		c <- ReportDataField{Emoji: statusFailingEmoji, Title: failingJobsFixesTitle, Records: jobs}
+		for i, repo := range repositories {
+			records := []ReportDataRecord{}
+			for _, t := range repositoryIssues[i] {
+				records = append(records, issueFixRecord(meta, t))
+			}
+			sortFixRecords(records)
+			c <- ReportDataField{Title: repo.String(), Records: records}
+		}
+	}()
+	return meta.DataPostProcessing(r, prSignalReport, c, wg), nil
+}
+
+// requestTrackedIssues requests the open failing-test and flake issues of a repository that have been updated in the last four months
+// with the pull requests that reference them. Linked pull requests are only available using the github graphql api
+func requestTrackedIssues(meta Meta, repo GithubRepository) ([]trackedIssue, error) {
+	cfg := newGithubIssueRequest(meta, repo, "")
+	since, err := githubTimestamp(cfg.Params[IssueReqParamSince])
+	if err != nil {
+		return nil, err
+	}
+	variables := map[string]interface{}{
+		"owner": repo.Owner,
+		"repo":  repo.Repo,
+		// graphql returns issues with any of the labels
+		"labels": []string{"kind/failing-test", "kind/flake"},
+		"since":  since,
+	}
+	issues := GithubIssues{}
+	prs := map[int64][]FixPR{}
+	for {
+		var page graphQLFixesResponse
+		if err := requestGithubGraphQL(httpClientOrDefault(cfg.HTTPClient), githubFixesQuery, variables, cfg.AuthToken, &page); err != nil {
+			return nil, err
+		}
+		for _, node := range page.Repository.Issues.Nodes {
+			issues = append(issues, GithubIssueElement{HTMLURL: node.URL, Number: node.Number, Title: node.Title, Labels: node.Labels.Nodes, Assignees: node.Assignees.Nodes})
+			prs[node.Number] = node.fixPRs(repo)
+		}
+		pageInfo := page.Repository.Issues.PageInfo
+		if !pageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = pageInfo.EndCursor
+	}
+	filtered := filterGithubIssues(issues)
+	numbers := []int64{}
+	for number := range filtered {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	tracked := []trackedIssue{}
+	for _, number := range numbers {
+		tracked = append(tracked, trackedIssue{repo: repo, issue: filtered[number], prs: prs[number]})
+	}
+	return tracked, nil
+}
+
+// fixStatus tells if a fix of the issue is pending or merged, if not the issue is unowned unless someone is assigned
+func (t trackedIssue) fixStatus() string {
+	status := fixStatusNoFix
+	if len(t.issue.Assignees) == 0 {
+		status = fixStatusUnowned
+	}
+	for _, pr := range t.prs {
+		if !pr.Closes {
+			continue
+		}
+		if pr.State == "open" {
+			return fixStatusPending
+		}
+		status = fixStatusMerged
+	}
+	return status
+}
+
+// reference names the issue like "#123", the repository is added if issues of multiple repositories are reported
+func (t trackedIssue) reference(multipleRepositories bool) string {
+	if multipleRepositories {
+		return fmt.Sprintf("%s#%d", t.repo, t.issue.Number)
+	}
+	return fmt.Sprintf("#%d", t.issue.Number)
+}
+
+// issueFixRecord a tracked issue with its fix status and the pull requests that fix or reference it
+func issueFixRecord(meta Meta, t trackedIssue) ReportDataRecord {
+	status := t.fixStatus()
+	labels := []string{}
+	sigs := []string{}
+	severity := LightSeverity
+	for _, label := range t.issue.Labels {
+		labels = append(labels, label.Name)
+		if sig := sigNameRegex.FindString(label.Name); sig != "" {
+			sigs = append(sigs, normalizeSig(sig))
+		}
+		if strings.Contains(label.Name, "priority") {
+			severity = issuePrioritySeverity(label.Name, severity)
+		}
+	}
+	return ReportDataRecord{
+		ID:        t.issue.Number,
+		Title:     t.issue.Title,
+		URL:       t.issue.HTMLURL,
+		Status:    status,
+		Severity:  severity,
+		Highlight: fixStatusEmoji(status),
+		Sigs:      sigs,
+		Labels:    labels,
+		Assignees: issueAssignees(t.issue),
+		Notes:     fixPRNotes(meta, t.repo, t.prs),
+		FixPRs:    t.prs,
+	}
+}
+
+// jobFixRecord a failing or flaky job with the fix status of the issues that track it (issues that name the job as a whole word in their title,
+// so "gce-default" is not tracked by the issues of "gce-default-serial").
+// A job is unowned if no issue tracks it
+func jobFixRecord(meta Meta, jobName string, url string, statusNote string, tracked []trackedIssue, multipleRepositories bool) ReportDataRecord {
+	status := fixStatusUnowned
+	references := []string{}
+	prs := []FixPR{}
+	notes := []string{statusNote}
+	sigs := []string{}
+	for _, t := range tracked {
+		if !containsJobName(strings.ToLower(t.issue.Title), strings.ToLower(jobName)) {
+			continue
+		}
+		references = append(references, t.reference(multipleRepositories))
+		if issueStatus := t.fixStatus(); fixStatusOrder[issueStatus] > fixStatusOrder[status] {
+			status = issueStatus
+		}
+		notes = append(notes, fixPRNotes(meta, t.repo, t.prs)...)
+		prs = append(prs, t.prs...)
+		for _, label := range t.issue.Labels {
+			if sig := sigNameRegex.FindString(label.Name); sig != "" {
+				sigs = append(sigs, normalizeSig(sig))
+			}
+		}
+	}
+	if len(references) > 0 {
+		notes = append(notes[:1], append([]string{fmt.Sprintf("Tracked in %s", strings.Join(references, ", "))}, notes[1:]...)...)
+	} else {
+		notes = append(notes, "No kind/failing-test or kind/flake issue found")
+	}
+	return ReportDataRecord{
+		ID:        testgridReportDetails,
+		Title:     jobName,
+		URL:       url,
+		Status:    status,
+		Highlight: fixStatusEmoji(status),
+		Sigs:      sigs,
+		Notes:     notes,
+		FixPRs:    prs,
+	}
+}
+
+// fixPRNotes one note per pull request that fixes the issue ("Fix #123 Bump timeout (@alice, lgtm, approved, in merge queue)"),
+// pull requests that only reference the issue are listed in one note unless the report is shortened
+func fixPRNotes(meta Meta, repo GithubRepository, prs []FixPR) []string {
+	notes := []string{}
+	references := []string{}
+	for _, pr := range prs {
+		name := fmt.Sprintf("#%d", pr.Number)
+		if pr.Repository != "" && !strings.EqualFold(pr.Repository, repo.String()) {
+			name = fmt.Sprintf("%s#%d", pr.Repository, pr.Number)
+		}
+		if !pr.Closes {
+			references = append(references, fmt.Sprintf("%s (%s)", name, pr.State))
+			continue
+		}
+		if pr.State == "merged" {
+			notes = append(notes, fmt.Sprintf("Fix merged: %s %s", name, pr.Title))
+			continue
+		}
+		details := []string{}
+		if pr.Author != "" {
+			details = append(details, "@"+pr.Author)
+		}
+		details = append(details, pr.Review)
+		if pr.InMergeQueue {
+			details = append(details, "in merge queue")
+		}
+		notes = append(notes, fmt.Sprintf("Fix %s %s (%s)", name, pr.Title, strings.Join(details, ", ")))
+	}
+	if len(references) > 0 && !meta.Flags.ShortOn {
+		notes = append(notes, fmt.Sprintf("Referenced by %s", strings.Join(references, ", ")))
+	}
+	return notes
+}
+
+// fixStatusEmoji highlights unowned records and records with a fix
+func fixStatusEmoji(status string) string {
+	switch status {
+	case fixStatusUnowned:
+		return unassignedEmoji
+	case fixStatusPending, fixStatusMerged:
+		return fixPendingEmoji
+	}
+	return ""
+}
+
+// sortFixRecords orders records from unowned to fixed, then by title (jobs) or issue number
+func sortFixRecords(records []ReportDataRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if fixStatusOrder[records[i].Status] != fixStatusOrder[records[j].Status] {
+			return fixStatusOrder[records[i].Status] < fixStatusOrder[records[j].Status]
+		}
+		if records[i].ID != records[j].ID {
+			return records[i].ID < records[j].ID
+		}
+		return records[i].Title < records[j].Title
+	})
+}
+
+// closesIssue checks if the pull request body contains a closing keyword for the issue, references without a repository point to the repository of the pull request
+func closesIssue(body string, prRepository string, repo GithubRepository, number int64) bool {
+	for _, match := range closingKeywordRegex.FindAllStringSubmatch(body, -1) {
+		reference := match[1] + match[2]
+		if reference == "" {
+			reference = prRepository
+		}
+		if strings.EqualFold(reference, repo.String()) && match[3] == fmt.Sprint(number) {
+			return true
+		}
+	}
+	return false
+}
+
+// prReview summarizes the review state using the prow labels (lgtm, approved) and the review decision of github
+func prReview(pr graphQLFixPullRequest, labels map[string]bool) string {
+	if pr.IsDraft {
+		return "draft"
+	}
+	if labels["lgtm"] || labels["approved"] {
+		review := []string{}
+		for _, label := range []string{"lgtm", "approved"} {
+			if labels[label] {
+				review = append(review, label)
+			}
+		}
+		return strings.Join(review, ", ")
+	}
+	switch pr.ReviewDecision {
+	case "APPROVED":
+		return "approved"
+	case "CHANGES_REQUESTED":
+		return "changes requested"
+	}
+	return "awaiting review"
+}
+
+// inMergeQueue tells if the pull request is part of the github merge queue or the tide pool (lgtm and approved without labels that block merging)
+func inMergeQueue(pr graphQLFixPullRequest, labels map[string]bool) bool {
+	if pr.MergeQueueEntry != nil {
+		return true
+	}
+	if pr.IsDraft || !labels["lgtm"] || !labels["approved"] || labels["needs-rebase"] {
+		return false
+	}
+	for label := range labels {
+		if strings.HasPrefix(label, "do-not-merge/") {
+			return false
+		}
+	}
+	return true
+}
+
+// Print extends PRSignalReport and prints report data to the console
+func (r *PRSignalReport) Print(meta Meta, reportData ReportData) {
+	c := meta.console()
+	counts := map[string]int{}
+	for _, field := range reportData.Data {
+		c.Printf("\n\n%s (%d)\n", c.heading(field.Emoji, strings.ToUpper(field.Title)), len(field.Records))
+		for _, record := range field.Records {
+			counts[record.Status]++
+			title := record.Title
+			if field.Title != failingJobsFixesTitle {
+				title = fmt.Sprintf("#%d %s", record.ID, record.Title)
+			}
+			c.Printf("%s\n", c.heading(record.Highlight, fmt.Sprintf("%s %s", record.Status, title)))
+			if !meta.Flags.ShortOn {
+				c.Printf("- %s\n", record.URL)
+			}
+			for _, note := range record.Notes {
+				c.Printf("- %s\n", note)
+			}
+		}
+	}
+	c.Printf("\n%d unowned, %d without fix, %d fix pending, %d fix merged\n\n", counts[fixStatusUnowned], counts[fixStatusNoFix], counts[fixStatusPending], counts[fixStatusMerged])
+}
+
+// PutData extends PRSignalReport and stores the data at runtime to the struct val ReportData
+func (r *PRSignalReport) PutData(reportData ReportData) {
+	r.ReportData = reportData
+}
+
+// GetData extends PRSignalReport and returns the data that has been stored at runtime int the struct val ReportData
+func (r PRSignalReport) GetData() ReportData {
+	return r.ReportData
+}
+
+// The types below reflect the response of githubFixesQuery
+
+type graphQLFixesResponse struct {
+	Repository struct {
+		Issues struct {
+			PageInfo graphQLPageInfo   `json:"pageInfo"`
+			Nodes    []graphQLFixIssue `json:"nodes"`
+		} `json:"issues"`
+	} `json:"repository"`
+}
+
+type graphQLFixIssue struct {
+	Number int64  `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Labels struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+	Assignees struct {
+		Nodes []Assignee `json:"nodes"`
+	} `json:"assignees"`
+	TimelineItems struct {
+		Nodes []struct {
+			Subject         *graphQLFixPullRequest `json:"subject"`
+			WillCloseTarget bool                   `json:"willCloseTarget"`
+			Source          *graphQLFixPullRequest `json:"source"`
+		} `json:"nodes"`
+	} `json:"timelineItems"`
+}
+
+type graphQLFixPullRequest struct {
+	Number         int64  `json:"number"`
+	Title          string `json:"title"`
+	URL            string `json:"url"`
+	State          string `json:"state"`
+	IsDraft        bool   `json:"isDraft"`
+	Body           string `json:"body"`
+	ReviewDecision string `json:"reviewDecision"`
+	Author         *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"repository"`
+	Labels struct {
+		Nodes []Label `json:"nodes"`
+	} `json:"labels"`
+	MergeQueueEntry *struct {
+		Position int `json:"position"`
+	} `json:"mergeQueueEntry"`
+}
+
+// fixPRs returns the open and merged pull requests that reference the issue, closing pull requests first.
+// Pull requests linked via the sidebar (connected) or with a closing keyword close the issue
+func (i graphQLFixIssue) fixPRs(repo GithubRepository) []FixPR {
+	prs := []FixPR{}
+	index := map[string]int{}
+	for _, item := range i.TimelineItems.Nodes {
+		pr, closes := item.Source, item.WillCloseTarget
+		if item.Subject != nil && item.Subject.Number != 0 {
+			pr, closes = item.Subject, true
+		}
+		// timeline items that do not reference a pull request are decoded as empty objects, closed pull requests are left out
+		if pr == nil || pr.Number == 0 || pr.State == "CLOSED" {
+			continue
+		}
+		closes = closes || closesIssue(pr.Body, pr.Repository.NameWithOwner, repo, i.Number)
+		key := fmt.Sprintf("%s#%d", pr.Repository.NameWithOwner, pr.Number)
+		if j, ok := index[key]; ok {
+			prs[j].Closes = prs[j].Closes || closes
+			continue
+		}
+		labels := map[string]bool{}
+		for _, label := range pr.Labels.Nodes {
+			labels[label.Name] = true
+		}
+		author := ""
+		if pr.Author != nil {
+			author = pr.Author.Login
+		}
+		index[key] = len(prs)
+		prs = append(prs, FixPR{
+			Repository:   pr.Repository.NameWithOwner,
+			Number:       pr.Number,
+			Title:        pr.Title,
+			URL:          pr.URL,
+			Author:       author,
+			State:        strings.ToLower(pr.State),
+			Review:       prReview(*pr, labels),
+			InMergeQueue: pr.State == "OPEN" && inMergeQueue(*pr, labels),
+			Closes:       closes,
+		})
+	}
+	sort.SliceStable(prs, func(a, b int) bool { return prs[a].Closes && !prs[b].Closes })
+	return prs
+}
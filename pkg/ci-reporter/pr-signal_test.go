@@ -0,0 +1,202 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClosesIssue(t *testing.T) {
+	repo := GithubRepository{Owner: "kubernetes", Repo: "kubernetes"}
+	for body, expected := range map[string]bool{
+		"Fixes #105242":                       true,
+		"fixes: kubernetes/kubernetes#105242": true,
+		"Closes https://github.com/kubernetes/kubernetes/issues/105242": true,
+		"Fixes #1052421":                       false,
+		"Ref #105242":                          false,
+		"Fixes kubernetes/test-infra#105242":   false,
+		"Resolved #42, fixes #105242 and more": true,
+	} {
+		if closes := closesIssue(body, "kubernetes/kubernetes", repo, 105242); closes != expected {
+			t.Errorf("expected %q to close the issue: %v, got %v", body, expected, closes)
+		}
+	}
+}
+
+// prSignalTestIssues graphql response of githubFixesQuery
+const prSignalTestIssues = `{"data": {"repository": {"issues": {"pageInfo": {"hasNextPage": false, "endCursor": "c1"}, "nodes": [
+  {"number": 101, "title": "[Failing test] gce-cos-master-serial", "url": "https://github.com/kubernetes/kubernetes/issues/101",
+   "labels": {"nodes": [{"name": "kind/failing-test"}, {"name": "sig/node"}, {"name": "priority/critical-urgent"}]},
+   "assignees": {"nodes": [{"login": "alice"}]},
+   "timelineItems": {"nodes": [
+     {"willCloseTarget": false, "source": {"number": 201, "title": "Bump serial timeout", "url": "https://github.com/kubernetes/kubernetes/pull/201", "state": "OPEN", "body": "Fixes #101",
+      "author": {"login": "bob"}, "repository": {"nameWithOwner": "kubernetes/kubernetes"}, "labels": {"nodes": [{"name": "lgtm"}, {"name": "approved"}]}}},
+     {"willCloseTarget": false, "source": {"number": 202, "title": "Unrelated cleanup", "url": "https://github.com/kubernetes/kubernetes/pull/202", "state": "OPEN", "body": "see #101",
+      "author": {"login": "carol"}, "repository": {"nameWithOwner": "kubernetes/kubernetes"}, "labels": {"nodes": []}}},
+     {"willCloseTarget": true, "source": {"number": 203, "title": "Abandoned fix", "url": "https://github.com/kubernetes/kubernetes/pull/203", "state": "CLOSED", "body": "",
+      "repository": {"nameWithOwner": "kubernetes/kubernetes"}, "labels": {"nodes": []}}},
+     {}
+   ]}},
+  {"number": 102, "title": "[Flaky test] verify-master", "url": "https://github.com/kubernetes/kubernetes/issues/102",
+   "labels": {"nodes": [{"name": "kind/flake"}]}, "assignees": {"nodes": []}, "timelineItems": {"nodes": []}},
+  {"number": 103, "title": "[Flaky test] gce-cos-master-default", "url": "https://github.com/kubernetes/kubernetes/issues/103",
+   "labels": {"nodes": [{"name": "kind/flake"}]}, "assignees": {"nodes": []},
+   "timelineItems": {"nodes": [
+     {"subject": {"number": 7, "title": "Fix image", "url": "https://github.com/kubernetes/test-infra/pull/7", "state": "MERGED", "body": "",
+      "author": {"login": "dave"}, "repository": {"nameWithOwner": "kubernetes/test-infra"}, "labels": {"nodes": []}}}
+   ]}},
+  {"number": 104, "title": "[Flaky test] stale one", "url": "https://github.com/kubernetes/kubernetes/issues/104",
+   "labels": {"nodes": [{"name": "kind/flake"}, {"name": "lifecycle/stale"}]}, "assignees": {"nodes": []}, "timelineItems": {"nodes": []}}
+]}}}}`
+
+// prSignalTransport answers graphql requests with prSignalTestIssues and replays the testgrid fixtures
+type prSignalTransport struct {
+	variables map[string]interface{}
+}
+
+func (t *prSignalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.String() != githubGraphQLURL {
+		return NewReplayTransport(testFixturesDir).RoundTrip(req)
+	}
+	var payload struct {
+		Variables map[string]interface{} `json:"variables"`
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	t.variables = payload.Variables
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(prSignalTestIssues)), Request: req}, nil
+}
+
+func TestPRSignalReportRequestData(t *testing.T) {
+	meta := newTestMeta(metaFlags{EmojisOff: true})
+	transport := &prSignalTransport{}
+	meta.HTTPClient = &http.Client{Transport: transport}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&PRSignalReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(transport.variables["labels"], []interface{}{"kind/failing-test", "kind/flake"}) {
+		t.Errorf("expected failing-test and flake issues to be requested, got %v", transport.variables)
+	}
+	if len(reportData.Data) != 2 || reportData.Data[0].Title != failingJobsFixesTitle || reportData.Data[1].Title != "kubernetes/kubernetes" {
+		t.Fatalf("expected the failing jobs and one section per repository, got %+v", reportData.Data)
+	}
+
+	jobs := map[string]ReportDataRecord{}
+	for _, record := range reportData.Data[0].Records {
+		jobs[record.Title] = record
+	}
+	expectedJobs := map[string]string{
+		"gce-cos-master-serial":          fixStatusPending,
+		"verify-master":                  fixStatusUnowned,
+		"gce-cos-master-default":         fixStatusMerged,
+		"post-release-push-image-setcap": fixStatusUnowned,
+	}
+	if len(jobs) != len(expectedJobs) {
+		t.Errorf("expected the failing and flaky jobs %v, got %v", expectedJobs, jobs)
+	}
+	for job, status := range expectedJobs {
+		if jobs[job].Status != status {
+			t.Errorf("expected %s to be %s, got %s (%v)", job, status, jobs[job].Status, jobs[job].Notes)
+		}
+	}
+	serial := jobs["gce-cos-master-serial"]
+	expectedNotes := []string{"FAILING in Master-Informing", "Tracked in #101", "Fix #201 Bump serial timeout (@bob, lgtm, approved, in merge queue)", "Referenced by #202 (open)"}
+	if !reflect.DeepEqual(serial.Notes, expectedNotes) || !reflect.DeepEqual(serial.Sigs, []string{"sig-node"}) {
+		t.Errorf("expected notes %v and sig-node, got %v %v", expectedNotes, serial.Notes, serial.Sigs)
+	}
+	if last := jobs["post-release-push-image-setcap"].Notes; last[len(last)-1] != "No kind/failing-test or kind/flake issue found" {
+		t.Errorf("expected an untracked job, got %v", last)
+	}
+
+	issues := []string{}
+	for _, record := range reportData.Data[1].Records {
+		issues = append(issues, record.Status)
+	}
+	// unowned issues first, the stale issue is left out
+	if !reflect.DeepEqual(issues, []string{fixStatusUnowned, fixStatusPending, fixStatusMerged}) {
+		t.Errorf("expected issues ordered by fix status, got %v", issues)
+	}
+	if prs := reportData.Data[1].Records[1].FixPRs; len(prs) != 2 || !prs[0].Closes || prs[1].Closes {
+		t.Errorf("expected the closing pull request first and the closed one to be left out, got %+v", prs)
+	}
+	if merged := reportData.Data[1].Records[2].Notes; !reflect.DeepEqual(merged, []string{"Fix merged: kubernetes/test-infra#7 Fix image"}) {
+		t.Errorf("expected the merged fix of another repository, got %v", merged)
+	}
+
+	var buf bytes.Buffer
+	meta.Console = NewConsole(&buf, true, true)
+	(&PRSignalReport{}).Print(meta, reportData)
+	if !strings.Contains(buf.String(), "FIX PENDING #101 [Failing test] gce-cos-master-serial") || !strings.Contains(buf.String(), "3 unowned, 0 without fix, 2 fix pending, 2 fix merged") {
+		t.Errorf("unexpected print-out\n%s", buf.String())
+	}
+}
+
+func TestPRSignalOutput(t *testing.T) {
+	report := Report{{Name: prSignalReport, Data: []ReportDataField{
+		{Title: failingJobsFixesTitle, Records: []ReportDataRecord{{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: fixStatusUnowned}}},
+		{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 101, Title: "[Failing test] gce-cos-master-serial", Status: fixStatusPending,
+			FixPRs: []FixPR{{Repository: "kubernetes/kubernetes", Number: 201, State: "open", Review: "lgtm", Closes: true}}}}},
+	}}}
+	data, err := json.Marshal(report.Output(time.Date(2021, 11, 4, 10, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations, err := ValidateReport(data)
+	if err != nil || len(violations) > 0 {
+		t.Fatalf("expected a valid report, got %v (%v)", violations, err)
+	}
+	sections := report.Output(time.Date(2021, 11, 4, 10, 0, 0, 0, time.UTC)).Sources[0].Sections
+	if sections[0].Title != failingJobsFixesTitle || sections[0].Records[0].Kind != "job" {
+		t.Errorf("expected the failing jobs first, got %+v", sections[0])
+	}
+	if issue := sections[1].Records[0]; issue.Kind != "issue" || issue.Number != 101 || len(issue.FixPRs) != 1 || issue.FixPRs[0].Number != 201 {
+		t.Errorf("expected the issue with its fix, got %+v", issue)
+	}
+	if md := MarkdownReport(Meta{Flags: metaFlags{EmojisOff: true}}, report); !strings.Contains(md, "**FIX PENDING** #101 [Failing test] gce-cos-master-serial") {
+		t.Errorf("expected the issue number in the markdown report, got\n%s", md)
+	}
+}
+
+func TestJobFixRecordMatchesWholeJobNames(t *testing.T) {
+	repo := GithubRepository{Owner: "kubernetes", Repo: "kubernetes"}
+	tracked := []trackedIssue{
+		{repo: repo, issue: GithubIssueElement{Number: 1, Title: "[Failing Test] ci-kubernetes-e2e-gce-master-default-serial"}, prs: []FixPR{{Number: 11, State: "OPEN"}}},
+		{repo: repo, issue: GithubIssueElement{Number: 2, Title: "[Flaky Test] CI-Kubernetes-E2E-GCE-Master-Default: pods are not ready"}, prs: []FixPR{{Number: 12, State: "OPEN"}}},
+	}
+	record := jobFixRecord(Meta{}, "ci-kubernetes-e2e-gce-master-default", "", "FAILING", tracked, false)
+	if len(record.FixPRs) != 1 || record.FixPRs[0].Number != 12 {
+		t.Errorf("expected only the fix of the issue that names the job, got %+v", record.FixPRs)
+	}
+	if record.Notes[1] != "Tracked in #2" {
+		t.Errorf("expected the job to be tracked in #2 only, got %v", record.Notes)
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// testgrid test status values used in the table of a job (see testgrid TestStatus)
+const (
+	testgridStatusNoResult       = 0
+	testgridStatusPass           = 1
+	testgridStatusPassWithErrors = 2
+	testgridStatusPassWithSkips  = 3
+	testgridStatusRunning        = 4
+	testgridStatusFlaky          = 13
+	testgridOverallRow           = "Overall"
+)
+
+// only runs of this time before the latest run are assessed
+const promotionWindow = 7 * 24 * time.Hour
+
+// PromotionCriteria documented criteria an informing job has to meet to be promoted to release-blocking
+// (https://github.com/kubernetes/sig-release/blob/master/release-blocking-jobs.md)
+type PromotionCriteria struct {
+	// MinPassRate share of runs of the last week that passed
+	MinPassRate float64
+	// MaxRuntime median time a run may take
+	MaxRuntime time.Duration
+	// MaxRunInterval time between two runs
+	MaxRunInterval time.Duration
+	// MaxConsecutiveFailures number of runs in a row that may fail
+	MaxConsecutiveFailures int
+}
+
+// DefaultPromotionCriteria returns the criteria documented for release-blocking jobs
+func DefaultPromotionCriteria() PromotionCriteria {
+	return PromotionCriteria{
+		MinPassRate:            0.75,
+		MaxRuntime:             120 * time.Minute,
+		MaxRunInterval:         3 * time.Hour,
+		MaxConsecutiveFailures: 10,
+	}
+}
+
+// PromotionCheck result of one promotion criterion
+type PromotionCheck struct {
+	Criterion string
+	Passed    bool
+	Detail    string
+}
+
+// PromotionAssessment readiness of a job to be promoted to release-blocking
+type PromotionAssessment struct {
+	Dashboard string
+	Job       string
+	Runs      int
+	Checks    []PromotionCheck
+}
+
+// Ready tells if all criteria are met
+func (a PromotionAssessment) Ready() bool {
+	for _, c := range a.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return len(a.Checks) > 0
+}
+
+func (a PromotionAssessment) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Promotion readiness of %s (%s), %d runs of the last week\n\n", a.Job, a.Dashboard, a.Runs))
+	for _, c := range a.Checks {
+		result := "FAIL"
+		if c.Passed {
+			result = "PASS"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s: %s\n", result, c.Criterion, c.Detail))
+	}
+	if a.Ready() {
+		sb.WriteString("\nThe job meets all criteria to be promoted to release-blocking.\n")
+	} else {
+		sb.WriteString("\nThe job is not ready to be promoted to release-blocking.\n")
+	}
+	return sb.String()
+}
+
+// testgridTable the run history of a job (e.g. https://testgrid.k8s.io/sig-release-master-informing/table?tab=gce-cos-master-serial)
+type testgridTable struct {
+	Description string `json:"description"`
+	// Timestamps start of each run in milliseconds, the latest run first
+	Timestamps []int64             `json:"timestamps"`
+	Tests      []testgridTableTest `json:"tests"`
+}
+
+// testgridTableTest one row of the table, the "Overall" row contains the result of each run
+type testgridTableTest struct {
+	Name string `json:"name"`
+	// ShortTexts text of each cell, the "Overall" row shows the runtime like "47m"
+	ShortTexts []string `json:"short_texts"`
+	// Statuses run length encoded status of each run
+	Statuses []testgridTableStatus `json:"statuses"`
+}
+
+type testgridTableStatus struct {
+	Count int `json:"count"`
+	Value int `json:"value"`
+}
+
+// reqTestgridTable requests the run history of a job of a dashboard
+func reqTestgridTable(ctx context.Context, client *http.Client, testgridURL string, dashboard string, job string) (testgridTable, error) {
+	var table testgridTable
+	tableURL := fmt.Sprintf("%s/%s/table?tab=%s", testgridURL, dashboard, url.QueryEscape(job))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tableURL, nil)
+	if err != nil {
+		return table, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return table, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return table, fmt.Errorf("requesting %s failed with status %s", tableURL, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return table, err
+	}
+	err = json.Unmarshal(body, &table)
+	return table, err
+}
+
+// RequestPromotionAssessment requests the run history of a job from testgrid and assesses it against the criteria
+func RequestPromotionAssessment(client *http.Client, testgridURL string, dashboard string, job string, criteria PromotionCriteria) (PromotionAssessment, error) {
+	table, err := reqTestgridTable(context.Background(), httpClientOrDefault(client), strings.TrimSuffix(testgridURL, "/"), dashboard, job)
+	if err != nil {
+		return PromotionAssessment{}, err
+	}
+	return assessPromotion(dashboard, job, table, criteria), nil
+}
+
+// promotionRun one finished run of a job
+type promotionRun struct {
+	startedAt time.Time
+	passed    bool
+	runtime   time.Duration
+	// hasRuntime is false if the runtime could not be read from the table
+	hasRuntime bool
+}
+
+// assessPromotion checks the runs of the last week against the criteria
+func assessPromotion(dashboard string, job string, table testgridTable, criteria PromotionCriteria) PromotionAssessment {
+	runs := promotionRuns(table)
+	assessment := PromotionAssessment{Dashboard: dashboard, Job: job, Runs: len(runs)}
+	if len(runs) == 0 {
+		assessment.Checks = append(assessment.Checks, PromotionCheck{Criterion: "runs", Detail: "no finished runs found in the last week"})
+		return assessment
+	}
+
+	passes, consecutiveFailures, maxConsecutiveFailures := 0, 0, 0
+	runtimes := []time.Duration{}
+	var maxInterval time.Duration
+	for i, r := range runs {
+		if r.passed {
+			passes++
+			consecutiveFailures = 0
+		} else {
+			consecutiveFailures++
+			if consecutiveFailures > maxConsecutiveFailures {
+				maxConsecutiveFailures = consecutiveFailures
+			}
+		}
+		if r.hasRuntime {
+			runtimes = append(runtimes, r.runtime)
+		}
+		if i > 0 {
+			if interval := runs[i-1].startedAt.Sub(r.startedAt); interval > maxInterval {
+				maxInterval = interval
+			}
+		}
+	}
+
+	passRate := float64(passes) / float64(len(runs))
+	assessment.Checks = append(assessment.Checks, PromotionCheck{
+		Criterion: "pass rate",
+		Passed:    passRate >= criteria.MinPassRate,
+		Detail:    fmt.Sprintf("%.0f%% of runs passed (%d of %d), at least %.0f%% required", passRate*100, passes, len(runs), criteria.MinPassRate*100),
+	})
+	assessment.Checks = append(assessment.Checks, PromotionCheck{
+		Criterion: "consecutive failures",
+		Passed:    maxConsecutiveFailures <= criteria.MaxConsecutiveFailures,
+		Detail:    fmt.Sprintf("at most %d runs failed in a row, %d allowed", maxConsecutiveFailures, criteria.MaxConsecutiveFailures),
+	})
+
+	runtimeCheck := PromotionCheck{Criterion: "runtime", Detail: "runtime of the runs is unknown"}
+	if len(runtimes) > 0 {
+		sort.Slice(runtimes, func(i, j int) bool { return runtimes[i] < runtimes[j] })
+		median := runtimes[len(runtimes)/2]
+		runtimeCheck.Passed = median <= criteria.MaxRuntime
+		runtimeCheck.Detail = fmt.Sprintf("runs take %s (median, longest %s), at most %s allowed", median, runtimes[len(runtimes)-1], criteria.MaxRuntime)
+	}
+	assessment.Checks = append(assessment.Checks, runtimeCheck)
+
+	frequencyCheck := PromotionCheck{Criterion: "frequency", Detail: "only one run in the last week"}
+	if len(runs) > 1 {
+		frequencyCheck.Passed = maxInterval <= criteria.MaxRunInterval
+		frequencyCheck.Detail = fmt.Sprintf("up to %s between two runs, at most %s allowed", maxInterval, criteria.MaxRunInterval)
+	}
+	assessment.Checks = append(assessment.Checks, frequencyCheck)
+
+	ownerCheck := PromotionCheck{Criterion: "ownership", Detail: "the job description does not name an owning sig"}
+	if sigs := extractSigs(table.Description); len(sigs) > 0 {
+		ownerCheck.Passed = true
+		ownerCheck.Detail = fmt.Sprintf("owned by %s", strings.Join(sigs, ", "))
+	}
+	assessment.Checks = append(assessment.Checks, ownerCheck)
+	return assessment
+}
+
+// promotionRuns returns the finished runs of the last week from the overall row of the table, the latest run first
+func promotionRuns(table testgridTable) []promotionRun {
+	var overall *testgridTableTest
+	for i := range table.Tests {
+		if table.Tests[i].Name == testgridOverallRow {
+			overall = &table.Tests[i]
+		}
+	}
+	if overall == nil || len(table.Timestamps) == 0 {
+		return nil
+	}
+	latest := time.Unix(0, table.Timestamps[0]*int64(time.Millisecond))
+	runs := []promotionRun{}
+	column := 0
+	for _, status := range overall.Statuses {
+		for i := 0; i < status.Count; i++ {
+			if column >= len(table.Timestamps) {
+				return runs
+			}
+			startedAt := time.Unix(0, table.Timestamps[column]*int64(time.Millisecond))
+			if latest.Sub(startedAt) > promotionWindow {
+				return runs
+			}
+			if status.Value != testgridStatusNoResult && status.Value != testgridStatusRunning {
+				run := promotionRun{startedAt: startedAt, passed: isPassingStatus(status.Value)}
+				if column < len(overall.ShortTexts) {
+					if runtime, err := time.ParseDuration(overall.ShortTexts[column]); err == nil {
+						run.runtime, run.hasRuntime = runtime, true
+					}
+				}
+				runs = append(runs, run)
+			}
+			column++
+		}
+	}
+	return runs
+}
+
+// isPassingStatus tells if a run with the testgrid status passed, runs that passed with errors or skipped tests passed as well
+func isPassingStatus(value int) bool {
+	switch value {
+	case testgridStatusPass, testgridStatusPassWithErrors, testgridStatusPassWithSkips, testgridStatusFlaky:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPromotionTable returns a table with one run every hour, the latest run first
+func testPromotionTable(statuses []testgridTableStatus, shortText string) testgridTable {
+	table := testgridTable{Description: "Owned by sig-node, runs serial node tests"}
+	latest := time.Date(2021, 11, 4, 12, 0, 0, 0, time.UTC)
+	overall := testgridTableTest{Name: testgridOverallRow, Statuses: statuses}
+	for _, s := range statuses {
+		for i := 0; i < s.Count; i++ {
+			table.Timestamps = append(table.Timestamps, latest.Add(-time.Duration(len(table.Timestamps))*time.Hour).UnixNano()/int64(time.Millisecond))
+			overall.ShortTexts = append(overall.ShortTexts, shortText)
+		}
+	}
+	table.Tests = []testgridTableTest{{Name: "Kubernetes e2e suite.[sig-node] Pods"}, overall}
+	return table
+}
+
+func TestAssessPromotion(t *testing.T) {
+	criteria := DefaultPromotionCriteria()
+
+	ready := assessPromotion("sig-release-master-informing", "job", testPromotionTable([]testgridTableStatus{
+		{Count: 1, Value: testgridStatusRunning},
+		{Count: 8, Value: testgridStatusPass},
+		{Count: 2, Value: 12},
+		{Count: 10, Value: testgridStatusPass},
+	}, "47m"), criteria)
+	if !ready.Ready() {
+		t.Errorf("expected job to be ready for promotion, got\n%s", ready)
+	}
+	if ready.Runs != 20 {
+		t.Errorf("expected running columns not to be assessed, got %d runs", ready.Runs)
+	}
+
+	notReady := assessPromotion("sig-release-master-informing", "job", testPromotionTable([]testgridTableStatus{
+		{Count: 12, Value: 12},
+		{Count: 8, Value: testgridStatusPass},
+	}, "2h30m"), criteria)
+	failed := []string{}
+	for _, c := range notReady.Checks {
+		if !c.Passed {
+			failed = append(failed, c.Criterion)
+		}
+	}
+	if strings.Join(failed, ",") != "pass rate,consecutive failures,runtime" {
+		t.Errorf("expected pass rate, consecutive failures and runtime to fail, got %v\n%s", failed, notReady)
+	}
+}
+
+func TestPromotionRunsPassingStatuses(t *testing.T) {
+	runs := promotionRuns(testPromotionTable([]testgridTableStatus{
+		{Count: 1, Value: testgridStatusPassWithErrors},
+		{Count: 1, Value: testgridStatusPassWithSkips},
+		{Count: 1, Value: testgridStatusFlaky},
+		{Count: 1, Value: testgridStatusPass},
+		{Count: 1, Value: 12},
+	}, ""))
+	passed := []bool{}
+	for _, r := range runs {
+		passed = append(passed, r.passed)
+	}
+	if expected := []bool{true, true, true, true, false}; !reflect.DeepEqual(passed, expected) {
+		t.Errorf("expected runs that passed with errors or skips to pass, got %v", passed)
+	}
+}
+
+func TestPromotionRunsWindow(t *testing.T) {
+	// 200 hourly runs, only the runs of the last week are assessed
+	runs := promotionRuns(testPromotionTable([]testgridTableStatus{{Count: 200, Value: testgridStatusPass}}, ""))
+	if len(runs) != 169 {
+		t.Errorf("expected 169 runs within a week, got %d", len(runs))
+	}
+	if runs[0].hasRuntime {
+		t.Error("expected runtime to be unknown if the cell text is not a duration")
+	}
+}
+
+func TestRequestPromotionAssessment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sig-release-master-informing/table" || r.URL.Query().Get("tab") != "gce-cos-master-serial" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(testPromotionTable([]testgridTableStatus{{Count: 24, Value: testgridStatusPass}}, "1h5m"))
+	}))
+	defer server.Close()
+
+	assessment, err := RequestPromotionAssessment(server.Client(), server.URL+"/", "sig-release-master-informing", "gce-cos-master-serial", DefaultPromotionCriteria())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assessment.Ready() || assessment.Runs != 24 {
+		t.Errorf("expected job with 24 passing runs to be ready, got\n%s", assessment)
+	}
+	if _, err := RequestPromotionAssessment(server.Client(), server.URL, "sig-release-master-informing", "unknown", DefaultPromotionCriteria()); err == nil {
+		t.Error("expected an error for an unknown job")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// jobs that do not run on one of the known cloud providers (like verify or build jobs)
+const otherProvider = "other"
+
+// cloudProviderTokens job name tokens that identify the cloud provider a job runs on, the first matching provider wins
+var cloudProviderTokens = []struct {
+	provider string
+	tokens   []string
+}{
+	{provider: "gce", tokens: []string{"gce", "gci"}},
+	{provider: "gke", tokens: []string{"gke"}},
+	{provider: "aws", tokens: []string{"aws", "eks", "ec2"}},
+	{provider: "azure", tokens: []string{"azure", "aks", "capz"}},
+	{provider: "kind", tokens: []string{"kind", "kinder"}},
+}
+
+// ProviderReport used to implement RequestData & Print for the job health per cloud provider
+type ProviderReport struct {
+	ReportData ReportData
+}
+
+// providerJob a job of a dashboard that runs on a cloud provider or platform
+type providerJob struct {
+	name       string
+	jobBaseURL string
+	data       testgridValue
+}
+
+// RequestData this function is used to group the jobs of all dashboards by the cloud provider they run on
+func (r *ProviderReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	dashboards := meta.Flags.dashboards()
+	client := httpClientOrDefault(meta.HTTPClient)
+	dashboardJobs, err := meta.requestDashboards(ctx, client, dashboards)
+	if err != nil {
+		return ReportData{Name: providerReport}, fmt.Errorf("requesting dashboards: %v", err)
+	}
+
+	providerJobs := map[string][]providerJob{}
+	for i, dashboard := range dashboards {
+		jobBaseURL := fmt.Sprintf("%s/%s", meta.Flags.testgridURL(), dashboard.URLName)
+		for jobName, jobData := range dashboardJobs[i] {
+			provider := cloudProvider(jobName)
+			providerJobs[provider] = append(providerJobs[provider], providerJob{name: jobName, jobBaseURL: jobBaseURL, data: jobData})
+		}
+	}
+	c := make(chan ReportDataField)
+	go func() {
+		defer close(c)
+		for _, provider := range sortedProviders(providerJobs) {
+			c <- jobGroupSummary(meta, provider, providerJobs[provider])
+		}
+	}()
+	return meta.DataPostProcessing(r, providerReport, c, wg), nil
+}
+
+// cloudProvider parses the cloud provider from a job name (like "gce-cos-master-default" -> "gce")
+func cloudProvider(jobName string) string {
+	tokens := jobNameTokens(jobName)
+	for _, p := range cloudProviderTokens {
+		for _, token := range tokens {
+			for _, providerToken := range p.tokens {
+				if token == providerToken {
+					return p.provider
+				}
+			}
+		}
+	}
+	return otherProvider
+}
+
+// jobNameTokens splits a job name into lower case tokens (like "ci-kubernetes-e2e-gci-gce" -> [ci kubernetes e2e gci gce])
+func jobNameTokens(jobName string) []string {
+	return strings.FieldsFunc(strings.ToLower(jobName), func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+}
+
+// sortedProviders returns the known providers in the order of cloudProviderTokens, jobs of other providers are listed last
+func sortedProviders(providerJobs map[string][]providerJob) []string {
+	providers := []string{}
+	for _, p := range cloudProviderTokens {
+		if _, ok := providerJobs[p.provider]; ok {
+			providers = append(providers, p.provider)
+		}
+	}
+	if _, ok := providerJobs[otherProvider]; ok {
+		providers = append(providers, otherProvider)
+	}
+	return providers
+}
+
+// jobGroupSummary counts the job statuses and the recent pass rate of the jobs of a provider or platform, failing and flaky jobs are listed to route them to their owners
+func jobGroupSummary(meta Meta, title string, jobs []providerJob) ReportDataField {
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].name < jobs[j].name })
+	jobsData := map[string]testgridValue{}
+	var passes, runs float64
+	for _, job := range jobs {
+		jobsData[job.jobBaseURL+"#"+job.name] = job.data
+		jobPasses, jobRuns := parseRecentRuns(meta.logger(), job.data.Status)
+		passes += jobPasses
+		runs += jobRuns
+	}
+	summary := getSummary(jobsData)
+	if runs > 0 {
+		passRate := passes / runs
+		summary.RecentPassRate = &passRate
+		summary.Notes = append(summary.Notes, fmt.Sprintf("%.1f%% of recent runs passed (%g of %g)", passRate*100, passes, runs))
+	}
+	records := []ReportDataRecord{summary}
+	if !meta.Flags.ShortOn {
+		for _, job := range jobs {
+			if job.data.OverallStatus == failing || job.data.OverallStatus == flaky {
+				records = append(records, getDetails(meta, job.name, job.data, job.jobBaseURL))
+			}
+		}
+	}
+	return ReportDataField{Title: title, Records: records}
+}
+
+// Print extends ProviderReport and prints report data to the console
+func (r *ProviderReport) Print(meta Meta, reportData ReportData) {
+	c := meta.console()
+	c.Print("\n\nCLOUD PROVIDERS\n")
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			if record.ID == testgridReportSummary {
+				c.Printf("\n%s\n", strings.ToUpper(field.Title))
+				for _, note := range record.Notes {
+					c.Printf("- %s\n", note)
+				}
+				continue
+			}
+			c.Printf("%s\n", c.recordLine(record))
+			c.Printf("- %s\n", record.URL)
+		}
+	}
+	c.Println()
+}
+
+// PutData extends ProviderReport and stores the data at runtime to the struct val ReportData
+func (r *ProviderReport) PutData(reportData ReportData) {
+	r.ReportData = reportData
+}
+
+// GetData extends ProviderReport and returns the data that has been stored at runtime int the struct val ReportData
+func (r ProviderReport) GetData() ReportData {
+	return r.ReportData
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCloudProvider(t *testing.T) {
+	for jobName, expected := range map[string]string{
+		"gce-cos-master-default":              "gce",
+		"ci-kubernetes-e2e-gci-gce":           "gce",
+		"ci-kubernetes-e2e-gke-stable":        "gke",
+		"ci-kubernetes-e2e-ec2-eks":           "aws",
+		"capz-conformance-master":             "azure",
+		"ci-kubernetes-kind-e2e-parallel":     "kind",
+		"kubeadm-kinder-latest":               "kind",
+		"verify-master":                       "other",
+		"ci-kubernetes-e2e-gce-kindergarten0": "gce",
+	} {
+		if provider := cloudProvider(jobName); provider != expected {
+			t.Errorf("expected provider of %s to be %s, got %s", jobName, expected, provider)
+		}
+	}
+}
+
+func TestProviderReportRequestData(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&ProviderReport{}).RequestData(context.Background(), newTestMeta(metaFlags{}), &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providers := []string{}
+	for _, field := range reportData.Data {
+		providers = append(providers, field.Title)
+	}
+	if !reflect.DeepEqual(providers, []string{"gce", "kind", "other"}) {
+		t.Fatalf("expected providers [gce kind other], got %v", providers)
+	}
+	gce := reportData.Data[0]
+	summary := gce.Records[0]
+	if summary.ID != testgridReportSummary || summary.Counts["total"] != 2 || summary.Counts["failing"] != 1 || summary.Counts["flaky"] != 1 {
+		t.Errorf("unexpected gce summary %+v", summary)
+	}
+	if summary.RecentPassRate == nil || *summary.RecentPassRate != 3.0/18 {
+		t.Errorf("expected a recent pass rate of 3 of 18 runs, got %v", summary.RecentPassRate)
+	}
+	jobs := []string{}
+	for _, record := range gce.Records[1:] {
+		jobs = append(jobs, record.Title)
+	}
+	if !reflect.DeepEqual(jobs, []string{"gce-cos-master-serial", "gce-cos-master-default"}) {
+		t.Errorf("expected failing and flaky gce jobs to be listed by severity, got %v", jobs)
+	}
+	if len(reportData.Data[1].Records) != 1 {
+		t.Errorf("expected only the summary of passing kind jobs, got %+v", reportData.Data[1].Records)
+	}
+}
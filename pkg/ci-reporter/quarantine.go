@@ -0,0 +1,313 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	quarantinedTestsTitle  = "Quarantined tests"
+	quarantineChangesTitle = "Quarantine changes"
+	statusQuarantined      = "QUARANTINED"
+	statusAdded            = "ADDED"
+	statusRemoved          = "REMOVED"
+)
+
+// quarantineTagRegex tags of tests that are skipped by release-blocking jobs (like "[Flaky]" or "[Feature:Flaky]")
+var quarantineTagRegex = regexp.MustCompile(`\[(Feature:)?(Flaky|Quarantined?)\]`)
+
+// QuarantineReport used to implement RequestData & Print for tests that are quarantined via tags or a skip list
+type QuarantineReport struct {
+	ReportData ReportData
+}
+
+// RequestData this function is used to collect the quarantined tests of all dashboards and to compare them with the previous snapshots
+func (r *QuarantineReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	dashboards := meta.Flags.dashboards()
+	client := httpClientOrDefault(meta.HTTPClient)
+	dashboardJobs, err := meta.requestDashboards(ctx, client, dashboards)
+	if err != nil {
+		return ReportData{Name: quarantineReport}, fmt.Errorf("requesting dashboards: %v", err)
+	}
+	tableTests, err := requestTaggedTableTests(ctx, meta, client, dashboards, dashboardJobs)
+	if err != nil {
+		return ReportData{Name: quarantineReport}, fmt.Errorf("requesting job tables: %v", err)
+	}
+	skipList := []string{}
+	if meta.Flags.QuarantineList != "" {
+		if skipList, err = LoadQuarantineList(meta.Flags.QuarantineList); err != nil {
+			return ReportData{Name: quarantineReport}, fmt.Errorf("loading quarantine list: %v", err)
+		}
+	}
+	history := []Snapshot{}
+	if meta.Flags.SnapshotDir != "" {
+		snapshots, err := LoadSnapshots(meta.logger(), meta.Flags.SnapshotDir, time.Time{}, time.Time{})
+		if err != nil && !os.IsNotExist(err) {
+			return ReportData{Name: quarantineReport}, fmt.Errorf("reading report snapshots: %v", err)
+		}
+		history = snapshots
+	}
+
+	tests := quarantinedTests(dashboards, dashboardJobs, tableTests, skipList)
+	c := make(chan ReportDataField)
+	go func() {
+		defer close(c)
+		for _, field := range quarantineFields(tests, history, time.Now()) {
+			c <- field
+		}
+	}()
+	return meta.DataPostProcessing(r, quarantineReport, c, wg), nil
+}
+
+// LoadQuarantineList reads a skip list, one test name per line (empty lines and lines starting with # are ignored)
+func LoadQuarantineList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tests := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			tests = append(tests, line)
+		}
+	}
+	return tests, scanner.Err()
+}
+
+// requestTaggedTableTests requests the table of every job and returns the tests that carry a quarantine tag per job of each dashboard.
+// Tagged tests are skipped, so they are not part of the failing or flaky tests of the dashboard summary, the table lists them
+func requestTaggedTableTests(ctx context.Context, meta Meta, client *http.Client, dashboards []testgridJob, dashboardJobs []TestgridData) ([]map[string][]string, error) {
+	type tableJob struct {
+		dashboard int
+		name      string
+	}
+	jobs := []tableJob{}
+	for i := range dashboards {
+		for name := range dashboardJobs[i] {
+			jobs = append(jobs, tableJob{dashboard: i, name: name})
+		}
+	}
+	tagged := make([][]string, len(jobs))
+	errs := runWorkerPool(meta.Flags.concurrency(), len(jobs), func(i int) error {
+		table, err := reqTestgridTable(ctx, client, meta.Flags.testgridURL(), dashboards[jobs[i].dashboard].URLName, jobs[i].name)
+		if err != nil {
+			return err
+		}
+		for _, t := range table.Tests {
+			if quarantineTagRegex.MatchString(t.Name) {
+				tagged[i] = append(tagged[i], t.Name)
+			}
+		}
+		return nil
+	})
+	if err := collectWorkerErrors(errs); err != nil {
+		return nil, err
+	}
+	tableTests := make([]map[string][]string, len(dashboards))
+	for i := range tableTests {
+		tableTests[i] = map[string][]string{}
+	}
+	for i, job := range jobs {
+		tableTests[job.dashboard][job.name] = tagged[i]
+	}
+	return tableTests, nil
+}
+
+// quarantinedTests returns the tests of all dashboards that carry a quarantine tag (found in the dashboard summary or the job tables)
+// and the tests of the skip list, each test points to where it has been found
+func quarantinedTests(dashboards []testgridJob, dashboardJobs []TestgridData, tableTests []map[string][]string, skipList []string) map[string][]string {
+	tests := map[string][]string{}
+	add := func(name string, source string) {
+		for _, s := range tests[name] {
+			if s == source {
+				return
+			}
+		}
+		tests[name] = append(tests[name], source)
+	}
+	for i, dashboard := range dashboards {
+		for jobName, jobData := range dashboardJobs[i] {
+			source := fmt.Sprintf("Tagged in %s (%s)", jobName, dashboard.OutputName)
+			for _, t := range jobData.Tests {
+				if quarantineTagRegex.MatchString(t.TestName) {
+					add(t.TestName, source)
+				}
+			}
+			for _, t := range jobData.Healthiness.Tests {
+				if quarantineTagRegex.MatchString(t.DisplayName) {
+					add(t.DisplayName, source)
+				}
+			}
+			if i < len(tableTests) {
+				for _, name := range tableTests[i][jobName] {
+					add(name, source)
+				}
+			}
+		}
+	}
+	for _, name := range skipList {
+		add(name, "Listed in the skip list")
+	}
+	for _, sources := range tests {
+		sort.Strings(sources)
+	}
+	return tests
+}
+
+// quarantineFields lists the quarantined tests with the time they have been quarantined since and the changes since the previous snapshot
+func quarantineFields(tests map[string][]string, history []Snapshot, now time.Time) []ReportDataField {
+	// since tracks when each test has been quarantined continuously since, snapshots without quarantine data are skipped
+	since := map[string]time.Time{}
+	var previous map[string]bool
+	var previousAt time.Time
+	for _, snapshot := range history {
+		quarantined, ok := snapshotQuarantine(snapshot.Report)
+		if !ok {
+			continue
+		}
+		for name := range since {
+			if !quarantined[name] {
+				delete(since, name)
+			}
+		}
+		for name := range quarantined {
+			if _, ok := since[name]; !ok {
+				since[name] = snapshot.GeneratedAt
+			}
+		}
+		previous, previousAt = quarantined, snapshot.GeneratedAt
+	}
+
+	names := []string{}
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	added, removed := []string{}, []string{}
+	if previous != nil {
+		for _, name := range names {
+			if !previous[name] {
+				added = append(added, name)
+			}
+		}
+		for name := range previous {
+			if _, ok := tests[name]; !ok {
+				removed = append(removed, name)
+			}
+		}
+		sort.Strings(removed)
+	}
+
+	summary := ReportDataRecord{
+		ID:     testgridReportSummary,
+		Counts: map[string]int{"quarantined": len(names), "added": len(added), "removed": len(removed)},
+		Notes:  []string{fmt.Sprintf("%d tests quarantined", len(names))},
+	}
+	if previous != nil {
+		summary.Notes = append(summary.Notes, fmt.Sprintf("%d added, %d removed since %s", len(added), len(removed), previousAt.UTC().Format("2006-01-02 15:04")))
+	}
+	records := []ReportDataRecord{summary}
+	for _, name := range names {
+		notes := append([]string{}, tests[name]...)
+		if start, ok := since[name]; ok && previous[name] {
+			notes = append(notes, fmt.Sprintf("Quarantined since %s (for %s)", start.UTC().Format("2006-01-02"), formatDays(now.Sub(start))))
+		}
+		records = append(records, ReportDataRecord{ID: testgridReportDetails, Title: name, Status: statusQuarantined, Sigs: extractSigs(name), Notes: notes})
+	}
+	fields := []ReportDataField{{Emoji: statusFlakyEmoji, Title: quarantinedTestsTitle, Records: records}}
+
+	if len(added)+len(removed) > 0 {
+		changes := []ReportDataRecord{}
+		for _, name := range added {
+			changes = append(changes, ReportDataRecord{ID: testgridReportDetails, Title: name, Status: statusAdded, Sigs: extractSigs(name)})
+		}
+		for _, name := range removed {
+			changes = append(changes, ReportDataRecord{ID: testgridReportDetails, Title: name, Status: statusRemoved, Sigs: extractSigs(name)})
+		}
+		fields = append(fields, ReportDataField{Emoji: statusNewEmoji, Title: quarantineChangesTitle, Records: changes})
+	}
+	return fields
+}
+
+// snapshotQuarantine returns the quarantined tests stored in a report, ok is false if the quarantine report has not been requested
+func snapshotQuarantine(report Report) (quarantined map[string]bool, ok bool) {
+	for _, reportData := range report {
+		if reportData.Name != quarantineReport {
+			continue
+		}
+		quarantined = map[string]bool{}
+		for _, field := range reportData.Data {
+			if field.Title != quarantinedTestsTitle {
+				continue
+			}
+			for _, record := range field.Records {
+				if record.ID == testgridReportDetails {
+					quarantined[record.Title] = true
+				}
+			}
+		}
+		return quarantined, true
+	}
+	return nil, false
+}
+
+// Print extends QuarantineReport and prints report data to the console
+func (r *QuarantineReport) Print(meta Meta, reportData ReportData) {
+	c := meta.console()
+	for _, field := range reportData.Data {
+		c.Printf("\n\n%s\n", c.heading(field.Emoji, strings.ToUpper(field.Title)))
+		for _, record := range field.Records {
+			if record.ID == testgridReportSummary {
+				for _, note := range record.Notes {
+					c.Printf("- %s\n", note)
+				}
+				c.Print("\n")
+				continue
+			}
+			c.Printf("%s %s\n", record.Status, record.Title)
+			if !meta.Flags.ShortOn {
+				for _, note := range record.Notes {
+					c.Printf("- %s\n", note)
+				}
+			}
+		}
+	}
+	c.Println()
+}
+
+// PutData extends QuarantineReport and stores the data at runtime to the struct val ReportData
+func (r *QuarantineReport) PutData(reportData ReportData) {
+	r.ReportData = reportData
+}
+
+// GetData extends QuarantineReport and returns the data that has been stored at runtime int the struct val ReportData
+func (r QuarantineReport) GetData() ReportData {
+	return r.ReportData
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadQuarantineList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skip-list.txt")
+	content := "# skipped until kubernetes/kubernetes#105000 is fixed\n[sig-node] Pods should be restarted\n\n  [sig-storage] CSI mock volume  \n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests, err := LoadQuarantineList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"[sig-node] Pods should be restarted", "[sig-storage] CSI mock volume"}
+	if !reflect.DeepEqual(tests, expected) {
+		t.Errorf("expected %v, got %v", expected, tests)
+	}
+}
+
+func TestQuarantinedTests(t *testing.T) {
+	dashboards := []testgridJob{{OutputName: "Master-Blocking", URLName: "sig-release-master-blocking"}}
+	dashboardJobs := []TestgridData{{
+		"gce-cos-master-default": testgridValue{
+			Tests: []test{{TestName: "[sig-network] DNS should resolve [Flaky]"}, {TestName: "[sig-node] Pods should be restarted"}},
+			Healthiness: healthiness{Tests: []healthinessTest{
+				{DisplayName: "[sig-apps] Deployment should roll over [Feature:Flaky]"},
+				{DisplayName: "[sig-network] DNS should resolve [Flaky]"},
+			}},
+		},
+	}}
+	tests := quarantinedTests(dashboards, dashboardJobs, nil, []string{"[sig-storage] CSI mock volume"})
+	expected := map[string][]string{
+		"[sig-network] DNS should resolve [Flaky]":               {"Tagged in gce-cos-master-default (Master-Blocking)"},
+		"[sig-apps] Deployment should roll over [Feature:Flaky]": {"Tagged in gce-cos-master-default (Master-Blocking)"},
+		"[sig-storage] CSI mock volume":                          {"Listed in the skip list"},
+	}
+	if !reflect.DeepEqual(tests, expected) {
+		t.Errorf("expected %v, got %v", expected, tests)
+	}
+}
+
+func TestQuarantineReportTableTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sig-release-master-blocking/summary":
+			fmt.Fprint(w, `{"gce-cos-master-default": {"overall_status": "PASSING", "tests": [], "healthiness": {"tests": []}}}`)
+		case "/sig-release-master-blocking/table":
+			if r.URL.Query().Get("tab") != "gce-cos-master-default" {
+				http.NotFound(w, r)
+				return
+			}
+			// skipped tests are rows of the table without results
+			fmt.Fprint(w, `{"timestamps": [1636000000000], "tests": [
+				{"name": "Overall", "statuses": [{"count": 1, "value": 1}]},
+				{"name": "Kubernetes e2e suite.[sig-network] DNS should resolve [Flaky]", "statuses": [{"count": 1, "value": 0}]},
+				{"name": "Kubernetes e2e suite.[sig-node] Pods should be restarted", "statuses": [{"count": 1, "value": 1}]}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	meta := newTestMeta(metaFlags{Quarantine: true, TestgridURL: server.URL, Dashboards: []string{"sig-release-master-blocking"}})
+	meta.HTTPClient = server.Client()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&QuarantineReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := reportData.Data[0].Records
+	if len(records) != 2 || records[1].Title != "Kubernetes e2e suite.[sig-network] DNS should resolve [Flaky]" {
+		t.Fatalf("expected the tagged test of the job table to be quarantined without a skip list, got %+v", records)
+	}
+	if expected := []string{"Tagged in gce-cos-master-default (sig-release-master-blocking)"}; !reflect.DeepEqual(records[1].Notes, expected) {
+		t.Errorf("expected notes %v, got %v", expected, records[1].Notes)
+	}
+}
+
+func TestQuarantineFields(t *testing.T) {
+	quarantineSnapshot := func(generatedAt time.Time, names ...string) Snapshot {
+		records := []ReportDataRecord{{ID: testgridReportSummary}}
+		for _, name := range names {
+			records = append(records, ReportDataRecord{ID: testgridReportDetails, Title: name})
+		}
+		return Snapshot{GeneratedAt: generatedAt, Report: Report{{Name: quarantineReport, Data: []ReportDataField{{Title: quarantinedTestsTitle, Records: records}}}}}
+	}
+	start := time.Date(2021, 9, 1, 8, 0, 0, 0, time.UTC)
+	history := []Snapshot{
+		quarantineSnapshot(start, "[sig-node] A", "[sig-node] B"),
+		quarantineSnapshot(start.Add(24*time.Hour), "[sig-node] B"),
+		// snapshots without the quarantine report do not interrupt quarantines
+		{GeneratedAt: start.Add(36 * time.Hour), Report: Report{{Name: testgridReport}}},
+		quarantineSnapshot(start.Add(48*time.Hour), "[sig-node] A", "[sig-node] B", "[sig-node] C"),
+	}
+	tests := map[string][]string{"[sig-node] A": {"Listed in the skip list"}, "[sig-node] B": {"Listed in the skip list"}, "[sig-node] D": {"Listed in the skip list"}}
+	fields := quarantineFields(tests, history, start.Add(10*24*time.Hour))
+
+	if len(fields) != 2 || fields[0].Title != quarantinedTestsTitle || fields[1].Title != quarantineChangesTitle {
+		t.Fatalf("expected quarantined tests and their changes, got %+v", fields)
+	}
+	summary := fields[0].Records[0]
+	if !reflect.DeepEqual(summary.Counts, map[string]int{"quarantined": 3, "added": 1, "removed": 1}) {
+		t.Errorf("unexpected summary counts %v", summary.Counts)
+	}
+	notes := map[string][]string{}
+	for _, record := range fields[0].Records[1:] {
+		notes[record.Title] = record.Notes
+	}
+	expected := map[string][]string{
+		"[sig-node] A": {"Listed in the skip list", "Quarantined since 2021-09-03 (for 8.0 days)"},
+		"[sig-node] B": {"Listed in the skip list", "Quarantined since 2021-09-01 (for 10.0 days)"},
+		"[sig-node] D": {"Listed in the skip list"},
+	}
+	if !reflect.DeepEqual(notes, expected) {
+		t.Errorf("expected notes %v, got %v", expected, notes)
+	}
+	changes := map[string]string{}
+	for _, record := range fields[1].Records {
+		changes[record.Title] = record.Status
+	}
+	if !reflect.DeepEqual(changes, map[string]string{"[sig-node] D": statusAdded, "[sig-node] C": statusRemoved}) {
+		t.Errorf("unexpected quarantine changes %v", changes)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// names shorter than this are only matched exactly, longer names also match if they are part of another name
+const recurrenceMinContainsLength = 12
+
+// tracking prefixes like "[Failing test]", "[sig-storage]" or "Flaky test:" are not part of the tracked name
+var recurrenceTitlePrefixRegex = regexp.MustCompile(`(?i)^\s*(\[[^\]]*\]|(failing|failure|flaky|flaking)\s+(test|job)s?\s*:)\s*`)
+
+// issueURLRegex matches the repository of github issue and pull request urls
+var issueURLRegex = regexp.MustCompile(`^https://github\.com/([\w.-]+/[\w.-]+)/(?:issues|pull)/\d+`)
+
+// RecurrenceEntry a test or job name that has been tracked in a github issue during a release cycle
+type RecurrenceEntry struct {
+	// Name normalized test or job name (issue title without tracking prefixes)
+	Name string `json:"name"`
+	// Cycle release cycle the issue has been tracked in (like "1.22")
+	Cycle string `json:"cycle"`
+	// Repository of the tracking issue (like "kubernetes/kubernetes"), empty for entries of older indexes
+	Repository string `json:"repository,omitempty"`
+	// Issue number of the tracking issue
+	Issue int64 `json:"issue"`
+	// URL of the tracking issue
+	URL string `json:"url"`
+}
+
+// RecurrenceIndex long-term index of test and job names that have been tracked in issues, used to flag recurring failures
+type RecurrenceIndex struct {
+	Entries []RecurrenceEntry `json:"entries"`
+}
+
+// LoadRecurrenceIndex reads the index from path, a missing file is an empty index
+func LoadRecurrenceIndex(path string) (RecurrenceIndex, error) {
+	var index RecurrenceIndex
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return index, err
+	}
+	err = json.Unmarshal(data, &index)
+	return index, err
+}
+
+// Save writes the index to path
+func (idx RecurrenceIndex) Save(path string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Track adds all github issues of the report to the index as tracked in the given cycle
+func (idx *RecurrenceIndex) Track(report Report, cycle string) {
+	known := map[string]bool{}
+	for _, e := range idx.Entries {
+		known[recurrenceKey(e.Cycle, e.Repository, e.Issue)] = true
+	}
+	for _, reportData := range report {
+		if reportData.Name != githubReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				repository := issueRepository(record.URL)
+				key := recurrenceKey(cycle, repository, record.ID)
+				if known[key] {
+					continue
+				}
+				known[key] = true
+				idx.Entries = append(idx.Entries, RecurrenceEntry{Name: recurrenceName(record.Title), Cycle: cycle, Repository: repository, Issue: record.ID, URL: record.URL})
+			}
+		}
+	}
+	sort.SliceStable(idx.Entries, func(i, j int) bool {
+		a, b := idx.Entries[i], idx.Entries[j]
+		if a.Cycle != b.Cycle {
+			return releaseVersionLess(a.Cycle, b.Cycle)
+		}
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		return a.Issue < b.Issue
+	})
+}
+
+// Annotate adds a note to every failing job, flaky test and github issue that has been tracked in another cycle before
+func (idx RecurrenceIndex) Annotate(report Report, cycle string) Report {
+	annotated := Report{}
+	for _, reportData := range report {
+		fields := []ReportDataField{}
+		for _, field := range reportData.Data {
+			records := []ReportDataRecord{}
+			for _, record := range field.Records {
+				isSummary := isSummaryRecord(reportData.Name, record)
+				if !isSummary {
+					// issues that are carried over from a previous cycle are no recurrence
+					var repository string
+					var issue int64
+					if reportData.Name == githubReport {
+						repository, issue = issueRepository(record.URL), record.ID
+					}
+					notes := append([]string{}, record.Notes...)
+					for _, e := range idx.recurrences(recurrenceName(record.Title), cycle, repository, issue) {
+						notes = append(notes, fmt.Sprintf("Also tracked in %s as %s#%d", e.Cycle, e.Repository, e.Issue))
+					}
+					if len(notes) > len(record.Notes) {
+						record.Notes = notes
+					}
+				}
+				records = append(records, record)
+			}
+			field.Records = records
+			fields = append(fields, field)
+		}
+		reportData.Data = fields
+		annotated = append(annotated, reportData)
+	}
+	return annotated
+}
+
+// recurrences returns the entries of other cycles that track the given name in another issue
+func (idx RecurrenceIndex) recurrences(name string, cycle string, repository string, issue int64) []RecurrenceEntry {
+	found := []RecurrenceEntry{}
+	if name == "" {
+		return found
+	}
+	for _, e := range idx.Entries {
+		if e.Cycle != cycle && !sameIssue(e.Repository, e.Issue, repository, issue) && recurrenceNamesMatch(e.Name, name) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// recurrenceKey identifies an issue tracked in a cycle, issue numbers are only unique within a repository
+func recurrenceKey(cycle string, repository string, issue int64) string {
+	return fmt.Sprintf("%s/%s#%d", cycle, repository, issue)
+}
+
+// sameIssue tells if two issue references name the same issue, an unknown repository only compares the numbers
+func sameIssue(aRepository string, aIssue int64, bRepository string, bIssue int64) bool {
+	if aIssue != bIssue {
+		return false
+	}
+	return aRepository == "" || bRepository == "" || aRepository == bRepository
+}
+
+// issueRepository returns the repository of a github issue or pull request url ("https://github.com/kubernetes/kubernetes/issues/1" -> "kubernetes/kubernetes")
+func issueRepository(url string) string {
+	if m := issueURLRegex.FindStringSubmatch(url); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func recurrenceNamesMatch(a string, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) < recurrenceMinContainsLength || len(b) < recurrenceMinContainsLength {
+		return false
+	}
+	return strings.Contains(a, b) || strings.Contains(b, a)
+}
+
+// recurrenceName normalizes an issue title or test name ("[Failing test][sig-storage] ci-kubernetes-e2e-gci-gce-serial" -> "ci-kubernetes-e2e-gci-gce-serial")
+func recurrenceName(title string) string {
+	name := title
+	for {
+		trimmed := recurrenceTitlePrefixRegex.ReplaceAllString(name, "")
+		if trimmed == name {
+			break
+		}
+		name = trimmed
+	}
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecurrenceName(t *testing.T) {
+	tests := map[string]string{
+		"[Failing test][sig-storage] ci-kubernetes-e2e-gci-gce-serial": "ci-kubernetes-e2e-gci-gce-serial",
+		"[Flaky Test] gce-ubuntu-master-containerd":                    "gce-ubuntu-master-containerd",
+		"Failing test: TestVolumeUnmount":                              "testvolumeunmount",
+		"HPA CPU e2e tests are failing":                                "hpa cpu e2e tests are failing",
+	}
+	for title, expected := range tests {
+		if name := recurrenceName(title); name != expected {
+			t.Errorf("expected name of %q to be %q, got %q", title, expected, name)
+		}
+	}
+}
+
+func TestRecurrenceIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recurrence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.json")
+
+	index, err := LoadRecurrenceIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousCycle := Report{{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{
+		{ID: 100, Title: "[Failing test] gce-cos-master-serial"},
+		{ID: 101, Title: "[Flaky test] carried over issue"},
+	}}}}}
+	index.Track(previousCycle, "1.22")
+	index.Track(previousCycle, "1.22")
+	if len(index.Entries) != 2 {
+		t.Fatalf("expected issues to be tracked once, got %+v", index.Entries)
+	}
+	if err := index.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	index, err = LoadRecurrenceIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Informing", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Title: ""},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: string(failing)},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{
+			{ID: 200, Title: "[Failing test][sig-node] gce-cos-master-serial"},
+			{ID: 101, Title: "[Flaky test] carried over issue"},
+		}}}},
+	}
+	annotated := index.Annotate(report, "1.23")
+	expectedNote := []string{"Also tracked in 1.22 as #100"}
+	if notes := annotated[0].Data[0].Records[1].Notes; !reflect.DeepEqual(notes, expectedNote) {
+		t.Errorf("expected failing job to be flagged as recurrence, got %v", notes)
+	}
+	if notes := annotated[1].Data[0].Records[0].Notes; !reflect.DeepEqual(notes, expectedNote) {
+		t.Errorf("expected new issue to be flagged as recurrence, got %v", notes)
+	}
+	if notes := annotated[1].Data[0].Records[1].Notes; len(notes) != 0 {
+		t.Errorf("expected carried over issue not to be flagged, got %v", notes)
+	}
+	if len(report[0].Data[0].Records[1].Notes) != 0 {
+		t.Error("expected the given report not to be modified")
+	}
+	// records of the same cycle are no recurrences
+	if notes := index.Annotate(report, "1.22")[0].Data[0].Records[1].Notes; len(notes) != 0 {
+		t.Errorf("expected no recurrence within the same cycle, got %v", notes)
+	}
+}
+
+func TestRecurrenceIndexRepositories(t *testing.T) {
+	index := RecurrenceIndex{}
+	index.Track(Report{{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{
+		{ID: 100, Title: "[Failing test] gce-cos-master-serial", URL: "https://github.com/kubernetes/kubernetes/issues/100"},
+	}}}}}, "1.10")
+	// the same number in another repository is another issue
+	index.Track(Report{{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{
+		{ID: 100, Title: "[Failing test] kubeadm upgrade job", URL: "https://github.com/kubernetes/kubeadm/issues/100"},
+		{ID: 100, Title: "[Failing test] gce-cos-master-serial", URL: "https://github.com/kubernetes/kubernetes/issues/100"},
+	}}}}}, "1.9")
+	cycles := []string{}
+	for _, e := range index.Entries {
+		cycles = append(cycles, e.Cycle+" "+e.Repository)
+	}
+	expected := []string{"1.9 kubernetes/kubeadm", "1.9 kubernetes/kubernetes", "1.10 kubernetes/kubernetes"}
+	if !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("expected entries sorted by release version and repository %v, got %v", expected, cycles)
+	}
+
+	report := Report{{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{
+		{ID: 100, Title: "[Flaky test] kubeadm upgrade job", URL: "https://github.com/kubernetes/kubernetes/issues/100"},
+	}}}}}
+	expectedNote := []string{"Also tracked in 1.9 as kubernetes/kubeadm#100"}
+	if notes := index.Annotate(report, "1.11")[0].Data[0].Records[0].Notes; !reflect.DeepEqual(notes, expectedNote) {
+		t.Errorf("expected the issue of another repository with the same number to be flagged, got %v", notes)
+	}
+}
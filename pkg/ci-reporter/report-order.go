@@ -0,0 +1,233 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Options of -group-by
+const (
+	groupBySig       = "sig"
+	groupBySeverity  = "severity"
+	groupByDashboard = "dashboard"
+)
+
+// title of the group of records that are not attributed to a sig
+const noSigGroup = "no sig"
+
+// sortReportData orders the records by importance: testgrid jobs by severity then recent pass rate,
+// github issues by priority (severity) then age (issue number). Flake rankings keep their order.
+func sortReportData(reportData ReportData) ReportData {
+	switch reportData.Name {
+	case testgridReport, providerReport, platformReport, triageReport:
+		fields := []ReportDataField{}
+		for _, field := range reportData.Data {
+			records := append([]ReportDataRecord{}, field.Records...)
+			sort.SliceStable(records, func(i, j int) bool {
+				// summaries stay in front of the jobs of a dashboard
+				iSummary, jSummary := isSummaryRecord(reportData.Name, records[i]), isSummaryRecord(reportData.Name, records[j])
+				if iSummary != jSummary {
+					return iSummary
+				}
+				return jobRecordLess(records[i], records[j])
+			})
+			field.Records = records
+			fields = append(fields, field)
+		}
+		reportData.Data = fields
+	case githubReport:
+		// each issue is sent as its own field, fields are titled with the repository and repositories keep their order
+		repositoryOrder := map[string]int{}
+		for _, field := range reportData.Data {
+			if _, ok := repositoryOrder[field.Title]; !ok {
+				repositoryOrder[field.Title] = len(repositoryOrder)
+			}
+		}
+		fields := append([]ReportDataField{}, reportData.Data...)
+		sort.SliceStable(fields, func(i, j int) bool {
+			if repositoryOrder[fields[i].Title] != repositoryOrder[fields[j].Title] {
+				return repositoryOrder[fields[i].Title] < repositoryOrder[fields[j].Title]
+			}
+			if len(fields[i].Records) == 0 || len(fields[j].Records) == 0 {
+				return len(fields[i].Records) > len(fields[j].Records)
+			}
+			return issueRecordLess(fields[i].Records[0], fields[j].Records[0])
+		})
+		reportData.Data = fields
+	}
+	return reportData
+}
+
+// jobRecordLess orders jobs by severity (highest first), recent pass rate (lowest first) and title
+func jobRecordLess(a ReportDataRecord, b ReportDataRecord) bool {
+	if a.Severity != b.Severity {
+		return a.Severity > b.Severity
+	}
+	if (a.RecentPassRate == nil) != (b.RecentPassRate == nil) {
+		return a.RecentPassRate != nil
+	}
+	if a.RecentPassRate != nil && *a.RecentPassRate != *b.RecentPassRate {
+		return *a.RecentPassRate < *b.RecentPassRate
+	}
+	return a.Title < b.Title
+}
+
+// issueRecordLess orders issues by severity of their priority label (highest first) and age (oldest issue first)
+func issueRecordLess(a ReportDataRecord, b ReportDataRecord) bool {
+	if a.Severity != b.Severity {
+		return a.Severity > b.Severity
+	}
+	return a.ID < b.ID
+}
+
+// ReportGroup records of the whole report that share a sig, a severity or a dashboard
+type ReportGroup struct {
+	Title   string
+	Records []GroupedRecord
+}
+
+// GroupedRecord a record together with the report and field it belongs to
+type GroupedRecord struct {
+	ReportName string
+	FieldTitle string
+	Record     ReportDataRecord
+}
+
+// GroupBy groups the records of the whole report by sig, severity or dashboard (summaries are left out).
+// Sig groups are ordered by name, severity groups from high to light severity and dashboard groups keep the order of the report.
+func (r Report) GroupBy(by string) ([]ReportGroup, error) {
+	if by != groupBySig && by != groupBySeverity && by != groupByDashboard {
+		return nil, fmt.Errorf("%q does not match options [%s, %s, %s]", by, groupBySig, groupBySeverity, groupByDashboard)
+	}
+	groups := []ReportGroup{}
+	groupIndex := map[string]int{}
+	add := func(title string, record GroupedRecord) {
+		i, ok := groupIndex[title]
+		if !ok {
+			i = len(groups)
+			groupIndex[title] = i
+			groups = append(groups, ReportGroup{Title: title})
+		}
+		groups[i].Records = append(groups[i].Records, record)
+	}
+	for _, reportData := range r {
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if isSummaryRecord(reportData.Name, record) {
+					continue
+				}
+				grouped := GroupedRecord{ReportName: reportData.Name, FieldTitle: field.Title, Record: record}
+				switch by {
+				case groupBySig:
+					if len(record.Sigs) == 0 {
+						add(noSigGroup, grouped)
+					}
+					for _, sig := range record.Sigs {
+						add(sig, grouped)
+					}
+				case groupBySeverity:
+					add(severityName(record.Severity), grouped)
+				case groupByDashboard:
+					add(groupedFieldTitle(reportData.Name, field.Title), grouped)
+				}
+			}
+		}
+	}
+
+	switch by {
+	case groupBySig:
+		sort.SliceStable(groups, func(i, j int) bool {
+			if (groups[i].Title == noSigGroup) != (groups[j].Title == noSigGroup) {
+				return groups[j].Title == noSigGroup
+			}
+			return groups[i].Title < groups[j].Title
+		})
+	case groupBySeverity:
+		sort.SliceStable(groups, func(i, j int) bool {
+			return groups[i].Records[0].Record.Severity > groups[j].Records[0].Record.Severity
+		})
+	}
+	for _, g := range groups {
+		records := g.Records
+		sort.SliceStable(records, func(i, j int) bool {
+			if records[i].Record.Severity != records[j].Record.Severity {
+				return records[i].Record.Severity > records[j].Record.Severity
+			}
+			// records without recent pass rate (issues) follow the jobs and keep the order of the report
+			a, b := records[i].Record.RecentPassRate, records[j].Record.RecentPassRate
+			if a == nil || b == nil {
+				return a != nil && b == nil
+			}
+			return *a < *b
+		})
+	}
+	return groups, nil
+}
+
+// groupedFieldTitle names the dashboard of testgrid jobs and the repository of github issues
+func groupedFieldTitle(reportName string, fieldTitle string) string {
+	if reportName == githubReport {
+		if fieldTitle == "" {
+			return "GitHub issues"
+		}
+		return fmt.Sprintf("GitHub issues of %s", fieldTitle)
+	}
+	return fieldTitle
+}
+
+func severityName(severity Severity) string {
+	switch severity {
+	case HighSeverity:
+		return "high severity"
+	case MediumSeverity:
+		return "medium severity"
+	default:
+		return "light severity"
+	}
+}
+
+// PrintGrouped prints the records of the whole report grouped by sig, severity or dashboard
+func (r Report) PrintGrouped(meta Meta, by string) error {
+	groups, err := r.GroupBy(by)
+	if err != nil {
+		return err
+	}
+	c := meta.console()
+	c.Printf("\nREPORT GROUPED BY %s\n", strings.ToUpper(by))
+	for _, g := range groups {
+		c.Printf("\n%s (%d)\n", strings.ToUpper(g.Title), len(g.Records))
+		for _, grouped := range g.Records {
+			record := grouped.Record
+			if grouped.ReportName == githubReport {
+				c.Printf("%s#%d %s %s\n", grouped.FieldTitle, record.ID, record.Title, record.Sig)
+			} else {
+				c.Printf("%s (%s)\n", c.recordLine(record), grouped.FieldTitle)
+			}
+			if !meta.Flags.ShortOn {
+				c.Printf("- %s\n", record.URL)
+				for _, note := range record.Notes {
+					c.Printf("- %s\n", note)
+				}
+			}
+		}
+	}
+	c.Println()
+	return nil
+}
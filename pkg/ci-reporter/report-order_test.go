@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortReportData(t *testing.T) {
+	testgrid := sortReportData(ReportData{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+		{ID: testgridReportDetails, Title: "flaky-job", Severity: LightSeverity, RecentPassRate: floatPointer(0.9)},
+		{ID: testgridReportDetails, Title: "mostly-failing", Severity: HighSeverity, RecentPassRate: floatPointer(0.3)},
+		{ID: testgridReportSummary},
+		{ID: testgridReportDetails, Title: "failing", Severity: HighSeverity, RecentPassRate: floatPointer(0)},
+	}}}})
+	titles := []string{}
+	for _, record := range testgrid.Data[0].Records {
+		titles = append(titles, record.Title)
+	}
+	if !reflect.DeepEqual(titles, []string{"", "failing", "mostly-failing", "flaky-job"}) {
+		t.Errorf("expected summary first and jobs ordered by severity and pass rate, got %v", titles)
+	}
+
+	github := sortReportData(ReportData{Name: githubReport, Data: []ReportDataField{
+		{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 300, Severity: LightSeverity}}},
+		{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 200, Severity: LightSeverity}}},
+		{Title: "kubernetes-sigs/kind", Records: []ReportDataRecord{{ID: 1, Severity: HighSeverity}}},
+		{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 400, Severity: HighSeverity}}},
+	}})
+	ids := []int64{}
+	for _, field := range github.Data {
+		ids = append(ids, field.Records[0].ID)
+	}
+	if !reflect.DeepEqual(ids, []int64{400, 200, 300, 1}) {
+		t.Errorf("expected issues ordered by repository, priority and age, got %v", ids)
+	}
+}
+
+func TestReportGroupBy(t *testing.T) {
+	report := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Severity: HighSeverity, Sigs: []string{"sig-node", "sig-storage"}},
+			{ID: testgridReportDetails, Title: "verify-master", Severity: LightSeverity},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{{ID: 42, Severity: MediumSeverity, Sigs: []string{"sig-node"}}}}}},
+	}
+	groupTitles := func(groups []ReportGroup) []string {
+		titles := []string{}
+		for _, g := range groups {
+			titles = append(titles, g.Title)
+		}
+		return titles
+	}
+
+	bySig, err := report.GroupBy(groupBySig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groupTitles(bySig), []string{"sig-node", "sig-storage", noSigGroup}) {
+		t.Errorf("unexpected sig groups %v", groupTitles(bySig))
+	}
+	if len(bySig[0].Records) != 2 || bySig[0].Records[1].Record.ID != 42 {
+		t.Errorf("expected the job and the issue of sig-node, got %+v", bySig[0].Records)
+	}
+
+	bySeverity, _ := report.GroupBy(groupBySeverity)
+	if !reflect.DeepEqual(groupTitles(bySeverity), []string{"high severity", "medium severity", "light severity"}) {
+		t.Errorf("unexpected severity groups %v", groupTitles(bySeverity))
+	}
+
+	byDashboard, _ := report.GroupBy(groupByDashboard)
+	if !reflect.DeepEqual(groupTitles(byDashboard), []string{"Master-Blocking", "GitHub issues"}) {
+		t.Errorf("unexpected dashboard groups %v", groupTitles(byDashboard))
+	}
+	if _, err := report.GroupBy("team"); err == nil {
+		t.Error("expected an error for an unknown grouping")
+	}
+}
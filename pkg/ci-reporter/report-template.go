@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"strconv"
+	"text/template"
+)
+
+// RenderReportTemplate renders the report with a user provided go template (-template), templates get the same data and functions as payload templates
+func RenderReportTemplate(meta Meta, tmpl *template.Template, report Report) (string, error) {
+	rendered, err := executePayloadTemplate(meta, tmpl, report, nil)
+	return string(rendered), err
+}
+
+// templateEmoji returns the emoji unless emojis are turned off via -emoji-off
+func templateEmoji(meta Meta) func(string) string {
+	return func(emoji string) string {
+		if meta.Flags.EmojisOff {
+			return ""
+		}
+		return emoji
+	}
+}
+
+// templateGroupBy groups the records of the report by 'sig', 'severity' or 'dashboard' e.g. {{ range groupBy "sig" .Report }}
+func templateGroupBy(by string, report Report) ([]ReportGroup, error) {
+	return report.GroupBy(by)
+}
+
+// templateMinSeverity removes all records with a lower severity than 'high', 'medium', 'light' (or 3, 2, 1) from the report, summaries are kept
+// e.g. {{ range minSeverity "high" .Report }}
+func templateMinSeverity(level string, report Report) (Report, error) {
+	minSeverity, err := parseSeverityLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	filtered := Report{}
+	for _, reportData := range report {
+		fields := []ReportDataField{}
+		for _, field := range reportData.Data {
+			records := []ReportDataRecord{}
+			for _, record := range field.Records {
+				if isSummaryRecord(reportData.Name, record) || record.Severity >= minSeverity {
+					records = append(records, record)
+				}
+			}
+			if len(records) > 0 {
+				field.Records = records
+				fields = append(fields, field)
+			}
+		}
+		filtered = append(filtered, ReportData{Name: reportData.Name, Data: fields, Incomplete: reportData.Incomplete})
+	}
+	return filtered, nil
+}
+
+// parseSeverityLevel parses severity names ('high', 'medium', 'light') and numbers (3, 2, 1)
+func parseSeverityLevel(level string) (Severity, error) {
+	switch level {
+	case "high":
+		return HighSeverity, nil
+	case "medium":
+		return MediumSeverity, nil
+	case "light":
+		return LightSeverity, nil
+	}
+	n, err := strconv.Atoi(level)
+	if err != nil || n < int(LightSeverity) || n > int(HighSeverity) {
+		return 0, fmt.Errorf("%q does not match options [high, medium, light, 3, 2, 1]", level)
+	}
+	return Severity(n), nil
+}
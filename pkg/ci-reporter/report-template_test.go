@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderReportTemplate(t *testing.T) {
+	report := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Notes: []string{"3 jobs total"}},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: "FAILING", Severity: HighSeverity, Sigs: []string{"sig-storage"}},
+			{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FLAKY", Severity: LightSeverity, Sigs: []string{"sig-node"}},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{
+			{ID: 105242, Title: "[Failing test] ci-kubernetes-e2e-gci-gce-serial", Severity: MediumSeverity, Sigs: []string{"sig-storage"}, Notes: []string{colorGreen + "priority/important-soon" + colorReset}},
+		}}}},
+	}
+	path := filepath.Join(t.TempDir(), "notes.tmpl")
+	content := `{{ emoji "🔥" }}Meeting notes
+{{ range minSeverity "medium" .Report }}{{ $name := .Name }}{{ range .Data }}{{ range .Records }}{{ if not (isSummary $name .) }}- {{ .Title }}{{ range .Notes }} {{ plain . }}{{ end }}
+{{ end }}{{ end }}{{ end }}{{ end }}{{ range groupBy "sig" .Report }}{{ .Title }}: {{ len .Records }}
+{{ end }}`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := LoadPayloadTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Meeting notes
+- gce-cos-master-serial
+- [Failing test] ci-kubernetes-e2e-gci-gce-serial priority/important-soon
+sig-node: 1
+sig-storage: 2
+`
+	rendered, err := RenderReportTemplate(Meta{Flags: metaFlags{EmojisOff: true}}, tmpl, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, rendered)
+	}
+	if rendered, _ := RenderReportTemplate(Meta{}, tmpl, report); rendered != "🔥"+expected {
+		t.Errorf("expected the emoji to be rendered, got\n%s", rendered)
+	}
+}
+
+func TestParseSeverityLevel(t *testing.T) {
+	for level, expected := range map[string]Severity{"high": HighSeverity, "medium": MediumSeverity, "1": LightSeverity} {
+		if severity, err := parseSeverityLevel(level); err != nil || severity != expected {
+			t.Errorf("expected severity %d of %q, got %d (%v)", expected, level, severity, err)
+		}
+	}
+	if _, err := parseSeverityLevel("4"); err == nil {
+		t.Errorf("expected an error for severity 4")
+	}
+}
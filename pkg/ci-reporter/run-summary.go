@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunSummary one line with the key numbers of a run that wrapper scripts can grep for without parsing the report
+// ("blocking_failing=2 informing_failing=5 failing=7 open_issues=17 errors=0 duration=42s").
+// Jobs are counted using the dashboard summaries, failing counts the failing jobs of all dashboards and blocking_failing and
+// informing_failing those of the dashboards whose name contains blocking or informing (like -dashboards "knative-blocking").
+// errors counts the sources that failed or whose deadline passed before all data has been requested (see -deadlines), err is
+// the error the run stopped with and counts as one more error if it did not come from a source
+func (r Report) RunSummary(duration time.Duration, err error) string {
+	blockingFailing, informingFailing, allFailing, openIssues := 0, 0, 0, 0
+	failedSources := map[string]bool{}
+	for _, reportData := range r {
+		if reportData.Incomplete {
+			failedSources[reportData.Name] = true
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				switch {
+				case reportData.Name == githubReport:
+					openIssues++
+				case reportData.Name == testgridReport && isSummaryRecord(reportData.Name, record):
+					count := record.Counts[strings.ToLower(string(failing))]
+					allFailing += count
+					dashboard := strings.ToLower(field.Title)
+					if strings.Contains(dashboard, "blocking") {
+						blockingFailing += count
+					} else if strings.Contains(dashboard, "informing") {
+						informingFailing += count
+					}
+				}
+			}
+		}
+	}
+	errCount := 0
+	var sourceErrs SourceErrors
+	if errors.As(err, &sourceErrs) {
+		for _, sourceErr := range sourceErrs {
+			failedSources[sourceErr.Source] = true
+		}
+	} else if err != nil {
+		errCount++
+	}
+	errCount += len(failedSources)
+	return fmt.Sprintf("blocking_failing=%d informing_failing=%d failing=%d open_issues=%d errors=%d duration=%s", blockingFailing, informingFailing, allFailing, openIssues, errCount, duration.Round(time.Second))
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSummary(t *testing.T) {
+	report := Report{
+		{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{{ID: 1}}}, {Records: []ReportDataRecord{{ID: 2}}}}},
+		{Name: testgridReport, Incomplete: true, Data: []ReportDataField{
+			{Emoji: masterBlockingEmoji, Title: "Master-Blocking", Records: []ReportDataRecord{{ID: testgridReportSummary, Counts: map[string]int{"failing": 2, "flaky": 1}}, {ID: testgridReportDetails, Status: string(failing)}}},
+			{Emoji: masterInformingEmoji, Title: "Master-Informing", Records: []ReportDataRecord{{ID: testgridReportSummary, Counts: map[string]int{"failing": 5}}}},
+		}},
+	}
+	expected := "blocking_failing=2 informing_failing=5 failing=7 open_issues=2 errors=1 duration=42s"
+	if got := report.RunSummary(41600*time.Millisecond, nil); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestRunSummaryCustomDashboards(t *testing.T) {
+	// custom dashboards are counted by their name, whatever emoji they are shown with
+	report := Report{{Name: testgridReport, Data: []ReportDataField{
+		{Emoji: masterInformingEmoji, Title: "knative-blocking", Records: []ReportDataRecord{{ID: testgridReportSummary, Counts: map[string]int{"failing": 3}}}},
+		{Title: "serving", Records: []ReportDataRecord{{ID: testgridReportSummary, Counts: map[string]int{"failing": 4}}}},
+	}}}
+	expected := "blocking_failing=3 informing_failing=0 failing=7 open_issues=0 errors=0 duration=1s"
+	if got := report.RunSummary(time.Second, nil); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestRunSummaryErrors(t *testing.T) {
+	report := Report{{Name: githubReport, Incomplete: true}, {Name: testgridReport}}
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"source errors of incomplete reports are counted once", SourceErrors{{Source: githubReport, Err: errors.New("connection refused")}}, "errors=1"},
+		{"source errors of other sources are counted", SourceErrors{{Source: testgridReport, Err: errors.New("connection refused")}}, "errors=2"},
+		{"other errors the run stopped with are counted", errors.New("writing report: webhook returned 500"), "errors=2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := report.RunSummary(time.Second, tc.err); !strings.Contains(got, tc.expected) {
+				t.Errorf("expected %q in %q", tc.expected, got)
+			}
+		})
+	}
+}
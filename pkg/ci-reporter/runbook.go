@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	// embed is used to ship the default runbooks inside of the binary
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+)
+
+//go:embed runbooks.json
+var defaultRunbooks []byte
+
+// Failure classes of failing testgrid jobs
+const (
+	failureClassInfraQuota        = "infra quota"
+	failureClassRegistryOutage    = "registry outage"
+	failureClassProductRegression = "product regression"
+	failureClassNewTest           = "new test"
+)
+
+// failureClassRules failure messages of failing tests that point to an infrastructure problem instead of the product, the first matching rule wins
+var failureClassRules = []struct {
+	class   string
+	message *regexp.Regexp
+}{
+	// cloud provider quotas (like "Quota 'CPUS' exceeded", "QUOTA_EXCEEDED" or "VcpuLimitExceeded") and boskos, tests of resource quotas
+	// ("exceeded quota: compute-resources") and scheduling failures ("Insufficient cpu") are product failures
+	{class: failureClassInfraQuota, message: regexp.MustCompile(`(?i)quota '[^']+' exceeded|quota_exceeded|\w*limitexceeded|rate limit exceeded|resources? exhausted|failed to acquire (project|resource)|boskos[^\n]*(error|fail)`)},
+	{class: failureClassRegistryOutage, message: regexp.MustCompile(`(?i)ErrImagePull|ImagePullBackOff|manifest unknown|pull access denied|toomanyrequests|failed to pull image`)},
+}
+
+// Runbook short instructions how to handle failures of a failure class
+type Runbook struct {
+	// Class failure class the runbook applies to (like "infra quota")
+	Class string `json:"class"`
+	// Runbook instructions for the ci signal shadow
+	Runbook string `json:"runbook"`
+}
+
+// runbooksFile format of the knowledge file set via -runbooks
+type runbooksFile struct {
+	Runbooks []Runbook `json:"runbooks"`
+}
+
+// LoadRunbooks reads runbooks from a json file, the runbooks shipped with the binary are used if path is empty.
+// Runbooks are only attached if they are turned on via -runbooks or -features runbooks=true
+func LoadRunbooks(path string) ([]Runbook, error) {
+	data := defaultRunbooks
+	if path != "" {
+		var err error
+		data, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var file runbooksFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i, r := range file.Runbooks {
+		if r.Class == "" || r.Runbook == "" {
+			return nil, fmt.Errorf("runbook %d: class and runbook have to be set", i)
+		}
+	}
+	return file.Runbooks, nil
+}
+
+// classifyFailure classifies why a job is failing based on the failure messages of its tests,
+// new jobs are classified as new tests and failing jobs without infrastructure failures as product regressions
+func classifyFailure(jobData testgridValue, isNew bool) string {
+	for _, rule := range failureClassRules {
+		for _, t := range jobData.Tests {
+			if rule.message.MatchString(t.FailureMessage) {
+				return rule.class
+			}
+		}
+	}
+	if isNew {
+		return failureClassNewTest
+	}
+	if jobData.OverallStatus == failing {
+		return failureClassProductRegression
+	}
+	return ""
+}
+
+// withRunbooks adds the runbook of their failure class to every classified record
+func withRunbooks(reportData ReportData, runbooks []Runbook) ReportData {
+	if len(runbooks) == 0 {
+		return reportData
+	}
+	runbookByClass := map[string]string{}
+	for _, r := range runbooks {
+		runbookByClass[r.Class] = r.Runbook
+	}
+	fields := []ReportDataField{}
+	for _, field := range reportData.Data {
+		records := []ReportDataRecord{}
+		for _, record := range field.Records {
+			if runbook, ok := runbookByClass[record.FailureClass]; ok {
+				record.Notes = append(append([]string{}, record.Notes...), fmt.Sprintf("Runbook (%s): %s", record.FailureClass, runbook))
+			}
+			records = append(records, record)
+		}
+		field.Records = records
+		fields = append(fields, field)
+	}
+	reportData.Data = fields
+	return reportData
+}
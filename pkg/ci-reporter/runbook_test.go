@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDefaultRunbooks(t *testing.T) {
+	runbooks, err := LoadRunbooks("")
+	if err != nil {
+		t.Fatalf("expected default runbooks to be valid, got %v", err)
+	}
+	classes := map[string]bool{}
+	for _, r := range runbooks {
+		classes[r.Class] = true
+	}
+	for _, class := range []string{failureClassInfraQuota, failureClassRegistryOutage, failureClassProductRegression, failureClassNewTest} {
+		if !classes[class] {
+			t.Errorf("expected a default runbook for %q", class)
+		}
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		jobData  testgridValue
+		isNew    bool
+		expected string
+	}{
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: "timeout"}, {FailureMessage: "Quota 'CPUS' exceeded. Limit: 500.0 in region us-central1."}}}, false, failureClassInfraQuota},
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: "Back-off pulling image: ErrImagePull"}}}, true, failureClassRegistryOutage},
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: "timeout"}}}, true, failureClassNewTest},
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: "timeout"}}}, false, failureClassProductRegression},
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: "Error creating instance: VcpuLimitExceeded"}}}, false, failureClassInfraQuota},
+		// resource quota tests and scheduling failures are no infrastructure problems
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: `pods "test-pod" is forbidden: exceeded quota: compute-resources`}}}, false, failureClassProductRegression},
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: "0/3 nodes are available: 3 Insufficient cpu."}}}, false, failureClassProductRegression},
+		{testgridValue{OverallStatus: failing, Tests: []test{{FailureMessage: "[sig-api-machinery] ResourceQuota should verify quota with terminating scopes"}}}, false, failureClassProductRegression},
+		{testgridValue{OverallStatus: flaky}, false, ""},
+	}
+	for i, test := range tests {
+		if class := classifyFailure(test.jobData, test.isNew); class != test.expected {
+			t.Errorf("expected failure class %q of case %d, got %q", test.expected, i, class)
+		}
+	}
+}
+
+func TestRunbooksOptIn(t *testing.T) {
+	if meta := SetMetaFromArgs([]string{"-replay", testFixturesDir}); len(meta.Flags.Runbooks) != 0 {
+		t.Errorf("expected no runbooks by default, got %v", meta.Flags.Runbooks)
+	}
+	if meta := SetMetaFromArgs([]string{"-replay", testFixturesDir, "-features", "runbooks=true"}); len(meta.Flags.Runbooks) == 0 {
+		t.Error("expected the shipped runbooks if they are turned on")
+	}
+}
+
+func TestTestgridReportRunbooks(t *testing.T) {
+	runbooks := []Runbook{{Class: failureClassProductRegression, Runbook: "Bisect the failing builds."}}
+	meta := newTestMeta(metaFlags{Runbooks: runbooks})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&TestgridReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			hasRunbook := len(record.Notes) > 0 && strings.HasPrefix(record.Notes[len(record.Notes)-1], "Runbook (")
+			if record.Title == "gce-cos-master-serial" {
+				found = true
+				if record.Notes[len(record.Notes)-1] != "Runbook (product regression): Bisect the failing builds." {
+					t.Errorf("expected the product regression runbook, got %v", record.Notes)
+				}
+			} else if hasRunbook && record.FailureClass != failureClassProductRegression {
+				t.Errorf("unexpected runbook for %s job %s: %v", record.Status, record.Title, record.Notes)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected failing job gce-cos-master-serial in the report")
+	}
+}
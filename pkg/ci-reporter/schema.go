@@ -0,0 +1,247 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leonardpahlke/ci-signal-report/pkg/ci-reporter/schema"
+)
+
+// ReportSchemaVersion version of the json schema the -output json report follows
+const ReportSchemaVersion = schema.Version
+
+// ReportSchema returns the json schema of the report output
+func ReportSchema() []byte {
+	return schema.JSONSchema()
+}
+
+// Output converts the report into the versioned output schema, sections are ordered by title and records keep the order of the report (see sortReportData)
+func (r Report) Output(generatedAt time.Time) schema.Output {
+	output := schema.Output{SchemaVersion: schema.Version, GeneratedAt: generatedAt.UTC(), Sources: []schema.Source{}}
+	for _, reportData := range r {
+		source := schema.Source{Name: reportData.Name, Sections: []schema.Section{}, Incomplete: reportData.Incomplete}
+		// github issues are sent as one field per issue, they are grouped by their field title (the repository)
+		sectionIndex := map[string]int{}
+		for _, field := range reportData.Data {
+			i, ok := sectionIndex[field.Title]
+			if !ok {
+				i = len(source.Sections)
+				sectionIndex[field.Title] = i
+				source.Sections = append(source.Sections, schema.Section{Title: field.Title, Records: []schema.Record{}})
+			}
+			for _, record := range field.Records {
+				if isSummaryRecord(reportData.Name, record) {
+					source.Sections[i].Summary = &schema.Summary{Counts: record.Counts, Notes: outputStrings(record.Notes)}
+					continue
+				}
+				source.Sections[i].Records = append(source.Sections[i].Records, outputRecord(reportData.Name, field.Title, record))
+			}
+		}
+		for _, k := range reportData.KnownIssues {
+			source.KnownIssues = append(source.KnownIssues, schema.KnownIssue{Section: k.FieldTitle, Record: outputRecord(reportData.Name, k.FieldTitle, k.Record), Reason: k.Ack.Reason, Expires: k.Ack.Expires})
+		}
+		// flake sections are ranked, provider and platform sections are ordered by provider and platform, quarantined tests are listed before their changes
+		// and failing jobs before the issues of each repository, they keep their order
+		if reportData.Name != flakeReport && reportData.Name != providerReport && reportData.Name != platformReport && reportData.Name != quarantineReport && reportData.Name != prSignalReport {
+			sort.SliceStable(source.Sections, func(i, j int) bool { return source.Sections[i].Title < source.Sections[j].Title })
+		}
+		output.Sources = append(output.Sources, source)
+	}
+	return output
+}
+
+func outputRecord(reportName string, fieldTitle string, record ReportDataRecord) schema.Record {
+	o := schema.Record{
+		Kind:           schema.KindJob,
+		Title:          record.Title,
+		URL:            record.URL,
+		Status:         record.Status,
+		Severity:       int(record.Severity),
+		Sigs:           outputStrings(record.Sigs),
+		Notes:          outputStrings(record.Notes),
+		RecentPassRate: record.RecentPassRate,
+	}
+	if total, ok := record.Counts[failingTestsCount]; ok {
+		o.FailingTests = &schema.FailingTests{Total: total, Regressions: record.Counts[regressionsCount], NeverPassed: record.Counts[neverPassedCount]}
+	}
+	if reportName == githubReport {
+		o.Kind = schema.KindIssue
+		o.Number = record.ID
+		o.Milestone = record.Milestone
+		o.Assignees = record.Assignees
+	} else if (reportName == flakeReport && fieldTitle == flakiestTestsTitle) || reportName == quarantineReport {
+		o.Kind = schema.KindTest
+	} else if reportName == prSignalReport && fieldTitle != failingJobsFixesTitle {
+		o.Kind = schema.KindIssue
+		o.Number = record.ID
+		o.Assignees = record.Assignees
+	}
+	for _, pr := range record.FixPRs {
+		o.FixPRs = append(o.FixPRs, schema.PullRequest{Repository: pr.Repository, Number: pr.Number, Title: pr.Title, URL: pr.URL, Author: pr.Author, State: pr.State, Review: pr.Review, InMergeQueue: pr.InMergeQueue, Closes: pr.Closes})
+	}
+	return o
+}
+
+// outputStrings never returns nil and removes terminal colors, so the output only contains plain text lists
+func outputStrings(values []string) []string {
+	plain := []string{}
+	for _, v := range values {
+		plain = append(plain, strings.TrimSpace(stripColors(v)))
+	}
+	return plain
+}
+
+// ValidateReportFile validates a json report file against the report schema and returns all violations
+func ValidateReportFile(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateReport(data)
+}
+
+// ValidateReport validates json data against the report schema and returns all violations
+func ValidateReport(data []byte) ([]string, error) {
+	var reportSchema jsonSchema
+	if err := json.Unmarshal(ReportSchema(), &reportSchema); err != nil {
+		return nil, fmt.Errorf("could not parse report schema: %v", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse report: %v", err)
+	}
+	return reportSchema.validate("", doc), nil
+}
+
+// jsonSchema the subset of json schema draft-07 that is used by the report schema
+type jsonSchema struct {
+	Type       schemaTypes            `json:"type"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Items      *jsonSchema            `json:"items"`
+	Enum       []interface{}          `json:"enum"`
+}
+
+// schemaTypes json schema allows "type" to be a string or a list of strings
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+// validate checks value against the schema, path is a json pointer to the value used in violation messages
+func (s *jsonSchema) validate(path string, value interface{}) []string {
+	violations := []string{}
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		return append(violations, fmt.Sprintf("%s: expected type %v, got %s", pointer(path), []string(s.Type), jsonTypeOf(value)))
+	}
+	if len(s.Enum) > 0 && !s.matchesEnum(value) {
+		violations = append(violations, fmt.Sprintf("%s: value %v is not one of %v", pointer(path), value, s.Enum))
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing required property %q", pointer(path), name))
+			}
+		}
+		// iterate in a stable order so violations are reported deterministically
+		names := []string{}
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if propSchema, ok := s.Properties[name]; ok {
+				violations = append(violations, propSchema.validate(path+"/"+name, v[name])...)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				violations = append(violations, s.Items.validate(fmt.Sprintf("%s/%d", path, i), item)...)
+			}
+		}
+	}
+	return violations
+}
+
+func (s *jsonSchema) matchesType(value interface{}) bool {
+	valueType := jsonTypeOf(value)
+	for _, t := range s.Type {
+		if t == valueType || (t == "number" && valueType == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *jsonSchema) matchesEnum(value interface{}) bool {
+	for _, e := range s.Enum {
+		if e == value {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf returns the json schema type name of a value decoded by encoding/json
+func jsonTypeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schema contains the types of the json report output (ci-reporter -output json).
+// The types only change together with Version, downstream automation can import the package to unmarshal reports.
+package schema
+
+import (
+	// embed is used to ship the json schema of the output inside of the binary
+	_ "embed"
+	"encoding/json"
+	"time"
+)
+
+// Version of the output schema, it is part of every report as schema_version.
+// The major version changes if fields are removed or change their meaning.
+const Version = "2.4.0"
+
+//go:embed report.schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the json schema (draft-07) of the output
+func JSONSchema() []byte {
+	return jsonSchema
+}
+
+// Record kinds
+const (
+	KindJob   = "job"
+	KindTest  = "test"
+	KindIssue = "issue"
+)
+
+// Output a report generated by one run of the ci-reporter
+type Output struct {
+	// SchemaVersion version of the schema the output follows (see Version)
+	SchemaVersion string `json:"schema_version"`
+	// GeneratedAt time the report has been generated
+	GeneratedAt time.Time `json:"generated_at"`
+	// Sources one entry per data source like "github" or "testgrid"
+	Sources []Source `json:"sources"`
+}
+
+// Source data requested from one data source
+type Source struct {
+	// Name of the source like "github", "testgrid" or "flakes"
+	Name string `json:"name"`
+	// Sections like testgrid dashboards ("Master-Blocking") or repositories of github issues
+	Sections []Section `json:"sections"`
+	// Incomplete the deadline of the source passed before all sections have been requested (since 2.1.0)
+	Incomplete bool `json:"incomplete,omitempty"`
+	// KnownIssues records that have been acknowledged as known failures, they are not part of the sections (since 2.3.0)
+	KnownIssues []KnownIssue `json:"known_issues,omitempty"`
+}
+
+// KnownIssue a record that has been acknowledged until it expires
+type KnownIssue struct {
+	// Section title of the section the record would be part of
+	Section string `json:"section"`
+	Record  Record `json:"record"`
+	Reason  string `json:"reason"`
+	// Expires last day the acknowledgement applies (YYYY-MM-DD)
+	Expires string `json:"expires"`
+}
+
+// Section a group of records like the jobs of a testgrid dashboard
+type Section struct {
+	Title string `json:"title"`
+	// Summary statistics of the section, only set for testgrid dashboards
+	Summary *Summary `json:"summary,omitempty"`
+	Records []Record `json:"records"`
+}
+
+// Summary statistics of a section
+type Summary struct {
+	// Counts number of jobs per status (like "total", "passing", "failing", "flaky", "stale")
+	Counts map[string]int `json:"counts"`
+	Notes  []string       `json:"notes"`
+}
+
+// Record a job, test or issue
+type Record struct {
+	// Kind of the record, one of KindJob, KindTest, KindIssue
+	Kind string `json:"kind"`
+	// Number of the github issue, only set for issues
+	Number int64  `json:"number,omitempty"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	// Status like "FAILING" or "FLAKY", empty for issues. Records of the pr-signal source carry their fix status
+	// ("UNOWNED", "NO FIX", "FIX PENDING" or "FIX MERGED")
+	Status string `json:"status"`
+	// Severity from 1 (light) to 3 (high), 0 if the record is not ranked
+	Severity int      `json:"severity"`
+	Sigs     []string `json:"sigs"`
+	Notes    []string `json:"notes"`
+	// Assignees logins of the users the github issue is assigned to, only set for issues (since 2.2.0)
+	Assignees []string `json:"assignees,omitempty"`
+	// Milestone of the github issue (like "v1.22"), only set for issues with a milestone (since 2.2.0)
+	Milestone string `json:"milestone,omitempty"`
+	// RecentPassRate share of recent runs that passed (0.0 ... 1.0), only set for jobs
+	RecentPassRate *float64 `json:"recent_pass_rate,omitempty"`
+	// FailingTests classification of the failing tests, only set for failing jobs (since 2.2.0)
+	FailingTests *FailingTests `json:"failing_tests,omitempty"`
+	// FixPRs open and merged pull requests that reference the issue (or the issues tracking the job),
+	// only set for records of the pr-signal source (since 2.4.0)
+	FixPRs []PullRequest `json:"fix_prs,omitempty"`
+}
+
+// PullRequest a pull request that references an issue
+type PullRequest struct {
+	// Repository like "kubernetes/kubernetes"
+	Repository string `json:"repository"`
+	Number     int64  `json:"number"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Author     string `json:"author"`
+	// State "open" or "merged"
+	State string `json:"state"`
+	// Review like "lgtm, approved", "changes requested", "awaiting review" or "draft"
+	Review       string `json:"review"`
+	InMergeQueue bool   `json:"in_merge_queue"`
+	// Closes the pull request closes the issue once merged, otherwise it only references it
+	Closes bool `json:"closes"`
+}
+
+// FailingTests failing tests of a job by whether they passed before
+type FailingTests struct {
+	Total int `json:"total"`
+	// Regressions tests that passed before and are failing now
+	Regressions int `json:"regressions"`
+	// NeverPassed tests that did not pass since they have been added
+	NeverPassed int `json:"never_passed"`
+}
+
+// Unmarshal parses a json report
+func Unmarshal(data []byte) (Output, error) {
+	var o Output
+	err := json.Unmarshal(data, &o)
+	return o, err
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leonardpahlke/ci-signal-report/pkg/ci-reporter/schema"
+)
+
+func TestReportOutput(t *testing.T) {
+	meta := newTestMeta(metaFlags{})
+	report, _, err := meta.RequestReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	generatedAt := time.Date(2021, 11, 4, 12, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(report.Output(generatedAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations, err := ValidateReport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) > 0 {
+		t.Errorf("expected output to match the report schema, got %v", violations)
+	}
+
+	// downstream automation unmarshals the output with the published types
+	output, err := schema.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.SchemaVersion != schema.Version || !output.GeneratedAt.Equal(generatedAt) {
+		t.Errorf("unexpected output header %s %s", output.SchemaVersion, output.GeneratedAt)
+	}
+	sources := map[string]schema.Source{}
+	for _, s := range output.Sources {
+		sources[s.Name] = s
+	}
+	github := sources[githubReport]
+	if len(github.Sections) != 1 {
+		t.Fatalf("expected issues of one repository to be in one section, got %d sections", len(github.Sections))
+	}
+	numbers := []int64{}
+	for _, r := range github.Sections[0].Records {
+		if r.Kind != schema.KindIssue {
+			t.Errorf("expected kind %s, got %s", schema.KindIssue, r.Kind)
+		}
+		numbers = append(numbers, r.Number)
+	}
+	// #105242 is labeled priority/important-soon, other issues follow by age
+	if !reflect.DeepEqual(numbers, []int64{105242, 97783, 105965}) {
+		t.Errorf("expected issues ordered by priority and number, got %v", numbers)
+	}
+	blocking := sources[testgridReport].Sections[0]
+	if blocking.Title != "Master-Blocking" || blocking.Summary == nil || blocking.Summary.Counts["total"] != 3 {
+		t.Errorf("expected master-blocking summary with 3 jobs, got %+v", blocking.Summary)
+	}
+	for _, r := range blocking.Records {
+		if r.Kind != schema.KindJob || r.RecentPassRate == nil {
+			t.Errorf("expected job with recent pass rate, got %+v", r)
+		}
+	}
+}
+
+func TestValidateReportViolations(t *testing.T) {
+	violations, err := ValidateReport([]byte(`{"schema_version": "2.0.0", "sources": [{"name": "github", "sections": [{"title": "", "records": [{"kind": "pr"}]}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`/: missing required property "generated_at"`,
+		`/sources/0/sections/0/records/0: missing required property "title"`,
+	}
+	for _, e := range expected {
+		found := false
+		for _, v := range violations {
+			found = found || v == e
+		}
+		if !found {
+			t.Errorf("expected violation %q, got %v", e, violations)
+		}
+	}
+}
+
+func TestValidateReportTypesAndEnums(t *testing.T) {
+	violations, err := ValidateReport([]byte(`{"schema_version": "2.2.0", "generated_at": "2021-11-04T12:00:00Z", "sources": [{"name": "testgrid", "incomplete": "no", "sections": [
+		{"title": "Master-Blocking", "summary": {"counts": null, "notes": null}, "records": [
+			{"kind": "pr", "title": 42, "url": "", "status": "FAILING", "severity": 7, "sigs": [], "notes": [1.5]}
+		]}
+	]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`/sources/0/incomplete: expected type [boolean], got string`,
+		`/sources/0/sections/0/records/0/kind: value pr is not one of [job test issue]`,
+		`/sources/0/sections/0/records/0/notes/0: expected type [string], got number`,
+		`/sources/0/sections/0/records/0/severity: value 7 is not one of [0 1 2 3]`,
+		`/sources/0/sections/0/records/0/title: expected type [string], got integer`,
+	}
+	// the summary may be null, so it has no violations
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("expected violations\n%v\ngot\n%v", strings.Join(expected, "\n"), strings.Join(violations, "\n"))
+	}
+}
+
+func TestValidateReportFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "report.json")
+	data, err := json.Marshal(Report{}.Output(time.Date(2021, 11, 4, 12, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(valid, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if violations, err := ValidateReportFile(valid); err != nil || len(violations) > 0 {
+		t.Errorf("expected the report to be valid, got %v %v", violations, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(invalid, []byte(`{"sources": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateReportFile(invalid); err == nil {
+		t.Error("expected an error if the report is no json")
+	}
+	if _, err := ValidateReportFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error if the report does not exist")
+	}
+}
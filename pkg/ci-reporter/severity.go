@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SeverityConfig thresholds used to rank testgrid jobs and emojis used to highlight them
+type SeverityConfig struct {
+	// ThresholdWarning jobs with a recent success rate of 0.0 ... ThresholdWarning get HighSeverity
+	ThresholdWarning float64
+	// ThresholdInfo jobs with a recent success rate of ThresholdWarning ... ThresholdInfo get MediumSeverity
+	ThresholdInfo float64
+	// NewTestRuns jobs with less or equal recent runs are highlighted as new tests
+	NewTestRuns float64
+	// Emojis overwrites the highlight of a severity (by default the status emoji is repeated severity times)
+	Emojis map[Severity]string
+	// Scorer ranks the jobs, the thresholds above are used if it is not set (see NewSeverityScorer)
+	Scorer SeverityScorer
+}
+
+// DefaultSeverityConfig returns the thresholds that are used if no flags are set
+func DefaultSeverityConfig() SeverityConfig {
+	return SeverityConfig{
+		ThresholdWarning: 0.5,
+		ThresholdInfo:    0.8,
+		NewTestRuns:      5.0,
+		Emojis:           map[Severity]string{},
+	}
+}
+
+// Severity scorers that can be selected via -severity-scorer
+const (
+	// rank jobs by the recent success rate thresholds
+	thresholdScorerName = "threshold"
+	// raise the severity of jobs with many failing tests
+	testCountScorerName = "test-count"
+	// raise the severity of jobs that are failing for days
+	daysFailingScorerName = "days-failing"
+	// raise the severity of jobs of blocking dashboards
+	dashboardWeightScorerName = "dashboard-weight"
+)
+
+// Limits used by the weighted scorers to raise the severity of a job by one
+const (
+	severityFailingTestsLimit = 10
+	severityDaysFailingLimit  = 3.0
+)
+
+// SeverityInput what is known about a testgrid job when it gets ranked
+type SeverityInput struct {
+	// Dashboard name of the testgrid dashboard (like sig-release-master-blocking)
+	Dashboard string
+	// Status overall status of the job (FAILING, FLAKY)
+	Status string
+	// RecentRuns and RecentSuccessRate of the job as shown by testgrid (like 3 of 10 passed recently)
+	RecentRuns        float64
+	RecentSuccessRate float64
+	// FailingTests number of tests that are currently failing
+	FailingTests int
+	// DaysFailing time since the first failure of the failing tests (0 if unknown)
+	DaysFailing float64
+}
+
+// SeverityScorer ranks a testgrid job, new jobs (isNew) are highlighted as new tests instead of ranked
+type SeverityScorer interface {
+	Score(job SeverityInput) (severity Severity, isNew bool)
+}
+
+// NewSeverityScorer returns the scorer with the name ('threshold', 'test-count', 'days-failing', 'dashboard-weight'),
+// the weighted scorers use the thresholds of the config and raise the severity by one if their weight applies
+func NewSeverityScorer(name string, config SeverityConfig) (SeverityScorer, error) {
+	thresholds := thresholdScorer{config: config}
+	switch name {
+	case thresholdScorerName:
+		return thresholds, nil
+	case testCountScorerName:
+		return weightedScorer{base: thresholds, weight: func(job SeverityInput) bool { return job.FailingTests >= severityFailingTestsLimit }}, nil
+	case daysFailingScorerName:
+		return weightedScorer{base: thresholds, weight: func(job SeverityInput) bool { return job.DaysFailing >= severityDaysFailingLimit }}, nil
+	case dashboardWeightScorerName:
+		return weightedScorer{base: thresholds, weight: func(job SeverityInput) bool { return job.Dashboard == string(sigReleaseMasterBlocking) }}, nil
+	}
+	return nil, fmt.Errorf("%q does not match options [%s, %s, %s, %s]", name, thresholdScorerName, testCountScorerName, daysFailingScorerName, dashboardWeightScorerName)
+}
+
+// thresholdScorer ranks jobs based on their recent runs and success rate, new jobs always get LightSeverity
+type thresholdScorer struct {
+	config SeverityConfig
+}
+
+func (s thresholdScorer) Score(job SeverityInput) (Severity, bool) {
+	c := s.config
+	if job.RecentRuns <= c.NewTestRuns {
+		return LightSeverity, true
+	}
+	if job.RecentSuccessRate <= c.ThresholdWarning {
+		return HighSeverity, false
+	} else if job.RecentSuccessRate <= c.ThresholdInfo {
+		return MediumSeverity, false
+	}
+	return LightSeverity, false
+}
+
+// weightedScorer raises the severity of the base scorer by one (up to HighSeverity) if the weight applies to a job
+type weightedScorer struct {
+	base   SeverityScorer
+	weight func(job SeverityInput) bool
+}
+
+func (s weightedScorer) Score(job SeverityInput) (Severity, bool) {
+	severity, isNew := s.base.Score(job)
+	if !isNew && severity < HighSeverity && s.weight(job) {
+		severity++
+	}
+	return severity, isNew
+}
+
+// rate ranks a job with the configured scorer (the thresholds by default)
+func (c SeverityConfig) rate(job SeverityInput) (severity Severity, isNew bool) {
+	if c.Scorer != nil {
+		return c.Scorer.Score(job)
+	}
+	return thresholdScorer{config: c}.Score(job)
+}
+
+// highlight returns the custom emoji of a severity or repeats the given status emoji severity times
+func (c SeverityConfig) highlight(severity Severity, statusEmoji string) string {
+	if emoji, ok := c.Emojis[severity]; ok {
+		return emoji
+	}
+	return strings.Repeat(statusEmoji, int(severity))
+}
+
+// validate checks that thresholds are ordered and in the range of success rates
+func (c SeverityConfig) validate() error {
+	if c.ThresholdWarning < 0 || c.ThresholdInfo > 1 || c.ThresholdWarning > c.ThresholdInfo {
+		return fmt.Errorf("thresholds have to satisfy 0 <= warning (%g) <= info (%g) <= 1", c.ThresholdWarning, c.ThresholdInfo)
+	}
+	if c.NewTestRuns < 0 {
+		return fmt.Errorf("new test runs (%g) can not be negative", c.NewTestRuns)
+	}
+	return nil
+}
+
+// This function is used to split severity emoji input ("3=🚨, 2=⚠️" => {HighSeverity: "🚨", MediumSeverity: "⚠️"})
+func parseSeverityEmojis(input string) (map[Severity]string, error) {
+	emojis := map[Severity]string{}
+	for _, e := range strings.Split(input, ",") {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q does not match severity=emoji", strings.TrimSpace(e))
+		}
+		severity, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || severity < int(LightSeverity) || severity > int(HighSeverity) {
+			return nil, fmt.Errorf("%q is not a severity, options: %d, %d, %d", strings.TrimSpace(parts[0]), LightSeverity, MediumSeverity, HighSeverity)
+		}
+		emojis[Severity(severity)] = strings.TrimSpace(parts[1])
+	}
+	return emojis, nil
+}
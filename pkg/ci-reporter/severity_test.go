@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeverityConfigThresholds(t *testing.T) {
+	config := SeverityConfig{ThresholdWarning: 0.2, ThresholdInfo: 0.9, NewTestRuns: 2}
+	tests := []struct {
+		runs, rate       float64
+		expectedSeverity Severity
+		expectedNew      bool
+	}{
+		{runs: 2, rate: 0, expectedSeverity: LightSeverity, expectedNew: true},
+		{runs: 9, rate: 0.1, expectedSeverity: HighSeverity},
+		{runs: 9, rate: 0.5, expectedSeverity: MediumSeverity},
+		{runs: 9, rate: 0.95, expectedSeverity: LightSeverity},
+	}
+	for _, tc := range tests {
+		severity, isNew := config.rate(SeverityInput{RecentRuns: tc.runs, RecentSuccessRate: tc.rate})
+		if severity != tc.expectedSeverity || isNew != tc.expectedNew {
+			t.Errorf("rate(%g, %g): expected (%d, %t), got (%d, %t)", tc.runs, tc.rate, tc.expectedSeverity, tc.expectedNew, severity, isNew)
+		}
+	}
+	if err := (SeverityConfig{ThresholdWarning: 0.9, ThresholdInfo: 0.5}).validate(); err == nil {
+		t.Error("expected an error for a warning threshold above the info threshold")
+	}
+}
+
+func TestSeverityScorers(t *testing.T) {
+	config := DefaultSeverityConfig()
+	// 3 of 10 passed recently => MediumSeverity with the default thresholds
+	job := SeverityInput{Dashboard: "sig-release-master-informing", RecentRuns: 10, RecentSuccessRate: 0.7, FailingTests: 2, DaysFailing: 1}
+	tests := []struct {
+		scorer   string
+		job      func(SeverityInput) SeverityInput
+		expected Severity
+	}{
+		{scorer: "threshold", expected: MediumSeverity},
+		{scorer: "test-count", expected: MediumSeverity},
+		{scorer: "test-count", job: func(j SeverityInput) SeverityInput { j.FailingTests = 12; return j }, expected: HighSeverity},
+		{scorer: "days-failing", job: func(j SeverityInput) SeverityInput { j.DaysFailing = 4.5; return j }, expected: HighSeverity},
+		{scorer: "dashboard-weight", job: func(j SeverityInput) SeverityInput { j.Dashboard = "sig-release-master-blocking"; return j }, expected: HighSeverity},
+		{scorer: "dashboard-weight", job: func(j SeverityInput) SeverityInput {
+			j.RecentSuccessRate = 0.1
+			j.Dashboard = "sig-release-master-blocking"
+			return j
+		}, expected: HighSeverity},
+	}
+	for _, tc := range tests {
+		scorer, err := NewSeverityScorer(tc.scorer, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := job
+		if tc.job != nil {
+			input = tc.job(job)
+		}
+		if severity, isNew := scorer.Score(input); severity != tc.expected || isNew {
+			t.Errorf("%s scorer: expected severity %d of %+v, got %d (new %t)", tc.scorer, tc.expected, input, severity, isNew)
+		}
+	}
+
+	// new jobs are not weighted
+	scorer, _ := NewSeverityScorer("dashboard-weight", config)
+	if severity, isNew := scorer.Score(SeverityInput{Dashboard: "sig-release-master-blocking", RecentRuns: 2}); severity != LightSeverity || !isNew {
+		t.Errorf("expected a new job with light severity, got %d (new %t)", severity, isNew)
+	}
+	if _, err := NewSeverityScorer("random", config); err == nil {
+		t.Error("expected an error for an unknown scorer")
+	}
+}
+
+func TestParseSeverityEmojis(t *testing.T) {
+	emojis, err := parseSeverityEmojis("3=A, 1=B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(emojis, map[Severity]string{HighSeverity: "A", LightSeverity: "B"}) {
+		t.Errorf("unexpected mapping %v", emojis)
+	}
+	config := DefaultSeverityConfig()
+	config.Emojis = emojis
+	if h := config.highlight(HighSeverity, statusFailingEmoji); h != "A" {
+		t.Errorf("expected custom highlight A, got %q", h)
+	}
+	if h := config.highlight(MediumSeverity, statusFlakyEmoji); h != statusFlakyEmoji+statusFlakyEmoji {
+		t.Errorf("expected default highlight for medium severity, got %q", h)
+	}
+	for _, input := range []string{"4=A", "x=A", "3"} {
+		if _, err := parseSeverityEmojis(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This function is used to split sig input ("sig-node, sig/network" => ["sig-node", "sig-network"])
+func splitSigInput(input string) []string {
+	sigs := []string{}
+	for _, e := range strings.Split(input, ",") {
+		if strings.TrimSpace(e) != "" {
+			sigs = append(sigs, normalizeSig(e))
+		}
+	}
+	return sigs
+}
+
+// matchesSigs checks if a record is attributed to one of the given sigs
+func matchesSigs(record ReportDataRecord, sigs []string) bool {
+	for _, recordSig := range record.Sigs {
+		for _, sig := range sigs {
+			if recordSig == sig {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterReportDataBySigs removes all records that are not attributed to one of the given sigs (testgrid and provider summaries are kept)
+func filterReportDataBySigs(reportData ReportData, sigs []string) ReportData {
+	if len(sigs) == 0 {
+		return reportData
+	}
+	filteredData := []ReportDataField{}
+	for _, field := range reportData.Data {
+		records := []ReportDataRecord{}
+		for _, record := range field.Records {
+			isSummary := isSummaryRecord(reportData.Name, record)
+			if isSummary || matchesSigs(record, sigs) {
+				records = append(records, record)
+			}
+		}
+		if len(records) > 0 {
+			field.Records = records
+			filteredData = append(filteredData, field)
+		}
+	}
+	reportData.Data = filteredData
+	return reportData
+}
+
+// PrintSigRollup prints a section per sig that lists the testgrid jobs and github issues attributed to the sig
+func (r Report) PrintSigRollup(meta Meta, sigs []string) {
+	c := meta.console()
+	c.Print("\nSIG ROLLUP\n")
+	for _, sig := range sigs {
+		jobs := []string{}
+		issues := []string{}
+		for _, reportData := range r {
+			for _, field := range reportData.Data {
+				for _, record := range field.Records {
+					if !matchesSigs(record, []string{sig}) {
+						continue
+					}
+					if reportData.Name == testgridReport {
+						jobs = append(jobs, fmt.Sprintf("%s %s (%s)", record.Status, record.Title, field.Title))
+					} else if reportData.Name == githubReport {
+						issues = append(issues, fmt.Sprintf("#%d %s", record.ID, record.Title))
+					}
+				}
+			}
+		}
+		c.Printf("\n%s: %d failing & flaky jobs, %d issues\n", sig, len(jobs), len(issues))
+		for _, job := range jobs {
+			c.Printf("- %s\n", job)
+		}
+		for _, issue := range issues {
+			c.Printf("- %s\n", issue)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSigInput(t *testing.T) {
+	expected := []string{"sig-cluster-lifecycle", "sig-api-machinery", "sig-node"}
+	if got := splitSigInput("sig-cluster-lifecycle, sig/api-machinery,SIG-Node, "); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestFilterReportDataBySigs(t *testing.T) {
+	reportData := ReportData{Name: testgridReport, Data: []ReportDataField{
+		{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Notes: []string{"3 jobs total"}},
+			{ID: testgridReportDetails, Title: "kubeadm-gce", Sigs: extractSigs("[sig-cluster-lifecycle] kubeadm upgrade should succeed")},
+			{ID: testgridReportDetails, Title: "gce-serial", Sigs: extractSigs("[sig-node] Pods should be restarted")},
+		}},
+		{Title: "Master-Informing", Records: []ReportDataRecord{
+			{ID: testgridReportDetails, Title: "gce-apimachinery", Sigs: extractSigs("[sig-api-machinery] Watchers should observe events")},
+		}},
+	}}
+
+	filtered := filterReportDataBySigs(reportData, splitSigInput("sig-cluster-lifecycle"))
+	if len(filtered.Data) != 1 || len(filtered.Data[0].Records) != 2 || filtered.Data[0].Records[1].Title != "kubeadm-gce" {
+		t.Errorf("expected the summary and the sig-cluster-lifecycle job of Master-Blocking, got %+v", filtered.Data)
+	}
+
+	filtered = filterReportDataBySigs(reportData, splitSigInput("sig/api-machinery"))
+	if len(filtered.Data) != 2 || len(filtered.Data[1].Records) != 1 || filtered.Data[1].Records[0].Title != "gce-apimachinery" {
+		t.Errorf("expected the summary of Master-Blocking and the sig-api-machinery job of Master-Informing, got %+v", filtered.Data)
+	}
+
+	if filtered = filterReportDataBySigs(reportData, nil); !reflect.DeepEqual(filtered, reportData) {
+		t.Errorf("expected the report data to be kept without sigs, got %+v", filtered)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sigNameRegex finds sig names in labels ("sig/cluster-lifecycle"), test names ("[sig-storage] CSI mock volume")
+// and job descriptions ("OWNER: SIG-Node"), multi-word names are canonicalized with knownSigs
+var sigNameRegex = regexp.MustCompile(`(?i)\bsig[-/]([a-z][a-z0-9]*(?:-[a-z0-9]+)*)`)
+
+// knownSigs kubernetes sigs, names mentioned in test and job names are cut to the longest known sig
+// ("sig-node-containerd" -> "sig-node"), unknown names are kept as they are
+var knownSigs = []string{
+	"api-machinery", "apps", "architecture", "auth", "autoscaling", "cli", "cloud-provider", "cluster-lifecycle",
+	"contributor-experience", "docs", "instrumentation", "k8s-infra", "multicluster", "network", "node", "release",
+	"scalability", "scheduling", "security", "storage", "testing", "ui", "usability", "windows",
+}
+
+// sigAliases short names of sigs that are used in labels and test names
+var sigAliases = map[string]string{
+	"contribex":     "contributor-experience",
+	"cloudprovider": "cloud-provider",
+	"apimachinery":  "api-machinery",
+}
+
+// extractSigs returns the canonical names of all sigs mentioned in the text, each sig once in the order they are mentioned
+func extractSigs(text string) []string {
+	sigs := []string{}
+	seen := map[string]bool{}
+	for _, match := range sigNameRegex.FindAllString(text, -1) {
+		sig := normalizeSig(match)
+		if !seen[sig] {
+			seen[sig] = true
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// normalizeSig brings sig names from labels, test names and flags into the same format
+// ("sig/node", "SIG-Node" -> "sig-node", "[sig-cluster-lifecycle-kubeadm]" -> "sig-cluster-lifecycle")
+func normalizeSig(sig string) string {
+	name := strings.ToLower(strings.Trim(strings.TrimSpace(sig), "[]"))
+	if !strings.HasPrefix(name, "sig-") && !strings.HasPrefix(name, "sig/") {
+		return name
+	}
+	name = name[len("sig-"):]
+	if alias, ok := sigAliases[name]; ok {
+		name = alias
+	}
+	known := ""
+	for _, k := range knownSigs {
+		if (name == k || strings.HasPrefix(name, k+"-")) && len(k) > len(known) {
+			known = k
+		}
+	}
+	if known != "" {
+		name = known
+	}
+	return "sig-" + name
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractSigs(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"Kubernetes e2e suite.[sig-storage] CSI mock volume", []string{"sig-storage"}},
+		{"[sig-cluster-lifecycle] kubeadm upgrade [sig-node] Pods", []string{"sig-cluster-lifecycle", "sig-node"}},
+		{"sig/cluster-lifecycle", []string{"sig-cluster-lifecycle"}},
+		{"sig/api-machinery", []string{"sig-api-machinery"}},
+		{"OWNER: SIG-Node, sig-node-containerd", []string{"sig-node"}},
+		{"sig/contribex", []string{"sig-contributor-experience"}},
+		// unknown sigs are kept as they are
+		{"[sig-new-thing] test", []string{"sig-new-thing"}},
+		// no sig
+		{"kubernetes-sigs/kind", []string{}},
+	}
+	for _, test := range tests {
+		if sigs := extractSigs(test.text); !reflect.DeepEqual(sigs, test.expected) {
+			t.Errorf("expected %v for %q, got %v", test.expected, test.text, sigs)
+		}
+	}
+}
+
+func TestNormalizeSig(t *testing.T) {
+	for input, expected := range map[string]string{
+		"sig/node":                "sig-node",
+		" SIG-Network ":           "sig-network",
+		"[sig-cluster-lifecycle]": "sig-cluster-lifecycle",
+		"sig-windows-containerd":  "sig-windows",
+	} {
+		if sig := normalizeSig(input); sig != expected {
+			t.Errorf("expected %s for %q, got %s", expected, input, sig)
+		}
+	}
+}
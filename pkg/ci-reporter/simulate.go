@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// simulationTimeLayout format of the run times printed by the simulate subcommand
+const simulationTimeLayout = "2006-01-02 15:04 UTC"
+
+// Simulation result of replaying the severity configuration and the check conditions against stored snapshots
+type Simulation struct {
+	Runs []SimulatedRun `json:"runs"`
+}
+
+// SimulatedRun one snapshot ranked with the simulated severity configuration
+type SimulatedRun struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Paged tells if a condition trips with the simulated configuration
+	Paged bool `json:"paged"`
+	// PagedBefore tells if a condition trips with the severities stored in the snapshot
+	PagedBefore bool              `json:"paged_before"`
+	Conditions  []ConditionResult `json:"conditions"`
+	// SeverityChanges jobs that are ranked differently than stored in the snapshot
+	SeverityChanges []SeverityChange `json:"severity_changes"`
+}
+
+// SeverityChange a job ranked differently by the simulated severity configuration
+type SeverityChange struct {
+	// Job like "Master-Blocking/gce-cos-master-default"
+	Job    string   `json:"job"`
+	Before Severity `json:"before"`
+	After  Severity `json:"after"`
+}
+
+// Simulate ranks the testgrid jobs of the snapshots with the severity configuration and evaluates the conditions against
+// the re-ranked reports, so thresholds and conditions can be tuned before they page anyone.
+// Jobs are ranked with what has been stored (recent runs, failing tests), the time since the first failure is not stored and left out
+func Simulate(snapshots []Snapshot, severity SeverityConfig, conditions []FailCondition) Simulation {
+	simulation := Simulation{Runs: []SimulatedRun{}}
+	for _, snapshot := range snapshots {
+		rated, changes := rerateReport(snapshot.Report, severity)
+		check := rated.Check(conditions)
+		simulation.Runs = append(simulation.Runs, SimulatedRun{
+			GeneratedAt:     snapshot.GeneratedAt.UTC(),
+			Paged:           !check.Healthy,
+			PagedBefore:     !snapshot.Report.Check(conditions).Healthy,
+			Conditions:      check.Conditions,
+			SeverityChanges: changes,
+		})
+	}
+	return simulation
+}
+
+// rerateReport returns a copy of the report with the testgrid jobs ranked by the severity configuration
+func rerateReport(report Report, severity SeverityConfig) (Report, []SeverityChange) {
+	rated := Report{}
+	changes := []SeverityChange{}
+	for _, reportData := range report {
+		if reportData.Name != testgridReport {
+			rated = append(rated, reportData)
+			continue
+		}
+		fields := []ReportDataField{}
+		for _, field := range reportData.Data {
+			records := []ReportDataRecord{}
+			for _, record := range field.Records {
+				if input, ok := storedSeverityInput(record); ok && !isSummaryRecord(reportData.Name, record) {
+					before := record.Severity
+					record.Severity, _ = severity.rate(input)
+					if record.Severity != before {
+						changes = append(changes, SeverityChange{Job: fmt.Sprintf("%s/%s", field.Title, record.Title), Before: before, After: record.Severity})
+					}
+				}
+				records = append(records, record)
+			}
+			field.Records = records
+			fields = append(fields, field)
+		}
+		reportData.Data = fields
+		rated = append(rated, reportData)
+	}
+	return rated, changes
+}
+
+// storedSeverityInput collects what has been stored about a job to rank it again, ok is false if the recent runs are unknown
+func storedSeverityInput(record ReportDataRecord) (SeverityInput, bool) {
+	runs, ok := record.Counts[recentRunsCount]
+	if !ok || record.RecentPassRate == nil {
+		return SeverityInput{}, false
+	}
+	return SeverityInput{
+		Dashboard:         path.Base(strings.SplitN(record.URL, "#", 2)[0]),
+		Status:            record.Status,
+		RecentRuns:        float64(runs),
+		RecentSuccessRate: *record.RecentPassRate,
+		FailingTests:      record.Counts[failingTestsCount],
+	}, true
+}
+
+func (s Simulation) String() string {
+	if len(s.Runs) == 0 {
+		return "No snapshots to simulate\n"
+	}
+	var sb strings.Builder
+	paged, pagedBefore := 0, 0
+	for _, run := range s.Runs {
+		if run.Paged {
+			paged++
+		}
+		if run.PagedBefore {
+			pagedBefore++
+		}
+	}
+	sb.WriteString(fmt.Sprintf("Simulated %d runs (%s ... %s)\n", len(s.Runs), s.Runs[0].GeneratedAt.Format(simulationTimeLayout), s.Runs[len(s.Runs)-1].GeneratedAt.Format(simulationTimeLayout)))
+	sb.WriteString(fmt.Sprintf("%d of %d runs would have paged, %d paged with the stored severities\n", paged, len(s.Runs), pagedBefore))
+	for _, run := range s.Runs {
+		if !run.Paged && !run.PagedBefore && len(run.SeverityChanges) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n%s %s (before: %s)\n", run.GeneratedAt.Format(simulationTimeLayout), pagedText(run.Paged), pagedText(run.PagedBefore)))
+		for _, c := range run.Conditions {
+			if c.Tripped {
+				sb.WriteString(fmt.Sprintf("- %s tripped: %d > %d %v\n", c.Condition, c.Count, c.Limit, c.Records))
+			}
+		}
+		for _, c := range run.SeverityChanges {
+			sb.WriteString(fmt.Sprintf("- %s: severity %d -> %d\n", c.Job, c.Before, c.After))
+		}
+	}
+	return sb.String()
+}
+
+func pagedText(paged bool) string {
+	if paged {
+		return "PAGED"
+	}
+	return "ok"
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// simulationSnapshot snapshot with one flaky job per pass rate, every job ran 10 times recently
+func simulationSnapshot(generatedAt time.Time, passRates ...float64) Snapshot {
+	records := []ReportDataRecord{{ID: testgridReportSummary, Counts: map[string]int{"failing": len(passRates)}}}
+	for i, passRate := range passRates {
+		records = append(records, ReportDataRecord{
+			ID:             testgridReportDetails,
+			Title:          []string{"job-a", "job-b"}[i],
+			URL:            "https://testgrid.k8s.io/sig-release-master-blocking#job",
+			Status:         string(flaky),
+			Severity:       MediumSeverity,
+			Counts:         map[string]int{recentRunsCount: 10},
+			RecentPassRate: floatPointer(passRate),
+		})
+	}
+	return Snapshot{GeneratedAt: generatedAt, Report: Report{{Name: testgridReport, Data: []ReportDataField{{Emoji: masterBlockingEmoji, Title: "Master-Blocking", Records: records}}}}}
+}
+
+func TestStoredSeverityInput(t *testing.T) {
+	// the notes are left out like with -short, only stored values are used
+	record := ReportDataRecord{
+		URL:            "https://testgrid.k8s.io/sig-release-master-blocking#gce-cos-master-default",
+		Status:         string(failing),
+		Counts:         map[string]int{failingTestsCount: 3, recentRunsCount: 10},
+		RecentPassRate: floatPointer(0.4),
+	}
+	input, ok := storedSeverityInput(record)
+	expected := SeverityInput{Dashboard: "sig-release-master-blocking", Status: string(failing), RecentRuns: 10, RecentSuccessRate: 0.4, FailingTests: 3}
+	if !ok || !reflect.DeepEqual(input, expected) {
+		t.Errorf("expected %+v, got %+v (ok %t)", expected, input, ok)
+	}
+	if _, ok := storedSeverityInput(ReportDataRecord{Notes: []string{"4 of 10 passed recently"}}); ok {
+		t.Error("expected records without recent runs to be skipped")
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	day := time.Date(2021, 11, 1, 10, 0, 0, 0, time.UTC)
+	snapshots := []Snapshot{
+		simulationSnapshot(day, 0.7),
+		simulationSnapshot(day.Add(24*time.Hour), 0.4, 0.9),
+	}
+	conditions, err := ParseFailConditions("high-severity")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	severity := DefaultSeverityConfig()
+	simulation := Simulate(snapshots, severity, conditions)
+	if len(simulation.Runs) != 2 {
+		t.Fatalf("expected 2 simulated runs, got %d", len(simulation.Runs))
+	}
+	if simulation.Runs[0].Paged || !simulation.Runs[1].Paged {
+		t.Errorf("expected only the second run to page with the default thresholds, got %+v", simulation.Runs)
+	}
+	expectedChanges := []SeverityChange{{Job: "Master-Blocking/job-a", Before: MediumSeverity, After: HighSeverity}, {Job: "Master-Blocking/job-b", Before: MediumSeverity, After: LightSeverity}}
+	if !reflect.DeepEqual(simulation.Runs[1].SeverityChanges, expectedChanges) {
+		t.Errorf("expected severity changes %v, got %v", expectedChanges, simulation.Runs[1].SeverityChanges)
+	}
+	if simulation.Runs[1].PagedBefore {
+		t.Error("expected the stored severities not to page")
+	}
+
+	severity.ThresholdWarning = 0.3
+	simulation = Simulate(snapshots, severity, conditions)
+	if simulation.Runs[0].Paged || simulation.Runs[1].Paged {
+		t.Errorf("expected no run to page with threshold 0.3, got %+v", simulation.Runs)
+	}
+	if !strings.Contains(simulation.String(), "0 of 2 runs would have paged, 0 paged with the stored severities") {
+		t.Errorf("unexpected simulation summary:\n%s", simulation)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetSinks returns the sinks the report of a run is written to: the console (stdout) in the output format set via
+// -output or -template and the files set via -out. Notifiers are returned by GetNotifierSinks
+func (m Meta) GetSinks() []Sink {
+	var console Renderer = textRenderer{meta: m}
+	if m.Flags.JSONOut {
+		console = JSONRenderer{}
+	} else if m.Flags.Template != "" {
+		tmpl, err := LoadPayloadTemplate(m.Flags.Template)
+		if err != nil {
+			Fatalf("Error loading report template.\n[ERROR] %v", err)
+		}
+		console = TemplateRenderer{Template: tmpl, EmojisOff: m.Flags.EmojisOff}
+	}
+	sinks := []Sink{WriterSink{Writer: os.Stdout, Renderer: console}}
+	for _, path := range m.Flags.Out {
+		renderer, err := rendererForFile(m, path)
+		if err != nil {
+			Fatalf("Information given via flag -out is invalid.\n[ERROR] %v", err)
+		}
+		sinks = append(sinks, FileSink{Path: path, Renderer: renderer})
+	}
+	return sinks
+}
+
+// GetNotifierSinks returns the notifiers configured via flags as sinks (see GetNotifiers), they are written to after the
+// snapshot and the trend store of the run have been written, so a failing notifier does not lose the data of the run
+func (m Meta) GetNotifierSinks() []Sink {
+	sinks := []Sink{}
+	for _, n := range m.GetNotifiers() {
+		sinks = append(sinks, notifierSink{meta: func(context.Context) Meta { return m }, notifier: n})
+	}
+	return sinks
+}
+
+// WriteSinks writes the report to all sinks, the first error is returned after all sinks have been written to,
+// so a failing sink (like an unreachable webhook) does not keep the report from the other sinks
+func WriteSinks(ctx context.Context, report Report, sinks ...Sink) error {
+	var first error
+	for _, s := range sinks {
+		if err := s.Write(ctx, report); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// FileSink renders the report to a file, the file is replaced atomically so readers never see a partially written report
+type FileSink struct {
+	Path     string
+	Renderer Renderer
+}
+
+// Write extends FileSink
+func (s FileSink) Write(ctx context.Context, report Report) error {
+	var buf bytes.Buffer
+	if err := s.Renderer.Render(&buf, report); err != nil {
+		return fmt.Errorf("rendering %s: %v", s.Path, err)
+	}
+	if err := writeFileAtomic(s.Path, buf.Bytes()); err != nil {
+		return fmt.Errorf("writing %s: %v", s.Path, err)
+	}
+	return nil
+}
+
+// rendererForFile returns the renderer of the format of the file extension ('.md', '.json', '.html' or '.txt').
+// Files get the full report, -short only shortens the print-out on the console
+func rendererForFile(meta Meta, path string) (Renderer, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return MarkdownRenderer{EmojisOff: meta.Flags.EmojisOff}, nil
+	case ".json":
+		return JSONRenderer{}, nil
+	case ".html", ".htm":
+		return HTMLRenderer{EmojisOff: meta.Flags.EmojisOff}, nil
+	case ".txt":
+		meta.Flags.ShortOn = false
+		meta.Flags.NoColor = true
+		return textRenderer{meta: meta}, nil
+	}
+	return nil, fmt.Errorf("format of %q is unknown, the file extension has to be one of [.md, .json, .html, .txt]", path)
+}
+
+// TextRenderer renders the report like the print-out of the ci-reporter binary, without terminal colors
+type TextRenderer struct {
+	Short     bool
+	EmojisOff bool
+}
+
+// Render extends TextRenderer
+func (t TextRenderer) Render(w io.Writer, report Report) error {
+	return textRenderer{meta: Meta{Flags: metaFlags{ShortOn: t.Short, EmojisOff: t.EmojisOff, NoColor: true}}}.Render(w, report)
+}
+
+// textRenderer renders the print-out with the flags of the run (like -short, -group-by and -sig)
+type textRenderer struct {
+	meta Meta
+}
+
+func (t textRenderer) Render(w io.Writer, report Report) error {
+	meta := t.meta
+	meta.Console = NewConsole(w, meta.Flags.NoColor, meta.Flags.EmojisOff)
+	return meta.PrintReport(report)
+}
+
+// PrintReport prints the report to the console: a section per report (or the records of all reports grouped
+// if -group-by is set), the sig rollup if -sig is set and the known issues
+func (m Meta) PrintReport(report Report) error {
+	if m.Flags.GroupBy != "" {
+		if err := report.PrintGrouped(m, m.Flags.GroupBy); err != nil {
+			return err
+		}
+		report.PrintKnownIssues(m)
+		return nil
+	}
+	c := m.console()
+	for _, reportData := range report {
+		r := reporterFor(reportData.Name)
+		if r == nil {
+			continue
+		}
+		if reportData.Incomplete {
+			c.Printf("\n%s REPORT (incomplete, deadline passed)\n", strings.ToUpper(reportData.Name))
+		} else {
+			c.Printf("\n%s REPORT\n", strings.ToUpper(reportData.Name))
+		}
+		r.Print(m, reportData)
+	}
+	if len(m.Flags.Sigs) > 0 {
+		report.PrintSigRollup(m, m.Flags.Sigs)
+	}
+	report.PrintKnownIssues(m)
+	return nil
+}
+
+// reporterFor returns the reporter that prints the data of a report (the counterpart of reportName), nil if the name is unknown
+func reporterFor(name string) CIReport {
+	switch name {
+	case githubReport:
+		return &GithubReport{}
+	case testgridReport:
+		return &TestgridReport{}
+	case flakeReport:
+		return &FlakeReport{}
+	case providerReport:
+		return &ProviderReport{}
+	case triageReport:
+		return &TriageReport{}
+	case platformReport:
+		return &PlatformReport{}
+	case quarantineReport:
+		return &QuarantineReport{}
+	case prSignalReport:
+		return &PRSignalReport{}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sinksTestReport() Report {
+	return Report{
+		{Name: githubReport, Data: []ReportDataField{{Title: "kubernetes/kubernetes", Records: []ReportDataRecord{{ID: 105242, Title: "[Failing test] gce-serial", URL: "https://github.com/kubernetes/kubernetes/issues/105242"}}}}},
+		{Name: testgridReport, Incomplete: true, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{{Title: "gce-serial", Status: string(failing), Sigs: []string{"sig-node"}}}}}},
+	}
+}
+
+func TestTextRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (TextRenderer{EmojisOff: true}).Render(&buf, sinksTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	if !strings.Contains(text, "\nGITHUB REPORT\n") || !strings.Contains(text, "\nTESTGRID REPORT (incomplete, deadline passed)\n") {
+		t.Errorf("expected a section per report, got\n%s", text)
+	}
+	if !strings.Contains(text, "gce-serial") || strings.Contains(text, "\x1b[") {
+		t.Errorf("expected the records without terminal colors, got\n%s", text)
+	}
+
+	buf.Reset()
+	meta := Meta{Flags: metaFlags{EmojisOff: true, NoColor: true, Sigs: []string{"sig-node"}}}
+	if err := (textRenderer{meta: meta}).Render(&buf, sinksTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "SIG ROLLUP") || !strings.Contains(buf.String(), "sig-node: 1 failing & flaky jobs, 0 issues") {
+		t.Errorf("expected the sig rollup to be written to the renderer, got\n%s", buf.String())
+	}
+}
+
+func TestFileSinks(t *testing.T) {
+	dir := t.TempDir()
+	meta := Meta{Flags: metaFlags{EmojisOff: true, Out: []string{filepath.Join(dir, "report.md"), filepath.Join(dir, "report.json"), filepath.Join(dir, "report.txt")}}}
+	sinks := meta.GetSinks()
+	if len(sinks) != 4 {
+		t.Fatalf("expected the console and 3 file sinks, got %d", len(sinks))
+	}
+	if err := WriteSinks(context.Background(), sinksTestReport(), sinks[1:]...); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{"report.md": "[Failing test] gce-serial", "report.json": `"schema_version"`, "report.txt": "TESTGRID REPORT"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %q in %s, got\n%s", expected, file, data)
+		}
+	}
+
+	if _, err := rendererForFile(meta, "report.pdf"); err == nil {
+		t.Error("expected an unknown file extension to be rejected")
+	}
+}
+
+func TestNotifierSinks(t *testing.T) {
+	meta := Meta{Flags: metaFlags{WebhookURL: "https://hooks.example.com/ci-signal"}}
+	if sinks := meta.GetSinks(); len(sinks) != 1 {
+		t.Errorf("expected only the console sink, notifiers are written after the snapshot and the trend store, got %d sinks", len(sinks))
+	}
+	if sinks := meta.GetNotifierSinks(); len(sinks) != 1 {
+		t.Errorf("expected the webhook notifier sink, got %d sinks", len(sinks))
+	}
+}
+
+// failingSink fails each write
+type failingSink struct{}
+
+func (failingSink) Write(ctx context.Context, report Report) error {
+	return errors.New("webhook unreachable")
+}
+
+func TestWriteSinksWritesAllSinks(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSinks(context.Background(), sinksTestReport(), failingSink{}, WriterSink{Writer: &buf, Renderer: JSONRenderer{}})
+	if err == nil || err.Error() != "webhook unreachable" {
+		t.Errorf("expected the error of the failing sink, got %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Error("expected the sinks after a failing sink to be written")
+	}
+}
+
+func TestOutputFlagJSON(t *testing.T) {
+	for _, args := range [][]string{{"-output", "json"}, {"-json"}} {
+		meta := SetMetaFromArgs(append(args, "-replay", testFixturesDir))
+		sink, ok := meta.GetSinks()[0].(WriterSink)
+		if !ok {
+			t.Fatalf("expected the console to be written by a writer sink, got %T", meta.GetSinks()[0])
+		}
+		var buf bytes.Buffer
+		if err := sink.Renderer.Render(&buf, sinksTestReport()); err != nil {
+			t.Fatal(err)
+		}
+		var output map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &output); err != nil || output["schema_version"] == nil {
+			t.Errorf("expected %v to print the report as json, got error %v for\n%s", args, err, buf.String())
+		}
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SnapshotSchemaVersion version of the snapshot format written by this binary
+//
+//	0: plain report as printed with -json (stored by cron jobs before snapshots existed)
+//	1: report wrapped with generated_at
+//	2: schema_version field, records contain normalized sigs and testgrid summaries contain counts
+//	3: testgrid jobs contain the recent pass rate and the counts of recent runs and failing tests
+const SnapshotSchemaVersion = 3
+
+// snapshotMigrations migrates a decoded snapshot from version i to version i+1
+var snapshotMigrations = []func(snapshot map[string]interface{}) error{
+	migrateSnapshotV0ToV1,
+	migrateSnapshotV1ToV2,
+	migrateSnapshotV2ToV3,
+}
+
+// migrateSnapshot detects the version of a decoded snapshot and migrates it to SnapshotSchemaVersion
+func migrateSnapshot(logger *Logger, data []byte) ([]byte, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	snapshot, version := snapshotVersion(decoded)
+	if version > SnapshotSchemaVersion {
+		// fields unknown to this version are ignored, everything known is still loaded
+		logger.Warn("Snapshot schema version is newer than the supported version, unknown fields are ignored", "version", version, "supported", SnapshotSchemaVersion)
+		return data, nil
+	}
+	for v := version; v < SnapshotSchemaVersion; v++ {
+		if err := snapshotMigrations[v](snapshot); err != nil {
+			return nil, fmt.Errorf("could not migrate snapshot from version %d to %d: %v", v, v+1, err)
+		}
+	}
+	return json.Marshal(snapshot)
+}
+
+// snapshotVersion returns the snapshot as map together with its version
+func snapshotVersion(decoded interface{}) (map[string]interface{}, int) {
+	switch s := decoded.(type) {
+	case []interface{}:
+		return map[string]interface{}{"report": s}, 0
+	case map[string]interface{}:
+		if v, ok := s["schema_version"].(float64); ok {
+			return s, int(v)
+		}
+		return s, 1
+	default:
+		return map[string]interface{}{}, 1
+	}
+}
+
+// migrateSnapshotV0ToV1 plain reports have no generation time, the zero time is used
+func migrateSnapshotV0ToV1(snapshot map[string]interface{}) error {
+	if _, ok := snapshot["generated_at"]; !ok {
+		snapshot["generated_at"] = "0001-01-01T00:00:00Z"
+	}
+	return nil
+}
+
+var migrationCountRegex = regexp.MustCompile(`^(\d+) jobs ([a-z]+)$`)
+
+// migrateSnapshotV1ToV2 derives sigs and summary counts from the printed sig and note strings
+func migrateSnapshotV1ToV2(snapshot map[string]interface{}) error {
+	snapshot["schema_version"] = 2
+	report, _ := snapshot["report"].([]interface{})
+	for _, rd := range report {
+		reportData, ok := rd.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected report data %v", rd)
+		}
+		fields, _ := reportData["data"].([]interface{})
+		for _, f := range fields {
+			field, _ := f.(map[string]interface{})
+			records, _ := field["records"].([]interface{})
+			for _, r := range records {
+				record, _ := r.(map[string]interface{})
+				if record == nil {
+					continue
+				}
+				notes, _ := record["notes"].([]interface{})
+				if _, ok := record["sigs"]; !ok {
+					// github records store sigs in "sig", testgrid records in a note like "Sig's involved [sig-storage]"
+					source, _ := record["sig"].(string)
+					for _, n := range notes {
+						if note, ok := n.(string); ok && strings.HasPrefix(note, "Sig's involved") {
+							source += " " + note
+						}
+					}
+					sigs := []interface{}{}
+					for _, sig := range extractSigs(source) {
+						sigs = append(sigs, sig)
+					}
+					record["sigs"] = sigs
+				}
+				if _, ok := record["counts"]; !ok && reportData["name"] == testgridReport && record["id"] == float64(testgridReportSummary) {
+					counts := map[string]interface{}{}
+					for _, n := range notes {
+						note, _ := n.(string)
+						if match := migrationCountRegex.FindStringSubmatch(note); match != nil {
+							count, _ := strconv.Atoi(match[1])
+							counts[match[2]] = count
+						}
+					}
+					record["counts"] = counts
+				}
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	// migrationRecentRunsRegex matches the note about the recent runs of a job ("7 of 9 passed recently")
+	migrationRecentRunsRegex = regexp.MustCompile(`^(\d+) of (\d+) passed recently$`)
+	// migrationFailingTestsRegex matches the note about the failing tests of a job ("Currently 3 test are failing")
+	migrationFailingTestsRegex = regexp.MustCompile(`^Currently (\d+) test are failing$`)
+)
+
+// migrateSnapshotV2ToV3 derives the recent pass rate and the counts of recent runs and failing tests of testgrid jobs from their notes
+func migrateSnapshotV2ToV3(snapshot map[string]interface{}) error {
+	snapshot["schema_version"] = 3
+	report, _ := snapshot["report"].([]interface{})
+	for _, rd := range report {
+		reportData, ok := rd.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected report data %v", rd)
+		}
+		if reportData["name"] != testgridReport {
+			continue
+		}
+		fields, _ := reportData["data"].([]interface{})
+		for _, f := range fields {
+			field, _ := f.(map[string]interface{})
+			records, _ := field["records"].([]interface{})
+			for _, r := range records {
+				record, _ := r.(map[string]interface{})
+				if record == nil || record["id"] != float64(testgridReportDetails) {
+					continue
+				}
+				counts, _ := record["counts"].(map[string]interface{})
+				if counts == nil {
+					counts = map[string]interface{}{}
+				}
+				notes, _ := record["notes"].([]interface{})
+				for _, n := range notes {
+					note, _ := n.(string)
+					note = strings.TrimSpace(stripColors(note))
+					if match := migrationRecentRunsRegex.FindStringSubmatch(note); match != nil {
+						passes, _ := strconv.Atoi(match[1])
+						runs, _ := strconv.Atoi(match[2])
+						if counts[recentRunsCount] == nil && runs > 0 {
+							counts[recentRunsCount] = runs
+						}
+						if _, ok := record["recent_pass_rate"]; !ok && runs > 0 {
+							record["recent_pass_rate"] = float64(passes) / float64(runs)
+						}
+					} else if match := migrationFailingTestsRegex.FindStringSubmatch(note); match != nil {
+						// failing test counts are only stored for jobs with failing tests
+						if failingTests, _ := strconv.Atoi(match[1]); failingTests > 0 && counts[failingTestsCount] == nil {
+							counts[failingTestsCount] = failingTests
+						}
+					}
+				}
+				if len(counts) > 0 {
+					record["counts"] = counts
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// v1ReportJSON report as stored before snapshots had a schema version, sigs and counts are only part of the printed strings
+const v1ReportJSON = `[
+	{"name": "testgrid", "data": [{"emoji": "", "title": "Master-Blocking", "records": [
+		{"id": 0, "title": "", "notes": ["3 jobs total", "1 jobs passing", "0 jobs flaky", "2 jobs failing"]},
+		{"id": 1, "title": "gce-cos-master-serial", "status": "FAILING", "notes": ["Sig's involved [sig-storage sig-cluster-lifecycle]"]}
+	]}]},
+	{"name": "github", "data": [{"emoji": "", "title": "", "records": [
+		{"id": 105242, "title": "[Failing test] gce-serial", "sig": "sig/node, sig/api-machinery"}
+	]}]}
+]`
+
+func writeTestSnapshotFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "snapshot-20211020T090000Z.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkMigratedV1Report checks that sigs and summary counts have been derived from the printed strings
+func checkMigratedV1Report(t *testing.T, snapshot Snapshot) {
+	if snapshot.SchemaVersion != SnapshotSchemaVersion {
+		t.Errorf("expected schema version %d, got %d", SnapshotSchemaVersion, snapshot.SchemaVersion)
+	}
+	if len(snapshot.Report) != 2 {
+		t.Fatalf("expected the testgrid and github report, got %+v", snapshot.Report)
+	}
+	testgridRecords := snapshot.Report[0].Data[0].Records
+	expectedCounts := map[string]int{"total": 3, "passing": 1, "flaky": 0, "failing": 2}
+	if !reflect.DeepEqual(testgridRecords[0].Counts, expectedCounts) {
+		t.Errorf("expected summary counts %v, got %v", expectedCounts, testgridRecords[0].Counts)
+	}
+	if expected := []string{"sig-storage", "sig-cluster-lifecycle"}; !reflect.DeepEqual(testgridRecords[1].Sigs, expected) {
+		t.Errorf("expected the sigs of the job note %v, got %v", expected, testgridRecords[1].Sigs)
+	}
+	if testgridRecords[1].Counts != nil || testgridRecords[1].RecentPassRate != nil {
+		t.Errorf("expected no job counts without notes about them, got %v", testgridRecords[1].Counts)
+	}
+	if expected := []string{"sig-node", "sig-api-machinery"}; !reflect.DeepEqual(snapshot.Report[1].Data[0].Records[0].Sigs, expected) {
+		t.Errorf("expected the sigs of the github issue %v, got %v", expected, snapshot.Report[1].Data[0].Records[0].Sigs)
+	}
+}
+
+func TestReadSnapshotMigratesV0(t *testing.T) {
+	snapshot, err := ReadSnapshot(discardLogger, writeTestSnapshotFile(t, v1ReportJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.GeneratedAt.IsZero() {
+		t.Errorf("expected the zero time for plain reports, got %v", snapshot.GeneratedAt)
+	}
+	checkMigratedV1Report(t, snapshot)
+}
+
+func TestReadSnapshotMigratesV1(t *testing.T) {
+	snapshot, err := ReadSnapshot(discardLogger, writeTestSnapshotFile(t, `{"generated_at": "2021-10-20T09:00:00Z", "report": `+v1ReportJSON+`}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.GeneratedAt.Equal(time.Date(2021, 10, 20, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the generation time to be kept, got %v", snapshot.GeneratedAt)
+	}
+	checkMigratedV1Report(t, snapshot)
+}
+
+func TestReadSnapshotCurrentVersion(t *testing.T) {
+	// sigs and counts of the current version are kept as they are, even if the notes tell otherwise
+	snapshot, err := ReadSnapshot(discardLogger, writeTestSnapshotFile(t, `{"schema_version": 3, "generated_at": "2021-10-20T09:00:00Z", "report": [
+		{"name": "testgrid", "data": [{"title": "Master-Blocking", "records": [
+			{"id": 0, "notes": ["3 jobs total"], "counts": {"total": 4}, "sigs": []},
+			{"id": 1, "title": "gce-cos-master-serial", "notes": ["Sig's involved [sig-storage]"], "sigs": ["sig-node"]}
+		]}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := snapshot.Report[0].Data[0].Records
+	if records[0].Counts["total"] != 4 || !reflect.DeepEqual(records[1].Sigs, []string{"sig-node"}) {
+		t.Errorf("expected the snapshot not to be migrated, got %+v", records)
+	}
+}
+
+func TestReadSnapshotMigratesV2(t *testing.T) {
+	snapshot, err := ReadSnapshot(discardLogger, writeTestSnapshotFile(t, `{"schema_version": 2, "generated_at": "2021-10-20T09:00:00Z", "report": [
+		{"name": "testgrid", "data": [{"title": "Master-Blocking", "records": [
+			{"id": 0, "notes": ["3 jobs total"], "counts": {"total": 3}},
+			{"id": 1, "title": "gce-cos-master-serial", "status": "FAILING", "notes": ["Currently 3 test are failing", "4 of 10 passed recently"], "counts": {"failing_tests": 3, "regressions": 2, "never_passed": 1}},
+			{"id": 1, "title": "gce-cos-master-default", "status": "FLAKY", "notes": ["7 of 9 passed recently"]}
+		]}]},
+		{"name": "github", "data": [{"title": "", "records": [{"id": 1, "title": "4 of 10 passed recently", "notes": ["4 of 10 passed recently"]}]}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := snapshot.Report[0].Data[0].Records
+	if !reflect.DeepEqual(records[0].Counts, map[string]int{"total": 3}) || records[0].RecentPassRate != nil {
+		t.Errorf("expected the summary to be kept, got %+v", records[0])
+	}
+	expectedCounts := map[string]int{failingTestsCount: 3, regressionsCount: 2, neverPassedCount: 1, recentRunsCount: 10}
+	if !reflect.DeepEqual(records[1].Counts, expectedCounts) || records[1].RecentPassRate == nil || *records[1].RecentPassRate != 0.4 {
+		t.Errorf("expected counts %v and the pass rate 0.4, got %+v", expectedCounts, records[1])
+	}
+	if !reflect.DeepEqual(records[2].Counts, map[string]int{recentRunsCount: 9}) || records[2].RecentPassRate == nil || *records[2].RecentPassRate != 7.0/9 {
+		t.Errorf("expected the recent runs of the flaky job, got %+v", records[2])
+	}
+	if issue := snapshot.Report[1].Data[0].Records[0]; issue.Counts != nil || issue.RecentPassRate != nil {
+		t.Errorf("expected github records not to be migrated, got %+v", issue)
+	}
+}
+
+func TestReadSnapshotNewerVersion(t *testing.T) {
+	snapshot, err := ReadSnapshot(discardLogger, writeTestSnapshotFile(t, `{"schema_version": 99, "generated_at": "2021-10-20T09:00:00Z", "unknown": true, "report": [
+		{"name": "github", "data": [{"title": "", "records": [{"id": 1, "title": "issue", "sigs": ["sig-node"], "future": 1}]}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.SchemaVersion != 99 || snapshot.Report[0].Data[0].Records[0].Title != "issue" {
+		t.Errorf("expected the known fields of a newer snapshot to be loaded, got %+v", snapshot)
+	}
+}
+
+func TestReadSnapshotMigrationErrors(t *testing.T) {
+	if _, err := ReadSnapshot(discardLogger, writeTestSnapshotFile(t, `{"generated_at": "2021-10-20T09:00:00Z", "report": ["testgrid"]}`)); err == nil {
+		t.Error("expected an error if the report data of an old snapshot can not be migrated")
+	}
+	if _, err := ReadSnapshot(discardLogger, writeTestSnapshotFile(t, `{"report": [`)); err == nil {
+		t.Error("expected an error if the snapshot is no json")
+	}
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Snapshot compression options
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+const (
+	snapshotFilePrefix = "snapshot-"
+	snapshotTimeLayout = "20060102T150405Z"
+	snapshotLockFile   = ".snapshot.lock"
+	// a lock file older than this is considered to be left behind by a crashed run
+	snapshotLockStaleAfter = 10 * time.Minute
+	snapshotLockRefresh    = snapshotLockStaleAfter / 4
+	snapshotLockTimeout    = 30 * time.Second
+)
+
+// Magic bytes used to detect the compression of a stored snapshot
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Snapshot the report of one ci-reporter run that is stored to compare runs with each other
+type Snapshot struct {
+	SchemaVersion int       `json:"schema_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Report        Report    `json:"report"`
+}
+
+// WriteSnapshot stores the snapshot in the directory dir using the given compression and returns the path of the file
+func WriteSnapshot(dir string, snapshot Snapshot, compression string) (string, error) {
+	snapshot.SchemaVersion = SnapshotSchemaVersion
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	extension := ".json"
+	switch compression {
+	case compressionNone, "":
+	case compressionGzip:
+		extension += ".gz"
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		data = buf.Bytes()
+	case compressionZstd:
+		extension += ".zst"
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			return "", err
+		}
+		data = w.EncodeAll(data, nil)
+		w.Close()
+	default:
+		return "", fmt.Errorf("unknown snapshot compression %q, options: '%s', '%s', '%s'", compression, compressionNone, compressionGzip, compressionZstd)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	unlock, err := lockSnapshotDir(dir)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	first := filepath.Join(dir, snapshotFilePrefix+snapshot.GeneratedAt.UTC().Format(snapshotTimeLayout))
+	name := first
+	// runs of the same second get a counter (snapshot-20211020T090000Z-2.json) instead of replacing the snapshot of the earlier run,
+	// snapshots of all compressions are counted so the counter keeps the order of the runs
+	for i := 2; ; i++ {
+		exists, err := snapshotExists(name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", first, i)
+	}
+	path := name + extension
+	return path, writeFileAtomic(path, data)
+}
+
+// snapshotExists tells if a snapshot of any compression has been stored under the name (path without extension)
+func snapshotExists(name string) (bool, error) {
+	for _, extension := range []string{".json", ".json.gz", ".json.zst"} {
+		if _, err := os.Lstat(name + extension); err == nil {
+			return true, nil
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// writeFileAtomic writes data to a temporary file which gets synced and renamed to path,
+// readers never see a partially written file even if the process crashes while writing
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	// removing the temp file fails after a successful rename, which is fine
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// sync the directory so the rename itself is persisted (not supported on every platform)
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// lockSnapshotDir creates a lock file in dir so concurrent runs (watch mode, cron) don't write snapshots at the same time.
+// The returned function releases the lock.
+func lockSnapshotDir(dir string) (func(), error) {
+	lockPath := filepath.Join(dir, snapshotLockFile)
+	deadline := time.Now().Add(snapshotLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			// the lock is touched while it is held, so a slow write is not mistaken for a crashed run
+			done := make(chan struct{})
+			go func() {
+				ticker := time.NewTicker(snapshotLockRefresh)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						now := time.Now()
+						_ = os.Chtimes(lockPath, now, now)
+					}
+				}
+			}()
+			return func() {
+				close(done)
+				os.Remove(lockPath)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		// remove lock files that have been left behind by crashed runs
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > snapshotLockStaleAfter {
+			if err := removeStaleLock(lockPath, info); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("could not acquire snapshot lock %s, another run is still writing", lockPath)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// removeStaleLock removes the lock file if it is still the stale lock. The lock is renamed before it is removed,
+// so if two runs found the same stale lock only one of them removes it and the lock the other run took meanwhile is kept
+func removeStaleLock(lockPath string, stale os.FileInfo) error {
+	moved := fmt.Sprintf("%s.stale-%d-%d", lockPath, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockPath, moved); err != nil {
+		if os.IsNotExist(err) {
+			// another run removed the stale lock first
+			return nil
+		}
+		return err
+	}
+	// a new lock may reuse the file of the stale lock, so the modification time is compared as well
+	if info, err := os.Stat(moved); err == nil && (!os.SameFile(info, stale) || !info.ModTime().Equal(stale.ModTime())) {
+		// another run replaced the stale lock in the meantime, its lock is put back unless a newer lock exists
+		if err := os.Link(moved, lockPath); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return os.Remove(moved)
+}
+
+// ReadSnapshot reads a stored snapshot, compressed (gzip, zstd) and plain json snapshots are detected automatically.
+// Snapshots written by older versions are migrated to the current SnapshotSchemaVersion, snapshots of newer versions are logged as warning.
+func ReadSnapshot(logger *Logger, path string) (Snapshot, error) {
+	var snapshot Snapshot
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	data, err = decompressSnapshot(data)
+	if err != nil {
+		return snapshot, fmt.Errorf("could not decompress snapshot %s: %v", path, err)
+	}
+	data, err = migrateSnapshot(logger, data)
+	if err != nil {
+		return snapshot, fmt.Errorf("could not load snapshot %s: %v", path, err)
+	}
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
+}
+
+func decompressSnapshot(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	case bytes.HasPrefix(data, zstdMagic):
+		r, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return r.DecodeAll(data, nil)
+	default:
+		return data, nil
+	}
+}
+
+// ListSnapshots returns the paths of all snapshots stored in dir, ordered from oldest to newest
+func ListSnapshots(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, e := range entries {
+		// temporary files of in-flight writes start with a dot and are skipped
+		if !e.IsDir() && strings.HasPrefix(e.Name(), snapshotFilePrefix) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	// file names start with the UTC timestamp of the run followed by the counter of runs of the same second
+	sort.SliceStable(paths, func(i, j int) bool {
+		timeI, countI := snapshotOrder(filepath.Base(paths[i]))
+		timeJ, countJ := snapshotOrder(filepath.Base(paths[j]))
+		if timeI != timeJ {
+			return timeI < timeJ
+		}
+		return countI < countJ
+	})
+	return paths, nil
+}
+
+// snapshotOrder returns the timestamp and the counter of a snapshot file name,
+// e.g. "snapshot-20211020T090000Z-2.json.gz" -> "20211020T090000Z", 2
+func snapshotOrder(name string) (string, int) {
+	stamp := strings.TrimPrefix(name, snapshotFilePrefix)
+	if i := strings.Index(stamp, "."); i >= 0 {
+		stamp = stamp[:i]
+	}
+	if i := strings.LastIndex(stamp, "-"); i >= 0 {
+		if count, err := strconv.Atoi(stamp[i+1:]); err == nil {
+			return stamp[:i], count
+		}
+	}
+	return stamp, 1
+}
+
+// LatestSnapshot reads the newest snapshot stored in dir, ok is false if there is no snapshot yet
+func LatestSnapshot(logger *Logger, dir string) (snapshot Snapshot, ok bool, err error) {
+	paths, err := ListSnapshots(dir)
+	if os.IsNotExist(err) || len(paths) == 0 {
+		return snapshot, false, nil
+	}
+	if err != nil {
+		return snapshot, false, err
+	}
+	snapshot, err = ReadSnapshot(logger, paths[len(paths)-1])
+	return snapshot, err == nil, err
+}
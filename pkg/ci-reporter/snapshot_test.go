@@ -0,0 +1,246 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotCompressionRoundTrip(t *testing.T) {
+	generatedAt := time.Date(2021, 10, 20, 9, 0, 0, 0, time.UTC)
+	report := Report{{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+		{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FAILING", Notes: []string{"2 failing tests"}},
+	}}}}}
+	magic := map[string][]byte{compressionNone: []byte("{"), compressionGzip: gzipMagic, compressionZstd: zstdMagic}
+	for compression, extension := range map[string]string{compressionNone: ".json", compressionGzip: ".json.gz", compressionZstd: ".json.zst"} {
+		t.Run(compression, func(t *testing.T) {
+			dir := t.TempDir()
+			path, err := WriteSnapshot(dir, Snapshot{GeneratedAt: generatedAt, Report: report}, compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.Base(path) != "snapshot-20211020T090000Z"+extension {
+				t.Errorf("expected the file extension %s, got %s", extension, path)
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(data, magic[compression]) {
+				t.Errorf("expected the snapshot to be stored with %s compression, got %x", compression, data[:4])
+			}
+			snapshot, err := ReadSnapshot(discardLogger, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snapshot.SchemaVersion != SnapshotSchemaVersion || !snapshot.GeneratedAt.Equal(generatedAt) || !reflect.DeepEqual(snapshot.Report, report) {
+				t.Errorf("expected the snapshot to be read as it has been written, got %+v", snapshot)
+			}
+		})
+	}
+
+	if _, err := WriteSnapshot(t.TempDir(), Snapshot{GeneratedAt: generatedAt}, "lz4"); err == nil {
+		t.Error("expected an unknown compression to be rejected")
+	}
+}
+
+func TestWriteSnapshotSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	generatedAt := time.Date(2021, 10, 20, 9, 0, 0, 0, time.UTC)
+	paths := []string{}
+	for i, compression := range []string{compressionNone, compressionNone, compressionGzip, compressionNone} {
+		report := Report{{Name: testgridReport, Data: []ReportDataField{{Title: fmt.Sprintf("run %d", i)}}}}
+		path, err := WriteSnapshot(dir, Snapshot{GeneratedAt: generatedAt, Report: report}, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	expected := []string{"snapshot-20211020T090000Z.json", "snapshot-20211020T090000Z-2.json", "snapshot-20211020T090000Z-3.json.gz", "snapshot-20211020T090000Z-4.json"}
+	for i, path := range paths {
+		if filepath.Base(path) != expected[i] {
+			t.Errorf("expected run %d to be stored as %s, got %s", i, expected[i], path)
+		}
+	}
+
+	// no run replaced the snapshot of an earlier run, the last run is the latest snapshot
+	listed, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(listed, paths) {
+		t.Errorf("expected the snapshots ordered by their counter %v, got %v", paths, listed)
+	}
+	for i, path := range paths {
+		snapshot, err := ReadSnapshot(discardLogger, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title := snapshot.Report[0].Data[0].Title; title != fmt.Sprintf("run %d", i) {
+			t.Errorf("expected %s to keep the report of run %d, got %q", path, i, title)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.json")
+	if err := ioutil.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("expected the file to be replaced, got %q", data)
+	}
+	// the temporary file has been renamed, nothing else is left behind
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "report.json" || entries[0].Mode().Perm() != 0o644 {
+		t.Errorf("expected only report.json with mode 0644 in the directory, got %v", entries)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "report.json"), []byte("new")); err == nil {
+		t.Error("expected an error if the directory does not exist")
+	}
+}
+
+func TestLockSnapshotDir(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := lockSnapshotDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a second run waits until the first run releases the lock
+	locked := make(chan func())
+	go func() {
+		unlockSecond, err := lockSnapshotDir(dir)
+		if err != nil {
+			t.Error(err)
+		}
+		locked <- unlockSecond
+	}()
+	select {
+	case <-locked:
+		t.Fatal("expected the second run to wait for the lock")
+	case <-time.After(300 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case unlockSecond := <-locked:
+		unlockSecond()
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the second run to get the lock after it has been released")
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotLockFile)); !os.IsNotExist(err) {
+		t.Errorf("expected the lock file to be removed after the lock has been released, got %v", err)
+	}
+}
+
+func TestLockSnapshotDirStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, snapshotLockFile)
+	if err := ioutil.WriteFile(lockPath, []byte("4242\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the lock file of a crashed run
+	stale := time.Now().Add(-2 * snapshotLockStaleAfter)
+	if err := os.Chtimes(lockPath, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		unlock, err := lockSnapshotDir(dir)
+		if err == nil {
+			unlock()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stale lock to be removed")
+	}
+}
+
+func TestRemoveStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, snapshotLockFile)
+	if err := ioutil.WriteFile(lockPath, []byte("4242\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	staleTime := time.Now().Add(-2 * snapshotLockStaleAfter)
+	if err := os.Chtimes(lockPath, staleTime, staleTime); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := os.Stat(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another run that found the same stale lock removed it and took the lock before this run
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(lockPath, []byte("4343\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeStaleLock(lockPath, stale); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(lockPath)
+	if err != nil || string(data) != "4343\n" {
+		t.Errorf("expected the lock of the other run to be kept, got %q (%v)", data, err)
+	}
+
+	// the stale lock itself is removed, nothing is left behind
+	current, err := os.Stat(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := removeStaleLock(lockPath, current); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected the stale lock to be removed, got %v", entries)
+	}
+	if err := removeStaleLock(lockPath, current); err != nil {
+		t.Errorf("expected no error if another run removed the stale lock first, got %v", err)
+	}
+}
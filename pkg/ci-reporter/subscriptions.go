@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Subscription sends the failures attributed to a sig to a sig specific slack channel or webhook
+type Subscription struct {
+	// Sig like "sig-node"
+	Sig string `json:"sig"`
+	// SlackWebhookURL slack incoming webhook the failures are posted to
+	SlackWebhookURL string `json:"slack_webhook_url"`
+	// Channel overwrites the channel of the slack incoming webhook (like "#sig-node-ci")
+	Channel string `json:"channel"`
+	// WebhookURL generic webhook the failures are posted to in json format
+	WebhookURL string `json:"webhook_url"`
+	// Template go template file used to render the payload
+	Template string `json:"template"`
+	// Mentions slack users or groups mentioned with the failures (like "<!subteam^S0123>"), see MentionPolicy
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+// subscriptionsFile format of the file set via -subscriptions
+type subscriptionsFile struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// LoadSubscriptions reads sig subscriptions from a json file
+func LoadSubscriptions(path string) ([]Subscription, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file subscriptionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i, s := range file.Subscriptions {
+		if s.Sig == "" || (s.SlackWebhookURL == "" && s.WebhookURL == "") {
+			return nil, fmt.Errorf("subscription %d needs a sig and a slack_webhook_url or webhook_url", i)
+		}
+		file.Subscriptions[i].Sig = normalizeSig(s.Sig)
+	}
+	return file.Subscriptions, nil
+}
+
+// NotifySubscriptions sends each subscribed sig the failures attributed to it.
+// If a previous report is given only records that are new since the previous report are sent.
+func NotifySubscriptions(meta Meta, subscriptions []Subscription, report Report, previous *Report) error {
+	if meta.Flags.ReadOnly {
+		return errReadOnly
+	}
+	failures := report
+	if previous != nil {
+		failures = NewRecords(*previous, report)
+	}
+	for _, s := range subscriptions {
+		slice := sliceReportBySig(failures, s.Sig)
+		if len(slice) == 0 {
+			continue
+		}
+		tmpl, err := loadOptionalPayloadTemplate(s.Template)
+		if err != nil {
+			return err
+		}
+		notifiers := []Notifier{}
+		if s.SlackWebhookURL != "" {
+			notifiers = append(notifiers, SlackNotifier{URL: s.SlackWebhookURL, Channel: s.Channel, Template: tmpl, Text: recordsText, Mentions: s.Mentions})
+		}
+		if s.WebhookURL != "" {
+			notifiers = append(notifiers, WebhookNotifier{URL: s.WebhookURL, Template: tmpl})
+		}
+		for _, n := range notifiers {
+			if err := n.Notify(meta, slice); err != nil {
+				return fmt.Errorf("could not notify %s subscription: %v", s.Sig, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sliceReportBySig returns the failing & flaky jobs and issues attributed to a sig, testgrid summaries are dropped
+func sliceReportBySig(report Report, sig string) Report {
+	slice := Report{}
+	for _, reportData := range report {
+		filtered := filterReportDataBySigs(reportData, []string{sig})
+		fields := []ReportDataField{}
+		for _, field := range filtered.Data {
+			records := []ReportDataRecord{}
+			for _, record := range field.Records {
+				if matchesSigs(record, []string{sig}) {
+					records = append(records, record)
+				}
+			}
+			if len(records) > 0 {
+				field.Records = records
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			slice = append(slice, ReportData{Name: reportData.Name, Data: fields, Incomplete: reportData.Incomplete})
+		}
+	}
+	return slice
+}
+
+// recordKey identifies a record across runs (github issues by number, jobs and tests of the other reports by their title per section)
+func recordKey(reportName string, field ReportDataField, record ReportDataRecord) string {
+	if reportName == githubReport {
+		return fmt.Sprintf("%s/%s/%d", reportName, field.Title, record.ID)
+	}
+	return fmt.Sprintf("%s/%s/%d/%s", reportName, field.Title, record.ID, record.Title)
+}
+
+// NewRecords returns all records of current that are not part of previous
+func NewRecords(previous Report, current Report) Report {
+	known := map[string]bool{}
+	for _, reportData := range previous {
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				known[recordKey(reportData.Name, field, record)] = true
+			}
+		}
+	}
+	newReport := Report{}
+	for _, reportData := range current {
+		fields := []ReportDataField{}
+		for _, field := range reportData.Data {
+			records := []ReportDataRecord{}
+			for _, record := range field.Records {
+				if !known[recordKey(reportData.Name, field, record)] {
+					records = append(records, record)
+				}
+			}
+			if len(records) > 0 {
+				field.Records = records
+				fields = append(fields, field)
+			}
+		}
+		newReport = append(newReport, ReportData{Name: reportData.Name, Data: fields, Incomplete: reportData.Incomplete})
+	}
+	return newReport
+}
+
+// recordsText lists every record of a report as one line
+func recordsText(report Report) string {
+	var sb strings.Builder
+	for _, reportData := range report {
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				if reportData.Name == testgridReport {
+					sb.WriteString(fmt.Sprintf("%s %s (%s) %s\n", record.Status, record.Title, field.Title, record.URL))
+				} else if reportData.Name == githubReport {
+					// field.Title names the repository if multiple repositories are reported
+					sb.WriteString(fmt.Sprintf("%s#%d %s %s\n", field.Title, record.ID, record.Title, record.URL))
+				} else {
+					sb.WriteString(fmt.Sprintf("#%d %s %s\n", record.ID, record.Title, record.URL))
+				}
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testSubscriptionReport() Report {
+	return Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Notes: []string{"2 jobs total"}},
+			{ID: testgridReportDetails, Title: "gce-serial", Status: "FAILING", Sigs: []string{"sig-node"}},
+			{ID: testgridReportDetails, Title: "gce-slow", Status: "FLAKY", Sigs: []string{"sig-network"}},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{
+			{Records: []ReportDataRecord{{ID: 1, Title: "node issue", Sigs: []string{"sig-node"}}}},
+			{Records: []ReportDataRecord{{ID: 2, Title: "network issue", Sigs: []string{"sig-network"}}}},
+		}},
+	}
+}
+
+func TestNotifySubscriptions(t *testing.T) {
+	messages := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+		messages[message["channel"]] = message["text"]
+	}))
+	defer server.Close()
+
+	subscriptions := []Subscription{
+		{Sig: "sig-node", SlackWebhookURL: server.URL, Channel: "#sig-node-ci"},
+		{Sig: "sig-network", SlackWebhookURL: server.URL, Channel: "#sig-network-ci"},
+	}
+	// the network issue has been reported before and is not sent again
+	previous := Report{{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{{ID: 2}}}}}}
+	if err := NotifySubscriptions(Meta{}, subscriptions, testSubscriptionReport(), &previous); err != nil {
+		t.Fatal(err)
+	}
+
+	node := messages["#sig-node-ci"]
+	if !strings.Contains(node, "gce-serial") || !strings.Contains(node, "#1 node issue") || strings.Contains(node, "network") {
+		t.Errorf("unexpected sig-node message %q", node)
+	}
+	network := messages["#sig-network-ci"]
+	if !strings.Contains(network, "gce-slow") || strings.Contains(network, "#2") {
+		t.Errorf("unexpected sig-network message %q", network)
+	}
+}
+
+func TestReadOnlyDisablesIntegrations(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	meta := Meta{Flags: metaFlags{ReadOnly: true}}
+	notifiers := []Notifier{
+		WebhookNotifier{URL: server.URL},
+		SlackNotifier{URL: server.URL},
+		IssueCommentNotifier{Issue: IssueReference{Owner: "kubernetes", Repo: "sig-release", Number: 1}},
+	}
+	for _, n := range notifiers {
+		if err := n.Notify(meta, testSubscriptionReport()); err != errReadOnly {
+			t.Errorf("expected %T to be disabled in read-only mode, got %v", n, err)
+		}
+	}
+	subscriptions := []Subscription{{Sig: "sig-node", SlackWebhookURL: server.URL}}
+	if err := NotifySubscriptions(meta, subscriptions, testSubscriptionReport(), nil); err != errReadOnly {
+		t.Errorf("expected subscriptions to be disabled in read-only mode, got %v", err)
+	}
+	meta.Flags.SlackWebhookURL = server.URL
+	if notifiers := meta.GetNotifiers(); len(notifiers) != 0 {
+		t.Errorf("expected no notifiers in read-only mode, got %v", notifiers)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests in read-only mode, got %d", requests)
+	}
+}
+
+func TestNewRecordsOfReportsWithoutIssueNumbers(t *testing.T) {
+	previous := Report{{Name: flakeReport, Data: []ReportDataField{{Title: "Flakiest jobs", Records: []ReportDataRecord{
+		{Title: "gce-serial", Status: "FLAKY"},
+	}}}}}
+	current := Report{{Name: flakeReport, Data: []ReportDataField{{Title: "Flakiest jobs", Records: []ReportDataRecord{
+		{Title: "gce-serial", Status: "FLAKY"},
+		{Title: "gce-slow", Status: "FLAKY"},
+	}}}}}
+	newRecords := NewRecords(previous, current)
+	if len(newRecords) != 1 || len(newRecords[0].Data) != 1 || len(newRecords[0].Data[0].Records) != 1 || newRecords[0].Data[0].Records[0].Title != "gce-slow" {
+		t.Errorf("expected the records of the flake report to be told apart by their title but got %v", newRecords)
+	}
+}
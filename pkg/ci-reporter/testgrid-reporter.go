@@ -17,30 +17,33 @@ limitations under the License.
 package cireporter
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"reflect"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // TestgridReport used to implement RequestData & Print for testgrid report data
 type TestgridReport struct {
 	ReportData ReportData
+	// JobStatus status of every job per dashboard (like "Master-Blocking" => "gce-cos-master-default" => "PASSING"),
+	// ReportData only lists the jobs that are not passing
+	JobStatus map[string]map[string]string
 }
 
 // RequestData this function is used to accumulate a summary of testgrid
-func (r *TestgridReport) RequestData(meta Meta, wg *sync.WaitGroup) ReportData {
-	// The report checks master-blocking and master-informing
-	requiredJobs := []testgridJob{
-		{OutputName: "Master-Blocking", URLName: string(sigReleaseMasterBlocking), Emoji: masterBlockingEmoji},
-		{OutputName: "Master-Informing", URLName: string(sigReleaseMasterInforming), Emoji: masterInformingEmoji},
-	}
+func (r *TestgridReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	meta = meta.withContext(ctx)
+	// The report checks master-blocking and master-informing unless other dashboards are set via -dashboards
+	requiredJobs := meta.Flags.dashboards()
 
 	// If a release version got specified add additional jobs to report
 	if len(meta.Flags.ReleaseVersion) > 0 {
@@ -50,35 +53,39 @@ func (r *TestgridReport) RequestData(meta Meta, wg *sync.WaitGroup) ReportData {
 		}
 	}
 
-	return meta.DataPostProcessing(r, testgridReport, assembleTestgridRequests(meta, requiredJobs), wg)
+	errc := make(chan error, 1)
+	jobStatus := make([]map[string]string, len(requiredJobs))
+	reportData := meta.DataPostProcessing(r, testgridReport, assembleTestgridRequests(ctx, meta, requiredJobs, jobStatus, errc), wg)
+	if err := <-errc; err != nil {
+		return reportData, fmt.Errorf("requesting dashboards: %v", err)
+	}
+	r.JobStatus = map[string]map[string]string{}
+	for i, job := range requiredJobs {
+		r.JobStatus[job.OutputName] = jobStatus[i]
+	}
+	return reportData, nil
 }
 
 // Print extends TestgridReport and prints report data to the console
 func (r *TestgridReport) Print(meta Meta, reportData ReportData) {
+	c := meta.console()
 	for _, reportField := range reportData.Data {
-		headerLine := fmt.Sprintf("\n\n%s Tests in %s", reportField.Emoji, reportField.Title)
-		if meta.Flags.EmojisOff {
-			headerLine = fmt.Sprintf("\n\nTests in %s", reportField.Title)
-		}
+		headerLine := "\n\n" + c.heading(reportField.Emoji, fmt.Sprintf("Tests in %s", reportField.Title))
 		for _, stat := range reportField.Records {
 			if stat.ID == testgridReportSummary {
-				fmt.Println(headerLine)
+				c.Println(headerLine)
 				for _, note := range stat.Notes {
-					fmt.Println("- " + note)
+					c.Println("- " + note)
 				}
-				fmt.Print("\n")
+				c.Print("\n")
 				if !meta.Flags.ShortOn {
-					fmt.Print("\nFAILING & FLAKY JOBS:\n")
+					c.Print("\nFAILING & FLAKY JOBS:\n")
 				}
 			} else if stat.ID == testgridReportDetails {
-				if meta.Flags.EmojisOff {
-					fmt.Printf("%s severity:%d, %s\n", stat.Status, stat.Severity, stat.Title)
-				} else {
-					fmt.Printf("%s %s %s\n", stat.Status, stat.Highlight, stat.Title)
-				}
-				fmt.Printf("- %s\n", stat.URL)
+				c.Printf("%s\n", c.recordLine(stat))
+				c.Printf("- %s\n", stat.URL)
 				for _, note := range stat.Notes {
-					fmt.Printf("- %s\n", note)
+					c.Printf("- %s\n", note)
 				}
 			}
 		}
@@ -95,51 +102,92 @@ func (r TestgridReport) GetData() ReportData {
 	return r.ReportData
 }
 
-func assembleTestgridRequests(meta Meta, requiredJobs []testgridJob) chan ReportDataField {
+// assembleTestgridRequests requests the dashboards and passes them on as fields, the status of every job of requiredJobs[i] is stored in jobStatus[i].
+// The error of the requests is sent to errc before c is closed
+func assembleTestgridRequests(ctx context.Context, meta Meta, requiredJobs []testgridJob, jobStatus []map[string]string, errc chan<- error) chan ReportDataField {
 	c := make(chan ReportDataField)
+	var dependencyPRs *dependencyPRFinder
+	if meta.Flags.CorrelateDependencies {
+		dependencyPRs = newDependencyPRFinder(ctx, meta)
+	}
+	var triageClusters *triageClusterFinder
+	if meta.Flags.Triage {
+		triageClusters = newTriageClusterFinder(ctx, meta, httpClientOrDefault(meta.HTTPClient))
+	}
 	go func() {
 		defer close(c)
-		wg := sync.WaitGroup{}
-		for _, j := range requiredJobs {
-			wg.Add(1)
-			go func(job testgridJob) {
-				jobBaseURL := fmt.Sprintf("https://testgrid.k8s.io/%s", job.URLName)
-				jobsData, err := reqTestgridSiteData(job, jobBaseURL)
-				if err != nil {
-					log.Fatalf("error %v", err)
-				}
-				records := []ReportDataRecord{getSummary(jobsData)}
+		// dashboards are requested with a bounded number of workers and passed on in the order they have been configured
+		// as soon as they are requested, so dashboards requested before a deadline (-deadlines) are reported
+		fields := make([]ReportDataField, len(requiredJobs))
+		errs := runWorkerPoolInOrder(meta.Flags.concurrency(), len(requiredJobs), func(i int) error {
+			job := requiredJobs[i]
+			jobBaseURL := fmt.Sprintf("%s/%s", meta.Flags.testgridURL(), job.URLName)
+			jobsData, err := meta.requestDashboard(ctx, httpClientOrDefault(meta.HTTPClient), jobBaseURL)
+			if err != nil {
+				return err
+			}
+			records := []ReportDataRecord{getSummary(jobsData)}
+			jobStatus[i] = map[string]string{}
+			for jobName, jobData := range jobsData {
+				jobStatus[i][jobName] = string(jobData.OverallStatus)
+			}
 
-				if !meta.Flags.ShortOn {
-					for jobName, jobData := range jobsData {
-						if jobData.OverallStatus != passing {
-							records = append(records, getDetails(jobName, jobData, jobBaseURL, meta.Flags.EmojisOff))
+			if !meta.Flags.ShortOn {
+				jobNames := []string{}
+				for jobName := range jobsData {
+					jobNames = append(jobNames, jobName)
+				}
+				sort.Strings(jobNames)
+				for _, jobName := range jobNames {
+					jobData := jobsData[jobName]
+					if jobData.OverallStatus != passing {
+						record := getDetails(meta, jobName, jobData, jobBaseURL)
+						if triageClusters != nil && jobData.OverallStatus == failing {
+							record.Notes = append(record.Notes, triageClusters.clusterNotes(jobData)...)
+						}
+						if dependencyPRs != nil && jobData.OverallStatus == failing {
+							// dependency correlation is a hint, the job is reported without it if the github requests fail
+							note, err := dependencyPRs.dependencyNote(jobData)
+							if err != nil {
+								meta.logger().Warn("Could not correlate dependency updates", "job", jobName, "error", err)
+							} else if note != "" {
+								record.Notes = append(record.Notes, note)
+							}
 						}
+						records = append(records, record)
 					}
 				}
+			}
 
-				reportData := ReportDataField{
-					Emoji:   job.Emoji,
-					Title:   job.OutputName,
-					Records: records,
-				}
-				c <- reportData
-				wg.Done()
-			}(j)
-		}
-		wg.Wait()
+			fields[i] = ReportDataField{
+				Emoji:   job.Emoji,
+				Title:   job.OutputName,
+				Records: records,
+			}
+			return nil
+		}, func(i int) {
+			if fields[i].Title != "" {
+				c <- fields[i]
+			}
+		})
+		errc <- collectWorkerErrors(errs)
 	}()
 	return c
 }
 
-// This function is used to request job summary data from a testgrid subpage
-func reqTestgridSiteData(job testgridJob, jobBaseURL string) (TestgridData, error) {
+// This function is used to request job summary data from a testgrid subpage, the request is canceled if the context is done
+func reqTestgridSiteData(ctx context.Context, client *http.Client, jobBaseURL string) (TestgridData, error) {
 	// This url points to testgrid/summary which returns a JSON document
 	url := fmt.Sprintf("%s/summary", jobBaseURL)
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 	// Parse body form http request
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -168,6 +216,10 @@ func getSummary(jobs map[string]testgridValue) ReportDataRecord {
 			statuses[stale]++
 		}
 	}
+	result.Counts = map[string]int{}
+	for status, count := range statuses {
+		result.Counts[strings.ToLower(string(status))] = count
+	}
 	result.Notes = append(result.Notes, fmt.Sprintf("%d jobs %s", statuses[total], strings.ToLower(string(total))))
 	result.Notes = append(result.Notes, fmt.Sprintf("%d jobs %s", statuses[passing], strings.ToLower(string(passing))))
 	result.Notes = append(result.Notes, fmt.Sprintf("%d jobs %s", statuses[flaky], strings.ToLower(string(flaky))))
@@ -179,7 +231,7 @@ func getSummary(jobs map[string]testgridValue) ReportDataRecord {
 }
 
 // This function is used get additional information about testgrid jobs
-func getDetails(jobName string, jobData testgridValue, jobBaseURL string, emojisOff bool) ReportDataRecord {
+func getDetails(meta Meta, jobName string, jobData testgridValue, jobBaseURL string) ReportDataRecord {
 	result := ReportDataRecord{ID: testgridReportDetails}
 	result.Status = string(jobData.OverallStatus)
 	result.Title = jobName
@@ -188,39 +240,28 @@ func getDetails(jobName string, jobData testgridValue, jobBaseURL string, emojis
 	// If the status is failing give information about failing tests
 	if jobData.OverallStatus == failing {
 		// Filter sigs
-		sigRegex := regexp.MustCompile(`sig-[a-zA-Z]+`)
 		sigsInvolved := map[string]int{}
 		for _, test := range jobData.Tests {
-			sigs := sigRegex.FindAllString(test.TestName, -1)
-			for _, sig := range sigs {
+			for _, sig := range extractSigs(test.TestName) {
 				sigsInvolved[sig] = sigsInvolved[sig] + 1
 			}
 		}
-		sigs := reflect.ValueOf(sigsInvolved).MapKeys()
+		for sig := range sigsInvolved {
+			result.Sigs = append(result.Sigs, sig)
+		}
+		sort.Strings(result.Sigs)
 
-		result.Notes = append(result.Notes, fmt.Sprintf("Sig's involved %v", sigs))
+		result.Notes = append(result.Notes, fmt.Sprintf("Sig's involved %v", result.Sigs))
 		result.Notes = append(result.Notes, fmt.Sprintf("Currently %d test are failing", len(jobData.Tests)))
+		if len(jobData.Tests) > 0 {
+			regressions, neverPassed := classifyFailingTests(jobData.Tests)
+			result.Counts = map[string]int{failingTestsCount: len(jobData.Tests), regressionsCount: regressions, neverPassedCount: neverPassed}
+			result.Notes = append(result.Notes, failingTestsNote(len(jobData.Tests), regressions, neverPassed))
+		}
+		result.Notes = append(result.Notes, failedBuildNotes(meta.Flags.prowURL(), jobData.Tests)...)
 	}
 
-	const (
-		testgridRegexRecentRuns   = "runs"
-		testgridRegexRecentPasses = "passes"
-
-		thresholdWarning  float64 = 0.5 // 0.0 ... 0.5 -> warning
-		thresholdInfo     float64 = 0.8 // 0.5 ... 0.8 -> info
-		newTestThreshhold float64 = 5.0 // if 0.0 ... 5.0 -> new test
-	)
-	// This regex filters the latest executions
-	// e.g. "8 of 9 (88.9%) recent columns passed (19455 of 19458 or 100.0% cells)" -> 8 passes of 9 runs recently
-	latestExec := getRegexParams(fmt.Sprintf(`(?P<%s>\d{1,2})\sof\s(?P<%s>\d{1,2})`, testgridRegexRecentPasses, testgridRegexRecentRuns), jobData.Status)
-	testgridRegexRecentPassesFloat, err := strconv.ParseFloat(latestExec[testgridRegexRecentPasses], 64)
-	if err != nil {
-		fmt.Println(err)
-	}
-	testgridRegexRecentRunsFloat, err := strconv.ParseFloat(latestExec[testgridRegexRecentRuns], 64)
-	if err != nil {
-		fmt.Println(err)
-	}
+	testgridRegexRecentPassesFloat, testgridRegexRecentRunsFloat := parseRecentRuns(meta.logger(), jobData.Status)
 
 	highlightEmoji := ""
 	if jobData.OverallStatus == failing {
@@ -229,25 +270,158 @@ func getDetails(jobName string, jobData testgridValue, jobBaseURL string, emojis
 		highlightEmoji = statusFlakyEmoji
 	}
 	recentSuccessRate := testgridRegexRecentPassesFloat / testgridRegexRecentRunsFloat
-	severity := Severity(0)
-	if testgridRegexRecentRunsFloat <= newTestThreshhold {
-		severity = LightSeverity
-		highlightEmoji = statusNewEmoji
+	// thresholds and the scorer can be configured via flags, see SeverityConfig
+	severity, isNew := meta.Flags.Severity.rate(severityInput(jobData, jobBaseURL, testgridRegexRecentRunsFloat, recentSuccessRate, time.Now()))
+
+	result.Severity = severity
+	result.FailureClass = classifyFailure(jobData, isNew)
+	if testgridRegexRecentRunsFloat > 0 {
+		result.RecentPassRate = &recentSuccessRate
+		if result.Counts == nil {
+			result.Counts = map[string]int{}
+		}
+		result.Counts[recentRunsCount] = int(testgridRegexRecentRunsFloat)
+	}
+	if isNew {
+		result.Highlight = statusNewEmoji
 	} else {
-		if recentSuccessRate <= thresholdWarning {
-			severity = HighSeverity
-		} else if recentSuccessRate <= thresholdInfo {
-			severity = MediumSeverity
+		result.Highlight = meta.Flags.Severity.highlight(severity, highlightEmoji)
+	}
+
+	result.Notes = append(result.Notes, fmt.Sprintf("%g of %g passed recently", testgridRegexRecentPassesFloat, testgridRegexRecentRunsFloat))
+	if jobData.LastRunTimestamp > 0 {
+		result.Notes = append(result.Notes, lastRunNote(time.Unix(jobData.LastRunTimestamp, 0), time.Now()))
+	}
+	if jobData.LatestGreen != "" {
+		result.Notes = append(result.Notes, fmt.Sprintf("Latest green build %s", jobData.LatestGreen))
+	}
+	return result
+}
+
+// severityInput collects what is known about a job to rank it, the dashboard is taken from the job url if testgrid does not name it
+func severityInput(jobData testgridValue, jobBaseURL string, recentRuns float64, recentSuccessRate float64, now time.Time) SeverityInput {
+	input := SeverityInput{
+		Dashboard:         string(jobData.DashboardName),
+		Status:            string(jobData.OverallStatus),
+		RecentRuns:        recentRuns,
+		RecentSuccessRate: recentSuccessRate,
+	}
+	if input.Dashboard == "" {
+		input.Dashboard = path.Base(jobBaseURL)
+	}
+	if jobData.OverallStatus == failing {
+		input.FailingTests = len(jobData.Tests)
+		if _, firstFailure, ok := failureWindow(jobData); ok {
+			input.DaysFailing = now.Sub(firstFailure).Hours() / 24
+		}
+	}
+	return input
+}
+
+// lastRunNote tells how long ago the job ran the last time (like "Last run 3h ago (2021-11-04 04:26 UTC)")
+func lastRunNote(lastRun time.Time, now time.Time) string {
+	since := now.Sub(lastRun)
+	ago := ""
+	switch {
+	case since < time.Hour:
+		ago = fmt.Sprintf("%dm", int(since.Minutes()))
+	case since < 48*time.Hour:
+		ago = fmt.Sprintf("%dh", int(since.Hours()))
+	default:
+		ago = fmt.Sprintf("%dd", int(since.Hours()/24))
+	}
+	return fmt.Sprintf("Last run %s ago (%s)", ago, lastRun.UTC().Format("2006-01-02 15:04 MST"))
+}
+
+// Counts of the failing tests of a failing job (see classifyFailingTests) and of the recent runs of a job
+const (
+	failingTestsCount = "failing_tests"
+	regressionsCount  = "regressions"
+	neverPassedCount  = "never_passed"
+	recentRunsCount   = "recent_runs"
+)
+
+// classifyFailingTests counts failing tests that passed before (regressions) and tests that never passed since they have been added,
+// testgrid sets pass_timestamp to 0 if a test has no passing run in the history of the tab
+func classifyFailingTests(tests []test) (regressions int, neverPassed int) {
+	for _, t := range tests {
+		if t.PassTimestamp > 0 {
+			regressions++
 		} else {
-			severity = LightSeverity
+			neverPassed++
 		}
 	}
+	return regressions, neverPassed
+}
 
-	result.Severity = severity
-	result.Highlight = strings.Repeat(highlightEmoji, int(severity))
+// failingTestsNote summarizes the classification ("12/15 failing tests are regressions, 3 are brand-new tests")
+func failingTestsNote(total int, regressions int, neverPassed int) string {
+	if neverPassed == 0 {
+		return fmt.Sprintf("%d/%d failing tests are regressions", regressions, total)
+	}
+	return fmt.Sprintf("%d/%d failing tests are regressions, %d are brand-new tests", regressions, total, neverPassed)
+}
 
-	result.Notes = append(result.Notes, fmt.Sprintf("%s of %s passed recently", latestExec[testgridRegexRecentPasses], latestExec[testgridRegexRecentRuns]))
-	return result
+// failedBuildNotes links the most recent failed build of each failing test to prow (spyglass) so triagers can jump straight to the logs
+func failedBuildNotes(prowURL string, tests []test) []string {
+	notes := []string{}
+	for _, t := range tests {
+		link := failedBuildLink(prowURL, t)
+		if link == "" {
+			continue
+		}
+		name := t.DisplayName
+		if name == "" {
+			name = t.TestName
+		}
+		notes = append(notes, fmt.Sprintf("Failed build of %s: %s", name, link))
+	}
+	if len(notes) > failedBuildLinksLimit {
+		more := len(notes) - failedBuildLinksLimit
+		notes = append(notes[:failedBuildLinksLimit], fmt.Sprintf("%d more failed builds, see testgrid", more))
+	}
+	return notes
+}
+
+// failedBuildLink returns the spyglass link of the most recent failed build of a test,
+// testgrid build links can be full urls, prow paths (like "/view/gs/...") or gcs paths (like "gs://kubernetes-jenkins/logs/...").
+// Prow paths and gcs paths are not linked if the prow instance is not known
+func failedBuildLink(prowURL string, t test) string {
+	link := strings.TrimSpace(t.BuildLink)
+	if link == "" && (strings.HasPrefix(t.FailTestLink, "http://") || strings.HasPrefix(t.FailTestLink, "https://")) {
+		link = t.FailTestLink
+	}
+	switch {
+	case link == "":
+		return ""
+	case strings.HasPrefix(link, "http://"), strings.HasPrefix(link, "https://"):
+		return link
+	case prowURL == "":
+		return ""
+	case strings.HasPrefix(link, "gs://"):
+		return fmt.Sprintf("%s/view/gs/%s", prowURL, strings.TrimPrefix(link, "gs://"))
+	default:
+		return prowURL + "/" + strings.TrimPrefix(link, "/")
+	}
+}
+
+// parseRecentRuns filters the latest executions from a testgrid status
+// e.g. "8 of 9 (88.9%) recent columns passed (19455 of 19458 or 100.0% cells)" -> 8 passes of 9 runs recently
+func parseRecentRuns(logger *Logger, status string) (passes float64, runs float64) {
+	const (
+		testgridRegexRecentRuns   = "runs"
+		testgridRegexRecentPasses = "passes"
+	)
+	latestExec := getRegexParams(fmt.Sprintf(`(?P<%s>\d{1,2})\sof\s(?P<%s>\d{1,2})`, testgridRegexRecentPasses, testgridRegexRecentRuns), status)
+	passes, err := strconv.ParseFloat(latestExec[testgridRegexRecentPasses], 64)
+	if err != nil {
+		logger.Debug("Could not parse recent passes", "status", status, "error", err)
+	}
+	runs, err = strconv.ParseFloat(latestExec[testgridRegexRecentRuns], 64)
+	if err != nil {
+		logger.Debug("Could not parse recent runs", "status", status, "error", err)
+	}
+	return passes, runs
 }
 
 // Parses string with the given regular expression and returns the group values defined in the expression.
@@ -302,10 +476,18 @@ type testgridValue struct {
 	BugURL              string        `json:"bug_url"`
 }
 
-// healthiness
+// healthiness flakiness statistics of a job and its tests for the last interval
 type healthiness struct {
-	Tests             []interface{} `json:"tests"`
-	PreviousFlakiness int64         `json:"previousFlakiness"`
+	Tests             []healthinessTest `json:"tests"`
+	AverageFlakiness  float64           `json:"averageFlakiness"`
+	PreviousFlakiness float64           `json:"previousFlakiness"`
+}
+
+// healthinessTest flakiness of one test in percent
+type healthinessTest struct {
+	DisplayName            string  `json:"displayName"`
+	Flakiness              float64 `json:"flakiness"`
+	ChangeFromLastInterval string  `json:"changeFromLastInterval"`
 }
 
 type test struct {
@@ -339,8 +521,16 @@ const (
 	stale   overallStatus = "STALE"
 )
 
+// number of failing tests per job that are linked to their failed build
+const failedBuildLinksLimit = 5
+
 // This information is used internally to differentiate between summary and detail ReportDataRecords
 const (
 	testgridReportSummary = 0
 	testgridReportDetails = 1
 )
+
+// isSummaryRecord tells if a record counts the job statuses of a dashboard, a cloud provider or a platform or the quarantined tests
+func isSummaryRecord(reportName string, record ReportDataRecord) bool {
+	return (reportName == testgridReport || reportName == providerReport || reportName == platformReport || reportName == quarantineReport) && record.ID == testgridReportSummary
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leonardpahlke/ci-signal-report/pkg/ci-reporter/schema"
+)
+
+const testFixturesDir = "testdata/fixtures"
+
+// newTestMeta returns meta information that replays recorded http responses instead of sending requests
+func newTestMeta(flags metaFlags) Meta {
+	if flags.Severity.Emojis == nil {
+		flags.Severity = DefaultSeverityConfig()
+	}
+	return Meta{
+		Flags:              flags,
+		HTTPClient:         &http.Client{Transport: NewReplayTransport(testFixturesDir)},
+		DataPostProcessing: newDataPostProcessing(flags),
+	}
+}
+
+func TestGetSummary(t *testing.T) {
+	jobs := TestgridData{
+		"a": {OverallStatus: passing},
+		"b": {OverallStatus: passing},
+		"c": {OverallStatus: flaky},
+		"d": {OverallStatus: failing},
+		"e": {OverallStatus: stale},
+	}
+	summary := getSummary(jobs)
+	if summary.ID != testgridReportSummary {
+		t.Errorf("expected summary id %d, got %d", testgridReportSummary, summary.ID)
+	}
+	expectedNotes := []string{"5 jobs total", "2 jobs passing", "1 jobs flaky", "1 jobs failing", "1 jobs stale"}
+	if !reflect.DeepEqual(summary.Notes, expectedNotes) {
+		t.Errorf("expected notes %v, got %v", expectedNotes, summary.Notes)
+	}
+	expectedCounts := map[string]int{"total": 5, "passing": 2, "flaky": 1, "failing": 1, "stale": 1}
+	if !reflect.DeepEqual(summary.Counts, expectedCounts) {
+		t.Errorf("expected counts %v, got %v", expectedCounts, summary.Counts)
+	}
+
+	// stale jobs are only mentioned if there are any
+	summary = getSummary(TestgridData{"a": {OverallStatus: passing}})
+	if len(summary.Notes) != 4 {
+		t.Errorf("expected 4 notes without stale jobs, got %v", summary.Notes)
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	tests := []struct {
+		name              string
+		jobData           testgridValue
+		expectedSeverity  Severity
+		expectedHighlight string
+		expectedSigs      []string
+	}{
+		{
+			name:              "flaky with high success rate",
+			jobData:           testgridValue{OverallStatus: flaky, Status: "8 of 9 (88.9%) recent columns passed (19455 of 19458 or 100.0% cells)"},
+			expectedSeverity:  LightSeverity,
+			expectedHighlight: statusFlakyEmoji,
+		},
+		{
+			name:              "flaky with medium success rate",
+			jobData:           testgridValue{OverallStatus: flaky, Status: "7 of 9 (77.8%) recent columns passed"},
+			expectedSeverity:  MediumSeverity,
+			expectedHighlight: statusFlakyEmoji + statusFlakyEmoji,
+		},
+		{
+			name:              "new job with few runs",
+			jobData:           testgridValue{OverallStatus: flaky, Status: "1 of 2 (50.0%) recent columns passed"},
+			expectedSeverity:  LightSeverity,
+			expectedHighlight: statusNewEmoji,
+		},
+		{
+			name: "failing job",
+			jobData: testgridValue{
+				OverallStatus: failing,
+				Status:        "0 of 9 (0.0%) recent columns passed",
+				Tests: []test{
+					{TestName: "Kubernetes e2e suite.[sig-storage] CSI mock volume"},
+					{TestName: "Kubernetes e2e suite.[sig-node] Pods"},
+				},
+			},
+			expectedSeverity:  HighSeverity,
+			expectedHighlight: statusFailingEmoji + statusFailingEmoji + statusFailingEmoji,
+			expectedSigs:      []string{"sig-node", "sig-storage"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			details := getDetails(Meta{Flags: metaFlags{Severity: DefaultSeverityConfig()}}, "job", tc.jobData, "https://testgrid.k8s.io/dashboard")
+			if details.ID != testgridReportDetails {
+				t.Errorf("expected details id %d, got %d", testgridReportDetails, details.ID)
+			}
+			if details.URL != "https://testgrid.k8s.io/dashboard#job" {
+				t.Errorf("unexpected url %s", details.URL)
+			}
+			if details.Severity != tc.expectedSeverity {
+				t.Errorf("expected severity %d, got %d", tc.expectedSeverity, details.Severity)
+			}
+			if details.Highlight != tc.expectedHighlight {
+				t.Errorf("expected highlight %q, got %q", tc.expectedHighlight, details.Highlight)
+			}
+			if !reflect.DeepEqual(details.Sigs, tc.expectedSigs) {
+				t.Errorf("expected sigs %v, got %v", tc.expectedSigs, details.Sigs)
+			}
+		})
+	}
+}
+
+func TestGetDetailsFailingTests(t *testing.T) {
+	jobData := testgridValue{
+		OverallStatus: failing,
+		Status:        "0 of 9 (0.0%) recent columns passed",
+		Tests: []test{
+			{TestName: "Kubernetes e2e suite.[sig-node] Pods", FailTimestamp: 1631000000, PassTimestamp: 1630900000},
+			{TestName: "Kubernetes e2e suite.[sig-node] Probes", FailTimestamp: 1631000000, PassTimestamp: 1630800000},
+			{TestName: "Kubernetes e2e suite.[sig-storage] new CSI test", FailTimestamp: 1631000000},
+		},
+	}
+	details := getDetails(Meta{Flags: metaFlags{Severity: DefaultSeverityConfig()}}, "job", jobData, "https://testgrid.k8s.io/dashboard")
+	expectedCounts := map[string]int{failingTestsCount: 3, regressionsCount: 2, neverPassedCount: 1, recentRunsCount: 9}
+	if !reflect.DeepEqual(details.Counts, expectedCounts) {
+		t.Errorf("expected counts %v, got %v", expectedCounts, details.Counts)
+	}
+	if details.Notes[2] != "2/3 failing tests are regressions, 1 are brand-new tests" {
+		t.Errorf("expected classification note, got %v", details.Notes)
+	}
+
+	o := outputRecord(testgridReport, "Master-Blocking", details)
+	expected := &schema.FailingTests{Total: 3, Regressions: 2, NeverPassed: 1}
+	if !reflect.DeepEqual(o.FailingTests, expected) {
+		t.Errorf("expected failing tests %+v, got %+v", expected, o.FailingTests)
+	}
+
+	flakyDetails := getDetails(Meta{Flags: metaFlags{Severity: DefaultSeverityConfig()}}, "job", testgridValue{OverallStatus: flaky, Status: "7 of 9 (77.8%) recent columns passed"}, "https://testgrid.k8s.io/dashboard")
+	if !reflect.DeepEqual(flakyDetails.Counts, map[string]int{recentRunsCount: 9}) || outputRecord(testgridReport, "Master-Blocking", flakyDetails).FailingTests != nil {
+		t.Errorf("expected no failing tests classification for flaky jobs, got %v", flakyDetails.Counts)
+	}
+}
+
+func TestSeverityInput(t *testing.T) {
+	now := time.Unix(1636000000, 0).Add(36 * time.Hour)
+	jobData := testgridValue{OverallStatus: failing, Tests: []test{{FailTimestamp: 1636000000000}, {FailTimestamp: 1636100000000}}}
+	input := severityInput(jobData, "https://testgrid.k8s.io/sig-release-master-blocking", 10, 0.3, now)
+	expected := SeverityInput{Dashboard: "sig-release-master-blocking", Status: "FAILING", RecentRuns: 10, RecentSuccessRate: 0.3, FailingTests: 2, DaysFailing: 1.5}
+	if input != expected {
+		t.Errorf("expected %+v, got %+v", expected, input)
+	}
+}
+
+func TestTestgridReportRequestData(t *testing.T) {
+	meta := newTestMeta(metaFlags{})
+	r := &TestgridReport{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := r.RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reportData.Name != testgridReport {
+		t.Errorf("expected report name %s, got %s", testgridReport, reportData.Name)
+	}
+	if !reflect.DeepEqual(r.GetData(), reportData) {
+		t.Error("expected reporter to store the requested report data")
+	}
+	jobsPerDashboard := map[string][]string{}
+	for _, field := range reportData.Data {
+		if field.Records[0].ID != testgridReportSummary {
+			t.Errorf("expected first record of %s to be the summary", field.Title)
+		}
+		for _, record := range field.Records[1:] {
+			jobsPerDashboard[field.Title] = append(jobsPerDashboard[field.Title], record.Title)
+		}
+		sort.Strings(jobsPerDashboard[field.Title])
+	}
+	expected := map[string][]string{
+		"Master-Blocking":  {"gce-cos-master-default", "verify-master"},
+		"Master-Informing": {"ci-kubernetes-e2e-stale", "gce-cos-master-serial", "post-release-push-image-setcap"},
+	}
+	if !reflect.DeepEqual(jobsPerDashboard, expected) {
+		t.Errorf("expected non passing jobs %v, got %v", expected, jobsPerDashboard)
+	}
+}
+
+func TestJobRunNotes(t *testing.T) {
+	lastRun := time.Date(2021, 11, 4, 4, 26, 40, 0, time.UTC)
+	for now, expected := range map[time.Time]string{
+		lastRun.Add(25 * time.Minute):  "Last run 25m ago (2021-11-04 04:26 UTC)",
+		lastRun.Add(5 * time.Hour):     "Last run 5h ago (2021-11-04 04:26 UTC)",
+		lastRun.Add(72 * time.Hour):    "Last run 3d ago (2021-11-04 04:26 UTC)",
+		lastRun.Add(47 * time.Hour):    "Last run 47h ago (2021-11-04 04:26 UTC)",
+		lastRun.Add(30 * time.Second):  "Last run 0m ago (2021-11-04 04:26 UTC)",
+		lastRun.Add(240 * time.Hour):   "Last run 10d ago (2021-11-04 04:26 UTC)",
+		lastRun.Add(119 * time.Minute): "Last run 1h ago (2021-11-04 04:26 UTC)",
+	} {
+		if note := lastRunNote(lastRun, now); note != expected {
+			t.Errorf("expected %q, got %q", expected, note)
+		}
+	}
+
+	notes := failedBuildNotes(defaultProwURL, []test{
+		{DisplayName: "a", BuildLink: "https://prow.k8s.io/view/gs/kubernetes-jenkins/logs/job/2"},
+		{DisplayName: "b", BuildLink: "gs://kubernetes-jenkins/logs/job/1"},
+		{DisplayName: "c", BuildLink: "/view/gs/kubernetes-jenkins/logs/job/3"},
+		{DisplayName: "no link"},
+	})
+	expected := []string{
+		"Failed build of a: https://prow.k8s.io/view/gs/kubernetes-jenkins/logs/job/2",
+		"Failed build of b: https://prow.k8s.io/view/gs/kubernetes-jenkins/logs/job/1",
+		"Failed build of c: https://prow.k8s.io/view/gs/kubernetes-jenkins/logs/job/3",
+	}
+	if !reflect.DeepEqual(notes, expected) {
+		t.Errorf("expected notes %v, got %v", expected, notes)
+	}
+	many := make([]test, failedBuildLinksLimit+2)
+	for i := range many {
+		many[i] = test{TestName: "t", BuildLink: "gs://bucket/logs/job/1"}
+	}
+	if notes := failedBuildNotes(defaultProwURL, many); len(notes) != failedBuildLinksLimit+1 || notes[failedBuildLinksLimit] != "2 more failed builds, see testgrid" {
+		t.Errorf("expected links to be limited, got %v", notes)
+	}
+}
+
+func TestFailedBuildLinksOfOtherInstances(t *testing.T) {
+	tests := []test{
+		{DisplayName: "a", BuildLink: "https://prow.knative.dev/view/gs/knative-prow/logs/job/2"},
+		{DisplayName: "b", BuildLink: "gs://knative-prow/logs/job/1"},
+		{DisplayName: "c", BuildLink: "/view/gs/knative-prow/logs/job/3"},
+	}
+	other := metaFlags{TestgridURL: "https://testgrid.knative.dev"}
+	if notes := failedBuildNotes(other.prowURL(), tests); !reflect.DeepEqual(notes, []string{"Failed build of a: https://prow.knative.dev/view/gs/knative-prow/logs/job/2"}) {
+		t.Errorf("expected only full build links if the prow instance of the testgrid instance is not known, got %v", notes)
+	}
+	other.ProwURL = "https://prow.knative.dev"
+	expected := []string{
+		"Failed build of a: https://prow.knative.dev/view/gs/knative-prow/logs/job/2",
+		"Failed build of b: https://prow.knative.dev/view/gs/knative-prow/logs/job/1",
+		"Failed build of c: https://prow.knative.dev/view/gs/knative-prow/logs/job/3",
+	}
+	if notes := failedBuildNotes(other.prowURL(), tests); !reflect.DeepEqual(notes, expected) {
+		t.Errorf("expected builds to be linked to -prow-url, got %v", notes)
+	}
+	if url := (metaFlags{}).prowURL(); url != defaultProwURL {
+		t.Errorf("expected %s for the default testgrid instance, got %s", defaultProwURL, url)
+	}
+}
+
+func TestTestgridReportRequestDataSigFilter(t *testing.T) {
+	meta := newTestMeta(metaFlags{Sigs: []string{"sig-node"}})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&TestgridReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			if record.ID == testgridReportDetails && record.Title != "gce-cos-master-serial" {
+				t.Errorf("expected only jobs with sig-node tests, got %s", record.Title)
+			}
+		}
+	}
+}
+
+func TestTestgridReportCustomInstance(t *testing.T) {
+	meta := newTestMeta(metaFlags{TestgridURL: "https://testgrid.k8s.io", Dashboards: []string{"sig-release-master-blocking"}})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&TestgridReport{}).RequestData(context.Background(), meta, &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reportData.Data) != 1 || reportData.Data[0].Title != "sig-release-master-blocking" {
+		t.Fatalf("expected only the configured dashboard to be reported, got %+v", reportData.Data)
+	}
+	if reportData.Data[0].Emoji != masterBlockingEmoji {
+		t.Errorf("expected blocking dashboards to be highlighted as blocking")
+	}
+}
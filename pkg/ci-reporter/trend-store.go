@@ -0,0 +1,491 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	// registers the pure go sqlite driver, so the binary can be built without cgo
+	_ "modernc.org/sqlite"
+)
+
+// Store backends that can be set via -store (like sqlite:ci-signal.db)
+const storeSQLite = "sqlite"
+
+// DefaultTrendWindow time between the runs that are compared by the trends subcommand
+const DefaultTrendWindow = 7 * 24 * time.Hour
+
+// statements that create the tables of the trend store, runs store the sources they covered and the filters they have been started with
+// (see TrendRun), runs without testgrid data do not record job statuses and runs without github data do not record issue counts
+var trendStoreSchema = []string{
+	`CREATE TABLE IF NOT EXISTS runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		generated_at INTEGER NOT NULL,
+		open_issues INTEGER NOT NULL,
+		sources TEXT NOT NULL DEFAULT '',
+		filters TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS runs_generated_at ON runs (generated_at)`,
+	`CREATE TABLE IF NOT EXISTS job_status (
+		run_id INTEGER NOT NULL REFERENCES runs (id),
+		dashboard TEXT NOT NULL,
+		job TEXT NOT NULL,
+		status TEXT NOT NULL,
+		severity INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS issue_counts (
+		run_id INTEGER NOT NULL REFERENCES runs (id),
+		repository TEXT NOT NULL,
+		sig TEXT NOT NULL,
+		open_issues INTEGER NOT NULL
+	)`,
+}
+
+// trendStoreColumns columns that have been added to the tables after the first version of the trend store. Runs of older stores
+// have no filters and are never compared with newer runs, because they only recorded the jobs that were not passing
+var trendStoreColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{table: "runs", column: "sources", definition: "TEXT NOT NULL DEFAULT ''"},
+	{table: "runs", column: "filters", definition: "TEXT NOT NULL DEFAULT ''"},
+}
+
+// TrendStore records the job statuses, severities and issue counts of every run to report trends over weeks
+type TrendStore struct {
+	db *sql.DB
+}
+
+// ParseStore splits -store input into backend and path ("sqlite:ci-signal.db" => "sqlite", "ci-signal.db")
+func ParseStore(input string) (string, string, error) {
+	parts := strings.SplitN(input, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("%q does not match backend:path (like %s:ci-signal.db)", input, storeSQLite)
+	}
+	if parts[0] != storeSQLite {
+		return "", "", fmt.Errorf("backend %q does not match options [%s]", parts[0], storeSQLite)
+	}
+	return parts[0], parts[1], nil
+}
+
+// OpenTrendStore opens the store set via -store and creates its tables if they do not exist yet
+func OpenTrendStore(input string) (*TrendStore, error) {
+	backend, path, err := ParseStore(input)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(backend, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range trendStoreSchema {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("creating tables of %s: %v", path, err)
+		}
+	}
+	if err := addTrendStoreColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("updating tables of %s: %v", path, err)
+	}
+	return &TrendStore{db: db}, nil
+}
+
+// addTrendStoreColumns adds the columns of trendStoreColumns that are missing in stores created by older versions
+func addTrendStoreColumns(db *sql.DB) error {
+	for _, c := range trendStoreColumns {
+		var exists bool
+		if err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database of the store
+func (s *TrendStore) Close() error {
+	return s.db.Close()
+}
+
+// TrendRun what a run recorded in the trend store has covered, runs are only compared with runs of the same sources and filters
+type TrendRun struct {
+	GeneratedAt time.Time
+	// Sources reports whose data has been requested completely ("github" and "testgrid")
+	Sources []string
+	// Filters flags that limit the jobs and issues of the sources (see metaFlags.trendFilters)
+	Filters string
+	// JobStatus status of every job per dashboard, set if the testgrid data is complete (see TestgridReport.JobStatus)
+	JobStatus map[string]map[string]string
+	Report    Report
+}
+
+// NewTrendRun collects what the run of the report has covered, cireporters are the reports the report has been requested with (see RequestReport)
+func (m Meta) NewTrendRun(generatedAt time.Time, report Report, cireporters []CIReport) TrendRun {
+	run := TrendRun{GeneratedAt: generatedAt, Filters: m.Flags.trendFilters(), Report: report}
+	for _, reportData := range report {
+		if reportData.Incomplete {
+			continue
+		}
+		switch reportData.Name {
+		case githubReport:
+			run.Sources = append(run.Sources, githubReport)
+		case testgridReport:
+			for _, r := range cireporters {
+				if testgrid, ok := r.(*TestgridReport); ok && testgrid.JobStatus != nil {
+					run.Sources = append(run.Sources, testgridReport)
+					run.JobStatus = testgrid.JobStatus
+				}
+			}
+		}
+	}
+	sort.Strings(run.Sources)
+	return run
+}
+
+// covers checks if the data of the source has been recorded
+func (r TrendRun) covers(source string) bool {
+	return coversSource(r.Sources, source)
+}
+
+func coversSource(sources []string, source string) bool {
+	for _, s := range sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// trendFilters composes the flags that limit the jobs and issues of a run, all of them are listed so runs of stores with
+// other filters are never compared (like "dashboards=sig-release-master-blocking versions= repositories=kubernetes/kubernetes ...")
+func (f metaFlags) trendFilters() string {
+	dashboards := []string{}
+	for _, d := range f.dashboards() {
+		dashboards = append(dashboards, d.URLName)
+	}
+	repositories := []string{}
+	for _, r := range f.repositories() {
+		repositories = append(repositories, r.String())
+	}
+	return fmt.Sprintf("testgrid=%s dashboards=%s versions=%s repositories=%s milestone=%s unassigned=%t sigs=%s",
+		f.testgridURL(), strings.Join(dashboards, ","), strings.Join(f.ReleaseVersion, ","), strings.Join(repositories, ","), f.milestone(), f.OnlyUnassigned, strings.Join(f.Sigs, ","))
+}
+
+// RecordRun stores the status of every testgrid job (with the severity of the jobs that are not passing) and the open issues per repository
+// and sig of the run. Job statuses are only stored if the run covered testgrid, issue counts only if it covered github
+func (s *TrendStore) RecordRun(run TrendRun) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := recordRun(tx, run); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func recordRun(tx *sql.Tx, run TrendRun) error {
+	counts, openIssues := openIssueCounts(run.Report)
+	if !run.covers(githubReport) {
+		counts, openIssues = nil, 0
+	}
+	result, err := tx.Exec(`INSERT INTO runs (generated_at, open_issues, sources, filters) VALUES (?, ?, ?, ?)`, run.GeneratedAt.Unix(), openIssues, strings.Join(run.Sources, ","), run.Filters)
+	if err != nil {
+		return fmt.Errorf("storing run: %v", err)
+	}
+	runID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if run.covers(testgridReport) {
+		severities := map[string]Severity{}
+		forEachTestgridJob(run.Report, func(dashboard string, record ReportDataRecord) {
+			severities[dashboard+"/"+record.Title] = record.Severity
+		})
+		for dashboard, jobs := range run.JobStatus {
+			for job, status := range jobs {
+				if _, err := tx.Exec(`INSERT INTO job_status (run_id, dashboard, job, status, severity) VALUES (?, ?, ?, ?, ?)`, runID, dashboard, job, status, int(severities[dashboard+"/"+job])); err != nil {
+					return fmt.Errorf("storing job status: %v", err)
+				}
+			}
+		}
+	}
+
+	for key, count := range counts {
+		if _, err := tx.Exec(`INSERT INTO issue_counts (run_id, repository, sig, open_issues) VALUES (?, ?, ?, ?)`, runID, key.repository, key.sig, count); err != nil {
+			return fmt.Errorf("storing issue counts: %v", err)
+		}
+	}
+	return nil
+}
+
+// issueCountKey open issues are counted per repository and sig ("" if the issue is not labeled with a sig)
+type issueCountKey struct {
+	repository string
+	sig        string
+}
+
+// openIssueCounts counts the open issues per repository and sig and in total (issues of multiple sigs are counted for each sig)
+func openIssueCounts(report Report) (map[issueCountKey]int, int) {
+	counts, total := map[issueCountKey]int{}, 0
+	for _, reportData := range report {
+		if reportData.Name != githubReport {
+			continue
+		}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				total++
+				if len(record.Sigs) == 0 {
+					counts[issueCountKey{repository: field.Title}]++
+				}
+				for _, sig := range record.Sigs {
+					counts[issueCountKey{repository: field.Title, sig: sig}]++
+				}
+			}
+		}
+	}
+	return counts, total
+}
+
+// JobTrend a job whose status changed between two runs
+type JobTrend struct {
+	Dashboard string
+	Job       string
+	Was       string
+	Is        string
+	Severity  Severity
+}
+
+// IssueTrend open issues of a sig in two runs
+type IssueTrend struct {
+	Sig string
+	Was int
+	Is  int
+}
+
+// Trends changes between the latest run and the run the trend window before
+type Trends struct {
+	Since time.Time
+	Until time.Time
+	// Sources covered by both runs, jobs are only compared if they covered testgrid and issues if they covered github
+	Sources   []string
+	Regressed []JobTrend
+	Recovered []JobTrend
+	// OpenIssues of all repositories in both runs
+	OpenIssues IssueTrend
+	// Issues per sig, issues of all repositories are summed up
+	Issues []IssueTrend
+}
+
+// statusRank orders statuses from good to bad, stale jobs stopped running and give no signal at all
+var statusRank = map[string]int{string(passing): 0, string(flaky): 1, string(failing): 2, string(stale): 3}
+
+// Trends compares the latest run up to until with the latest run at least window before it that covered the same sources with the same filters.
+// Jobs are only compared if they are recorded in both runs
+func (s *TrendStore) Trends(until time.Time, window time.Duration) (Trends, error) {
+	trends := Trends{}
+	current, err := s.latestRun(until, nil)
+	if err != nil {
+		return trends, err
+	}
+	previous, err := s.latestRun(current.generatedAt.Add(-window), &current)
+	if err != nil {
+		return trends, fmt.Errorf("no run with the same sources (%s) and filters recorded %s before the latest run: %v", current.sources, window, err)
+	}
+	trends.Since, trends.Until = previous.generatedAt, current.generatedAt
+	if current.sources != "" {
+		trends.Sources = strings.Split(current.sources, ",")
+	}
+	trends.OpenIssues = IssueTrend{Was: previous.openIssues, Is: current.openIssues}
+
+	previousJobs, err := s.jobStatus(previous.id)
+	if err != nil {
+		return trends, err
+	}
+	currentJobs, err := s.jobStatus(current.id)
+	if err != nil {
+		return trends, err
+	}
+	for key, job := range currentJobs {
+		previousJob, ok := previousJobs[key]
+		if !ok {
+			// the job has been added to the dashboard since the previous run
+			continue
+		}
+		job.Was = previousJob.Is
+		if statusRank[job.Is] > statusRank[job.Was] {
+			trends.Regressed = append(trends.Regressed, job)
+		} else if statusRank[job.Is] < statusRank[job.Was] {
+			trends.Recovered = append(trends.Recovered, job)
+		}
+	}
+	sortJobTrends(trends.Regressed)
+	sortJobTrends(trends.Recovered)
+
+	previousIssues, err := s.issueCounts(previous.id)
+	if err != nil {
+		return trends, err
+	}
+	currentIssues, err := s.issueCounts(current.id)
+	if err != nil {
+		return trends, err
+	}
+	sigs := map[string]bool{}
+	for sig := range previousIssues {
+		sigs[sig] = true
+	}
+	for sig := range currentIssues {
+		sigs[sig] = true
+	}
+	for sig := range sigs {
+		trends.Issues = append(trends.Issues, IssueTrend{Sig: sig, Was: previousIssues[sig], Is: currentIssues[sig]})
+	}
+	sort.Slice(trends.Issues, func(i, j int) bool { return trends.Issues[i].Sig < trends.Issues[j].Sig })
+	return trends, nil
+}
+
+// storedRun a run recorded in the trend store
+type storedRun struct {
+	id          int64
+	generatedAt time.Time
+	openIssues  int
+	sources     string
+	filters     string
+}
+
+// latestRun returns the latest run up to until, if like is set the run has to cover the same sources with the same filters
+func (s *TrendStore) latestRun(until time.Time, like *storedRun) (storedRun, error) {
+	var run storedRun
+	var generatedAt int64
+	query := `SELECT id, generated_at, open_issues, sources, filters FROM runs WHERE generated_at <= ?`
+	args := []interface{}{until.Unix()}
+	if like != nil {
+		query += ` AND sources = ? AND filters = ?`
+		args = append(args, like.sources, like.filters)
+	}
+	err := s.db.QueryRow(query+` ORDER BY generated_at DESC, id DESC LIMIT 1`, args...).Scan(&run.id, &generatedAt, &run.openIssues, &run.sources, &run.filters)
+	if err == sql.ErrNoRows {
+		return run, fmt.Errorf("no run recorded up to %s", until.UTC().Format(cycleDateLayout))
+	}
+	run.generatedAt = time.Unix(generatedAt, 0).UTC()
+	return run, err
+}
+
+func (s *TrendStore) jobStatus(runID int64) (map[string]JobTrend, error) {
+	rows, err := s.db.Query(`SELECT dashboard, job, status, severity FROM job_status WHERE run_id = ?`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	jobs := map[string]JobTrend{}
+	for rows.Next() {
+		var job JobTrend
+		if err := rows.Scan(&job.Dashboard, &job.Job, &job.Is, &job.Severity); err != nil {
+			return nil, err
+		}
+		jobs[job.Dashboard+"/"+job.Job] = job
+	}
+	return jobs, rows.Err()
+}
+
+func (s *TrendStore) issueCounts(runID int64) (map[string]int, error) {
+	rows, err := s.db.Query(`SELECT sig, SUM(open_issues) FROM issue_counts WHERE run_id = ? GROUP BY sig`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var sig string
+		var count int
+		if err := rows.Scan(&sig, &count); err != nil {
+			return nil, err
+		}
+		counts[sig] = count
+	}
+	return counts, rows.Err()
+}
+
+// sortJobTrends orders jobs by severity, dashboard and name
+func sortJobTrends(jobs []JobTrend) {
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Severity != jobs[j].Severity {
+			return jobs[i].Severity > jobs[j].Severity
+		}
+		if jobs[i].Dashboard != jobs[j].Dashboard {
+			return jobs[i].Dashboard < jobs[j].Dashboard
+		}
+		return jobs[i].Job < jobs[j].Job
+	})
+}
+
+// String composes the markdown section of the weekly CI signal summary for the release team
+func (t Trends) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# CI signal trends %s - %s\n", t.Since.Format(cycleDateLayout), t.Until.Format(cycleDateLayout)))
+
+	sb.WriteString("\n## Regressed jobs\n\n")
+	if !coversSource(t.Sources, testgridReport) {
+		sb.WriteString("Jobs have not been recorded, the runs did not cover testgrid.\n")
+	} else if len(t.Regressed) == 0 {
+		sb.WriteString("No job regressed.\n")
+	}
+	for _, job := range t.Regressed {
+		sb.WriteString(fmt.Sprintf("- %s (%s): %s -> %s\n", job.Job, job.Dashboard, job.Was, job.Is))
+	}
+
+	sb.WriteString("\n## Recovered jobs\n\n")
+	if !coversSource(t.Sources, testgridReport) {
+		sb.WriteString("Jobs have not been recorded, the runs did not cover testgrid.\n")
+	} else if len(t.Recovered) == 0 {
+		sb.WriteString("No job recovered.\n")
+	}
+	for _, job := range t.Recovered {
+		sb.WriteString(fmt.Sprintf("- %s (%s): %s -> %s\n", job.Job, job.Dashboard, job.Was, job.Is))
+	}
+
+	sb.WriteString("\n## Issue backlog\n\n")
+	if !coversSource(t.Sources, githubReport) {
+		sb.WriteString("Issues have not been recorded, the runs did not cover github.\n")
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("%d open issues (%+d since %s).\n", t.OpenIssues.Is, t.OpenIssues.Is-t.OpenIssues.Was, t.Since.Format(cycleDateLayout)))
+	if len(t.Issues) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, issues := range t.Issues {
+		sig := issues.Sig
+		if sig == "" {
+			sig = "no sig"
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %d (%+d)\n", sig, issues.Is, issues.Is-issues.Was))
+	}
+	return sb.String()
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestTrendStore(t *testing.T) *TrendStore {
+	store, err := OpenTrendStore("sqlite:" + filepath.Join(t.TempDir(), "ci-signal.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testTrendRun a run that covered github and testgrid, jobStatus lists the status of every job of the master-blocking dashboard
+func testTrendRun(at time.Time, jobStatus map[string]string, jobs []ReportDataRecord, issues []ReportDataRecord) TrendRun {
+	return TrendRun{
+		GeneratedAt: at,
+		Sources:     []string{githubReport, testgridReport},
+		Filters:     "dashboards=sig-release-master-blocking",
+		JobStatus:   map[string]map[string]string{"sig-release-master-blocking": jobStatus},
+		Report: Report{
+			{Name: testgridReport, Data: []ReportDataField{{Title: "sig-release-master-blocking", Records: append([]ReportDataRecord{{ID: testgridReportSummary}}, jobs...)}}},
+			{Name: githubReport, Data: []ReportDataField{{Records: issues}}},
+		},
+	}
+}
+
+func TestTrendStore(t *testing.T) {
+	store := openTestTrendStore(t)
+	lastWeek := time.Date(2021, 10, 13, 10, 0, 0, 0, time.UTC)
+	runs := []TrendRun{
+		testTrendRun(lastWeek,
+			map[string]string{"gce-cos-master-default": "FAILING", "kind-master-parallel": "FLAKY", "gce-cos-master-serial": "PASSING", "gce-cos-master-alpha": "PASSING"},
+			[]ReportDataRecord{
+				{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FAILING", Severity: HighSeverity},
+				{ID: testgridReportDetails, Title: "kind-master-parallel", Status: "FLAKY", Severity: MediumSeverity},
+			},
+			[]ReportDataRecord{{ID: 1, Sigs: []string{"sig-node"}}},
+		),
+		// runs of the week in between are not compared
+		testTrendRun(lastWeek.Add(3*24*time.Hour), map[string]string{}, nil, nil),
+		testTrendRun(lastWeek.Add(7*24*time.Hour),
+			map[string]string{"gce-cos-master-default": "FLAKY", "kind-master-parallel": "PASSING", "gce-cos-master-serial": "FAILING", "gce-cos-master-alpha": "STALE", "gce-cos-master-new": "FAILING"},
+			[]ReportDataRecord{
+				{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FLAKY", Severity: LightSeverity},
+				{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: "FAILING", Severity: HighSeverity},
+			},
+			[]ReportDataRecord{{ID: 1, Sigs: []string{"sig-node"}}, {ID: 2, Sigs: []string{"sig-node", "sig-storage"}}, {ID: 3}},
+		),
+	}
+	for _, run := range runs {
+		if err := store.RecordRun(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trends, err := store.Trends(lastWeek.Add(8*24*time.Hour), DefaultTrendWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stale jobs regressed, jobs that have been added to the dashboard since the previous run are not compared
+	expected := `# CI signal trends 2021-10-13 - 2021-10-20
+
+## Regressed jobs
+
+- gce-cos-master-serial (sig-release-master-blocking): PASSING -> FAILING
+- gce-cos-master-alpha (sig-release-master-blocking): PASSING -> STALE
+
+## Recovered jobs
+
+- gce-cos-master-default (sig-release-master-blocking): FAILING -> FLAKY
+- kind-master-parallel (sig-release-master-blocking): FLAKY -> PASSING
+
+## Issue backlog
+
+3 open issues (+2 since 2021-10-13).
+
+- no sig: 1 (+1)
+- sig-node: 2 (+1)
+- sig-storage: 1 (+1)
+`
+	if trends.String() != expected {
+		t.Errorf("expected trends\n%s\ngot\n%s", expected, trends)
+	}
+
+	if _, err := store.Trends(lastWeek.Add(time.Hour), DefaultTrendWindow); err == nil {
+		t.Error("expected an error if no run has been recorded a week before")
+	}
+}
+
+func TestTrendStoreComparesRunsOfSameCoverage(t *testing.T) {
+	store := openTestTrendStore(t)
+	lastWeek := time.Date(2021, 10, 13, 10, 0, 0, 0, time.UTC)
+	full := testTrendRun(lastWeek, map[string]string{"gce-cos-master-default": "FAILING"},
+		[]ReportDataRecord{{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FAILING"}}, []ReportDataRecord{{ID: 1}, {ID: 2}})
+	// a -report github run of the same week
+	githubOnly := testTrendRun(lastWeek.Add(-time.Hour), nil, nil, []ReportDataRecord{{ID: 1}, {ID: 2}, {ID: 3}})
+	githubOnly.Sources, githubOnly.JobStatus = []string{githubReport}, nil
+	// a -report github run and a run with other filters a week later
+	latest := testTrendRun(lastWeek.Add(7*24*time.Hour), nil, nil, []ReportDataRecord{{ID: 1}})
+	latest.Sources, latest.JobStatus = []string{githubReport}, nil
+	otherFilters := testTrendRun(lastWeek.Add(7*24*time.Hour-time.Hour), map[string]string{"gce-cos-master-default": "PASSING"}, nil, nil)
+	otherFilters.Filters = "dashboards=sig-release-master-informing"
+	for _, run := range []TrendRun{full, githubOnly, otherFilters, latest} {
+		if err := store.RecordRun(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trends, err := store.Trends(lastWeek.Add(8*24*time.Hour), DefaultTrendWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the github run is compared with the github run, so the job of the full run is not recovered
+	expected := `# CI signal trends 2021-10-13 - 2021-10-20
+
+## Regressed jobs
+
+Jobs have not been recorded, the runs did not cover testgrid.
+
+## Recovered jobs
+
+Jobs have not been recorded, the runs did not cover testgrid.
+
+## Issue backlog
+
+1 open issues (-2 since 2021-10-13).
+
+- no sig: 1 (-2)
+`
+	if trends.String() != expected {
+		t.Errorf("expected trends\n%s\ngot\n%s", expected, trends)
+	}
+	if !trends.Since.Equal(githubOnly.GeneratedAt) {
+		t.Errorf("expected the run with the same sources to be compared, got the run of %s", trends.Since)
+	}
+
+	// a testgrid run has no run with the same sources and filters a week before
+	testgridOnly := testTrendRun(lastWeek.Add(7*24*time.Hour+time.Hour), map[string]string{"gce-cos-master-default": "PASSING"}, nil, nil)
+	testgridOnly.Sources = []string{testgridReport}
+	if err := store.RecordRun(testgridOnly); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Trends(lastWeek.Add(8*24*time.Hour), DefaultTrendWindow); err == nil {
+		t.Error("expected an error if no run with the same coverage has been recorded a week before")
+	}
+}
+
+func TestNewTrendRun(t *testing.T) {
+	testgrid := &TestgridReport{JobStatus: map[string]map[string]string{"Master-Blocking": {"gce-cos-master-default": "PASSING"}}}
+	report := Report{{Name: githubReport}, {Name: testgridReport}}
+	meta := Meta{Flags: metaFlags{Sigs: []string{"sig-node"}}}
+	run := meta.NewTrendRun(time.Now(), report, []CIReport{&GithubReport{}, testgrid})
+	if !reflect.DeepEqual(run.Sources, []string{githubReport, testgridReport}) || !reflect.DeepEqual(run.JobStatus, testgrid.JobStatus) {
+		t.Errorf("expected github and testgrid to be covered with the job statuses of the testgrid report, got %+v", run)
+	}
+	if !strings.Contains(run.Filters, "dashboards=sig-release-master-blocking,sig-release-master-informing") || !strings.Contains(run.Filters, "sigs=sig-node") {
+		t.Errorf("expected the dashboards and sigs in the filters, got %q", run.Filters)
+	}
+
+	// sources whose deadline passed are not covered
+	report[1].Incomplete = true
+	if run := meta.NewTrendRun(time.Now(), report, []CIReport{&GithubReport{}, testgrid}); !reflect.DeepEqual(run.Sources, []string{githubReport}) || run.JobStatus != nil {
+		t.Errorf("expected only github to be covered, got %+v", run)
+	}
+}
+
+func TestOpenTrendStoreAddsColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ci-signal.db")
+	db, err := sql.Open(storeSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the runs table of the first version of the store
+	if _, err := db.Exec(`CREATE TABLE runs (id INTEGER PRIMARY KEY AUTOINCREMENT, generated_at INTEGER NOT NULL, open_issues INTEGER NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO runs (generated_at, open_issues) VALUES (?, 3)`, time.Date(2021, 10, 13, 10, 0, 0, 0, time.UTC).Unix()); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	store, err := OpenTrendStore("sqlite:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.RecordRun(testTrendRun(time.Date(2021, 10, 20, 10, 0, 0, 0, time.UTC), map[string]string{}, nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	// runs of the old store only recorded jobs that were not passing, they are not compared with new runs
+	if _, err := store.Trends(time.Date(2021, 10, 21, 0, 0, 0, 0, time.UTC), DefaultTrendWindow); err == nil {
+		t.Error("expected runs of the old store not to be compared")
+	}
+}
+
+func TestParseStore(t *testing.T) {
+	if backend, path, err := ParseStore("sqlite:data/ci-signal.db"); err != nil || backend != "sqlite" || path != "data/ci-signal.db" {
+		t.Errorf("expected sqlite backend with path data/ci-signal.db, got %q %q (%v)", backend, path, err)
+	}
+	for _, input := range []string{"ci-signal.db", "sqlite:", "postgres:ci-signal"} {
+		if _, _, err := ParseStore(input); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
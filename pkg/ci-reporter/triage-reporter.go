@@ -0,0 +1,271 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// number of failure clusters listed per failing job
+	triageClustersLimit = 3
+	// failure texts of clusters are shortened to this length
+	triageTextLength = 80
+)
+
+// TriageReport used to implement RequestData & Print for the failure clusters of failing testgrid jobs (https://go.k8s.io/triage)
+type TriageReport struct {
+	ReportData ReportData
+}
+
+// RequestData this function is used to find the triage failure clusters of the failing tests of failing testgrid jobs
+func (r *TriageReport) RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error) {
+	meta = meta.withContext(ctx)
+	dashboards := meta.Flags.dashboards()
+	client := httpClientOrDefault(meta.HTTPClient)
+	dashboardJobs, err := meta.requestDashboards(ctx, client, dashboards)
+	if err != nil {
+		return ReportData{Name: triageReport}, fmt.Errorf("requesting dashboards: %v", err)
+	}
+	triage, err := reqTriageData(ctx, client, meta.Flags.triageURL())
+	if err != nil {
+		return ReportData{Name: triageReport}, fmt.Errorf("requesting triage data: %v", err)
+	}
+
+	c := make(chan ReportDataField)
+	go func() {
+		defer close(c)
+		for i, dashboard := range dashboards {
+			jobBaseURL := fmt.Sprintf("%s/%s", meta.Flags.testgridURL(), dashboard.URLName)
+			records := []ReportDataRecord{}
+			for jobName, jobData := range dashboardJobs[i] {
+				if jobData.OverallStatus != failing {
+					continue
+				}
+				record := getDetails(meta, jobName, jobData, jobBaseURL)
+				record.Notes = triageNotes(meta.Flags.triageURL(), triage.clustersOf(jobData.Tests))
+				records = append(records, record)
+			}
+			if len(records) > 0 {
+				c <- ReportDataField{Emoji: dashboard.Emoji, Title: dashboard.OutputName, Records: records}
+			}
+		}
+	}()
+	return meta.DataPostProcessing(r, triageReport, c, wg), nil
+}
+
+// reqTriageData requests the failure clusters of the triage dashboard, the failure data is large so it is decoded while it is read
+func reqTriageData(ctx context.Context, client *http.Client, triageURL string) (triageData, error) {
+	var data triageData
+	dataURL := fmt.Sprintf("%s/failure_data.json", triageURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dataURL, nil)
+	if err != nil {
+		return data, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return data, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return data, fmt.Errorf("requesting %s failed with status %s", dataURL, resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&data)
+	return data, err
+}
+
+// triageClusterFinder adds the failure clusters of failing jobs to their details in the testgrid report (-triage).
+// The failure data is requested once, when the first failing job needs it
+type triageClusterFinder struct {
+	meta   Meta
+	ctx    context.Context
+	client *http.Client
+
+	once sync.Once
+	data triageData
+	err  error
+}
+
+func newTriageClusterFinder(ctx context.Context, meta Meta, client *http.Client) *triageClusterFinder {
+	return &triageClusterFinder{meta: meta, ctx: ctx, client: client}
+}
+
+// clusterNotes describes the failure clusters of the failing tests of a job, no notes are returned if the failure data could not be requested
+func (f *triageClusterFinder) clusterNotes(jobData testgridValue) []string {
+	f.once.Do(func() {
+		if f.data, f.err = reqTriageData(f.ctx, f.client, f.meta.Flags.triageURL()); f.err != nil {
+			f.meta.logger().Warn("Could not request triage failure clusters, failing jobs are reported without them", "error", f.err)
+		}
+	})
+	if f.err != nil {
+		return nil
+	}
+	return triageNotes(f.meta.Flags.triageURL(), f.data.clustersOf(jobData.Tests))
+}
+
+// clustersOf returns the clusters that contain one of the failing tests, the clusters that affect the most builds first
+func (d triageData) clustersOf(failingTests []test) []triageCluster {
+	testNames := map[string]bool{}
+	for _, t := range failingTests {
+		testNames[triageTestName(t.TestName)] = true
+	}
+	clusters := []triageCluster{}
+	for _, cluster := range d.Clustered {
+		for _, t := range cluster.Tests {
+			if testNames[triageTestName(t.Name)] {
+				clusters = append(clusters, cluster)
+				break
+			}
+		}
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		bi, _ := clusters[i].affected()
+		bj, _ := clusters[j].affected()
+		if bi != bj {
+			return bi > bj
+		}
+		return clusters[i].ID < clusters[j].ID
+	})
+	if len(clusters) > triageClustersLimit {
+		clusters = clusters[:triageClustersLimit]
+	}
+	return clusters
+}
+
+// triageTestName normalizes test names, testgrid and triage differ in the suite prefix and ginkgo node annotations
+// (like "Kubernetes e2e suite.[sig-node] Pods" and "Kubernetes e2e suite: [It] [sig-node] Pods")
+func triageTestName(name string) string {
+	name = strings.TrimPrefix(name, "Kubernetes e2e suite.")
+	name = strings.TrimPrefix(name, "Kubernetes e2e suite:")
+	name = strings.Replace(name, "[It] ", "", 1)
+	return strings.TrimSpace(name)
+}
+
+// triageNotes describes the clusters with the number of affected builds and jobs and a link to the triage dashboard
+func triageNotes(triageURL string, clusters []triageCluster) []string {
+	if len(clusters) == 0 {
+		return []string{"No triage failure cluster found for the failing tests"}
+	}
+	notes := []string{}
+	for _, cluster := range clusters {
+		builds, jobs := cluster.affected()
+		owner := ""
+		if cluster.Owner != "" {
+			owner = fmt.Sprintf(" (%s)", cluster.Owner)
+		}
+		notes = append(notes, fmt.Sprintf("Cluster %s%s: %d builds in %d jobs, %q %s", cluster.shortID(), owner, builds, jobs, cluster.shortText(), cluster.link(triageURL)))
+	}
+	return notes
+}
+
+// The types below reflect the triage failure data (e.g. https://storage.googleapis.com/k8s-gubernator/triage/failure_data.json)
+
+type triageData struct {
+	Clustered []triageCluster `json:"clustered"`
+}
+
+// triageCluster failures of tests that have a similar failure text
+type triageCluster struct {
+	ID    string       `json:"id"`
+	Key   string       `json:"key"`
+	Text  string       `json:"text"`
+	Owner string       `json:"owner"`
+	Tests []triageTest `json:"tests"`
+}
+
+type triageTest struct {
+	Name string      `json:"name"`
+	Jobs []triageJob `json:"jobs"`
+}
+
+type triageJob struct {
+	Name   string  `json:"name"`
+	Builds []int64 `json:"builds"`
+}
+
+// affected counts the builds and the distinct jobs that failed with the cluster
+func (c triageCluster) affected() (builds int, jobs int) {
+	jobNames := map[string]bool{}
+	for _, t := range c.Tests {
+		for _, j := range t.Jobs {
+			builds += len(j.Builds)
+			jobNames[j.Name] = true
+		}
+	}
+	return builds, len(jobNames)
+}
+
+func (c triageCluster) shortID() string {
+	if len(c.ID) > 8 {
+		return c.ID[:8]
+	}
+	return c.ID
+}
+
+// shortText returns the first line of the failure text
+func (c triageCluster) shortText() string {
+	text := strings.TrimSpace(strings.SplitN(strings.TrimSpace(c.Text), "\n", 2)[0])
+	if len(text) > triageTextLength {
+		return text[:triageTextLength] + "..."
+	}
+	return text
+}
+
+// link points to the cluster on the triage dashboard
+func (c triageCluster) link(triageURL string) string {
+	return fmt.Sprintf("%s/index.html?test=%s#%s", triageURL, url.QueryEscape(c.firstTest()), c.ID)
+}
+
+func (c triageCluster) firstTest() string {
+	if len(c.Tests) == 0 {
+		return ""
+	}
+	return c.Tests[0].Name
+}
+
+// Print extends TriageReport and prints report data to the console
+func (r *TriageReport) Print(meta Meta, reportData ReportData) {
+	c := meta.console()
+	for _, field := range reportData.Data {
+		c.Printf("\n\n%s\n", c.heading(field.Emoji, fmt.Sprintf("Failure clusters in %s", field.Title)))
+		for _, record := range field.Records {
+			c.Printf("%s\n", c.recordLine(record))
+			c.Printf("- %s\n", record.URL)
+			for _, note := range record.Notes {
+				c.Printf("- %s\n", note)
+			}
+		}
+	}
+	c.Println()
+}
+
+// PutData extends TriageReport and stores the data at runtime to the struct val ReportData
+func (r *TriageReport) PutData(reportData ReportData) {
+	r.ReportData = reportData
+}
+
+// GetData extends TriageReport and returns the data that has been stored at runtime int the struct val ReportData
+func (r TriageReport) GetData() ReportData {
+	return r.ReportData
+}
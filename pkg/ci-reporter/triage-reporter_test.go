@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTriageTestName(t *testing.T) {
+	for _, name := range []string{
+		"Kubernetes e2e suite.[sig-node] Pods should be restarted",
+		"Kubernetes e2e suite: [It] [sig-node] Pods should be restarted",
+		"[sig-node] Pods should be restarted",
+	} {
+		if normalized := triageTestName(name); normalized != "[sig-node] Pods should be restarted" {
+			t.Errorf("unexpected normalized name of %q: %q", name, normalized)
+		}
+	}
+}
+
+func TestTriageReportRequestData(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&TriageReport{}).RequestData(context.Background(), newTestMeta(metaFlags{}), &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reportData.Name != triageReport || len(reportData.Data) != 1 || reportData.Data[0].Title != "Master-Informing" {
+		t.Fatalf("expected failure clusters of the failing master-informing job, got %+v", reportData)
+	}
+	record := reportData.Data[0].Records[0]
+	if record.Title != "gce-cos-master-serial" {
+		t.Errorf("expected failing job gce-cos-master-serial, got %s", record.Title)
+	}
+	expected := []string{
+		`Cluster 8a7c5d3e (sig-storage): 4 builds in 2 jobs, "timeout waiting for CSI driver csi-mock to register" https://storage.googleapis.com/k8s-gubernator/triage/index.html?test=Kubernetes+e2e+suite%3A+%5BIt%5D+%5Bsig-storage%5D+CSI+mock+volume#8a7c5d3e9f10b2c4d6e8`,
+		`Cluster 1b2c3d4e (sig-node): 1 builds in 1 jobs, "expected restart count 1, got 0" https://storage.googleapis.com/k8s-gubernator/triage/index.html?test=Kubernetes+e2e+suite.%5Bsig-node%5D+Pods+should+be+restarted#1b2c3d4e5f`,
+	}
+	if !reflect.DeepEqual(record.Notes, expected) {
+		t.Errorf("expected notes %v, got %v", expected, record.Notes)
+	}
+}
+
+func TestTestgridReportTriageClusters(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	reportData, err := (&TestgridReport{}).RequestData(context.Background(), newTestMeta(metaFlags{Triage: true}), &wg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the clusters are listed in the details of the failing job, after the notes of the testgrid report
+	for _, field := range reportData.Data {
+		for _, record := range field.Records {
+			if record.Title != "gce-cos-master-serial" {
+				continue
+			}
+			if len(record.Notes) < 3 || !strings.HasPrefix(record.Notes[len(record.Notes)-2], "Cluster 8a7c5d3e (sig-storage)") || !strings.HasPrefix(record.Notes[len(record.Notes)-1], "Cluster 1b2c3d4e (sig-node)") {
+				t.Errorf("expected the failure clusters in the details of the failing job, got %v", record.Notes)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected failing job gce-cos-master-serial in the testgrid report, got %+v", reportData.Data)
+}
+
+func TestTriageClusterFinderErrors(t *testing.T) {
+	meta := Meta{Flags: metaFlags{TriageURL: "https://storage.googleapis.com/k8s-gubernator/triage"}}
+	finder := newTriageClusterFinder(context.Background(), meta, &http.Client{Transport: failingTransport{}})
+	if notes := finder.clusterNotes(testgridValue{Tests: []test{{TestName: "Kubernetes e2e suite.[sig-node] Pods should be restarted"}}}); notes != nil {
+		t.Errorf("expected no notes if the failure data could not be requested, got %v", notes)
+	}
+}
@@ -17,16 +17,22 @@ limitations under the License.
 package cireporter
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"os"
 	"sync"
 )
 
 // Reports
 const (
-	githubReport   = "github"
-	testgridReport = "testgrid"
+	githubReport     = "github"
+	testgridReport   = "testgrid"
+	flakeReport      = "flakes"
+	providerReport   = "providers"
+	triageReport     = "triage"
+	platformReport   = "platforms"
+	quarantineReport = "quarantine"
+	prSignalReport   = "pr-signal"
 )
 
 // Emojis
@@ -36,6 +42,9 @@ const (
 	statusFailingEmoji   = "\U0001F534"
 	statusFlakyEmoji     = "\U0001F535"
 	statusNewEmoji       = "\U00002728"
+	actionItemEmoji      = "\U0001F449"
+	unassignedEmoji      = "\U0001F464"
+	fixPendingEmoji      = "\U0001F527"
 )
 
 const (
@@ -59,9 +68,10 @@ const (
 	LightSeverity  Severity = 1
 )
 
-// CIReport this interface to implement Reporters
+// CIReport this interface to implement Reporters. RequestData returns errors instead of exiting, requests are canceled
+// if the context is done (like when the deadline of the source passed, see -deadlines)
 type CIReport interface {
-	RequestData(meta Meta, wg *sync.WaitGroup) ReportData
+	RequestData(ctx context.Context, meta Meta, wg *sync.WaitGroup) (ReportData, error)
 	Print(meta Meta, reportData ReportData)
 	PutData(reportData ReportData)
 	GetData() ReportData
@@ -79,13 +89,11 @@ func (r *Report) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
-// PrintJSON pretty print json to console
+// PrintJSON pretty print the report in the versioned output format (see package schema) to console
 func (r *Report) PrintJSON() {
-	b, err := json.MarshalIndent(r, "", "  ")
-	if err != nil {
-		log.Fatalf("Could not marshal Report %v", err)
+	if err := (JSONRenderer{}).Render(os.Stdout, *r); err != nil {
+		Fatalf("Could not marshal Report.\n[ERROR] %v", err)
 	}
-	fmt.Print(string(b))
 }
 
 // Report wraps multiple report data objects
@@ -96,6 +104,10 @@ type ReportData struct {
 	Data []ReportDataField `json:"data"`
 	// Name like 'github' or 'testgrid'
 	Name string `json:"name"`
+	// Incomplete the deadline of the source (-deadlines) passed before all data has been requested
+	Incomplete bool `json:"incomplete,omitempty"`
+	// KnownIssues records acknowledged via -acks, they are left out of Data and listed compactly (see ApplyAcks)
+	KnownIssues []KnownIssue `json:"known_issues,omitempty"`
 }
 
 // ReportDataField one field of a report that contains multiple records
@@ -115,6 +127,8 @@ type ReportDataRecord struct {
 	Title string `json:"title"`
 	// k8s sig reference
 	Sig string `json:"sig"`
+	// normalized k8s sigs the record is attributed to (like "sig-node")
+	Sigs []string `json:"sigs"`
 	// collection of additional information
 	Notes []string `json:"notes"`
 	// record status
@@ -123,4 +137,20 @@ type ReportDataRecord struct {
 	Severity Severity `json:"severity"`
 	// can be set to highlight the record (with an emoji for example)
 	Highlight string `json:"highlight"`
+	// counted values like the number of passing jobs of a testgrid dashboard
+	Counts map[string]int `json:"counts,omitempty"`
+	// share of recent runs that passed (0.0 ... 1.0), set for testgrid jobs
+	RecentPassRate *float64 `json:"recent_pass_rate,omitempty"`
+	// labels of a github issue (like "kind/failing-test" or "triage/accepted")
+	Labels []string `json:"labels,omitempty"`
+	// milestone of a github issue (like "v1.22")
+	Milestone string `json:"milestone,omitempty"`
+	// logins of the users a github issue is assigned to
+	Assignees []string `json:"assignees,omitempty"`
+	// why a testgrid job is failing (like "infra quota" or "product regression"), see classifyFailure
+	FailureClass string `json:"failure_class,omitempty"`
+	// manual annotations set via -annotations or carried over from the previous snapshot, see ApplyAnnotations
+	Annotations []RecordAnnotation `json:"annotations,omitempty"`
+	// pull requests that fix or reference the issue (or the issues tracking the job), set by the pr-signal report
+	FixPRs []FixPR `json:"fix_prs,omitempty"`
 }
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// clearScreen moves the cursor to the top left corner and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// Watch re-requests the report every meta.Flags.Interval and redraws the dashboard summaries in the terminal,
+// records that changed since the previous refresh are highlighted
+func Watch(meta Meta) {
+	var previous *Report
+	for {
+		previous = refreshWatch(context.Background(), meta, previous)
+		time.Sleep(meta.Flags.Interval)
+	}
+}
+
+// refreshWatch requests the report and redraws it, the report is returned to compare the next refresh with.
+// If the report can not be requested the error is logged and the previous report is returned, so watch mode retries in the next interval
+func refreshWatch(ctx context.Context, meta Meta, previous *Report) *Report {
+	refreshedAt := time.Now()
+	report, _, err := meta.RequestReport(ctx)
+	if err != nil {
+		meta.logger().Error("Could not refresh the report, retrying in the next interval", "interval", meta.Flags.Interval, "error", err)
+		return previous
+	}
+	console := meta.console()
+	console.clear()
+	console.Print(WatchScreen(meta, report, previous, refreshedAt))
+	return &report
+}
+
+// WatchScreen renders the summaries and failing & flaky jobs of the report and the records that are new, changed or resolved since the previous refresh
+func WatchScreen(meta Meta, report Report, previous *Report, refreshedAt time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("CI SIGNAL REPORT refreshed %s, next refresh in %s\n", refreshedAt.Format("2006-01-02 15:04:05"), meta.Flags.Interval))
+	previousRecords := map[string]ReportDataRecord{}
+	if previous != nil {
+		for _, reportData := range *previous {
+			for _, field := range reportData.Data {
+				for _, record := range field.Records {
+					previousRecords[recordKey(reportData.Name, field, record)] = record
+				}
+			}
+		}
+	}
+
+	for _, reportData := range report {
+		sb.WriteString(fmt.Sprintf("\n%s REPORT\n", strings.ToUpper(reportData.Name)))
+		records := 0
+		changes := []string{}
+		for _, field := range reportData.Data {
+			for _, record := range field.Records {
+				key := recordKey(reportData.Name, field, record)
+				previousRecord, known := previousRecords[key]
+				delete(previousRecords, key)
+				if isSummaryRecord(reportData.Name, record) {
+					line := fmt.Sprintf("%s: %s", field.Title, strings.Join(record.Notes, ", "))
+					if delta := countsDelta(previousRecord.Counts, record.Counts); known && delta != "" {
+						line = fmt.Sprintf("%s %s (%s)", watchHighlight(meta), line, delta)
+					}
+					sb.WriteString(line + "\n")
+					continue
+				}
+				records++
+				change := ""
+				if previous != nil && !known {
+					change = "new"
+				} else if known && previousRecord.Status != record.Status {
+					change = fmt.Sprintf("was %s", previousRecord.Status)
+				}
+				// jobs of dashboards are always listed, other records only if they changed
+				if hasSummaryRecord(reportData.Name, field) || change != "" {
+					line := watchRecordLine(reportData.Name, field, record)
+					if change != "" {
+						line = fmt.Sprintf("%s %s (%s)", watchHighlight(meta), line, change)
+					}
+					changes = append(changes, line)
+				}
+			}
+		}
+		if reportData.Name == githubReport {
+			sb.WriteString(fmt.Sprintf("%d open issues\n", records))
+		}
+		for _, line := range changes {
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	// records of the previous refresh that are not part of the report anymore have been resolved
+	resolved := []string{}
+	if previous != nil {
+		for _, reportData := range *previous {
+			for _, field := range reportData.Data {
+				for _, record := range field.Records {
+					if _, ok := previousRecords[recordKey(reportData.Name, field, record)]; ok && !isSummaryRecord(reportData.Name, record) {
+						resolved = append(resolved, watchRecordLine(reportData.Name, field, record))
+					}
+				}
+			}
+		}
+	}
+	if len(resolved) > 0 {
+		sb.WriteString("\nRESOLVED SINCE THE LAST REFRESH\n")
+		for _, line := range resolved {
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// hasSummaryRecord tells if the records of a field are summarized (like the jobs of a testgrid dashboard)
+func hasSummaryRecord(reportName string, field ReportDataField) bool {
+	for _, record := range field.Records {
+		if isSummaryRecord(reportName, record) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchRecordLine describes a record in one line
+func watchRecordLine(reportName string, field ReportDataField, record ReportDataRecord) string {
+	if reportName == githubReport {
+		// field.Title names the repository if multiple repositories are reported
+		return fmt.Sprintf("%s#%d %s", field.Title, record.ID, record.Title)
+	}
+	if record.Status == "" {
+		return fmt.Sprintf("%s (%s)", record.Title, field.Title)
+	}
+	return fmt.Sprintf("%s %s (%s)", record.Status, record.Title, field.Title)
+}
+
+// watchHighlight marks lines that changed since the previous refresh
+func watchHighlight(meta Meta) string {
+	if meta.Flags.EmojisOff {
+		return "CHANGED"
+	}
+	return statusNewEmoji
+}
+
+// countsDelta describes how the counts of a summary changed (like "failing +1, passing -1")
+func countsDelta(previous map[string]int, current map[string]int) string {
+	keys := []string{}
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	deltas := []string{}
+	for _, key := range keys {
+		if delta := current[key] - previous[key]; delta != 0 {
+			deltas = append(deltas, fmt.Sprintf("%s %+d", key, delta))
+		}
+	}
+	return strings.Join(deltas, ", ")
+}
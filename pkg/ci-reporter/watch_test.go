@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchScreen(t *testing.T) {
+	meta := Meta{Flags: metaFlags{EmojisOff: true, Interval: 10 * time.Minute}}
+	refreshedAt := time.Date(2021, 10, 20, 15, 4, 5, 0, time.UTC)
+	previous := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Counts: map[string]int{"total": 3, "passing": 2, "flaky": 1, "failing": 0}, Notes: []string{"3 jobs total"}},
+			{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FLAKY"},
+			{ID: testgridReportDetails, Title: "kind-master-parallel", Status: "FLAKY"},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{{Records: []ReportDataRecord{{ID: 105242, Title: "[Failing test] ci-kubernetes-e2e-gci-gce-serial"}}}}},
+	}
+	current := Report{
+		{Name: testgridReport, Data: []ReportDataField{{Title: "Master-Blocking", Records: []ReportDataRecord{
+			{ID: testgridReportSummary, Counts: map[string]int{"total": 3, "passing": 1, "flaky": 1, "failing": 1}, Notes: []string{"3 jobs total"}},
+			{ID: testgridReportDetails, Title: "gce-cos-master-default", Status: "FAILING"},
+			{ID: testgridReportDetails, Title: "gce-cos-master-serial", Status: "FLAKY"},
+		}}}},
+		{Name: githubReport, Data: []ReportDataField{
+			{Records: []ReportDataRecord{{ID: 105242, Title: "[Failing test] ci-kubernetes-e2e-gci-gce-serial"}}},
+			{Records: []ReportDataRecord{{ID: 106008, Title: "[Failing test] gce-cos-master-default"}}},
+		}},
+	}
+
+	expected := `CI SIGNAL REPORT refreshed 2021-10-20 15:04:05, next refresh in 10m0s
+
+TESTGRID REPORT
+CHANGED Master-Blocking: 3 jobs total (failing +1, passing -1)
+CHANGED FAILING gce-cos-master-default (Master-Blocking) (was FLAKY)
+CHANGED FLAKY gce-cos-master-serial (Master-Blocking) (new)
+
+GITHUB REPORT
+2 open issues
+CHANGED #106008 [Failing test] gce-cos-master-default (new)
+
+RESOLVED SINCE THE LAST REFRESH
+FLAKY kind-master-parallel (Master-Blocking)
+`
+	if screen := WatchScreen(meta, current, &previous, refreshedAt); screen != expected {
+		t.Errorf("expected screen\n%s\ngot\n%s", expected, screen)
+	}
+
+	// nothing is highlighted on the first refresh
+	first := WatchScreen(meta, current, nil, refreshedAt)
+	expectedFirst := `CI SIGNAL REPORT refreshed 2021-10-20 15:04:05, next refresh in 10m0s
+
+TESTGRID REPORT
+Master-Blocking: 3 jobs total
+FAILING gce-cos-master-default (Master-Blocking)
+FLAKY gce-cos-master-serial (Master-Blocking)
+
+GITHUB REPORT
+2 open issues
+`
+	if first != expectedFirst {
+		t.Errorf("expected screen\n%s\ngot\n%s", expectedFirst, first)
+	}
+}
+
+func TestRefreshWatchKeepsPreviousReportOnError(t *testing.T) {
+	var logs bytes.Buffer
+	meta := newTestMeta(metaFlags{SpecificReport: testgridReport, ShortOn: true, Interval: 10 * time.Minute})
+	meta.HTTPClient = &http.Client{Transport: failingTransport{}}
+	meta.Logger = NewLogger(&logs, LogLevelInfo)
+	previous := &Report{{Name: testgridReport}}
+
+	if refreshed := refreshWatch(context.Background(), meta, previous); refreshed != previous {
+		t.Errorf("expected the previous report to be kept if the report can not be requested but got %v", refreshed)
+	}
+	if !strings.Contains(logs.String(), "level=error") || !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("expected the error to be logged but got %q", logs.String())
+	}
+}
+
+func TestRefreshWatchPrintsToConsole(t *testing.T) {
+	var out bytes.Buffer
+	meta := newTestMeta(metaFlags{SpecificReport: testgridReport, ShortOn: true, Interval: 10 * time.Minute})
+	meta.Console = NewConsole(&out, false, false)
+	if refreshed := refreshWatch(context.Background(), meta, nil); refreshed == nil {
+		t.Fatal("expected the report to be refreshed")
+	}
+	if !strings.HasPrefix(out.String(), "CI SIGNAL REPORT refreshed ") {
+		t.Errorf("expected the screen to be printed without clearing the terminal if the console is no terminal, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "\033[") {
+		t.Errorf("expected no escape sequences if the console is no terminal, got %q", out.String())
+	}
+
+	out.Reset()
+	(&Console{w: &out, terminal: true}).clear()
+	if out.String() != clearScreen {
+		t.Errorf("expected a terminal to be cleared, got %q", out.String())
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"strings"
+	"sync"
+)
+
+// number of concurrent requests if -concurrency is not set
+const defaultConcurrency = 10
+
+// runWorkerPool runs task for the indices 0..n-1 with at most concurrency workers.
+// Tasks store their results at their index to keep the order of results deterministic,
+// the error of each task is returned at its index as well.
+func runWorkerPool(concurrency int, n int, task func(i int) error) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+	errs := make([]error, n)
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = task(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return errs
+}
+
+// runWorkerPoolInOrder runs task like runWorkerPool and calls done for each index as soon as its task and the tasks of all indices before it finished,
+// so results can be passed on while they are requested without changing their order
+func runWorkerPoolInOrder(concurrency int, n int, task func(i int) error, done func(i int)) []error {
+	finished := make([]chan struct{}, n)
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+	var errs []error
+	poolFinished := make(chan struct{})
+	go func() {
+		defer close(poolFinished)
+		errs = runWorkerPool(concurrency, n, func(i int) error {
+			defer close(finished[i])
+			return task(i)
+		})
+	}()
+	for i := 0; i < n; i++ {
+		<-finished[i]
+		done(i)
+	}
+	<-poolFinished
+	return errs
+}
+
+// workerErrors errors of the tasks of a worker pool that failed
+type workerErrors []error
+
+func (e workerErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// collectWorkerErrors returns the errors of all failed tasks in the order of the tasks (nil if no task failed)
+func collectWorkerErrors(errs []error) error {
+	failed := workerErrors{}
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cireporter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunWorkerPool(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	results := make([]int, 20)
+	errs := runWorkerPool(3, len(results), func(i int) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		results[i] = i * i
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if i%7 == 0 {
+			return fmt.Errorf("task %d failed", i)
+		}
+		return nil
+	})
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 workers, got %d", maxRunning)
+	}
+	for i, r := range results {
+		if r != i*i {
+			t.Errorf("expected result %d at index %d, got %d", i*i, i, r)
+		}
+	}
+	err := collectWorkerErrors(errs)
+	if err == nil || err.Error() != "task 0 failed; task 7 failed; task 14 failed" {
+		t.Errorf("expected the errors of the failed tasks in order, got %v", err)
+	}
+	if err := collectWorkerErrors(runWorkerPool(0, 2, func(i int) error { return nil })); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestRunWorkerPoolInOrder(t *testing.T) {
+	done := []int{}
+	errs := runWorkerPoolInOrder(4, 6, func(i int) error {
+		// later tasks finish first
+		time.Sleep(time.Duration(6-i) * time.Millisecond)
+		return nil
+	}, func(i int) {
+		done = append(done, i)
+	})
+	if fmt.Sprint(done) != "[0 1 2 3 4 5]" {
+		t.Errorf("expected the tasks to be done in order, got %v", done)
+	}
+	if err := collectWorkerErrors(errs); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}